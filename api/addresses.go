@@ -0,0 +1,140 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// MaxAccountAddresses is the maximum number of addresses in one multi-address account query
+const MaxAccountAddresses = 1000
+
+// getAddressesData loads balances and (depending on the option) txids of the addresses of a multi-address account,
+// the addresses are processed in the same way as the addresses derived from xpub
+func (w *Worker) getAddressesData(addresses []string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter) (*xpubData, uint32, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, 0, NewAPIError("Multiple addresses are supported only for bitcoin type coins", true)
+	}
+	if len(addresses) == 0 {
+		return nil, 0, NewAPIError("Missing address", true)
+	}
+	if len(addresses) > MaxAccountAddresses {
+		return nil, 0, NewAPIError(fmt.Sprintf("Too many addresses, maximum is %d", MaxAccountAddresses), true)
+	}
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, 0, errors.Annotatef(err, "GetBestBlock")
+	}
+	data := xpubData{
+		dataHeight: bestheight,
+		addresses:  make([]xpubAddress, 0, len(addresses)),
+	}
+	unique := make(map[string]struct{}, len(addresses))
+	for _, a := range addresses {
+		addrDesc, err := w.chainParser.GetAddrDescFromAddress(a)
+		if err != nil {
+			return nil, 0, NewAPIError(fmt.Sprintf("Invalid address '%v', %v", a, err), true)
+		}
+		if _, found := unique[string(addrDesc)]; found {
+			continue
+		}
+		unique[string(addrDesc)] = struct{}{}
+		ad := xpubAddress{addrDesc: addrDesc}
+		if _, err = w.xpubDerivedAddressBalance(&data, &ad); err != nil {
+			return nil, 0, err
+		}
+		if option >= AccountDetailsTxidHistory {
+			if err = w.xpubCheckAndLoadTxids(&ad, filter, bestheight, (page+1)*txsOnPage); err != nil {
+				return nil, 0, err
+			}
+		}
+		data.addresses = append(data.addresses, ad)
+	}
+	return &data, bestheight, nil
+}
+
+// GetAddresses computes combined balance of a list of addresses and gets their merged transaction history
+func (w *Worker) GetAddresses(addresses []string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter) (*Address, error) {
+	start := time.Now()
+	page--
+	if page < 0 {
+		page = 0
+	}
+	data, bestheight, err := w.getAddressesData(addresses, page, txsOnPage, option, filter)
+	if err != nil {
+		return nil, err
+	}
+	var addr Address
+	if err = w.xpubAddressesTxs(&addr, [][]xpubAddress{data.addresses}, data.txCountEstimate, bestheight, page, txsOnPage, option, filter); err != nil {
+		return nil, err
+	}
+	totalTokens := 0
+	var tokens []Token
+	if option > AccountDetailsBasic {
+		tokens = make([]Token, 0, 4)
+	}
+	for i := range data.addresses {
+		ad := &data.addresses[i]
+		if ad.balance != nil {
+			totalTokens++
+		}
+		if option > AccountDetailsBasic {
+			if filter.TokensToReturn == TokensToReturnDerived ||
+				filter.TokensToReturn == TokensToReturnUsed && ad.balance != nil ||
+				filter.TokensToReturn == TokensToReturnNonzeroBalance && ad.balance != nil && !IsZeroBigInt(&ad.balance.BalanceSat) {
+				tokens = append(tokens, w.tokenFromXpubAddress(data, ad, 0, i, option))
+			}
+		}
+	}
+	var totalReceived big.Int
+	totalReceived.Add(&data.balanceSat, &data.sentSat)
+	addr.BalanceSat = (*Amount)(&data.balanceSat)
+	addr.TotalReceivedSat = (*Amount)(&totalReceived)
+	addr.TotalSentSat = (*Amount)(&data.sentSat)
+	addr.TotalTokens = totalTokens
+	addr.Tokens = tokens
+	glog.Info("GetAddresses ", len(data.addresses), " addresses, ", addr.Txs, " confirmed txs, finished in ", time.Since(start))
+	return &addr, nil
+}
+
+// GetAddressesUtxo returns combined unspent outputs of a list of addresses
+func (w *Worker) GetAddressesUtxo(addresses []string, onlyConfirmed bool) (Utxos, error) {
+	start := time.Now()
+	data, _, err := w.getAddressesData(addresses, 0, 1, AccountDetailsBasic, &AddressFilter{
+		Vout:          AddressFilterVoutOff,
+		OnlyConfirmed: onlyConfirmed,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r := make(Utxos, 0, 8)
+	for i := range data.addresses {
+		ad := &data.addresses[i]
+		onlyMempool := false
+		if ad.balance == nil {
+			if onlyConfirmed {
+				continue
+			}
+			onlyMempool = true
+		}
+		utxos, err := w.getAddrDescUtxo(ad.addrDesc, ad.balance, onlyConfirmed, onlyMempool)
+		if err != nil {
+			return nil, err
+		}
+		if len(utxos) > 0 {
+			t := w.tokenFromXpubAddress(data, ad, 0, i, AccountDetailsTokens)
+			for j := range utxos {
+				utxos[j].Address = t.Name
+			}
+			r = append(r, utxos...)
+		}
+	}
+	sort.Stable(r)
+	glog.Info("GetAddressesUtxo ", len(data.addresses), " addresses, ", len(r), " utxos, finished in ", time.Since(start))
+	return r, nil
+}
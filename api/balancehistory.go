@@ -0,0 +1,74 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"math/big"
+	"sort"
+
+	"github.com/juju/errors"
+)
+
+// defaultBalanceHistoryGroupBy is the default length of the interval of the balance history in seconds
+const defaultBalanceHistoryGroupBy = 3600
+
+// GetBalanceHistory returns the balance history of an address or xpub in the intervals of groupBy seconds (0 means one hour)
+// in which there are confirmed transactions with the block time from fromTime to toTime (0 means no limit), ordered from
+// the oldest interval. It is computed from the rows of ExportHistory. If currencies are given, each interval contains
+// the fiat rates to them at the start of the interval. Only bitcoin type coins are supported.
+func (w *Worker) GetBalanceHistory(descriptor string, fromTime, toTime int64, groupBy int64, gap int, currencies []string) ([]BalanceHistory, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Balance history is not supported for this coin", true)
+	}
+	if groupBy <= 0 {
+		groupBy = defaultBalanceHistoryGroupBy
+	}
+	if toTime > 0 && fromTime > toTime {
+		return nil, NewAPIError("Invalid time range", true)
+	}
+	intervals := make(map[int64]*BalanceHistory)
+	err := w.ExportHistory(descriptor, 0, 0, gap, nil, func(row *ExportRow) error {
+		if row.Blocktime < fromTime || (toTime > 0 && row.Blocktime > toTime) {
+			return nil
+		}
+		t := row.Blocktime - row.Blocktime%groupBy
+		bh, found := intervals[t]
+		if !found {
+			// the rows are ordered from the newest transaction, the first row of the interval has the balance at its end
+			bh = &BalanceHistory{
+				Time:        t,
+				ReceivedSat: &Amount{},
+				SentSat:     &Amount{},
+				BalanceSat:  row.BalanceSat,
+			}
+			intervals[t] = bh
+		}
+		bh.Txs++
+		amount := (*big.Int)(row.AmountSat)
+		if amount.Sign() > 0 {
+			(*big.Int)(bh.ReceivedSat).Add((*big.Int)(bh.ReceivedSat), amount)
+		} else {
+			(*big.Int)(bh.SentSat).Sub((*big.Int)(bh.SentSat), amount)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	history := make([]BalanceHistory, 0, len(intervals))
+	for _, bh := range intervals {
+		history = append(history, *bh)
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].Time < history[j].Time })
+	if len(currencies) > 0 {
+		for i := range history {
+			t, err := w.findFiatRatesTicker(history[i].Time)
+			if err != nil {
+				return nil, errors.Annotatef(err, "findFiatRatesTicker %v", history[i].Time)
+			}
+			if t != nil {
+				history[i].Rates = NewFiatTicker(t, currencies).Rates
+			}
+		}
+	}
+	return history, nil
+}
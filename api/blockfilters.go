@@ -0,0 +1,107 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/juju/errors"
+)
+
+// MaxBlockFilterHeaders is the maximum number of filter headers returned by one request, the same as in BIP157
+const MaxBlockFilterHeaders = 2000
+
+// reversedHex returns the hash in the internal byte order as hex string in the reversed byte order
+func reversedHex(b []byte) string {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return hex.EncodeToString(r)
+}
+
+// getBlockHeightHash returns the height and hash of the block given by height or hash,
+// the block must be in the index, not orphaned
+func (w *Worker) getBlockHeightHash(bid string) (uint32, string, error) {
+	var height uint32
+	var hash string
+	h, err := strconv.Atoi(bid)
+	if err == nil && h >= 0 && h < int(maxUint32) {
+		height = uint32(h)
+		hash, err = w.db.GetBlockHash(height)
+		if err != nil {
+			return 0, "", errors.Annotatef(err, "GetBlockHash %v", height)
+		}
+	} else {
+		bh, err := w.chain.GetBlockHeader(bid)
+		if err != nil {
+			return 0, "", NewAPIError(fmt.Sprintf("Block %v not found", bid), true)
+		}
+		height = bh.Height
+		if hash, err = w.db.GetBlockHash(height); err != nil {
+			return 0, "", errors.Annotatef(err, "GetBlockHash %v", height)
+		}
+		if hash != bid {
+			hash = ""
+		}
+	}
+	if hash == "" {
+		return 0, "", NewAPIError(fmt.Sprintf("Block %v not found", bid), true)
+	}
+	return height, hash, nil
+}
+
+// GetBlockFilter returns the BIP158 basic filter of the block given by height or hash
+func (w *Worker) GetBlockFilter(bid string) (*BlockFilter, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	height, hash, err := w.getBlockHeightHash(bid)
+	if err != nil {
+		return nil, err
+	}
+	bf, err := w.db.GetBlockFilter(height)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlockFilter %v", height)
+	}
+	if bf == nil {
+		return nil, NewAPIError(fmt.Sprintf("Filter of block %v not found", bid), true)
+	}
+	r := &BlockFilter{
+		BlockHash:   hash,
+		BlockHeight: height,
+		Filter:      hex.EncodeToString(bf.Filter),
+	}
+	if bf.Header != nil {
+		r.FilterHeader = reversedHex(bf.Header)
+	}
+	return r, nil
+}
+
+// GetBlockFilterHeaders returns up to count filter headers of consecutive blocks starting at given height,
+// the result ends at the first block without a known filter header
+func (w *Worker) GetBlockFilterHeaders(start uint32, count int) (*BlockFilterHeaders, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	if count <= 0 || count > MaxBlockFilterHeaders {
+		return nil, NewAPIError(fmt.Sprintf("Count must be between 1 and %d", MaxBlockFilterHeaders), true)
+	}
+	headers, err := w.db.GetBlockFilterHeaders(start, count)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlockFilterHeaders %v %v", start, count)
+	}
+	if len(headers) == 0 {
+		return nil, NewAPIError(fmt.Sprintf("Filter header of block %v not found", start), true)
+	}
+	r := &BlockFilterHeaders{
+		Start:   start,
+		Count:   len(headers),
+		Headers: make([]string, len(headers)),
+	}
+	for i := range headers {
+		r.Headers[i] = reversedHex(headers[i])
+	}
+	return r, nil
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// The cursors are opaque strings pointing to the first item of the next page of a list ordered from the newest to the oldest items.
+// A history cursor contains the block height and the number of the items of the block preceding the position,
+// a mempool cursor contains the time and txid of the mempool transaction.
+const (
+	historyCursorPrefix = "h"
+	mempoolCursorPrefix = "m"
+)
+
+var errInvalidCursor = NewAPIError("Invalid cursor", true)
+
+func encodeCursor(prefix string, a, b string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(prefix + a + "." + b))
+}
+
+func decodeCursor(prefix string, cursor string) (string, string, error) {
+	d, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", errInvalidCursor
+	}
+	s := string(d)
+	if !strings.HasPrefix(s, prefix) {
+		return "", "", errInvalidCursor
+	}
+	i := strings.IndexByte(s, '.')
+	if i < 0 {
+		return "", "", errInvalidCursor
+	}
+	return s[len(prefix):i], s[i+1:], nil
+}
+
+func historyCursor(height uint32, index int) string {
+	return encodeCursor(historyCursorPrefix, strconv.FormatUint(uint64(height), 10), strconv.Itoa(index))
+}
+
+func parseHistoryCursor(cursor string) (uint32, int, error) {
+	h, i, err := decodeCursor(historyCursorPrefix, cursor)
+	if err != nil {
+		return 0, 0, err
+	}
+	height, err := strconv.ParseUint(h, 10, 32)
+	if err != nil {
+		return 0, 0, errInvalidCursor
+	}
+	index, err := strconv.Atoi(i)
+	if err != nil || index < 0 {
+		return 0, 0, errInvalidCursor
+	}
+	return uint32(height), index, nil
+}
+
+func mempoolCursor(time uint32, txid string) string {
+	return encodeCursor(mempoolCursorPrefix, strconv.FormatUint(uint64(time), 10), txid)
+}
+
+func parseMempoolCursor(cursor string) (uint32, string, error) {
+	t, txid, err := decodeCursor(mempoolCursorPrefix, cursor)
+	if err != nil {
+		return 0, "", err
+	}
+	time, err := strconv.ParseUint(t, 10, 32)
+	if err != nil || txid == "" {
+		return 0, "", errInvalidCursor
+	}
+	return uint32(time), txid, nil
+}
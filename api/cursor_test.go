@@ -0,0 +1,108 @@
+// +build unittest
+
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_historyCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		height uint32
+		index  int
+	}{
+		{name: "zero", height: 0, index: 0},
+		{name: "block", height: 225493, index: 2},
+		{name: "max", height: maxUint32, index: 100000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := historyCursor(tt.height, tt.index)
+			height, index, err := parseHistoryCursor(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if height != tt.height || index != tt.index {
+				t.Errorf("parseHistoryCursor(%v) = %v, %v, want %v, %v", c, height, index, tt.height, tt.index)
+			}
+		})
+	}
+	if c := historyCursor(225493, 0); c != "aDIyNTQ5My4w" {
+		t.Errorf("historyCursor() = %v, want aDIyNTQ5My4w", c)
+	}
+}
+
+func Test_parseCursor_invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"not base64!",
+		"eDEuMg",  // x1.2
+		"aDEuLTE", // h1.-1
+		"aDEy",    // h12
+		mempoolCursor(1600000000, "abc"),
+	}
+	for _, c := range tests {
+		if _, _, err := parseHistoryCursor(c); err != errInvalidCursor {
+			t.Errorf("parseHistoryCursor(%q) error = %v, want %v", c, err, errInvalidCursor)
+		}
+	}
+	if _, _, err := parseMempoolCursor(historyCursor(1, 2)); err != errInvalidCursor {
+		t.Errorf("parseMempoolCursor(history cursor) error = %v, want %v", err, errInvalidCursor)
+	}
+}
+
+func Test_mempoolCursor(t *testing.T) {
+	txid := "7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"
+	tm, id, err := parseMempoolCursor(mempoolCursor(1600000000, txid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tm != 1600000000 || id != txid {
+		t.Errorf("parseMempoolCursor() = %v, %v", tm, id)
+	}
+}
+
+func Test_xpubTxids_cursor(t *testing.T) {
+	txc := xpubTxids{
+		{txid: "a", height: 30},
+		{txid: "b", height: 20},
+		{txid: "c", height: 20},
+		{txid: "d", height: 20},
+		{txid: "e", height: 10},
+	}
+	var got []string
+	for i := range txc {
+		got = append(got, txc.cursor(i))
+	}
+	want := []string{historyCursor(30, 0), historyCursor(20, 0), historyCursor(20, 1), historyCursor(20, 2), historyCursor(10, 0)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cursor() = %v, want %v", got, want)
+	}
+	// every cursor must point back to its txid
+	for i := range txc {
+		height, skip, _ := parseHistoryCursor(txc.cursor(i))
+		if s := txc.cursorStart(height, skip); s != i {
+			t.Errorf("cursorStart(cursor(%d)) = %d", i, s)
+		}
+	}
+	tests := []struct {
+		name   string
+		height uint32
+		skip   int
+		want   int
+	}{
+		{name: "above all", height: 40, skip: 0, want: 0},
+		{name: "between blocks", height: 25, skip: 3, want: 1},
+		{name: "skip beyond block", height: 20, skip: 5, want: 4},
+		{name: "below all", height: 5, skip: 0, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := txc.cursorStart(tt.height, tt.skip); got != tt.want {
+				t.Errorf("cursorStart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
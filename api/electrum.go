@@ -0,0 +1,165 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"fmt"
+	"sort"
+
+	"github.com/juju/errors"
+)
+
+// ElectrumHistoryItem is a transaction of the scripthash history in the format of the Electrum protocol,
+// the height of a mempool transaction is 0, or -1 if the transaction has unconfirmed inputs
+type ElectrumHistoryItem struct {
+	Txid   string `json:"tx_hash"`
+	Height int    `json:"height"`
+	Fee    int64  `json:"fee,omitempty"`
+}
+
+// ElectrumBalance is the balance of a scripthash in the format of the Electrum protocol
+type ElectrumBalance struct {
+	Confirmed   int64 `json:"confirmed"`
+	Unconfirmed int64 `json:"unconfirmed"`
+}
+
+// ElectrumUtxo is an unspent output in the format of the Electrum protocol
+type ElectrumUtxo struct {
+	Txid   string `json:"tx_hash"`
+	Vout   int32  `json:"tx_pos"`
+	Height int    `json:"height"`
+	Value  int64  `json:"value"`
+}
+
+// ElectrumMerkle is the merkle branch of a transaction in the format of the Electrum protocol
+type ElectrumMerkle struct {
+	BlockHeight uint32   `json:"block_height"`
+	Merkle      []string `json:"merkle"`
+	Pos         int      `json:"pos"`
+}
+
+// GetElectrumHistory returns the confirmed transactions of the address descriptor in the blockchain order
+// followed by its mempool transactions
+func (w *Worker) GetElectrumHistory(addrDesc bchain.AddressDescriptor) ([]ElectrumHistoryItem, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	// the transactions come from the newest block, the order of transactions within a block is kept
+	var blocks [][]ElectrumHistoryItem
+	lastHeight := -1
+	err := w.db.GetAddrDescTransactions(addrDesc, 0, maxUint32, func(txid string, height uint32, indexes []int32) error {
+		if int(height) != lastHeight {
+			blocks = append(blocks, nil)
+			lastHeight = int(height)
+		}
+		blocks[len(blocks)-1] = append(blocks[len(blocks)-1], ElectrumHistoryItem{Txid: txid, Height: int(height)})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddrDescTransactions %v", addrDesc)
+	}
+	r := make([]ElectrumHistoryItem, 0, 8)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		r = append(r, blocks[i]...)
+	}
+	txm, err := w.getAddressTxids(addrDesc, true, &AddressFilter{Vout: AddressFilterVoutOff}, maxInt)
+	if err != nil {
+		return nil, errors.Annotatef(err, "getAddressTxids %v true", addrDesc)
+	}
+	mempool := make([]ElectrumHistoryItem, 0, len(txm))
+	for _, txid := range txm {
+		h := ElectrumHistoryItem{Txid: txid}
+		if p := w.mempool.GetTxPackage(txid); p != nil {
+			h.Fee = p.Fee.Int64()
+			if len(p.Parents) > 0 {
+				h.Height = -1
+			}
+		}
+		mempool = append(mempool, h)
+	}
+	// the order of mempool transactions must be stable, it is part of the scripthash status
+	sort.Slice(mempool, func(i, j int) bool {
+		if mempool[i].Height != mempool[j].Height {
+			return mempool[i].Height > mempool[j].Height
+		}
+		return mempool[i].Txid < mempool[j].Txid
+	})
+	return append(r, mempool...), nil
+}
+
+// GetElectrumBalance returns the confirmed balance of the address descriptor and the change of the balance by mempool transactions
+func (w *Worker) GetElectrumBalance(addrDesc bchain.AddressDescriptor) (*ElectrumBalance, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	ba, err := w.db.GetAddrDescBalance(addrDesc)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddrDescBalance %v", addrDesc)
+	}
+	r := &ElectrumBalance{}
+	// ba can be nil if the address is only in mempool
+	if ba != nil {
+		r.Confirmed = ba.BalanceSat.Int64()
+	}
+	txm, err := w.getAddressTxids(addrDesc, true, &AddressFilter{Vout: AddressFilterVoutOff}, maxInt)
+	if err != nil {
+		return nil, errors.Annotatef(err, "getAddressTxids %v true", addrDesc)
+	}
+	for _, txid := range txm {
+		tx, err := w.GetTransaction(txid, false, false)
+		// mempool transaction may fail, skip already confirmed txs, mempool may be out of sync
+		if err != nil || tx == nil || tx.Confirmations != 0 {
+			continue
+		}
+		r.Unconfirmed += tx.getAddrVoutValue(addrDesc).Int64()
+		r.Unconfirmed -= tx.getAddrVinValue(addrDesc).Int64()
+	}
+	return r, nil
+}
+
+// GetElectrumUtxo returns the unspent outputs of the address descriptor, the outputs of mempool transactions have height 0
+func (w *Worker) GetElectrumUtxo(addrDesc bchain.AddressDescriptor) ([]ElectrumUtxo, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	utxos, err := w.getAddrDescUtxo(addrDesc, nil, false, false)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]ElectrumUtxo, len(utxos))
+	for i := range utxos {
+		u := &utxos[i]
+		r[i] = ElectrumUtxo{
+			Txid:   u.Txid,
+			Vout:   u.Vout,
+			Height: u.Height,
+			Value:  u.AmountSat.AsInt64(),
+		}
+	}
+	return r, nil
+}
+
+// GetElectrumMerkle returns the merkle branch of the transaction in the block at given height
+func (w *Worker) GetElectrumMerkle(txid string, height uint32) (*ElectrumMerkle, error) {
+	p, err := w.getMerkleProof(txid, height)
+	if err != nil {
+		return nil, err
+	}
+	return &ElectrumMerkle{BlockHeight: p.BlockHeight, Merkle: p.Merkle, Pos: p.Pos}, nil
+}
+
+// GetElectrumFeeHistogram returns the mempool fee histogram as pairs of fee rate and virtual size
+// of the transactions paying at least the fee rate, ordered from the highest fee rate
+func (w *Worker) GetElectrumFeeHistogram() ([][2]float64, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	stats := computeMempoolStats(w.mempool.GetFeeEntries())
+	r := make([][2]float64, 0, len(stats.FeeHistogram))
+	for i := len(stats.FeeHistogram) - 1; i >= 0; i-- {
+		b := &stats.FeeHistogram[i]
+		if b.VSize > 0 {
+			r = append(r, [2]float64{b.FeeRate, float64(b.VSize)})
+		}
+	}
+	return r, nil
+}
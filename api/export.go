@@ -0,0 +1,236 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/db"
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// ExportCallback is called for each exported transaction, an error returned by the callback stops the export
+type ExportCallback func(row *ExportRow) error
+
+// exportTxid is a confirmed transaction of the exported account
+type exportTxid struct {
+	txid   string
+	height uint32
+}
+
+// exportState keeps the running balance of the account, which is computed backwards from the current balance,
+// and the info and fiat rates of the last processed block
+type exportState struct {
+	own        func(bchain.AddressDescriptor) bool
+	balance    big.Int
+	toHeight   uint32
+	currencies []string
+	blockInfo  *db.BlockInfo
+	rates      map[string]float64
+	onRow      ExportCallback
+}
+
+// ExportHistory streams the confirmed transactions of an address or xpub in blocks from fromHeight to toHeight (0 means the best block),
+// from the newest to the oldest transaction, to the callback. If currencies are given, the rows contain the fiat rates
+// to them at the time of the block. Only bitcoin type coins are supported.
+func (w *Worker) ExportHistory(descriptor string, fromHeight, toHeight uint32, gap int, currencies []string, onRow ExportCallback) error {
+	if w.chainType != bchain.ChainBitcoinType {
+		return NewAPIError("Export is not supported for this coin", true)
+	}
+	start := time.Now()
+	if toHeight == 0 {
+		toHeight = maxUint32
+	}
+	if fromHeight > toHeight {
+		return NewAPIError("Invalid block range", true)
+	}
+	es := exportState{toHeight: toHeight, currencies: currencies, onRow: onRow}
+	if _, err := w.chainParser.ParseXpub(descriptor); err == nil {
+		data, _, err := w.getXpubData(descriptor, 0, maxInt, AccountDetailsTxidHistory, &AddressFilter{Vout: AddressFilterVoutOff}, gap)
+		if err != nil {
+			return err
+		}
+		own := make(map[string]struct{})
+		unique := make(map[string]struct{})
+		var txc xpubTxids
+		for _, da := range [][]xpubAddress{data.addresses, data.changeAddresses} {
+			for i := range da {
+				ad := &da[i]
+				if ad.balance == nil {
+					continue
+				}
+				own[string(ad.addrDesc)] = struct{}{}
+				for _, txid := range ad.txids {
+					if _, found := unique[txid.txid]; !found {
+						unique[txid.txid] = struct{}{}
+						txc = append(txc, txid)
+					}
+				}
+			}
+		}
+		sort.Stable(txc)
+		txids := make([]exportTxid, 0, len(txc))
+		for i := range txc {
+			if txc[i].height >= fromHeight {
+				txids = append(txids, exportTxid{txid: txc[i].txid, height: txc[i].height})
+			}
+		}
+		es.own = func(addrDesc bchain.AddressDescriptor) bool {
+			_, found := own[string(addrDesc)]
+			return found
+		}
+		es.balance.Set(&data.balanceSat)
+		for i := range txids {
+			if err = w.exportTx(&es, &txids[i]); err != nil {
+				return err
+			}
+		}
+	} else {
+		addrDesc, _, err := w.getAddrDescAndNormalizeAddress(descriptor)
+		if err != nil {
+			return err
+		}
+		ba, err := w.db.GetAddrDescBalance(addrDesc)
+		if err != nil {
+			return NewAPIError(fmt.Sprintf("Address not found, %v", err), true)
+		}
+		if ba == nil {
+			return nil
+		}
+		es.own = func(ad bchain.AddressDescriptor) bool {
+			return bytes.Equal(ad, addrDesc)
+		}
+		es.balance.Set(&ba.BalanceSat)
+		// the transactions are processed directly from the db iterator so that the history is not held in memory
+		err = w.db.GetAddrDescTransactions(addrDesc, fromHeight, maxUint32, func(txid string, height uint32, indexes []int32) error {
+			return w.exportTx(&es, &exportTxid{txid: txid, height: height})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	glog.Info("ExportHistory ", descriptor, " finished in ", time.Since(start))
+	return nil
+}
+
+// exportTx computes the export row of the transaction and passes it to the callback if the transaction is in the exported range,
+// the transactions after the range are processed only to get the running balance
+func (w *Worker) exportTx(es *exportState, et *exportTxid) error {
+	ta, err := w.db.GetTxAddresses(et.txid)
+	if err != nil {
+		return errors.Annotatef(err, "GetTxAddresses %v", et.txid)
+	}
+	if ta == nil {
+		glog.Warning("DB inconsistency:  tx ", et.txid, ": not found in txAddresses")
+		return nil
+	}
+	row := newExportRow(w.chainParser, et.txid, ta, es.own)
+	balance := new(big.Int).Set(&es.balance)
+	row.BalanceSat = (*Amount)(balance)
+	es.balance.Sub(&es.balance, (*big.Int)(row.AmountSat))
+	if et.height > es.toHeight {
+		return nil
+	}
+	if es.blockInfo == nil || es.blockInfo.Height != et.height {
+		es.blockInfo, err = w.db.GetBlockInfo(et.height)
+		if err != nil {
+			return errors.Annotatef(err, "GetBlockInfo %v", et.height)
+		}
+		if es.blockInfo == nil {
+			glog.Warning("DB inconsistency:  block height ", et.height, ": not found in db")
+			es.blockInfo = &db.BlockInfo{}
+		}
+		es.blockInfo.Height = et.height
+		es.rates = nil
+		if len(es.currencies) > 0 && es.blockInfo.Time > 0 {
+			t, err := w.findFiatRatesTicker(es.blockInfo.Time)
+			if err != nil {
+				return errors.Annotatef(err, "findFiatRatesTicker %v", es.blockInfo.Time)
+			}
+			if t != nil {
+				es.rates = NewFiatTicker(t, es.currencies).Rates
+			}
+		}
+	}
+	row.Blocktime = es.blockInfo.Time
+	row.Rates = es.rates
+	return es.onRow(row)
+}
+
+// newExportRow computes the direction, amount, fee and counterparties of the transaction from the point of view of the account
+// owning the address descriptors for which the function own returns true. The fee is charged only if the account funded all inputs,
+// in a transaction with inputs of other parties (coinjoin, payjoin) the share of the fee paid by the account cannot be determined,
+// it is contained only in the amount and the fee is 0
+func newExportRow(parser bchain.BlockChainParser, txid string, ta *db.TxAddresses, own func(bchain.AddressDescriptor) bool) *ExportRow {
+	var valInSat, valOutSat, amountSat, feeSat big.Int
+	var inputAddresses, outputAddresses []string
+	allInputsOwn, allOutputsOwn := len(ta.Inputs) > 0, true
+	addAddresses := func(addresses []string, addrDesc bchain.AddressDescriptor) []string {
+		a, _, err := parser.GetAddressesFromAddrDesc(addrDesc)
+		if err != nil {
+			glog.V(2).Infof("GetAddressesFromAddrDesc error %v, %v", err, addrDesc)
+		}
+		for _, s := range a {
+			found := false
+			for _, e := range addresses {
+				if e == s {
+					found = true
+					break
+				}
+			}
+			if !found {
+				addresses = append(addresses, s)
+			}
+		}
+		return addresses
+	}
+	for i := range ta.Inputs {
+		tai := &ta.Inputs[i]
+		valInSat.Add(&valInSat, &tai.ValueSat)
+		if own(tai.AddrDesc) {
+			amountSat.Sub(&amountSat, &tai.ValueSat)
+		} else {
+			allInputsOwn = false
+			inputAddresses = addAddresses(inputAddresses, tai.AddrDesc)
+		}
+	}
+	for i := range ta.Outputs {
+		tao := &ta.Outputs[i]
+		valOutSat.Add(&valOutSat, &tao.ValueSat)
+		if own(tao.AddrDesc) {
+			amountSat.Add(&amountSat, &tao.ValueSat)
+		} else {
+			allOutputsOwn = false
+			outputAddresses = addAddresses(outputAddresses, tao.AddrDesc)
+		}
+	}
+	row := ExportRow{
+		Blockheight: ta.Height,
+		Txid:        txid,
+		AmountSat:   (*Amount)(&amountSat),
+		FeeSat:      (*Amount)(&feeSat),
+	}
+	if allInputsOwn {
+		feeSat.Sub(&valInSat, &valOutSat)
+		if feeSat.Sign() == -1 {
+			feeSat.SetUint64(0)
+		}
+	}
+	if allInputsOwn && allOutputsOwn {
+		row.Direction = ExportDirectionSelf
+	} else if amountSat.Sign() >= 0 {
+		row.Direction = ExportDirectionIncoming
+		row.Counterparties = inputAddresses
+	} else {
+		row.Direction = ExportDirectionOutgoing
+		row.Counterparties = outputAddresses
+	}
+	if row.Counterparties == nil {
+		row.Counterparties = []string{}
+	}
+	return &row
+}
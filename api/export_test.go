@@ -0,0 +1,111 @@
+// +build unittest
+
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"blockbook/db"
+	"blockbook/tests/dbtestdata"
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func Test_newExportRow(t *testing.T) {
+	parser := btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1})
+	addrDesc := func(address string) bchain.AddressDescriptor {
+		ad, err := parser.GetAddrDescFromAddress(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ad
+	}
+	// the account owns Addr1 and Addr2, Addr3 and Addr5 belong to other parties
+	own1, own2, other3, other5 := addrDesc(dbtestdata.Addr1), addrDesc(dbtestdata.Addr2), addrDesc(dbtestdata.Addr3), addrDesc(dbtestdata.Addr5)
+	own := func(ad bchain.AddressDescriptor) bool {
+		return bytes.Equal(ad, own1) || bytes.Equal(ad, own2)
+	}
+	input := func(ad bchain.AddressDescriptor, value int64) db.TxInput {
+		return db.TxInput{AddrDesc: ad, ValueSat: *big.NewInt(value)}
+	}
+	output := func(ad bchain.AddressDescriptor, value int64) db.TxOutput {
+		return db.TxOutput{AddrDesc: ad, ValueSat: *big.NewInt(value)}
+	}
+	tests := []struct {
+		name           string
+		ta             db.TxAddresses
+		direction      string
+		amount         int64
+		fee            int64
+		counterparties []string
+	}{
+		{
+			name:           "self",
+			ta:             db.TxAddresses{Inputs: []db.TxInput{input(own1, 1000)}, Outputs: []db.TxOutput{output(own2, 900)}},
+			direction:      ExportDirectionSelf,
+			amount:         -100,
+			fee:            100,
+			counterparties: []string{},
+		},
+		{
+			name:           "outgoing",
+			ta:             db.TxAddresses{Inputs: []db.TxInput{input(own1, 1000), input(own2, 500)}, Outputs: []db.TxOutput{output(other3, 1200), output(own2, 200)}},
+			direction:      ExportDirectionOutgoing,
+			amount:         -1300,
+			fee:            100,
+			counterparties: []string{dbtestdata.Addr3},
+		},
+		{
+			name:           "incoming",
+			ta:             db.TxAddresses{Inputs: []db.TxInput{input(other3, 1000)}, Outputs: []db.TxOutput{output(own1, 900), output(other5, 50)}},
+			direction:      ExportDirectionIncoming,
+			amount:         900,
+			fee:            0,
+			counterparties: []string{dbtestdata.Addr3},
+		},
+		{
+			// the share of the fee of the account is unknown, it is only a part of the amount
+			name:           "outgoing with mixed inputs",
+			ta:             db.TxAddresses{Inputs: []db.TxInput{input(own1, 1000), input(other3, 2000)}, Outputs: []db.TxOutput{output(other5, 2500), output(own2, 400)}},
+			direction:      ExportDirectionOutgoing,
+			amount:         -600,
+			fee:            0,
+			counterparties: []string{dbtestdata.Addr5},
+		},
+		{
+			name:           "incoming with mixed inputs to own outputs",
+			ta:             db.TxAddresses{Inputs: []db.TxInput{input(own1, 500), input(other3, 500)}, Outputs: []db.TxOutput{output(own2, 900)}},
+			direction:      ExportDirectionIncoming,
+			amount:         400,
+			fee:            0,
+			counterparties: []string{dbtestdata.Addr3},
+		},
+		{
+			name:           "coinbase",
+			ta:             db.TxAddresses{Outputs: []db.TxOutput{output(own1, 5000)}},
+			direction:      ExportDirectionIncoming,
+			amount:         5000,
+			fee:            0,
+			counterparties: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := newExportRow(parser, "txid", &tt.ta, own)
+			if row.Direction != tt.direction {
+				t.Errorf("Direction = %v, want %v", row.Direction, tt.direction)
+			}
+			if (*big.Int)(row.AmountSat).Int64() != tt.amount {
+				t.Errorf("AmountSat = %v, want %v", (*big.Int)(row.AmountSat), tt.amount)
+			}
+			if (*big.Int)(row.FeeSat).Int64() != tt.fee {
+				t.Errorf("FeeSat = %v, want %v", (*big.Int)(row.FeeSat), tt.fee)
+			}
+			if !reflect.DeepEqual(row.Counterparties, tt.counterparties) {
+				t.Errorf("Counterparties = %v, want %v", row.Counterparties, tt.counterparties)
+			}
+		})
+	}
+}
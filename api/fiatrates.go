@@ -0,0 +1,75 @@
+package api
+
+import (
+	"blockbook/db"
+	"fmt"
+
+	"github.com/juju/errors"
+)
+
+// NewFiatTicker returns the rates of the ticker to the given currencies, all rates of the ticker if no currency is given
+func NewFiatTicker(t *db.CurrencyRatesTicker, currencies []string) *FiatTicker {
+	if len(currencies) == 0 {
+		return &FiatTicker{Timestamp: t.Timestamp, Rates: t.Rates}
+	}
+	rates := make(map[string]float64, len(currencies))
+	for _, c := range currencies {
+		r, found := t.Rates[c]
+		if !found {
+			r = -1
+		}
+		rates[c] = r
+	}
+	return &FiatTicker{Timestamp: t.Timestamp, Rates: rates}
+}
+
+// findFiatRatesTicker returns the ticker valid at the timestamp, the hourly ticker of the hour or the daily ticker of the day,
+// or the newest ticker for timestamp <= 0; it returns nil if there is no such ticker
+func (w *Worker) findFiatRatesTicker(timestamp int64) (*db.CurrencyRatesTicker, error) {
+	if timestamp <= 0 {
+		t, err := w.db.FiatRatesGetLastTicker(db.FiatRatesHourly)
+		if err == nil && t == nil {
+			t, err = w.db.FiatRatesGetLastTicker(db.FiatRatesDaily)
+		}
+		return t, err
+	}
+	t, err := w.db.FiatRatesFindTicker(db.FiatRatesHourly, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if t != nil && timestamp-t.Timestamp < 3600 {
+		return t, nil
+	}
+	t, err = w.db.FiatRatesFindTicker(db.FiatRatesDaily, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if t != nil && timestamp-t.Timestamp < 86400 {
+		return t, nil
+	}
+	return nil, nil
+}
+
+// GetFiatRatesForTimestamp returns the rates valid at the timestamp (the current rates for timestamp <= 0) to the given currencies
+func (w *Worker) GetFiatRatesForTimestamp(timestamp int64, currencies []string) (*FiatTicker, error) {
+	t, err := w.findFiatRatesTicker(timestamp)
+	if err != nil {
+		return nil, errors.Annotatef(err, "findFiatRatesTicker %v", timestamp)
+	}
+	if t == nil {
+		return nil, NewAPIError(fmt.Sprintf("No fiat rates available for timestamp %d", timestamp), true)
+	}
+	return NewFiatTicker(t, currencies), nil
+}
+
+// SetTxFiatRates sets the rates to the given currencies valid at the time of the transaction, the rates are not set if not available
+func (w *Worker) SetTxFiatRates(tx *Tx, currencies []string) error {
+	t, err := w.findFiatRatesTicker(tx.Blocktime)
+	if err != nil {
+		return errors.Annotatef(err, "findFiatRatesTicker %v", tx.Blocktime)
+	}
+	if t != nil {
+		tx.Rates = NewFiatTicker(t, currencies).Rates
+	}
+	return nil
+}
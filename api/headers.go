@@ -0,0 +1,85 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/juju/errors"
+)
+
+// MaxBlockHeaders is the maximum number of block headers returned by one request
+const MaxBlockHeaders = 2016
+
+// GetBlockHeaderRaw returns the raw header of the block at given height, the header is read from the db
+// or from the backend if it is not stored yet
+func (w *Worker) GetBlockHeaderRaw(height uint32) ([]byte, error) {
+	headers, err := w.GetBlockHeadersRaw(height, 1)
+	if err != nil {
+		return nil, err
+	}
+	return headers[0], nil
+}
+
+// GetBlockHeadersRaw returns the raw headers of up to count blocks starting at given height,
+// the result ends at the best block, the headers not yet stored in the db are fetched from the backend
+func (w *Worker) GetBlockHeadersRaw(start uint32, count int) ([][]byte, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	if count <= 0 || count > MaxBlockHeaders {
+		return nil, NewAPIError(fmt.Sprintf("Count must be between 1 and %d", MaxBlockHeaders), true)
+	}
+	bestHeight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	if start > bestHeight {
+		return nil, NewAPIError(fmt.Sprintf("Block %v not found", start), true)
+	}
+	if uint32(count-1) > bestHeight-start {
+		count = int(bestHeight-start) + 1
+	}
+	headers, err := w.db.GetBlockHeadersRaw(start, count)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlockHeadersRaw %v %v", start, count)
+	}
+	for height := start + uint32(len(headers)); len(headers) < count; height++ {
+		hash, err := w.db.GetBlockHash(height)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBlockHash %v", height)
+		}
+		if hash == "" {
+			if hash, err = w.chain.GetBlockHash(height); err != nil {
+				return nil, errors.Annotatef(err, "GetBlockHash %v", height)
+			}
+		}
+		h, err := w.chain.GetBlockHeaderRaw(hash)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBlockHeaderRaw %v", hash)
+		}
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBlockHeaderRaw %v", hash)
+		}
+		headers = append(headers, b)
+	}
+	return headers, nil
+}
+
+// GetBlockHeaders returns the hex encoded raw headers of up to count blocks starting at given height
+func (w *Worker) GetBlockHeaders(start uint32, count int) (*BlockHeaders, error) {
+	headers, err := w.GetBlockHeadersRaw(start, count)
+	if err != nil {
+		return nil, err
+	}
+	r := &BlockHeaders{
+		Start:   start,
+		Count:   len(headers),
+		Headers: make([]string, len(headers)),
+	}
+	for i := range headers {
+		r.Headers[i] = hex.EncodeToString(headers[i])
+	}
+	return r, nil
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+)
+
+// projectedBlockVSize is the virtual size of a projected block
+const projectedBlockVSize = 1000000
+
+// maxProjectedBlocks is the max number of projected blocks, the last block contains all remaining transactions
+const maxProjectedBlocks = 8
+
+// mempoolFeeRateBuckets are the lower bounds of the fee rate histogram buckets in satoshi per vbyte
+var mempoolFeeRateBuckets = []float64{0, 1, 2, 3, 4, 5, 6, 8, 10, 12, 15, 20, 30, 40, 50, 60, 70, 80, 100, 125, 150, 175, 200, 250, 300, 350, 400, 500, 600, 700, 800, 900, 1000, 1200, 1400, 1700, 2000}
+
+// GetMempoolStats returns fee rate histogram, total virtual size and projected blocks of the mempool transactions
+func (w *Worker) GetMempoolStats() (*MempoolStats, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Mempool statistics are not supported", true)
+	}
+	_, _, mempoolSize := w.is.GetMempoolSyncState()
+	stats := computeMempoolStats(w.mempool.GetFeeEntries())
+	stats.MempoolSize = mempoolSize
+	return stats, nil
+}
+
+// GetMempoolTxPackage returns in-mempool dependencies of the transaction with aggregated fees and sizes of its ancestors and descendants
+func (w *Worker) GetMempoolTxPackage(txid string) (*MempoolTxPackage, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Mempool packages are not supported", true)
+	}
+	p := w.getMempoolTxPackage(txid)
+	if p == nil {
+		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found in mempool", txid), true)
+	}
+	return p, nil
+}
+
+// getMempoolTxPackage returns the package of the mempool transaction or nil if the transaction is not in mempool
+// or its fee is not known
+func (w *Worker) getMempoolTxPackage(txid string) *MempoolTxPackage {
+	p := w.mempool.GetTxPackage(txid)
+	if p == nil || p.VSize == 0 {
+		return nil
+	}
+	return &MempoolTxPackage{
+		Txid:             p.Txid,
+		VSize:            int64(p.VSize),
+		FeeRate:          roundFeeRate(p.FeeRate),
+		Parents:          p.Parents,
+		Children:         p.Children,
+		AncestorCount:    p.AncestorCount,
+		AncestorVSize:    p.AncestorVSize,
+		AncestorFees:     (*Amount)(&p.AncestorFee),
+		DescendantCount:  p.DescendantCount,
+		DescendantVSize:  p.DescendantVSize,
+		DescendantFees:   (*Amount)(&p.DescendantFee),
+		AncestorFeeRate:  roundFeeRate(p.AncestorFeeRate),
+		PackageFeeRate:   roundFeeRate(p.PackageFeeRate),
+		EffectiveFeeRate: roundFeeRate(p.EffectiveFeeRate),
+	}
+}
+
+func roundFeeRate(r float64) float64 {
+	return math.Round(r*100) / 100
+}
+
+// computeMempoolStats computes the statistics from the fee entries, the entries are reordered by fee rate
+func computeMempoolStats(entries []bchain.MempoolFeeEntry) *MempoolStats {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].FeeRate > entries[j].FeeRate
+	})
+	var totalFees big.Int
+	buckets := make([]MempoolFeeRateBucket, len(mempoolFeeRateBuckets))
+	for i := range buckets {
+		buckets[i].FeeRate = mempoolFeeRateBuckets[i]
+	}
+	stats := &MempoolStats{
+		TxCount:         len(entries),
+		FeeHistogram:    []MempoolFeeRateBucket{},
+		ProjectedBlocks: []MempoolProjectedBlock{},
+	}
+	// split the entries to projected blocks, blockStarts contains index of the first entry of each block
+	var blockStarts []int
+	var blockVSize int64
+	for i := range entries {
+		e := &entries[i]
+		vsize := int64(e.VSize)
+		stats.TotalVSize += vsize
+		totalFees.Add(&totalFees, &e.Fee)
+		// find the bucket with the highest lower bound not greater than the fee rate
+		b := sort.Search(len(buckets), func(j int) bool { return buckets[j].FeeRate > e.FeeRate }) - 1
+		if b < 0 {
+			b = 0
+		}
+		buckets[b].TxCount++
+		buckets[b].VSize += vsize
+		if len(blockStarts) == 0 || (blockVSize+vsize > projectedBlockVSize && len(blockStarts) < maxProjectedBlocks) {
+			blockStarts = append(blockStarts, i)
+			blockVSize = 0
+		}
+		blockVSize += vsize
+	}
+	stats.TotalFees = (*Amount)(&totalFees)
+	for i := range buckets {
+		if buckets[i].TxCount > 0 {
+			stats.FeeHistogram = append(stats.FeeHistogram, buckets[i])
+		}
+	}
+	for i, from := range blockStarts {
+		to := len(entries)
+		if i+1 < len(blockStarts) {
+			to = blockStarts[i+1]
+		}
+		stats.ProjectedBlocks = append(stats.ProjectedBlocks, projectBlock(entries[from:to]))
+	}
+	return stats
+}
+
+// projectBlock computes data of a projected block from the entries sorted by fee rate in descending order,
+// the median fee rate is weighted by the virtual size of the transactions
+func projectBlock(entries []bchain.MempoolFeeEntry) MempoolProjectedBlock {
+	var fees big.Int
+	pb := MempoolProjectedBlock{
+		TxCount:    len(entries),
+		MaxFeeRate: roundFeeRate(entries[0].FeeRate),
+		MinFeeRate: roundFeeRate(entries[len(entries)-1].FeeRate),
+	}
+	for i := range entries {
+		pb.VSize += int64(entries[i].VSize)
+		fees.Add(&fees, &entries[i].Fee)
+	}
+	pb.TotalFees = (*Amount)(&fees)
+	var vsize int64
+	for i := range entries {
+		vsize += int64(entries[i].VSize)
+		if 2*vsize >= pb.VSize {
+			pb.MedianFeeRate = roundFeeRate(entries[i].FeeRate)
+			break
+		}
+	}
+	return pb
+}
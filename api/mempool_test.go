@@ -0,0 +1,67 @@
+// +build unittest
+
+package api
+
+import (
+	"blockbook/bchain"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func newFeeEntry(fee int64, vsize uint32) bchain.MempoolFeeEntry {
+	return bchain.MempoolFeeEntry{
+		Fee:     *big.NewInt(fee),
+		VSize:   vsize,
+		FeeRate: float64(fee) / float64(vsize),
+	}
+}
+
+func Test_computeMempoolStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []bchain.MempoolFeeEntry
+		want    string
+	}{
+		{
+			name: "empty",
+			want: `{"mempoolSize":0,"txCount":0,"totalVSize":0,"totalFees":"0","feeHistogram":[],"projectedBlocks":[]}`,
+		},
+		{
+			name: "one block",
+			entries: []bchain.MempoolFeeEntry{
+				newFeeEntry(226, 226),
+				newFeeEntry(1000, 100),
+				newFeeEntry(50, 200),
+				newFeeEntry(2260, 226),
+			},
+			want: `{"mempoolSize":0,"txCount":4,"totalVSize":752,"totalFees":"3536","feeHistogram":[` +
+				`{"feeRate":0,"txCount":1,"vsize":200},{"feeRate":1,"txCount":1,"vsize":226},{"feeRate":10,"txCount":2,"vsize":326}],` +
+				`"projectedBlocks":[{"txCount":4,"vsize":752,"totalFees":"3536","minFeeRate":0.25,"medianFeeRate":1,"maxFeeRate":10}]}`,
+		},
+		{
+			name: "more blocks",
+			entries: []bchain.MempoolFeeEntry{
+				newFeeEntry(800000, 400000),
+				newFeeEntry(1200000, 400000),
+				newFeeEntry(400000, 400000),
+				newFeeEntry(1500, 1000),
+			},
+			want: `{"mempoolSize":0,"txCount":4,"totalVSize":1201000,"totalFees":"2401500","feeHistogram":[` +
+				`{"feeRate":1,"txCount":2,"vsize":401000},{"feeRate":2,"txCount":1,"vsize":400000},{"feeRate":3,"txCount":1,"vsize":400000}],` +
+				`"projectedBlocks":[{"txCount":3,"vsize":801000,"totalFees":"2001500","minFeeRate":1.5,"medianFeeRate":2,"maxFeeRate":3},` +
+				`{"txCount":1,"vsize":400000,"totalFees":"400000","minFeeRate":1,"medianFeeRate":1,"maxFeeRate":1}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(computeMempoolStats(tt.entries))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("computeMempoolStats() = %v, want %v", string(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"fmt"
+
+	"github.com/juju/errors"
+)
+
+// getBlockTxids returns the hash and the txids of the block at given height, the txids are taken from the index
+// if the block is still kept in the blockTxs column, otherwise they are fetched from the backend
+func (w *Worker) getBlockTxids(height uint32) (string, []string, error) {
+	bi, err := w.db.GetBlockInfo(height)
+	if err != nil {
+		return "", nil, errors.Annotatef(err, "GetBlockInfo %v", height)
+	}
+	if bi == nil {
+		return "", nil, NewAPIError(fmt.Sprintf("Block %v not found", height), true)
+	}
+	txids, err := w.db.GetBlockTxids(height)
+	if err != nil {
+		return "", nil, errors.Annotatef(err, "GetBlockTxids %v", height)
+	}
+	if len(txids) == int(bi.Txs) {
+		return bi.Hash, txids, nil
+	}
+	cbi, err := w.chain.GetBlockInfo(bi.Hash)
+	if err != nil {
+		return "", nil, errors.Annotatef(err, "GetBlockInfo %v", bi.Hash)
+	}
+	return bi.Hash, cbi.Txids, nil
+}
+
+// getMerkleProof returns the merkle proof of the transaction in the block at given height
+func (w *Worker) getMerkleProof(txid string, height uint32) (*MerkleProof, error) {
+	hash, txids, err := w.getBlockTxids(height)
+	if err != nil {
+		return nil, err
+	}
+	for pos := range txids {
+		if txids[pos] == txid {
+			branch, err := bchain.GetMerkleBranch(txids, pos)
+			if err != nil {
+				return nil, err
+			}
+			if branch == nil {
+				branch = []string{}
+			}
+			return &MerkleProof{
+				Txid:        txid,
+				BlockHash:   hash,
+				BlockHeight: height,
+				Pos:         pos,
+				Merkle:      branch,
+			}, nil
+		}
+	}
+	return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not in block %v", txid, height), true)
+}
+
+// GetMerkleProof returns the merkle proof of the inclusion of the transaction in its block
+func (w *Worker) GetMerkleProof(txid string) (*MerkleProof, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	ta, err := w.db.GetTxAddresses(txid)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Invalid txid '%v', %v", txid, err), true)
+	}
+	if ta == nil {
+		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found in any block", txid), true)
+	}
+	return w.getMerkleProof(txid, ta.Height)
+}
@@ -177,31 +177,44 @@ type EthereumSpecific struct {
 
 // Tx holds information about a transaction
 type Tx struct {
-	Txid             string            `json:"txid"`
-	Version          int32             `json:"version,omitempty"`
-	Locktime         uint32            `json:"locktime,omitempty"`
-	Vin              []Vin             `json:"vin"`
-	Vout             []Vout            `json:"vout"`
-	Blockhash        string            `json:"blockhash,omitempty"`
-	Blockheight      int               `json:"blockheight"`
-	Confirmations    uint32            `json:"confirmations"`
-	Blocktime        int64             `json:"blocktime"`
-	Size             int               `json:"size,omitempty"`
-	ValueOutSat      *Amount           `json:"value"`
-	ValueInSat       *Amount           `json:"valueIn,omitempty"`
-	FeesSat          *Amount           `json:"fees,omitempty"`
-	Hex              string            `json:"hex,omitempty"`
-	CoinSpecificData interface{}       `json:"-"`
-	CoinSpecificJSON json.RawMessage   `json:"-"`
-	TokenTransfers   []TokenTransfer   `json:"tokentransfers,omitempty"`
-	EthereumSpecific *EthereumSpecific `json:"ethereumspecific,omitempty"`
-}
-
-// Paging contains information about paging for address, blocks and block
+	Txid             string             `json:"txid"`
+	Version          int32              `json:"version,omitempty"`
+	Locktime         uint32             `json:"locktime,omitempty"`
+	Vin              []Vin              `json:"vin"`
+	Vout             []Vout             `json:"vout"`
+	Blockhash        string             `json:"blockhash,omitempty"`
+	Blockheight      int                `json:"blockheight"`
+	Confirmations    uint32             `json:"confirmations"`
+	Blocktime        int64              `json:"blocktime"`
+	Size             int                `json:"size,omitempty"`
+	ValueOutSat      *Amount            `json:"value"`
+	ValueInSat       *Amount            `json:"valueIn,omitempty"`
+	FeesSat          *Amount            `json:"fees,omitempty"`
+	Hex              string             `json:"hex,omitempty"`
+	Rbf              bool               `json:"rbf,omitempty"`
+	ReplacedBy       string             `json:"replacedBy,omitempty"`
+	MempoolPackage   *MempoolTxPackage  `json:"mempoolPackage,omitempty"`
+	CoinSpecificData interface{}        `json:"-"`
+	CoinSpecificJSON json.RawMessage    `json:"-"`
+	TokenTransfers   []TokenTransfer    `json:"tokentransfers,omitempty"`
+	EthereumSpecific *EthereumSpecific  `json:"ethereumspecific,omitempty"`
+	Rates            map[string]float64 `json:"rates,omitempty"`
+}
+
+// TxResult is an item of the result of batch transaction lookup, either Tx or Error is set
+type TxResult struct {
+	Txid  string `json:"txid"`
+	Tx    *Tx    `json:"tx,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Paging contains information about paging for address, blocks and block,
+// NextCursor is set if there are more items after the returned page and the list supports cursors
 type Paging struct {
-	Page        int `json:"page,omitempty"`
-	TotalPages  int `json:"totalPages,omitempty"`
-	ItemsOnPage int `json:"itemsOnPage,omitempty"`
+	Page        int    `json:"page,omitempty"`
+	TotalPages  int    `json:"totalPages,omitempty"`
+	ItemsOnPage int    `json:"itemsOnPage,omitempty"`
+	NextCursor  string `json:"nextCursor,omitempty"`
 }
 
 // TokensToReturn specifies what tokens are returned by GetAddress and GetXpubAddress
@@ -232,6 +245,9 @@ type AddressFilter struct {
 	TokensToReturn TokensToReturn
 	// OnlyConfirmed set to true will ignore mempool transactions; mempool is also ignored if FromHeight/ToHeight filter is specified
 	OnlyConfirmed bool
+	// Cursor is the nextCursor returned with the previous page, if set, the page starts at the cursor instead of the page number
+	// and the mempool transactions (returned only on the first page) are not listed
+	Cursor string
 }
 
 // Address holds information about address and its transactions
@@ -285,6 +301,95 @@ func (a Utxos) Less(i, j int) bool {
 	return hi >= hj
 }
 
+// XpubDiscoveryAddress is a used address of an xpub chain found by probing beyond the discovered addresses,
+// Gap is the distance from the previous used address, i.e. the gap parameter necessary to discover the address
+type XpubDiscoveryAddress struct {
+	Address    string  `json:"address"`
+	Path       string  `json:"path"`
+	Index      int     `json:"index"`
+	Gap        int     `json:"gap"`
+	Transfers  int     `json:"transfers"`
+	BalanceSat *Amount `json:"balance"`
+}
+
+// XpubDiscoveryChain contains the result of the discovery of one derivation chain (receiving or change addresses) of an xpub
+type XpubDiscoveryChain struct {
+	Chain               uint32                 `json:"chain"`
+	LastUsedIndex       int                    `json:"lastUsedIndex"`
+	DiscoveredAddresses int                    `json:"discoveredAddresses"`
+	DiscoveredPaths     string                 `json:"discoveredPaths"`
+	ProbedPaths         string                 `json:"probedPaths,omitempty"`
+	UsedBeyondGap       []XpubDiscoveryAddress `json:"usedBeyondGap,omitempty"`
+	RequiredGap         int                    `json:"requiredGap,omitempty"`
+}
+
+// XpubDiscovery is the diagnostic report of the discovery of the addresses of an xpub
+type XpubDiscovery struct {
+	Xpub   string               `json:"xpub"`
+	Gap    int                  `json:"gap"`
+	Probe  int                  `json:"probe"`
+	Chains []XpubDiscoveryChain `json:"chains"`
+}
+
+// WatchlistItemBalance contains the balance of an address or xpub of a watchlist, Error is set if the balance cannot be computed
+type WatchlistItemBalance struct {
+	Descriptor            string  `json:"descriptor"`
+	Label                 string  `json:"label,omitempty"`
+	BalanceSat            *Amount `json:"balance,omitempty"`
+	UnconfirmedBalanceSat *Amount `json:"unconfirmedBalance,omitempty"`
+	Txs                   int     `json:"txs"`
+	UnconfirmedTxs        int     `json:"unconfirmedTxs"`
+	Error                 string  `json:"error,omitempty"`
+}
+
+// WatchlistBalance contains the aggregated balance of a watchlist and the balances of its items
+type WatchlistBalance struct {
+	Name                  string                 `json:"name"`
+	Height                uint32                 `json:"height"`
+	BalanceSat            *Amount                `json:"balance"`
+	UnconfirmedBalanceSat *Amount                `json:"unconfirmedBalance"`
+	Items                 []WatchlistItemBalance `json:"items"`
+}
+
+// Directions of the exported transactions
+const (
+	ExportDirectionIncoming = "incoming"
+	ExportDirectionOutgoing = "outgoing"
+	ExportDirectionSelf     = "self"
+)
+
+// ExportRow is one transaction of the exported history of an address or xpub; AmountSat is the change of the balance
+// caused by the transaction (including the fee), FeeSat is the fee paid by the account and BalanceSat is the balance after the transaction
+type ExportRow struct {
+	Blocktime      int64              `json:"blockTime"`
+	Blockheight    uint32             `json:"blockHeight"`
+	Txid           string             `json:"txid"`
+	Direction      string             `json:"direction"`
+	AmountSat      *Amount            `json:"amount"`
+	FeeSat         *Amount            `json:"fee"`
+	BalanceSat     *Amount            `json:"balance"`
+	Counterparties []string           `json:"counterparties"`
+	Rates          map[string]float64 `json:"rates,omitempty"`
+}
+
+// BalanceHistory contains the confirmed transactions of an address or xpub in the time interval starting at Time,
+// the amounts received and sent (including the fees) in the interval and the balance at its end
+type BalanceHistory struct {
+	Time        int64              `json:"time"`
+	Txs         int                `json:"txs"`
+	ReceivedSat *Amount            `json:"received"`
+	SentSat     *Amount            `json:"sent"`
+	BalanceSat  *Amount            `json:"balance"`
+	Rates       map[string]float64 `json:"rates,omitempty"`
+}
+
+// FiatTicker contains the exchange rates of the coin to the requested currencies at the time Timestamp,
+// the rate of an unknown currency is -1
+type FiatTicker struct {
+	Timestamp int64              `json:"ts"`
+	Rates     map[string]float64 `json:"rates"`
+}
+
 // Blocks is list of blocks with paging information
 type Blocks struct {
 	Paging
@@ -322,6 +427,10 @@ type BlockbookInfo struct {
 	InSync            bool                         `json:"inSync"`
 	BestHeight        uint32                       `json:"bestHeight"`
 	LastBlockTime     time.Time                    `json:"lastBlockTime"`
+	BackendHeight     uint32                       `json:"backendHeight,omitempty"`
+	SyncBlocksPerSec  float64                      `json:"syncBlocksPerSec,omitempty"`
+	SyncTxsPerSec     float64                      `json:"syncTxsPerSec,omitempty"`
+	SyncETASeconds    int64                        `json:"syncEtaSeconds,omitempty"`
 	InSyncMempool     bool                         `json:"inSyncMempool"`
 	LastMempoolTime   time.Time                    `json:"lastMempoolTime"`
 	MempoolSize       int                          `json:"mempoolSize"`
@@ -350,3 +459,82 @@ type MempoolTxids struct {
 	Mempool     []MempoolTxid `json:"mempool"`
 	MempoolSize int           `json:"mempoolSize"`
 }
+
+// MempoolFeeRateBucket contains number and virtual size of mempool transactions
+// with fee rate greater or equal to FeeRate and lower than FeeRate of the next bucket
+type MempoolFeeRateBucket struct {
+	FeeRate float64 `json:"feeRate"`
+	TxCount int     `json:"txCount"`
+	VSize   int64   `json:"vsize"`
+}
+
+// MempoolProjectedBlock contains data about a block that would be mined from the mempool transactions with the highest fee rates
+type MempoolProjectedBlock struct {
+	TxCount       int     `json:"txCount"`
+	VSize         int64   `json:"vsize"`
+	TotalFees     *Amount `json:"totalFees"`
+	MinFeeRate    float64 `json:"minFeeRate"`
+	MedianFeeRate float64 `json:"medianFeeRate"`
+	MaxFeeRate    float64 `json:"maxFeeRate"`
+}
+
+// MempoolStats contains statistics of the mempool transactions, fee rates are in satoshi per vbyte
+type MempoolStats struct {
+	MempoolSize     int                     `json:"mempoolSize"`
+	TxCount         int                     `json:"txCount"`
+	TotalVSize      int64                   `json:"totalVSize"`
+	TotalFees       *Amount                 `json:"totalFees"`
+	FeeHistogram    []MempoolFeeRateBucket  `json:"feeHistogram"`
+	ProjectedBlocks []MempoolProjectedBlock `json:"projectedBlocks"`
+}
+
+// MempoolTxPackage contains in-mempool dependencies (CPFP package) of a mempool transaction, fee rates are in satoshi per vbyte
+type MempoolTxPackage struct {
+	Txid             string   `json:"txid"`
+	VSize            int64    `json:"vsize"`
+	FeeRate          float64  `json:"feeRate"`
+	Parents          []string `json:"parents,omitempty"`
+	Children         []string `json:"children,omitempty"`
+	AncestorCount    int      `json:"ancestorCount"`
+	AncestorVSize    int64    `json:"ancestorVSize"`
+	AncestorFees     *Amount  `json:"ancestorFees"`
+	DescendantCount  int      `json:"descendantCount"`
+	DescendantVSize  int64    `json:"descendantVSize"`
+	DescendantFees   *Amount  `json:"descendantFees"`
+	AncestorFeeRate  float64  `json:"ancestorFeeRate"`
+	PackageFeeRate   float64  `json:"packageFeeRate"`
+	EffectiveFeeRate float64  `json:"effectiveFeeRate"`
+}
+
+// MerkleProof contains the merkle branch proving the inclusion of a transaction in a block,
+// the hashes of the branch are in the same format as txids, from the level of transactions up to the merkle root
+type MerkleProof struct {
+	Txid        string   `json:"txid"`
+	BlockHash   string   `json:"blockHash"`
+	BlockHeight uint32   `json:"blockHeight"`
+	Pos         int      `json:"pos"`
+	Merkle      []string `json:"merkle"`
+}
+
+// BlockHeaders contains the hex encoded raw headers of consecutive blocks starting at the height Start
+type BlockHeaders struct {
+	Start   uint32   `json:"start"`
+	Count   int      `json:"count"`
+	Headers []string `json:"headers"`
+}
+
+// BlockFilter contains the hex encoded BIP158 basic filter of a block and its BIP157 filter header,
+// the header is in the reversed byte order like block hashes and it is empty if it is not known
+type BlockFilter struct {
+	BlockHash    string `json:"blockHash"`
+	BlockHeight  uint32 `json:"blockHeight"`
+	Filter       string `json:"filter"`
+	FilterHeader string `json:"filterHeader,omitempty"`
+}
+
+// BlockFilterHeaders contains the filter headers of consecutive blocks starting at the height Start
+type BlockFilterHeaders struct {
+	Start   uint32   `json:"start"`
+	Count   int      `json:"count"`
+	Headers []string `json:"headers"`
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/db"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// MaxWatchlistItems is the maximum number of addresses and xpubs in one watchlist
+const MaxWatchlistItems = 1000
+
+// ValidateWatchlist checks that the watchlist has a name and that its items are unique valid addresses or xpubs
+func (w *Worker) ValidateWatchlist(wl *db.Watchlist) error {
+	if wl.Name == "" {
+		return NewAPIError("Missing watchlist name", true)
+	}
+	if len(wl.Items) > MaxWatchlistItems {
+		return NewAPIError(fmt.Sprintf("Too many watchlist items, maximum is %d", MaxWatchlistItems), true)
+	}
+	unique := make(map[string]struct{}, len(wl.Items))
+	for i := range wl.Items {
+		d := wl.Items[i].Descriptor
+		if _, found := unique[d]; found {
+			return NewAPIError(fmt.Sprintf("Duplicate watchlist item '%v'", d), true)
+		}
+		unique[d] = struct{}{}
+		if w.chainType == bchain.ChainBitcoinType {
+			if _, err := w.chainParser.ParseXpub(d); err == nil {
+				continue
+			}
+		}
+		if _, err := w.chainParser.GetAddrDescFromAddress(d); err != nil {
+			return NewAPIError(fmt.Sprintf("Invalid watchlist item '%v', not an address or xpub", d), true)
+		}
+	}
+	return nil
+}
+
+// PersistWatchlistXpubs persists the state of the xpubs of the watchlist, it must be called after the watchlist is stored
+func (w *Worker) PersistWatchlistXpubs(wl *db.Watchlist) error {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil
+	}
+	for i := range wl.Items {
+		d := wl.Items[i].Descriptor
+		if _, err := w.chainParser.ParseXpub(d); err != nil {
+			continue
+		}
+		if err := w.PersistXpubState(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getWatchlistItemAddress returns the balance of the watchlist item, which is either an xpub or an address
+func (w *Worker) getWatchlistItemAddress(descriptor string) (*Address, error) {
+	filter := AddressFilter{Vout: AddressFilterVoutOff}
+	if w.chainType == bchain.ChainBitcoinType {
+		if _, err := w.chainParser.ParseXpub(descriptor); err == nil {
+			return w.GetXpubAddress(descriptor, 1, 1, AccountDetailsBasic, &filter, 0)
+		}
+	}
+	return w.GetAddress(descriptor, 1, 1, AccountDetailsBasic, &filter)
+}
+
+// GetWatchlistBalance computes the balances of the items of the watchlist and their aggregated balance
+func (w *Worker) GetWatchlistBalance(wl *db.Watchlist) (*WatchlistBalance, error) {
+	start := time.Now()
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	var balance, unconfirmedBalance big.Int
+	r := WatchlistBalance{
+		Name:   wl.Name,
+		Height: bestheight,
+		Items:  make([]WatchlistItemBalance, len(wl.Items)),
+	}
+	for i := range wl.Items {
+		item := &r.Items[i]
+		item.Descriptor = wl.Items[i].Descriptor
+		item.Label = wl.Items[i].Label
+		a, err := w.getWatchlistItemAddress(item.Descriptor)
+		if err != nil {
+			if apiErr, ok := err.(*APIError); ok && apiErr.Public {
+				item.Error = apiErr.Text
+				continue
+			}
+			return nil, err
+		}
+		item.BalanceSat = a.BalanceSat
+		item.UnconfirmedBalanceSat = a.UnconfirmedBalanceSat
+		item.Txs = a.Txs
+		item.UnconfirmedTxs = a.UnconfirmedTxs
+		if a.BalanceSat != nil {
+			balance.Add(&balance, (*big.Int)(a.BalanceSat))
+		}
+		if a.UnconfirmedBalanceSat != nil {
+			unconfirmedBalance.Add(&unconfirmedBalance, (*big.Int)(a.UnconfirmedBalanceSat))
+		}
+	}
+	r.BalanceSat = (*Amount)(&balance)
+	r.UnconfirmedBalanceSat = (*Amount)(&unconfirmedBalance)
+	glog.Info("GetWatchlistBalance ", wl.Name, ", ", len(wl.Items), " items, finished in ", time.Since(start))
+	return &r, nil
+}
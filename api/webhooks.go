@@ -0,0 +1,45 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/db"
+	"fmt"
+	"net/url"
+)
+
+// MaxWebhookItems is the maximum number of addresses and xpubs of one webhook
+const MaxWebhookItems = 1000
+
+// ValidateWebhook checks that the webhook has an http(s) url, subscribes to some events and that its addresses and xpubs are valid
+func (w *Worker) ValidateWebhook(wh *db.Webhook) error {
+	if wh.ID == "" {
+		return NewAPIError("Missing webhook id", true)
+	}
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return NewAPIError(fmt.Sprintf("Invalid webhook url '%v'", wh.URL), true)
+	}
+	if wh.Secret == "" {
+		return NewAPIError("Missing webhook secret", true)
+	}
+	if len(wh.Addresses) == 0 && len(wh.Xpubs) == 0 && !wh.Blocks {
+		return NewAPIError("Webhook must subscribe to addresses, xpubs or blocks", true)
+	}
+	if len(wh.Addresses)+len(wh.Xpubs) > MaxWebhookItems {
+		return NewAPIError(fmt.Sprintf("Too many webhook addresses and xpubs, maximum is %d", MaxWebhookItems), true)
+	}
+	for _, a := range wh.Addresses {
+		if _, err := w.chainParser.GetAddrDescFromAddress(a); err != nil {
+			return NewAPIError(fmt.Sprintf("Invalid webhook address '%v'", a), true)
+		}
+	}
+	for _, x := range wh.Xpubs {
+		if w.chainType != bchain.ChainBitcoinType {
+			return NewAPIError("Xpubs are not supported", true)
+		}
+		if _, err := w.chainParser.ParseXpub(x); err != nil {
+			return NewAPIError(fmt.Sprintf("Invalid webhook xpub '%v'", x), true)
+		}
+	}
+	return nil
+}
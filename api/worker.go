@@ -11,7 +11,9 @@ import (
 	//"log"
 	//"log/syslog"
 	"math/big"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
@@ -112,6 +114,15 @@ func (w *Worker) GetTransaction(txid string, spendingTxs bool, specificJSON bool
 	bchainTx, height, err := w.txCache.GetTransaction(txid)
 	if err != nil {
 		if err == bchain.ErrTxNotFound {
+			// replaced mempool transaction is not available in the backend anymore, return only the information about the replacement
+			if replacedBy := w.mempool.GetReplacedBy(txid); replacedBy != "" {
+				return &Tx{
+					Txid:       txid,
+					Vin:        []Vin{},
+					Vout:       []Vout{},
+					ReplacedBy: replacedBy,
+				}, nil
+			}
 			return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found", txid), true)
 		}
 		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found (%v)", txid, err), true)
@@ -119,6 +130,53 @@ func (w *Worker) GetTransaction(txid string, spendingTxs bool, specificJSON bool
 	return w.GetTransactionFromBchainTx(bchainTx, height, spendingTxs, specificJSON)
 }
 
+// MaxBatchTransactions is the maximum number of transactions requested by one call of GetTransactions
+const MaxBatchTransactions = 1000
+
+// batchTransactionsWorkers is the number of transactions fetched in parallel by GetTransactions
+const batchTransactionsWorkers = 8
+
+// GetTransactions reads transactions by txids using a bounded number of parallel workers,
+// the results are in the order of txids, the transactions which cannot be read have the Error set
+func (w *Worker) GetTransactions(txids []string, spendingTxs bool) ([]TxResult, error) {
+	if len(txids) > MaxBatchTransactions {
+		return nil, NewAPIError(fmt.Sprintf("Too many transactions requested, maximum is %d", MaxBatchTransactions), true)
+	}
+	rv := make([]TxResult, len(txids))
+	chanIndex := make(chan int)
+	workers := batchTransactionsWorkers
+	if workers > len(txids) {
+		workers = len(txids)
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range chanIndex {
+				rv[j].Txid = txids[j]
+				tx, err := w.GetTransaction(txids[j], spendingTxs, false)
+				if err != nil {
+					if _, ok := err.(*APIError); ok {
+						rv[j].Error = err.Error()
+					} else {
+						glog.Error("GetTransactions ", txids[j], ": ", err)
+						rv[j].Error = "Internal server error"
+					}
+					continue
+				}
+				rv[j].Tx = tx
+			}
+		}()
+	}
+	for i := range txids {
+		chanIndex <- i
+	}
+	close(chanIndex)
+	wg.Wait()
+	return rv, nil
+}
+
 // GetTransactionFromBchainTx reads transaction data from txid
 func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height uint32, spendingTxs bool, specificJSON bool) (*Tx, error) {
 	var err error
@@ -296,9 +354,17 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height uint32,
 			return nil, err
 		}
 	}
-	// for mempool transaction get first seen time
+	// for mempool transaction get first seen time and the replaceability
+	var rbf bool
+	var replacedBy string
+	var mempoolPackage *MempoolTxPackage
 	if bchainTx.Confirmations == 0 {
 		bchainTx.Blocktime = int64(w.mempool.GetTransactionTime(bchainTx.Txid))
+		if w.chainType == bchain.ChainBitcoinType {
+			rbf = bchain.IsRbfSignaled(bchainTx)
+			replacedBy = w.mempool.GetReplacedBy(bchainTx.Txid)
+			mempoolPackage = w.getMempoolTxPackage(bchainTx.Txid)
+		}
 	}
 	r := &Tx{
 		Blockhash:        blockhash,
@@ -312,6 +378,9 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height uint32,
 		ValueOutSat:      (*Amount)(&valOutSat),
 		Version:          bchainTx.Version,
 		Hex:              bchainTx.Hex,
+		Rbf:              rbf,
+		ReplacedBy:       replacedBy,
+		MempoolPackage:   mempoolPackage,
 		Vin:              vins,
 		Vout:             vouts,
 		CoinSpecificData: bchainTx.CoinSpecificData,
@@ -336,19 +405,10 @@ func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool
 		}
 	} else {
 		callback = func(txid string, height uint32, indexes []int32) error {
-			for _, index := range indexes {
-				vout := index
-				if vout < 0 {
-					vout = ^vout
-				}
-				if (filter.Vout == AddressFilterVoutInputs && index < 0) ||
-					(filter.Vout == AddressFilterVoutOutputs && index >= 0) ||
-					(vout == int32(filter.Vout)) {
-					txids = append(txids, txid)
-					if len(txids) >= maxResults {
-						return &db.StopIteration{}
-					}
-					break
+			if voutFilterMatches(filter.Vout, indexes) {
+				txids = append(txids, txid)
+				if len(txids) >= maxResults {
+					return &db.StopIteration{}
 				}
 			}
 			return nil
@@ -382,6 +442,60 @@ func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool
 	return txids, nil
 }
 
+// voutFilterMatches checks if the indexes of an address in a transaction (outputs or binary complement of inputs) match the vout filter
+func voutFilterMatches(vout int, indexes []int32) bool {
+	if vout == AddressFilterVoutOff {
+		return true
+	}
+	for _, index := range indexes {
+		v := index
+		if v < 0 {
+			v = ^v
+		}
+		if (vout == AddressFilterVoutInputs && index < 0) ||
+			(vout == AddressFilterVoutOutputs && index >= 0) ||
+			(v == int32(vout)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getAddressConfirmedTxids returns up to maxResults confirmed txids of the address starting at the position given by the block height
+// and the number of the transactions of the block to skip; the returned cursor points to the next txid or is empty if there is none,
+// the position counts all transactions of the address regardless of the vout filter so that it is stable
+func (w *Worker) getAddressConfirmedTxids(addrDesc bchain.AddressDescriptor, filter *AddressFilter, height uint32, skip int, maxResults int) ([]string, string, error) {
+	txids := make([]string, 0, 4)
+	var nextCursor string
+	lastHeight, n := uint32(0), 0
+	callback := func(txid string, h uint32, indexes []int32) error {
+		if h != lastHeight || n == 0 {
+			lastHeight, n = h, 0
+		}
+		n++
+		if h == height && n <= skip {
+			return nil
+		}
+		if !voutFilterMatches(filter.Vout, indexes) {
+			return nil
+		}
+		if len(txids) >= maxResults {
+			nextCursor = historyCursor(h, n-1)
+			return &db.StopIteration{}
+		}
+		txids = append(txids, txid)
+		return nil
+	}
+	to := filter.ToHeight
+	if to == 0 || to > height {
+		to = height
+	}
+	if err := w.db.GetAddrDescTransactions(addrDesc, filter.FromHeight, to, callback); err != nil {
+		return nil, "", err
+	}
+	return txids, nextCursor, nil
+}
+
 func (t *Tx) getAddrVoutValue(addrDesc bchain.AddressDescriptor) *big.Int {
 	var val big.Int
 	for _, vout := range t.Vout {
@@ -673,12 +787,19 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		unconfirmedTxs           int
 		nonTokenTxs              int
 		totalResults             int
+		cursorHeight             uint32 = maxUint32
+		cursorSkip               int
 	)
 	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
 	if err != nil {
 		//log.Print(err)
 		return nil, err
 	}
+	if filter.Cursor != "" {
+		if cursorHeight, cursorSkip, err = parseHistoryCursor(filter.Cursor); err != nil {
+			return nil, err
+		}
+	}
 	//log.Print(addrDesc)
 	if w.chainType == bchain.ChainEthereumType {
 		var n uint64
@@ -724,7 +845,7 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 					unconfirmedTxs++
 					uBalSat.Add(&uBalSat, tx.getAddrVoutValue(addrDesc))
 					uBalSat.Sub(&uBalSat, tx.getAddrVinValue(addrDesc))
-					if page == 0 {
+					if page == 0 && filter.Cursor == "" {
 						if option == AccountDetailsTxidHistory {
 							txids = append(txids, tx.Txid)
 						} else if option >= AccountDetailsTxHistoryLight {
@@ -737,23 +858,38 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 	}
 	// get tx history if requested by option or check mempool if there are some transactions for a new address
 	if option >= AccountDetailsTxidHistory {
-		txc, err := w.getAddressTxids(addrDesc, false, filter, (page+1)*txsOnPage)
+		var (
+			txc        []string
+			nextCursor string
+			from, to   int
+		)
+		if filter.Cursor != "" {
+			txc, nextCursor, err = w.getAddressConfirmedTxids(addrDesc, filter, cursorHeight, cursorSkip, txsOnPage)
+		} else {
+			txc, nextCursor, err = w.getAddressConfirmedTxids(addrDesc, filter, maxUint32, 0, (page+1)*txsOnPage)
+		}
 		if err != nil {
-			return nil, errors.Annotatef(err, "getAddressTxids %v false", addrDesc)
+			return nil, errors.Annotatef(err, "getAddressConfirmedTxids %v", addrDesc)
 		}
 		bestheight, _, err := w.db.GetBestBlock()
 		if err != nil {
 			return nil, errors.Annotatef(err, "GetBestBlock")
 		}
-		var from, to int
-		pg, from, to, page = computePaging(len(txc), page, txsOnPage)
-		if len(txc) >= txsOnPage {
-			if totalResults < 0 {
-				pg.TotalPages = -1
-			} else {
-				pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+		if filter.Cursor != "" {
+			pg = Paging{ItemsOnPage: txsOnPage}
+			from, to = 0, len(txc)
+		} else {
+			pg, from, to, page = computePaging(len(txc), page, txsOnPage)
+			if len(txc) >= txsOnPage {
+				if totalResults < 0 {
+					pg.TotalPages = -1
+				} else {
+					pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+				}
 			}
 		}
+		// the txids are read up to the end of the page, the cursor points after the page
+		pg.NextCursor = nextCursor
 		for i := from; i < to; i++ {
 			txid := txc[i]
 			if option == AccountDetailsTxidHistory {
@@ -930,8 +1066,8 @@ func (w *Worker) GetAddressUtxo(address string, onlyConfirmed bool) (Utxos, erro
 	return r, nil
 }
 
-// GetBlocks returns BlockInfo for blocks on given page
-func (w *Worker) GetBlocks(page int, blocksOnPage int) (*Blocks, error) {
+// GetBlocks returns BlockInfo for blocks on given page or starting at the cursor, if the cursor is set
+func (w *Worker) GetBlocks(page int, blocksOnPage int, cursor string) (*Blocks, error) {
 	start := time.Now()
 	page--
 	if page < 0 {
@@ -942,7 +1078,26 @@ func (w *Worker) GetBlocks(page int, blocksOnPage int) (*Blocks, error) {
 	if err != nil {
 		return nil, errors.Annotatef(err, "GetBestBlock")
 	}
-	pg, from, to, page := computePaging(bestheight+1, page, blocksOnPage)
+	var (
+		pg       Paging
+		from, to int
+	)
+	if cursor != "" {
+		height, _, err := parseHistoryCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		pg.ItemsOnPage = blocksOnPage
+		if int(height) < bestheight {
+			from = bestheight - int(height)
+		}
+		to = from + blocksOnPage
+		if to > bestheight+1 {
+			to = bestheight + 1
+		}
+	} else {
+		pg, from, to, page = computePaging(bestheight+1, page, blocksOnPage)
+	}
 	r := &Blocks{Paging: pg}
 	r.Blocks = make([]db.BlockInfo, to-from)
 	for i := from; i < to; i++ {
@@ -951,11 +1106,15 @@ func (w *Worker) GetBlocks(page int, blocksOnPage int) (*Blocks, error) {
 			return nil, err
 		}
 		if bi == nil {
-			r.Blocks = r.Blocks[:i]
+			r.Blocks = r.Blocks[:i-from]
+			to = bestheight + 1
 			break
 		}
 		r.Blocks[i-from] = *bi
 	}
+	if to <= bestheight {
+		r.NextCursor = historyCursor(uint32(bestheight-to), 0)
+	}
 	glog.Info("GetBlocks page ", page, " finished in ", time.Since(start))
 	return r, nil
 }
@@ -1054,6 +1213,13 @@ func (w *Worker) GetSystemInfo(internal bool) (*SystemInfo, error) {
 	vi := common.GetVersionInfo()
 	ss, bh, st := w.is.GetSyncState()
 	ms, mt, msz := w.is.GetMempoolSyncState()
+	backendHeight, bps, tps, eta := w.is.GetSyncProgress()
+	var etaSeconds int64
+	if !eta.IsZero() {
+		if etaSeconds = int64(time.Until(eta).Seconds()); etaSeconds < 0 {
+			etaSeconds = 0
+		}
+	}
 	var dbc []common.InternalStateColumn
 	var dbs int64
 	if internal {
@@ -1071,6 +1237,10 @@ func (w *Worker) GetSystemInfo(internal bool) (*SystemInfo, error) {
 		InSync:            ss,
 		BestHeight:        bh,
 		LastBlockTime:     st,
+		BackendHeight:     backendHeight,
+		SyncBlocksPerSec:  bps,
+		SyncTxsPerSec:     tps,
+		SyncETASeconds:    etaSeconds,
 		InSyncMempool:     ms,
 		LastMempoolTime:   mt,
 		MempoolSize:       msz,
@@ -1084,14 +1254,38 @@ func (w *Worker) GetSystemInfo(internal bool) (*SystemInfo, error) {
 	return &SystemInfo{bi, ci}, nil
 }
 
-// GetMempool returns a page of mempool txids
-func (w *Worker) GetMempool(page int, itemsOnPage int) (*MempoolTxids, error) {
+// GetMempool returns a page of mempool txids or the txids starting at the cursor, if the cursor is set
+func (w *Worker) GetMempool(page int, itemsOnPage int, cursor string) (*MempoolTxids, error) {
 	page--
 	if page < 0 {
 		page = 0
 	}
 	entries := w.mempool.GetAllEntries()
-	pg, from, to, page := computePaging(len(entries), page, itemsOnPage)
+	var (
+		pg       Paging
+		from, to int
+	)
+	if cursor != "" {
+		t, txid, err := parseMempoolCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		// the entries are sorted by time and txid descending, the cursor entry may be already removed from mempool
+		from = sort.Search(len(entries), func(i int) bool {
+			e := &entries[i]
+			return e.Time < t || e.Time == t && e.Txid <= txid
+		})
+		to = from + itemsOnPage
+		if to > len(entries) {
+			to = len(entries)
+		}
+		pg.ItemsOnPage = itemsOnPage
+	} else {
+		pg, from, to, page = computePaging(len(entries), page, itemsOnPage)
+	}
+	if to < len(entries) {
+		pg.NextCursor = mempoolCursor(entries[to].Time, entries[to].Txid)
+	}
 	r := &MempoolTxids{
 		Paging:      pg,
 		MempoolSize: len(entries),
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/big"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,10 +14,12 @@ import (
 	"github.com/juju/errors"
 )
 
-const xpubLen = 111
 const defaultAddressesGap = 20
 const maxAddressesGap = 10000
 
+const defaultXpubProbe = 1000
+const maxXpubProbe = 10000
+
 const txInput = 1
 const txOutput = 2
 
@@ -46,6 +49,24 @@ func (a xpubTxids) Less(i, j int) bool {
 	return hi > hj
 }
 
+// cursor returns the history cursor pointing to the i-th txid
+func (a xpubTxids) cursor(i int) string {
+	n := 0
+	for j := i - 1; j >= 0 && a[j].height == a[i].height; j-- {
+		n++
+	}
+	return historyCursor(a[i].height, n)
+}
+
+// cursorStart returns the index of the txid at the position given by the block height and the number of the txids of the block to skip
+func (a xpubTxids) cursorStart(height uint32, skip int) int {
+	i := sort.Search(len(a), func(i int) bool { return a[i].height <= height })
+	for ; skip > 0 && i < len(a) && a[i].height == height; skip-- {
+		i++
+	}
+	return i
+}
+
 type xpubAddress struct {
 	addrDesc  bchain.AddressDescriptor
 	balance   *db.AddrBalance
@@ -59,6 +80,7 @@ type xpubData struct {
 	gap             int
 	accessed        int64
 	basePath        string
+	changeIndexes   []uint32
 	dataHeight      uint32
 	dataHash        string
 	txCountEstimate uint32
@@ -66,6 +88,11 @@ type xpubData struct {
 	balanceSat      big.Int
 	addresses       []xpubAddress
 	changeAddresses []xpubAddress
+	// last used indexes of the chains at dataHeight
+	lastUsed []int
+	// last used indexes and number of derived addresses of the chains as persisted in db
+	storedLastUsed []int
+	storedDerived  []int
 }
 
 func (w *Worker) xpubGetAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool, fromHeight, toHeight uint32, maxResults int) ([]xpubTxid, bool, error) {
@@ -236,6 +263,11 @@ func (w *Worker) tokenFromXpubAddress(data *xpubData, ad *xpubAddress, changeInd
 			totalReceived = ad.balance.ReceivedSat()
 		}
 	}
+	// addresses of a multi-address account are not derived, they do not have a path
+	var path string
+	if data.basePath != "" {
+		path = fmt.Sprintf("%s/%d/%d", data.basePath, data.changeIndexes[changeIndex], index)
+	}
 	return Token{
 		Type:             XPUBAddressTokenType,
 		Name:             address,
@@ -244,7 +276,7 @@ func (w *Worker) tokenFromXpubAddress(data *xpubData, ad *xpubAddress, changeInd
 		TotalReceivedSat: (*Amount)(totalReceived),
 		TotalSentSat:     (*Amount)(totalSent),
 		Transfers:        transfers,
-		Path:             fmt.Sprintf("%s/%d/%d", data.basePath, changeIndex, index),
+		Path:             path,
 	}
 }
 
@@ -270,21 +302,126 @@ func evictXpubCacheItems() {
 	glog.Info("Evicted ", count, " items from xpub cache, oldest item accessed at ", time.Unix(oldest, 0), ", cache size ", len(cachedXpubs))
 }
 
+// xpubGap returns the gap of unused addresses used in the derivation of xpub addresses
+func xpubGap(gap int) int {
+	if gap <= 0 {
+		return defaultAddressesGap
+	}
+	if gap > maxAddressesGap {
+		// limit the maximum gap to protect against unreasonably big values that could cause high load of the server
+		return maxAddressesGap
+	}
+	return gap
+}
+
+// xpubChains returns the number of derivation chains scanned for the xpub
+func xpubChains(data *xpubData) int {
+	if len(data.changeIndexes) > 1 {
+		return 2
+	}
+	return 1
+}
+
+// xpubLoadState fills the derived addresses of the xpub from the state persisted in db,
+// the state derived with a wider gap is used up to the addresses which the derivation with the requested gap reaches
+func (w *Worker) xpubLoadState(xpub string, data *xpubData) error {
+	s, err := w.db.GetXpubState(xpub)
+	if err != nil || s == nil {
+		return err
+	}
+	if s.Gap < data.gap || len(s.Chains) != xpubChains(data) {
+		return nil
+	}
+	data.storedLastUsed = make([]int, len(s.Chains))
+	data.storedDerived = make([]int, len(s.Chains))
+	for c := range s.Chains {
+		ads := s.Chains[c].AddrDescs
+		if n := s.Chains[c].LastUsedIndex + data.gap; n < len(ads) {
+			ads = ads[:n]
+		}
+		addresses := make([]xpubAddress, len(ads))
+		for i := range addresses {
+			addresses[i].addrDesc = ads[i]
+		}
+		if c == 0 {
+			data.addresses = addresses
+		} else {
+			data.changeAddresses = addresses
+		}
+		data.storedLastUsed[c] = s.Chains[c].LastUsedIndex
+		data.storedDerived[c] = len(addresses)
+	}
+	return nil
+}
+
+// xpubStoreState persists the derived addresses of the xpub if they changed since they were loaded or stored,
+// the db keeps the state derived with the widest gap; failure to store the state is not an error of the request
+func (w *Worker) xpubStoreState(xpub string, data *xpubData, lastUsed []int) {
+	groups := [][]xpubAddress{data.addresses, data.changeAddresses}
+	changed := len(data.storedLastUsed) != len(lastUsed)
+	for c := range lastUsed {
+		if !changed && (data.storedLastUsed[c] != lastUsed[c] || data.storedDerived[c] != len(groups[c])) {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	s := db.XpubState{
+		Gap:    data.gap,
+		Height: data.dataHeight,
+		Chains: make([]db.XpubChain, len(lastUsed)),
+	}
+	derived := make([]int, len(lastUsed))
+	for c := range lastUsed {
+		s.Chains[c].LastUsedIndex = lastUsed[c]
+		s.Chains[c].AddrDescs = make([]bchain.AddressDescriptor, len(groups[c]))
+		for i := range groups[c] {
+			s.Chains[c].AddrDescs[i] = groups[c][i].addrDesc
+		}
+		derived[c] = len(groups[c])
+	}
+	stored, err := w.db.StoreXpubState(xpub, &s)
+	if err != nil {
+		glog.Warning("StoreXpubState ", err)
+		return
+	}
+	if !stored {
+		return
+	}
+	data.storedLastUsed = lastUsed
+	data.storedDerived = derived
+}
+
+// PersistXpubState persists the derived addresses of the xpub registered in a watchlist or a webhook,
+// so that the sync matches the transactions of its addresses
+func (w *Worker) PersistXpubState(xpub string) error {
+	data, _, err := w.getXpubData(xpub, 0, 1, AccountDetailsBasic, &AddressFilter{Vout: AddressFilterVoutOff}, 0)
+	if err != nil {
+		return err
+	}
+	// the state loaded from the cache may not be persisted yet
+	data.storedLastUsed = nil
+	w.xpubStoreState(xpub, data, data.lastUsed)
+	return nil
+}
+
 func (w *Worker) getXpubData(xpub string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter, gap int) (*xpubData, uint32, error) {
-	if w.chainType != bchain.ChainBitcoinType || len(xpub) != xpubLen {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, 0, ErrUnsupportedXpub
+	}
+	descriptor, err := w.chainParser.ParseXpub(xpub)
+	if err != nil {
+		if strings.ContainsRune(xpub, '(') {
+			return nil, 0, NewAPIError("Invalid descriptor, "+err.Error(), true)
+		}
 		return nil, 0, ErrUnsupportedXpub
 	}
 	var (
-		err        error
 		bestheight uint32
 		besthash   string
 	)
-	if gap <= 0 {
-		gap = defaultAddressesGap
-	} else if gap > maxAddressesGap {
-		// limit the maximum gap to protect against unreasonably big values that could cause high load of the server
-		gap = maxAddressesGap
-	}
+	gap = xpubGap(gap)
 	// gap is increased one as there must be gap of empty addresses before the derivation is stopped
 	gap++
 	var processedHash string
@@ -302,12 +439,15 @@ func (w *Worker) getXpubData(xpub string, page int, txsOnPage int, option Accoun
 		}
 		fork := false
 		if !found || data.gap != gap {
-			data = xpubData{gap: gap}
+			data = xpubData{gap: gap, changeIndexes: descriptor.ChangeIndexes}
 			data.basePath, err = w.chainParser.DerivationBasePath(xpub)
 			if err != nil {
 				glog.Warning("DerivationBasePath error", err)
 				data.basePath = "unknown"
 			}
+			if err = w.xpubLoadState(xpub, &data); err != nil {
+				return nil, 0, err
+			}
 		} else {
 			hash, err := w.db.GetBlockHash(data.dataHeight)
 			if err != nil {
@@ -325,15 +465,20 @@ func (w *Worker) getXpubData(xpub string, page int, txsOnPage int, option Accoun
 			data.balanceSat = *new(big.Int)
 			data.sentSat = *new(big.Int)
 			data.txCountEstimate = 0
-			var lastUsedIndex int
-			lastUsedIndex, data.addresses, err = w.xpubScanAddresses(xpub, &data, data.addresses, gap, 0, 0, fork)
+			lastUsed := make([]int, xpubChains(&data))
+			lastUsed[0], data.addresses, err = w.xpubScanAddresses(xpub, &data, data.addresses, gap, 0, 0, fork)
 			if err != nil {
 				return nil, 0, err
 			}
-			_, data.changeAddresses, err = w.xpubScanAddresses(xpub, &data, data.changeAddresses, gap, 1, lastUsedIndex, fork)
-			if err != nil {
-				return nil, 0, err
+			// descriptor with a single derivation path does not have change addresses
+			if len(lastUsed) > 1 {
+				lastUsed[1], data.changeAddresses, err = w.xpubScanAddresses(xpub, &data, data.changeAddresses, gap, 1, lastUsed[0], fork)
+				if err != nil {
+					return nil, 0, err
+				}
 			}
+			data.lastUsed = lastUsed
+			w.xpubStoreState(xpub, &data, lastUsed)
 		}
 		if option >= AccountDetailsTxidHistory {
 			for _, da := range [][]xpubAddress{data.addresses, data.changeAddresses} {
@@ -355,17 +500,9 @@ func (w *Worker) getXpubData(xpub string, page int, txsOnPage int, option Accoun
 	return &data, bestheight, nil
 }
 
-// GetXpubAddress computes address value and gets transactions for given address
-func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter, gap int) (*Address, error) {
-	start := time.Now()
-	page--
-	if page < 0 {
-		page = 0
-	}
-	type mempoolMap struct {
-		tx          *Tx
-		inputOutput byte
-	}
+// xpubAddressesTxs merges the transactions of the groups of addresses of one account, it sets the paging,
+// the confirmed and unconfirmed transactions (or txids) and the unconfirmed balance of the addr
+func (w *Worker) xpubAddressesTxs(addr *Address, groups [][]xpubAddress, txCountEstimate uint32, bestheight uint32, page int, txsOnPage int, option AccountDetails, filter *AddressFilter) error {
 	var (
 		txc            xpubTxids
 		txmMap         map[string]*Tx
@@ -374,13 +511,16 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 		txids          []string
 		pg             Paging
 		filtered       bool
-		err            error
 		uBalSat        big.Int
 		unconfirmedTxs int
+		cursorHeight   uint32
+		cursorSkip     int
+		err            error
 	)
-	data, bestheight, err := w.getXpubData(xpub, page, txsOnPage, option, filter, gap)
-	if err != nil {
-		return nil, err
+	if filter.Cursor != "" {
+		if cursorHeight, cursorSkip, err = parseHistoryCursor(filter.Cursor); err != nil {
+			return err
+		}
 	}
 	// setup filtering of txids
 	var txidFilter func(txid *xpubTxid, ad *xpubAddress) bool
@@ -407,12 +547,12 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 	if filter.ToHeight == 0 && !filter.OnlyConfirmed {
 		txmMap = make(map[string]*Tx)
 		mempoolEntries := make(bchain.MempoolTxidEntries, 0)
-		for _, da := range [][]xpubAddress{data.addresses, data.changeAddresses} {
+		for _, da := range groups {
 			for i := range da {
 				ad := &da[i]
 				newTxids, _, err := w.xpubGetAddressTxids(ad.addrDesc, true, 0, 0, maxInt)
 				if err != nil {
-					return nil, err
+					return err
 				}
 				for _, txid := range newTxids {
 					// the same tx can have multiple addresses from the same xpub, get it from backend it only once
@@ -434,7 +574,7 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 						uBalSat.Add(&uBalSat, tx.getAddrVoutValue(ad.addrDesc))
 						uBalSat.Sub(&uBalSat, tx.getAddrVinValue(ad.addrDesc))
 						// mempool txs are returned only on the first page, uniquely and filtered
-						if page == 0 && !foundTx && (txidFilter == nil || txidFilter(&txid, ad)) {
+						if page == 0 && filter.Cursor == "" && !foundTx && (txidFilter == nil || txidFilter(&txid, ad)) {
 							mempoolEntries = append(mempoolEntries, bchain.MempoolTxidEntry{Txid: txid.txid, Time: uint32(tx.Blocktime)})
 						}
 					}
@@ -454,7 +594,7 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 	if option >= AccountDetailsTxidHistory {
 		txcMap := make(map[string]bool)
 		txc = make(xpubTxids, 0, 32)
-		for _, da := range [][]xpubAddress{data.addresses, data.changeAddresses} {
+		for _, da := range groups {
 			for i := range da {
 				ad := &da[i]
 				for _, txid := range ad.txids {
@@ -481,14 +621,26 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 			totalResults = -1
 		}
 		var from, to int
-		pg, from, to, page = computePaging(len(txc), page, txsOnPage)
-		if len(txc) >= txsOnPage {
-			if totalResults < 0 {
-				pg.TotalPages = -1
-			} else {
-				pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+		if filter.Cursor != "" {
+			pg = Paging{ItemsOnPage: txsOnPage}
+			from = txc.cursorStart(cursorHeight, cursorSkip)
+			to = from + txsOnPage
+			if to > len(txc) {
+				to = len(txc)
+			}
+		} else {
+			pg, from, to, page = computePaging(len(txc), page, txsOnPage)
+			if len(txc) >= txsOnPage {
+				if totalResults < 0 {
+					pg.TotalPages = -1
+				} else {
+					pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+				}
 			}
 		}
+		if to < len(txc) {
+			pg.NextCursor = txc.cursor(to)
+		}
 		// get confirmed transactions
 		for i := from; i < to; i++ {
 			xpubTxid := &txc[i]
@@ -497,13 +649,37 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 			} else {
 				tx, err := w.txFromTxid(xpubTxid.txid, bestheight, option)
 				if err != nil {
-					return nil, err
+					return err
 				}
 				txs = append(txs, tx)
 			}
 		}
 	} else {
-		txCount = int(data.txCountEstimate)
+		txCount = int(txCountEstimate)
+	}
+	addr.Paging = pg
+	addr.Txs = txCount
+	addr.UnconfirmedBalanceSat = (*Amount)(&uBalSat)
+	addr.UnconfirmedTxs = unconfirmedTxs
+	addr.Transactions = txs
+	addr.Txids = txids
+	return nil
+}
+
+// GetXpubAddress computes address value and gets transactions for given address
+func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter, gap int) (*Address, error) {
+	start := time.Now()
+	page--
+	if page < 0 {
+		page = 0
+	}
+	data, bestheight, err := w.getXpubData(xpub, page, txsOnPage, option, filter, gap)
+	if err != nil {
+		return nil, err
+	}
+	var addr Address
+	if err = w.xpubAddressesTxs(&addr, [][]xpubAddress{data.addresses, data.changeAddresses}, data.txCountEstimate, bestheight, page, txsOnPage, option, filter); err != nil {
+		return nil, err
 	}
 	totalTokens := 0
 	var tokens []Token
@@ -531,22 +707,14 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 	}
 	var totalReceived big.Int
 	totalReceived.Add(&data.balanceSat, &data.sentSat)
-	addr := Address{
-		Paging:                pg,
-		AddrStr:               xpub,
-		BalanceSat:            (*Amount)(&data.balanceSat),
-		TotalReceivedSat:      (*Amount)(&totalReceived),
-		TotalSentSat:          (*Amount)(&data.sentSat),
-		Txs:                   txCount,
-		UnconfirmedBalanceSat: (*Amount)(&uBalSat),
-		UnconfirmedTxs:        unconfirmedTxs,
-		Transactions:          txs,
-		Txids:                 txids,
-		TotalTokens:           totalTokens,
-		Tokens:                tokens,
-		XPubAddresses:         xpubAddresses,
-	}
-	glog.Info("GetXpubAddress ", xpub[:16], ", ", len(data.addresses)+len(data.changeAddresses), " derived addresses, ", txCount, " confirmed txs, finished in ", time.Since(start))
+	addr.AddrStr = xpub
+	addr.BalanceSat = (*Amount)(&data.balanceSat)
+	addr.TotalReceivedSat = (*Amount)(&totalReceived)
+	addr.TotalSentSat = (*Amount)(&data.sentSat)
+	addr.TotalTokens = totalTokens
+	addr.Tokens = tokens
+	addr.XPubAddresses = xpubAddresses
+	glog.Info("GetXpubAddress ", xpub[:16], ", ", len(data.addresses)+len(data.changeAddresses), " derived addresses, ", addr.Txs, " confirmed txs, finished in ", time.Since(start))
 	return &addr, nil
 }
 
@@ -590,3 +758,77 @@ func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int) (Utxos, e
 	glog.Info("GetXpubUtxo ", xpub[:16], ", ", len(r), " utxos, finished in ", time.Since(start))
 	return r, nil
 }
+
+func xpubPathRange(data *xpubData, changeIndex int, from int, to int) string {
+	return fmt.Sprintf("%s/%d/%d-%d", data.basePath, data.changeIndexes[changeIndex], from, to-1)
+}
+
+// GetXpubDiscovery returns diagnostic report of the discovery of xpub addresses; for each chain it reports the addresses
+// discovered using the gap and the used addresses found by probing the following probe addresses of the chain
+func (w *Worker) GetXpubDiscovery(xpub string, gap int, probe int) (*XpubDiscovery, error) {
+	start := time.Now()
+	gap = xpubGap(gap)
+	if probe <= 0 {
+		probe = defaultXpubProbe
+	} else if probe > maxXpubProbe {
+		probe = maxXpubProbe
+	}
+	data, _, err := w.getXpubData(xpub, 0, 1, AccountDetailsBasic, &AddressFilter{
+		Vout:          AddressFilterVoutOff,
+		OnlyConfirmed: true,
+	}, gap)
+	if err != nil {
+		return nil, err
+	}
+	r := XpubDiscovery{
+		Xpub:   xpub,
+		Gap:    gap,
+		Probe:  probe,
+		Chains: make([]XpubDiscoveryChain, xpubChains(data)),
+	}
+	for ci, da := range [][]xpubAddress{data.addresses, data.changeAddresses}[:len(r.Chains)] {
+		chain := &r.Chains[ci]
+		chain.Chain = data.changeIndexes[ci]
+		chain.LastUsedIndex = -1
+		chain.DiscoveredAddresses = len(da)
+		chain.DiscoveredPaths = xpubPathRange(data, ci, 0, len(da))
+		for i := range da {
+			if da[i].balance != nil {
+				chain.LastUsedIndex = i
+			}
+		}
+		// the gap is counted from before the index 0 if there is no used address
+		lastUsed := chain.LastUsedIndex
+		descriptors, err := w.chainParser.DeriveAddressDescriptorsFromTo(xpub, uint32(ci), uint32(len(da)), uint32(len(da)+probe))
+		if err != nil {
+			return nil, err
+		}
+		chain.ProbedPaths = xpubPathRange(data, ci, len(da), len(da)+probe)
+		for i, addrDesc := range descriptors {
+			ad := xpubAddress{addrDesc: addrDesc}
+			if ad.balance, err = w.db.GetAddrDescBalance(addrDesc); err != nil {
+				return nil, err
+			}
+			if ad.balance == nil {
+				continue
+			}
+			index := len(da) + i
+			t := w.tokenFromXpubAddress(data, &ad, ci, index, AccountDetailsTokenBalances)
+			a := XpubDiscoveryAddress{
+				Address:    t.Name,
+				Path:       t.Path,
+				Index:      index,
+				Gap:        index - lastUsed,
+				Transfers:  t.Transfers,
+				BalanceSat: t.BalanceSat,
+			}
+			if a.Gap > chain.RequiredGap {
+				chain.RequiredGap = a.Gap
+			}
+			chain.UsedBeyondGap = append(chain.UsedBeyondGap, a)
+			lastUsed = index
+		}
+	}
+	glog.Info("GetXpubDiscovery ", xpub[:16], ", gap ", gap, ", probe ", probe, ", finished in ", time.Since(start))
+	return &r, nil
+}
@@ -34,6 +34,11 @@ func (b *BaseChain) GetMempoolEntry(txid string) (*MempoolEntry, error) {
 	return nil, errors.New("GetMempoolEntry: not supported")
 }
 
+// GetBlockHeaderRaw is not supported by default
+func (b *BaseChain) GetBlockHeaderRaw(hash string) (string, error) {
+	return "", errors.New("GetBlockHeaderRaw: not supported")
+}
+
 // EthereumTypeGetBalance is not supported
 func (b *BaseChain) EthereumTypeGetBalance(addrDesc AddressDescriptor) (*big.Int, error) {
 	return nil, errors.New("Not supported")
@@ -1,8 +1,29 @@
 package bchain
 
 import (
+	"math/big"
 	"sort"
 	"sync"
+
+	"github.com/golang/glog"
+)
+
+// replacementRetentionSeconds is the time for which the information about replaced transactions is kept
+const replacementRetentionSeconds = 24 * 60 * 60
+
+// rough estimates of memory used by the mempool structures, used to enforce the mempool memory limit
+const (
+	txEntryMemoryOverhead   = 256
+	addrIndexMemoryOverhead = 64
+	inputMemoryOverhead     = 128
+)
+
+type evictionReason int
+
+const (
+	evictionAge evictionReason = iota
+	evictionCount
+	evictionMemory
 )
 
 type addrIndex struct {
@@ -13,11 +34,50 @@ type addrIndex struct {
 type txEntry struct {
 	addrIndexes []addrIndex
 	time        uint32
+	fee         big.Int
+	vsize       uint32
+	feeRate     float64
+	inputs      []Outpoint
+	rbf         bool
 }
 
 type txidio struct {
-	txid string
-	io   []addrIndex
+	txid   string
+	io     []addrIndex
+	fee    big.Int
+	vsize  uint32
+	inputs []Outpoint
+	rbf    bool
+}
+
+type txReplacement struct {
+	replacedBy string
+	time       uint32
+}
+
+type replacedEntry struct {
+	txid  string
+	entry txEntry
+}
+
+// computeFeeRate returns fee rate in satoshi per vbyte, 0 if the size is not known
+func computeFeeRate(fee *big.Int, vsize uint32) float64 {
+	if vsize == 0 {
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(fee).Float64()
+	return f / float64(vsize)
+}
+
+// IsRbfSignaled returns true if the transaction signals replaceability by BIP125,
+// i.e. any of its inputs has sequence number lower than 0xfffffffe
+func IsRbfSignaled(tx *Tx) bool {
+	for i := range tx.Vin {
+		if tx.Vin[i].Coinbase == "" && tx.Vin[i].Sequence < 0xfffffffe {
+			return true
+		}
+	}
+	return false
 }
 
 // BaseMempool is mempool base handle
@@ -26,7 +86,29 @@ type BaseMempool struct {
 	mux          sync.Mutex
 	txEntries    map[string]txEntry
 	addrDescToTx map[string][]Outpoint
+	// spentOutpoints maps outpoints spent by mempool transactions to the spending txid
+	spentOutpoints map[Outpoint]string
+	// replacements maps txids of replaced transactions to the replacing transactions
+	replacements map[string]txReplacement
+	// childTxs maps txids of mempool transactions to the mempool transactions spending their outputs
+	childTxs     map[string][]string
 	OnNewTxAddr  OnNewTxAddrFunc
+	OnTxReplaced OnTxReplacedFunc
+	// memoryUsage is the estimated memory used by the mempool entries
+	memoryUsage int64
+	evictions   MempoolEvictionStats
+}
+
+// estimateEntryMemory returns rough estimate of the memory used by the entry in the mempool structures
+func estimateEntryMemory(txid string, entry *txEntry) int64 {
+	size := int64(txEntryMemoryOverhead + len(txid))
+	for i := range entry.addrIndexes {
+		size += int64(addrIndexMemoryOverhead + len(entry.addrIndexes[i].addrDesc))
+	}
+	for i := range entry.inputs {
+		size += int64(inputMemoryOverhead + len(entry.inputs[i].Txid))
+	}
+	return size
 }
 
 // GetTransactions returns slice of mempool transactions for given address
@@ -68,6 +150,14 @@ func (a MempoolTxidEntries) Less(i, j int) bool {
 // removeEntryFromMempool removes entry from mempool structs. The caller is responsible for locking!
 func (m *BaseMempool) removeEntryFromMempool(txid string, entry txEntry) {
 	delete(m.txEntries, txid)
+	m.memoryUsage -= estimateEntryMemory(txid, &entry)
+	for _, o := range entry.inputs {
+		if m.spentOutpoints[o] == txid {
+			delete(m.spentOutpoints, o)
+		}
+		m.unlinkChild(o.Txid, txid)
+	}
+	delete(m.childTxs, txid)
 	for _, si := range entry.addrIndexes {
 		outpoints, found := m.addrDescToTx[si.addrDesc]
 		if found {
@@ -113,3 +203,380 @@ func (m *BaseMempool) GetTransactionTime(txid string) uint32 {
 	}
 	return e.time
 }
+
+// addEntryToMempool adds entry to mempool structs. The caller is responsible for locking!
+func (m *BaseMempool) addEntryToMempool(txid string, entry txEntry) {
+	m.txEntries[txid] = entry
+	m.memoryUsage += estimateEntryMemory(txid, &entry)
+	for _, si := range entry.addrIndexes {
+		m.addrDescToTx[si.addrDesc] = append(m.addrDescToTx[si.addrDesc], Outpoint{txid, si.n})
+	}
+	for _, o := range entry.inputs {
+		m.spentOutpoints[o] = txid
+		if _, found := m.txEntries[o.Txid]; found {
+			m.linkChild(o.Txid, txid)
+		}
+	}
+	// the children may be processed before the parent during the parallel resync
+	for _, si := range entry.addrIndexes {
+		if si.n >= 0 {
+			if child, found := m.spentOutpoints[Outpoint{txid, si.n}]; found {
+				m.linkChild(txid, child)
+			}
+		}
+	}
+}
+
+// linkChild records that the child spends output of the parent. The caller is responsible for locking!
+func (m *BaseMempool) linkChild(parent, child string) {
+	children := m.childTxs[parent]
+	for _, c := range children {
+		if c == child {
+			return
+		}
+	}
+	m.childTxs[parent] = append(children, child)
+}
+
+// unlinkChild removes the child from the children of the parent. The caller is responsible for locking!
+func (m *BaseMempool) unlinkChild(parent, child string) {
+	children, found := m.childTxs[parent]
+	if !found {
+		return
+	}
+	for i, c := range children {
+		if c == child {
+			children = append(children[:i], children[i+1:]...)
+			break
+		}
+	}
+	if len(children) > 0 {
+		m.childTxs[parent] = children
+	} else {
+		delete(m.childTxs, parent)
+	}
+}
+
+// removeConflictingEntries removes entries spending any of the inputs of the new transaction from mempool structs
+// and records them as replaced by the new transaction. The caller is responsible for locking!
+func (m *BaseMempool) removeConflictingEntries(txid string, inputs []Outpoint, txTime uint32) []replacedEntry {
+	var replaced []replacedEntry
+	for _, o := range inputs {
+		ctxid, found := m.spentOutpoints[o]
+		if !found || ctxid == txid {
+			continue
+		}
+		if entry, exists := m.txEntries[ctxid]; exists {
+			m.removeEntryFromMempool(ctxid, entry)
+			m.replacements[ctxid] = txReplacement{replacedBy: txid, time: txTime}
+			replaced = append(replaced, replacedEntry{ctxid, entry})
+		}
+	}
+	return replaced
+}
+
+// notifyReplacedEntries sends notification about replacement of the entries to all their addresses
+func (m *BaseMempool) notifyReplacedEntries(replaced []replacedEntry, replacedBy string) {
+	for i := range replaced {
+		r := &replaced[i]
+		glog.Info("mempool: tx ", r.txid, " replaced by ", replacedBy, ", rbf ", r.entry.rbf)
+		if m.OnTxReplaced == nil {
+			continue
+		}
+		notified := make(map[string]struct{}, len(r.entry.addrIndexes))
+		for _, si := range r.entry.addrIndexes {
+			if _, found := notified[si.addrDesc]; !found {
+				notified[si.addrDesc] = struct{}{}
+				m.OnTxReplaced(r.txid, replacedBy, r.entry.rbf, AddressDescriptor(si.addrDesc))
+			}
+		}
+	}
+}
+
+// pruneReplacements removes the information about replaced transactions older than replacementRetentionSeconds.
+// The caller is responsible for locking!
+func (m *BaseMempool) pruneReplacements(now uint32) {
+	for txid, r := range m.replacements {
+		if now-r.time > replacementRetentionSeconds {
+			delete(m.replacements, txid)
+		}
+	}
+}
+
+// GetReplacedBy returns txid of the final transaction of the chain of replacements of the given transaction
+// or empty string if it was not replaced
+func (m *BaseMempool) GetReplacedBy(txid string) string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	replacedBy := m.replacements[txid].replacedBy
+	// the replacement may have been replaced too, the chain cannot be longer than the number of replacements
+	for i := 0; replacedBy != "" && i < len(m.replacements); i++ {
+		r, found := m.replacements[replacedBy]
+		if !found {
+			break
+		}
+		replacedBy = r.replacedBy
+	}
+	return replacedBy
+}
+
+// GetStoredEntries returns all mempool entries in the form suitable to be persisted
+func (m *BaseMempool) GetStoredEntries() []MempoolStoredEntry {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	entries := make([]MempoolStoredEntry, 0, len(m.txEntries))
+	for txid, entry := range m.txEntries {
+		ai := make([]MempoolAddrIndex, len(entry.addrIndexes))
+		for i := range entry.addrIndexes {
+			ai[i] = MempoolAddrIndex{
+				AddrDesc: AddressDescriptor(entry.addrIndexes[i].addrDesc),
+				N:        entry.addrIndexes[i].n,
+			}
+		}
+		entries = append(entries, MempoolStoredEntry{
+			Txid:        txid,
+			Time:        entry.time,
+			AddrIndexes: ai,
+			Fee:         entry.fee,
+			VSize:       entry.vsize,
+			Inputs:      entry.inputs,
+			Rbf:         entry.rbf,
+		})
+	}
+	return entries
+}
+
+// RestoreStoredEntries adds previously persisted entries to the mempool, entries already in mempool are skipped.
+// The restored entries are reconciled with the backend by the following Resync.
+// Returns the number of restored entries.
+func (m *BaseMempool) RestoreStoredEntries(entries []MempoolStoredEntry) int {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	restored := 0
+	for i := range entries {
+		e := &entries[i]
+		if _, exists := m.txEntries[e.Txid]; exists || len(e.AddrIndexes) == 0 {
+			continue
+		}
+		ai := make([]addrIndex, len(e.AddrIndexes))
+		for j := range e.AddrIndexes {
+			ai[j] = addrIndex{string(e.AddrIndexes[j].AddrDesc), e.AddrIndexes[j].N}
+		}
+		m.addEntryToMempool(e.Txid, txEntry{
+			addrIndexes: ai,
+			time:        e.Time,
+			fee:         e.Fee,
+			vsize:       e.VSize,
+			feeRate:     computeFeeRate(&e.Fee, e.VSize),
+			inputs:      e.Inputs,
+			rbf:         e.Rbf,
+		})
+		restored++
+	}
+	return restored
+}
+
+// GetFeeEntries returns fee data of all mempool transactions with known size, in no particular order
+func (m *BaseMempool) GetFeeEntries() []MempoolFeeEntry {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	entries := make([]MempoolFeeEntry, 0, len(m.txEntries))
+	for _, entry := range m.txEntries {
+		if entry.vsize > 0 {
+			entries = append(entries, MempoolFeeEntry{
+				Fee:     entry.fee,
+				VSize:   entry.vsize,
+				FeeRate: entry.feeRate,
+			})
+		}
+	}
+	return entries
+}
+
+// collectAncestors adds all in-mempool ancestors of the transaction to the set. The caller is responsible for locking!
+func (m *BaseMempool) collectAncestors(txid string, ancestors map[string]struct{}) {
+	for _, o := range m.txEntries[txid].inputs {
+		if _, found := m.txEntries[o.Txid]; found {
+			if _, found = ancestors[o.Txid]; !found {
+				ancestors[o.Txid] = struct{}{}
+				m.collectAncestors(o.Txid, ancestors)
+			}
+		}
+	}
+}
+
+// collectDescendants adds all in-mempool descendants of the transaction to the set. The caller is responsible for locking!
+func (m *BaseMempool) collectDescendants(txid string, descendants map[string]struct{}) {
+	for _, c := range m.childTxs[txid] {
+		if _, found := descendants[c]; !found {
+			descendants[c] = struct{}{}
+			m.collectDescendants(c, descendants)
+		}
+	}
+}
+
+// ancestorFeeRate returns fee rate of the transaction together with all its ancestors. The caller is responsible for locking!
+func (m *BaseMempool) ancestorFeeRate(txid string) float64 {
+	ancestors := make(map[string]struct{})
+	m.collectAncestors(txid, ancestors)
+	ancestors[txid] = struct{}{}
+	var fee big.Int
+	var vsize int64
+	for a := range ancestors {
+		e := m.txEntries[a]
+		fee.Add(&fee, &e.fee)
+		vsize += int64(e.vsize)
+	}
+	return computeFeeRate(&fee, uint32(vsize))
+}
+
+// GetTxPackage returns the in-mempool dependencies of the transaction with aggregated fees and sizes
+// of its ancestors and descendants or nil if the transaction is not in mempool.
+// The effective fee rate is the highest of the ancestor fee rates of the transaction and its descendants,
+// i.e. the fee rate at which the transaction is likely to be mined as a part of a CPFP package.
+func (m *BaseMempool) GetTxPackage(txid string) *MempoolTxPackage {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	entry, found := m.txEntries[txid]
+	if !found {
+		return nil
+	}
+	p := &MempoolTxPackage{
+		Txid:    txid,
+		Fee:     entry.fee,
+		VSize:   entry.vsize,
+		FeeRate: entry.feeRate,
+	}
+	for _, o := range entry.inputs {
+		if _, found := m.txEntries[o.Txid]; found {
+			dup := false
+			for _, parent := range p.Parents {
+				if parent == o.Txid {
+					dup = true
+					break
+				}
+			}
+			if !dup {
+				p.Parents = append(p.Parents, o.Txid)
+			}
+		}
+	}
+	p.Children = append([]string(nil), m.childTxs[txid]...)
+	sort.Strings(p.Children)
+	ancestors := make(map[string]struct{})
+	m.collectAncestors(txid, ancestors)
+	for a := range ancestors {
+		e := m.txEntries[a]
+		p.AncestorCount++
+		p.AncestorFee.Add(&p.AncestorFee, &e.fee)
+		p.AncestorVSize += int64(e.vsize)
+	}
+	descendants := make(map[string]struct{})
+	m.collectDescendants(txid, descendants)
+	for d := range descendants {
+		e := m.txEntries[d]
+		p.DescendantCount++
+		p.DescendantFee.Add(&p.DescendantFee, &e.fee)
+		p.DescendantVSize += int64(e.vsize)
+	}
+	var fee big.Int
+	fee.Add(&entry.fee, &p.AncestorFee)
+	p.AncestorFeeRate = computeFeeRate(&fee, uint32(int64(entry.vsize)+p.AncestorVSize))
+	fee.Add(&fee, &p.DescendantFee)
+	p.PackageFeeRate = computeFeeRate(&fee, uint32(int64(entry.vsize)+p.AncestorVSize+p.DescendantVSize))
+	p.EffectiveFeeRate = p.AncestorFeeRate
+	for d := range descendants {
+		if r := m.ancestorFeeRate(d); r > p.EffectiveFeeRate {
+			p.EffectiveFeeRate = r
+		}
+	}
+	return p
+}
+
+// evictEntry removes the transaction and all its in-mempool descendants, which cannot be mined without it,
+// and returns the txids of the removed transactions. The caller is responsible for locking!
+func (m *BaseMempool) evictEntry(txid string, reason evictionReason) []string {
+	descendants := make(map[string]struct{})
+	m.collectDescendants(txid, descendants)
+	evicted := make([]string, 0, len(descendants)+1)
+	descendants[txid] = struct{}{}
+	for t := range descendants {
+		if entry, found := m.txEntries[t]; found {
+			m.removeEntryFromMempool(t, entry)
+			evicted = append(evicted, t)
+		}
+	}
+	n := uint64(len(evicted))
+	switch reason {
+	case evictionAge:
+		m.evictions.Age += n
+	case evictionCount:
+		m.evictions.Count += n
+	case evictionMemory:
+		m.evictions.Memory += n
+	}
+	return evicted
+}
+
+// evictEntries enforces the mempool limits, zero value of a limit means no limit.
+// First the transactions older than timeout seconds are evicted, then, if the number of transactions
+// or the estimated memory usage is over the limit, the transactions with the lowest fee rate (the oldest first
+// if the fee rate is the same or unknown) are evicted. Returns txids of all evicted transactions.
+// The caller is responsible for locking!
+func (m *BaseMempool) evictEntries(now uint32, timeout uint32, maxTransactions int, maxMemory int64) []string {
+	var evicted []string
+	if timeout > 0 && now > timeout {
+		threshold := now - timeout
+		for txid, entry := range m.txEntries {
+			if entry.time < threshold {
+				evicted = append(evicted, m.evictEntry(txid, evictionAge)...)
+			}
+		}
+	}
+	overCount := func() bool { return maxTransactions > 0 && len(m.txEntries) > maxTransactions }
+	overMemory := func() bool { return maxMemory > 0 && m.memoryUsage > maxMemory }
+	if !overCount() && !overMemory() {
+		return evicted
+	}
+	type candidate struct {
+		txid    string
+		feeRate float64
+		time    uint32
+	}
+	candidates := make([]candidate, 0, len(m.txEntries))
+	for txid, entry := range m.txEntries {
+		candidates = append(candidates, candidate{txid, entry.feeRate, entry.time})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].feeRate != candidates[j].feeRate {
+			return candidates[i].feeRate < candidates[j].feeRate
+		}
+		if candidates[i].time != candidates[j].time {
+			return candidates[i].time < candidates[j].time
+		}
+		return candidates[i].txid < candidates[j].txid
+	})
+	for i := range candidates {
+		if _, found := m.txEntries[candidates[i].txid]; !found {
+			// already evicted as a descendant
+			continue
+		}
+		if overCount() {
+			evicted = append(evicted, m.evictEntry(candidates[i].txid, evictionCount)...)
+		} else if overMemory() {
+			evicted = append(evicted, m.evictEntry(candidates[i].txid, evictionMemory)...)
+		} else {
+			break
+		}
+	}
+	return evicted
+}
+
+// GetEvictionStats returns the numbers of transactions evicted from the mempool and its estimated memory usage
+func (m *BaseMempool) GetEvictionStats() MempoolEvictionStats {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	e := m.evictions
+	e.MemoryUsage = m.memoryUsage
+	return e
+}
@@ -0,0 +1,255 @@
+package bchain
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestIsRbfSignaled(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   Tx
+		want bool
+	}{
+		{
+			name: "final",
+			tx:   Tx{Vin: []Vin{{Txid: "a", Sequence: 0xffffffff}, {Txid: "b", Sequence: 0xfffffffe}}},
+			want: false,
+		},
+		{
+			name: "signaled",
+			tx:   Tx{Vin: []Vin{{Txid: "a", Sequence: 0xffffffff}, {Txid: "b", Sequence: 0xfffffffd}}},
+			want: true,
+		},
+		{
+			name: "coinbase",
+			tx:   Tx{Vin: []Vin{{Coinbase: "03", Sequence: 0}}},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRbfSignaled(&tt.tx); got != tt.want {
+				t.Errorf("IsRbfSignaled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseMempool_removeConflictingEntries(t *testing.T) {
+	m := &BaseMempool{
+		txEntries:      make(map[string]txEntry),
+		addrDescToTx:   make(map[string][]Outpoint),
+		spentOutpoints: make(map[Outpoint]string),
+		replacements:   make(map[string]txReplacement),
+		childTxs:       make(map[string][]string),
+	}
+	type notification struct {
+		txid       string
+		replacedBy string
+		rbf        bool
+		addrDesc   string
+	}
+	var notifications []notification
+	m.OnTxReplaced = func(txid string, replacedBy string, rbf bool, desc AddressDescriptor) {
+		notifications = append(notifications, notification{txid, replacedBy, rbf, string(desc)})
+	}
+	m.addEntryToMempool("tx1", txEntry{
+		addrIndexes: []addrIndex{{"addr1", 0}, {"addr2", ^0}, {"addr2", ^1}},
+		time:        1000,
+		inputs:      []Outpoint{{"in1", 0}, {"in2", 1}},
+		rbf:         true,
+	})
+	m.addEntryToMempool("tx2", txEntry{
+		addrIndexes: []addrIndex{{"addr3", 0}, {"addr2", ^0}},
+		time:        1000,
+		inputs:      []Outpoint{{"in3", 0}},
+	})
+
+	// tx3 spends one of the inputs of tx1
+	replaced := m.removeConflictingEntries("tx3", []Outpoint{{"in4", 0}, {"in2", 1}}, 2000)
+	m.addEntryToMempool("tx3", txEntry{
+		addrIndexes: []addrIndex{{"addr4", 0}, {"addr2", ^1}},
+		time:        2000,
+		inputs:      []Outpoint{{"in4", 0}, {"in2", 1}},
+	})
+	m.notifyReplacedEntries(replaced, "tx3")
+
+	if _, found := m.txEntries["tx1"]; found {
+		t.Error("tx1 not removed from mempool")
+	}
+	if _, found := m.addrDescToTx["addr1"]; found {
+		t.Error("addr1 not removed from mempool")
+	}
+	wantSpent := map[Outpoint]string{
+		{"in2", 1}: "tx3",
+		{"in3", 0}: "tx2",
+		{"in4", 0}: "tx3",
+	}
+	if !reflect.DeepEqual(m.spentOutpoints, wantSpent) {
+		t.Errorf("spentOutpoints = %v, want %v", m.spentOutpoints, wantSpent)
+	}
+	wantNotifications := []notification{
+		{"tx1", "tx3", true, "addr1"},
+		{"tx1", "tx3", true, "addr2"},
+	}
+	if !reflect.DeepEqual(notifications, wantNotifications) {
+		t.Errorf("notifications = %v, want %v", notifications, wantNotifications)
+	}
+	if got := m.GetReplacedBy("tx1"); got != "tx3" {
+		t.Errorf("GetReplacedBy(tx1) = %v, want tx3", got)
+	}
+	if got := m.GetReplacedBy("tx2"); got != "" {
+		t.Errorf("GetReplacedBy(tx2) = %v, want empty", got)
+	}
+
+	m.pruneReplacements(2000 + replacementRetentionSeconds)
+	if got := m.GetReplacedBy("tx1"); got != "tx3" {
+		t.Errorf("GetReplacedBy(tx1) = %v, want tx3", got)
+	}
+	m.pruneReplacements(2001 + replacementRetentionSeconds)
+	if got := m.GetReplacedBy("tx1"); got != "" {
+		t.Errorf("GetReplacedBy(tx1) after prune = %v, want empty", got)
+	}
+}
+
+func TestBaseMempool_GetReplacedBy(t *testing.T) {
+	m := &BaseMempool{
+		replacements: map[string]txReplacement{
+			"tx1": {replacedBy: "tx2", time: 1000},
+			"tx2": {replacedBy: "tx3", time: 1001},
+			"tx3": {replacedBy: "tx4", time: 1002},
+			"tx5": {replacedBy: "tx6", time: 1000},
+			"tx6": {replacedBy: "tx5", time: 1001},
+		},
+	}
+	tests := []struct {
+		txid string
+		want string
+	}{
+		{"tx1", "tx4"},
+		{"tx2", "tx4"},
+		{"tx3", "tx4"},
+		{"tx4", ""},
+		{"tx7", ""},
+	}
+	for _, tt := range tests {
+		if got := m.GetReplacedBy(tt.txid); got != tt.want {
+			t.Errorf("GetReplacedBy(%v) = %v, want %v", tt.txid, got, tt.want)
+		}
+	}
+	// a cycle of replacements must not loop forever
+	if got := m.GetReplacedBy("tx5"); got != "tx5" && got != "tx6" {
+		t.Errorf("GetReplacedBy(tx5) = %v, want tx5 or tx6", got)
+	}
+}
+
+func TestBaseMempool_GetTxPackage(t *testing.T) {
+	m := &BaseMempool{
+		txEntries:      make(map[string]txEntry),
+		addrDescToTx:   make(map[string][]Outpoint),
+		spentOutpoints: make(map[Outpoint]string),
+		replacements:   make(map[string]txReplacement),
+		childTxs:       make(map[string][]string),
+	}
+	newEntry := func(fee int64, vsize uint32, inputs []Outpoint) txEntry {
+		e := txEntry{
+			addrIndexes: []addrIndex{{"addr", 0}, {"addr", 1}},
+			vsize:       vsize,
+			inputs:      inputs,
+		}
+		e.fee.SetInt64(fee)
+		e.feeRate = computeFeeRate(&e.fee, vsize)
+		return e
+	}
+	// the child is added before the parent, as it may happen in the parallel resync
+	m.addEntryToMempool("c1", newEntry(1900, 100, []Outpoint{{"p1", 0}, {"confirmed", 0}}))
+	m.addEntryToMempool("p1", newEntry(100, 100, []Outpoint{{"confirmed", 1}}))
+	m.addEntryToMempool("c2", newEntry(100, 100, []Outpoint{{"p1", 1}}))
+
+	if got := m.GetTxPackage("unknown"); got != nil {
+		t.Errorf("GetTxPackage(unknown) = %+v, want nil", got)
+	}
+	p := m.GetTxPackage("p1")
+	if !reflect.DeepEqual(p.Children, []string{"c1", "c2"}) || len(p.Parents) != 0 {
+		t.Errorf("GetTxPackage(p1) parents %v, children %v", p.Parents, p.Children)
+	}
+	if p.AncestorCount != 0 || p.DescendantCount != 2 || p.DescendantFee.Int64() != 2000 || p.DescendantVSize != 200 {
+		t.Errorf("GetTxPackage(p1) = %+v", p)
+	}
+	if p.AncestorFeeRate != 1 || p.PackageFeeRate != 7 || p.EffectiveFeeRate != 10 {
+		t.Errorf("GetTxPackage(p1) fee rates %v %v %v, want 1 7 10", p.AncestorFeeRate, p.PackageFeeRate, p.EffectiveFeeRate)
+	}
+	p = m.GetTxPackage("c1")
+	if !reflect.DeepEqual(p.Parents, []string{"p1"}) || len(p.Children) != 0 {
+		t.Errorf("GetTxPackage(c1) parents %v, children %v", p.Parents, p.Children)
+	}
+	if p.AncestorCount != 1 || p.AncestorFee.Int64() != 100 || p.AncestorVSize != 100 || p.DescendantCount != 0 {
+		t.Errorf("GetTxPackage(c1) = %+v", p)
+	}
+	if p.FeeRate != 19 || p.AncestorFeeRate != 10 || p.PackageFeeRate != 10 || p.EffectiveFeeRate != 10 {
+		t.Errorf("GetTxPackage(c1) fee rates %v %v %v %v, want 19 10 10 10", p.FeeRate, p.AncestorFeeRate, p.PackageFeeRate, p.EffectiveFeeRate)
+	}
+
+	// the parent is mined
+	m.removeEntryFromMempool("p1", m.txEntries["p1"])
+	p = m.GetTxPackage("c1")
+	if len(p.Parents) != 0 || p.AncestorCount != 0 || p.EffectiveFeeRate != 19 {
+		t.Errorf("GetTxPackage(c1) after removal of p1 = %+v", p)
+	}
+	if len(m.childTxs) != 0 {
+		t.Errorf("childTxs = %v, want empty", m.childTxs)
+	}
+}
+
+func TestBaseMempool_evictEntries(t *testing.T) {
+	m := &BaseMempool{
+		txEntries:      make(map[string]txEntry),
+		addrDescToTx:   make(map[string][]Outpoint),
+		spentOutpoints: make(map[Outpoint]string),
+		replacements:   make(map[string]txReplacement),
+		childTxs:       make(map[string][]string),
+	}
+	newEntry := func(fee int64, time uint32, inputs []Outpoint) txEntry {
+		e := txEntry{
+			addrIndexes: []addrIndex{{"addr", 0}},
+			time:        time,
+			vsize:       100,
+			inputs:      inputs,
+		}
+		e.fee.SetInt64(fee)
+		e.feeRate = computeFeeRate(&e.fee, e.vsize)
+		return e
+	}
+	m.addEntryToMempool("old", newEntry(1000, 1000, []Outpoint{{"confirmed", 0}}))
+	m.addEntryToMempool("low", newEntry(100, 5000, []Outpoint{{"confirmed", 1}}))
+	m.addEntryToMempool("child", newEntry(5000, 5000, []Outpoint{{"low", 0}}))
+	m.addEntryToMempool("high1", newEntry(2000, 5000, []Outpoint{{"confirmed", 2}}))
+	m.addEntryToMempool("high2", newEntry(2000, 4000, []Outpoint{{"confirmed", 3}}))
+	high1 := m.txEntries["high1"]
+	high1Memory := estimateEntryMemory("high1", &high1)
+
+	// no limits
+	if evicted := m.evictEntries(6000, 0, 0, 0); len(evicted) != 0 {
+		t.Errorf("evictEntries() without limits = %v, want none", evicted)
+	}
+	// the old entry by age, then the lowest fee rate together with its descendant
+	evicted := m.evictEntries(6000, 3000, 2, 0)
+	sort.Strings(evicted)
+	if want := []string{"child", "low", "old"}; !reflect.DeepEqual(evicted, want) {
+		t.Errorf("evictEntries() = %v, want %v", evicted, want)
+	}
+	// high2 is older than high1 with the same fee rate
+	if evicted = m.evictEntries(6000, 3000, 0, high1Memory); !reflect.DeepEqual(evicted, []string{"high2"}) {
+		t.Errorf("evictEntries() = %v, want [high2]", evicted)
+	}
+	got := m.GetEvictionStats()
+	want := MempoolEvictionStats{Age: 1, Count: 2, Memory: 1, MemoryUsage: m.memoryUsage}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetEvictionStats() = %+v, want %+v", got, want)
+	}
+	if len(m.txEntries) != 1 || m.memoryUsage != high1Memory {
+		t.Errorf("txEntries %v, memoryUsage %v", m.txEntries, m.memoryUsage)
+	}
+}
@@ -273,6 +273,11 @@ func (p *BaseParser) UnpackTx(buf []byte) (*Tx, uint32, error) {
 	return &tx, pt.Height, nil
 }
 
+// ParseXpub is unsupported
+func (p *BaseParser) ParseXpub(xpub string) (*XpubDescriptor, error) {
+	return nil, errors.New("Not supported")
+}
+
 // DerivationBasePath is unsupported
 func (p *BaseParser) DerivationBasePath(xpub string) (string, error) {
 	return "", errors.New("Not supported")
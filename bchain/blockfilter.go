@@ -0,0 +1,142 @@
+package bchain
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math/bits"
+	"sort"
+
+	"github.com/juju/errors"
+)
+
+// parameters of the BIP158 basic filter
+const (
+	basicFilterP = 19
+	basicFilterM = 784931
+)
+
+// BuildBasicBlockFilter returns the serialized BIP158 basic filter of the block with given hash (in the reversed hex format)
+// containing given scripts, the scripts must be unique and must not contain the OP_RETURN outputs
+func BuildBasicBlockFilter(blockHash string, scripts [][]byte) ([]byte, error) {
+	b, err := hex.DecodeString(blockHash)
+	if err != nil || len(b) != 32 {
+		return nil, errors.Errorf("Invalid block hash %v", blockHash)
+	}
+	// the key is the first 16 bytes of the block hash in the internal byte order
+	key := reverseBytes(b)
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+	n := uint64(len(scripts))
+	f := n * basicFilterM
+	values := make([]uint64, len(scripts))
+	for i, s := range scripts {
+		values[i] = mulHi64(sipHash24(k0, k1, s), f)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	w := bitWriter{buf: appendCompactSize(make([]byte, 0, 9+len(scripts)*3), n)}
+	var last uint64
+	for _, v := range values {
+		delta := v - last
+		last = v
+		// golomb-rice coding: the quotient in unary followed by the remainder in P bits
+		for q := delta >> basicFilterP; q > 0; q-- {
+			w.writeBit(1)
+		}
+		w.writeBit(0)
+		for i := basicFilterP - 1; i >= 0; i-- {
+			w.writeBit(uint8(delta>>uint(i)) & 1)
+		}
+	}
+	return w.buf, nil
+}
+
+// GetBlockFilterHeader returns the BIP157 filter header (in the internal byte order) computed from the filter
+// and the header of the previous block filter, the previous header of the genesis block is 32 zero bytes
+func GetBlockFilterHeader(filter []byte, prevHeader []byte) []byte {
+	return doubleSha256(append(doubleSha256(filter), prevHeader...))
+}
+
+type bitWriter struct {
+	buf  []byte
+	free uint8
+}
+
+func (w *bitWriter) writeBit(bit uint8) {
+	if w.free == 0 {
+		w.buf = append(w.buf, 0)
+		w.free = 8
+	}
+	w.free--
+	w.buf[len(w.buf)-1] |= bit << w.free
+}
+
+func appendCompactSize(buf []byte, n uint64) []byte {
+	switch {
+	case n < 0xfd:
+		return append(buf, byte(n))
+	case n <= 0xffff:
+		buf = append(buf, 0xfd, 0, 0)
+		binary.LittleEndian.PutUint16(buf[len(buf)-2:], uint16(n))
+	case n <= 0xffffffff:
+		buf = append(buf, 0xfe, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(buf[len(buf)-4:], uint32(n))
+	default:
+		buf = append(buf, 0xff, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint64(buf[len(buf)-8:], n)
+	}
+	return buf
+}
+
+// mulHi64 returns the high 64 bits of the 128 bit product of a and b
+func mulHi64(a, b uint64) uint64 {
+	aLo, aHi := a&0xffffffff, a>>32
+	bLo, bHi := b&0xffffffff, b>>32
+	t := aHi*bLo + (aLo*bLo)>>32
+	w := t&0xffffffff + aLo*bHi
+	return aHi*bHi + t>>32 + w>>32
+}
+
+// sipHash24 returns the SipHash-2-4 of p with the key k0, k1
+func sipHash24(k0, k1 uint64, p []byte) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+	b := uint64(len(p)) << 56
+	for ; len(p) >= 8; p = p[8:] {
+		m := binary.LittleEndian.Uint64(p)
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+	for i, c := range p {
+		b |= uint64(c) << (8 * uint(i))
+	}
+	v3 ^= b
+	round()
+	round()
+	v0 ^= b
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+	return v0 ^ v1 ^ v2 ^ v3
+}
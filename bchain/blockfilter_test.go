@@ -0,0 +1,95 @@
+package bchain
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// testnet genesis block, the BIP158 test vector
+const filterGenesisHash = "000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943"
+
+func TestBuildBasicBlockFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		hash       string
+		scripts    []string
+		prevHeader string
+		want       string
+		wantHeader string
+		hasErr     bool
+	}{
+		{
+			name:       "testnet genesis",
+			hash:       filterGenesisHash,
+			scripts:    []string{"4104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac"},
+			prevHeader: "0000000000000000000000000000000000000000000000000000000000000000",
+			want:       "019dfca8",
+			wantHeader: "50b781aed7b7129012a6d20e2d040027937f3affaee573779908ebb779455821",
+		},
+		{
+			name: "multiple scripts",
+			hash: filterGenesisHash,
+			scripts: []string{
+				"76a914111111111111111111111111111111111111111188ac",
+				"a914222222222222222222222222222222222222222287",
+				"00143333333333333333333333333333333333333333",
+				"51204444444444444444444444444444444444444444444444444444444444444444",
+			},
+			prevHeader: "50b781aed7b7129012a6d20e2d040027937f3affaee573779908ebb779455821",
+			want:       "04a6de10f41945b803134430",
+			wantHeader: "e13eef05af3fe35d4122782a95621e78c8bde4b99f1a965af6ee0c4ddbc21a49",
+		},
+		{
+			name:       "empty",
+			hash:       filterGenesisHash,
+			prevHeader: "0000000000000000000000000000000000000000000000000000000000000000",
+			want:       "00",
+			wantHeader: "",
+		},
+		{
+			name:   "invalid hash",
+			hash:   "1234",
+			hasErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scripts := make([][]byte, len(tt.scripts))
+			for i, s := range tt.scripts {
+				scripts[i], _ = hex.DecodeString(s)
+			}
+			got, err := BuildBasicBlockFilter(tt.hash, scripts)
+			if (err != nil) != tt.hasErr {
+				t.Fatalf("BuildBasicBlockFilter() error = %v, hasErr %v", err, tt.hasErr)
+			}
+			if tt.hasErr {
+				return
+			}
+			if h := hex.EncodeToString(got); h != tt.want {
+				t.Errorf("BuildBasicBlockFilter() = %v, want %v", h, tt.want)
+			}
+			if tt.wantHeader != "" {
+				prev, _ := hex.DecodeString(tt.prevHeader)
+				if h := hex.EncodeToString(GetBlockFilterHeader(got, prev)); h != tt.wantHeader {
+					t.Errorf("GetBlockFilterHeader() = %v, want %v", h, tt.wantHeader)
+				}
+			}
+		})
+	}
+}
+
+func Test_mulHi64(t *testing.T) {
+	tests := []struct {
+		a, b, want uint64
+	}{
+		{0, 0xffffffffffffffff, 0},
+		{0xffffffffffffffff, 0xffffffffffffffff, 0xfffffffffffffffe},
+		{0x100000000, 0x100000000, 1},
+		{0x123456789abcdef0, 0xfedcba9876543210, 0x121fa00ad77d7422},
+	}
+	for _, tt := range tests {
+		if got := mulHi64(tt.a, tt.b); got != tt.want {
+			t.Errorf("mulHi64(%x, %x) = %x, want %x", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -159,8 +159,8 @@ func (c *blockChainWithMetrics) CreateMempool(chain bchain.BlockChain) (bchain.M
 	return c.b.CreateMempool(chain)
 }
 
-func (c *blockChainWithMetrics) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc) error {
-	return c.b.InitializeMempool(addrDescForOutpoint, onNewTxAddr)
+func (c *blockChainWithMetrics) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, onTxReplaced bchain.OnTxReplacedFunc) error {
+	return c.b.InitializeMempool(addrDescForOutpoint, onNewTxAddr, onTxReplaced)
 }
 
 func (c *blockChainWithMetrics) Shutdown(ctx context.Context) error {
@@ -208,6 +208,11 @@ func (c *blockChainWithMetrics) GetBlockHeader(hash string) (v *bchain.BlockHead
 	return c.b.GetBlockHeader(hash)
 }
 
+func (c *blockChainWithMetrics) GetBlockHeaderRaw(hash string) (v string, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("GetBlockHeaderRaw", s, err) }(time.Now())
+	return c.b.GetBlockHeaderRaw(hash)
+}
+
 func (c *blockChainWithMetrics) GetBlock(hash string, height uint32) (v *bchain.Block, err error) {
 	defer func(s time.Time) { c.observeRPCLatency("GetBlock", s, err) }(time.Now())
 	return c.b.GetBlock(hash, height)
@@ -288,8 +293,9 @@ func (c *blockChainWithMetrics) EthereumTypeGetErc20ContractBalance(addrDesc, co
 }
 
 type mempoolWithMetrics struct {
-	mempool bchain.Mempool
-	m       *common.Metrics
+	mempool   bchain.Mempool
+	m         *common.Metrics
+	evictions bchain.MempoolEvictionStats
 }
 
 func (c *mempoolWithMetrics) observeRPCLatency(method string, start time.Time, err error) {
@@ -305,10 +311,21 @@ func (c *mempoolWithMetrics) Resync() (count int, err error) {
 	count, err = c.mempool.Resync()
 	if err == nil {
 		c.m.MempoolSize.Set(float64(count))
+		c.observeEvictions()
 	}
 	return count, err
 }
 
+// observeEvictions updates the eviction counters by the increments since the last call
+func (c *mempoolWithMetrics) observeEvictions() {
+	e := c.mempool.GetEvictionStats()
+	c.m.MempoolMemory.Set(float64(e.MemoryUsage))
+	c.m.MempoolEvictions.With(common.Labels{"reason": "age"}).Add(float64(e.Age - c.evictions.Age))
+	c.m.MempoolEvictions.With(common.Labels{"reason": "count"}).Add(float64(e.Count - c.evictions.Count))
+	c.m.MempoolEvictions.With(common.Labels{"reason": "memory"}).Add(float64(e.Memory - c.evictions.Memory))
+	c.evictions = e
+}
+
 func (c *mempoolWithMetrics) GetTransactions(address string) (v []bchain.Outpoint, err error) {
 	defer func(s time.Time) { c.observeRPCLatency("GetMempoolTransactions", s, err) }(time.Now())
 	return c.mempool.GetTransactions(address)
@@ -327,3 +344,27 @@ func (c *mempoolWithMetrics) GetAllEntries() (v bchain.MempoolTxidEntries) {
 func (c *mempoolWithMetrics) GetTransactionTime(txid string) uint32 {
 	return c.mempool.GetTransactionTime(txid)
 }
+
+func (c *mempoolWithMetrics) GetStoredEntries() []bchain.MempoolStoredEntry {
+	return c.mempool.GetStoredEntries()
+}
+
+func (c *mempoolWithMetrics) RestoreStoredEntries(entries []bchain.MempoolStoredEntry) int {
+	return c.mempool.RestoreStoredEntries(entries)
+}
+
+func (c *mempoolWithMetrics) GetFeeEntries() []bchain.MempoolFeeEntry {
+	return c.mempool.GetFeeEntries()
+}
+
+func (c *mempoolWithMetrics) GetReplacedBy(txid string) string {
+	return c.mempool.GetReplacedBy(txid)
+}
+
+func (c *mempoolWithMetrics) GetTxPackage(txid string) *bchain.MempoolTxPackage {
+	return c.mempool.GetTxPackage(txid)
+}
+
+func (c *mempoolWithMetrics) GetEvictionStats() bchain.MempoolEvictionStats {
+	return c.mempool.GetEvictionStats()
+}
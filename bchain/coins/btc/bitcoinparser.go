@@ -341,8 +341,15 @@ func (p *BitcoinParser) addrDescFromExtKey(extKey *hdkeychain.ExtendedKey) (bcha
 	return txscript.PayToAddrScript(a)
 }
 
-// DeriveAddressDescriptors derives address descriptors from given xpub for listed indexes
+// DeriveAddressDescriptors derives address descriptors from given xpub or output descriptor for listed indexes
 func (p *BitcoinParser) DeriveAddressDescriptors(xpub string, change uint32, indexes []uint32) ([]bchain.AddressDescriptor, error) {
+	if isOutputDescriptor(xpub) {
+		d, err := p.parseOutputDescriptor(xpub)
+		if err != nil {
+			return nil, err
+		}
+		return p.deriveDescriptorAddresses(d, change, indexes)
+	}
 	extKey, err := hdkeychain.NewKeyFromString(xpub, p.Params.Base58CksumHasher)
 	if err != nil {
 		return nil, err
@@ -365,11 +372,22 @@ func (p *BitcoinParser) DeriveAddressDescriptors(xpub string, change uint32, ind
 	return ad, nil
 }
 
-// DeriveAddressDescriptorsFromTo derives address descriptors from given xpub for addresses in index range
+// DeriveAddressDescriptorsFromTo derives address descriptors from given xpub or output descriptor for addresses in index range
 func (p *BitcoinParser) DeriveAddressDescriptorsFromTo(xpub string, change uint32, fromIndex uint32, toIndex uint32) ([]bchain.AddressDescriptor, error) {
 	if toIndex <= fromIndex {
 		return nil, errors.New("toIndex<=fromIndex")
 	}
+	if isOutputDescriptor(xpub) {
+		d, err := p.parseOutputDescriptor(xpub)
+		if err != nil {
+			return nil, err
+		}
+		indexes := make([]uint32, toIndex-fromIndex)
+		for i := range indexes {
+			indexes[i] = fromIndex + uint32(i)
+		}
+		return p.deriveDescriptorAddresses(d, change, indexes)
+	}
 	extKey, err := hdkeychain.NewKeyFromString(xpub, p.Params.Base58CksumHasher)
 	if err != nil {
 		return nil, err
@@ -392,8 +410,48 @@ func (p *BitcoinParser) DeriveAddressDescriptorsFromTo(xpub string, change uint3
 	return ad, nil
 }
 
-// DerivationBasePath returns base path of xpub
+// ParseXpub parses xpub or output descriptor and returns its type and the derivation steps of the account chains
+func (p *BitcoinParser) ParseXpub(xpub string) (*bchain.XpubDescriptor, error) {
+	if isOutputDescriptor(xpub) {
+		d, err := p.parseOutputDescriptor(xpub)
+		if err != nil {
+			return nil, err
+		}
+		return &bchain.XpubDescriptor{
+			XpubDescriptor: xpub,
+			Type:           d.scriptType,
+			ChangeIndexes:  d.keys[0].chains,
+		}, nil
+	}
+	extKey, err := hdkeychain.NewKeyFromString(xpub, p.Params.Base58CksumHasher)
+	if err != nil {
+		return nil, err
+	}
+	t := descriptorPkh
+	if extKey.Version() == p.XPubMagicSegwitP2sh {
+		t = descriptorShWpkh
+	} else if extKey.Version() == p.XPubMagicSegwitNative {
+		t = descriptorWpkh
+	}
+	return &bchain.XpubDescriptor{
+		XpubDescriptor: xpub,
+		Type:           t,
+		ChangeIndexes:  []uint32{0, 1},
+	}, nil
+}
+
+// DerivationBasePath returns base path of xpub, for output descriptor it is the origin of its first key
 func (p *BitcoinParser) DerivationBasePath(xpub string) (string, error) {
+	if isOutputDescriptor(xpub) {
+		d, err := p.parseOutputDescriptor(xpub)
+		if err != nil {
+			return "", err
+		}
+		if d.keys[0].origin == "" {
+			return "unknown", nil
+		}
+		return d.keys[0].origin, nil
+	}
 	extKey, err := hdkeychain.NewKeyFromString(xpub, p.Params.Base58CksumHasher)
 	if err != nil {
 		return "", err
@@ -12,6 +12,7 @@ import (
 	"net"
 	"net/http"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
@@ -32,8 +33,15 @@ type BitcoinRPC struct {
 	mq           *bchain.MQ
 	ChainConfig  *Configuration
 	RPCMarshaler RPCMarshaler
+	// blocks received from the message queue, waiting to be used by the sync
+	parsedBlocks      map[string]*bchain.Block
+	parsedBlockHashes []string
+	parsedBlocksMux   sync.Mutex
 }
 
+// maxParsedBlocks is the maximum number of blocks received from the message queue kept for the sync
+const maxParsedBlocks = 16
+
 // Configuration represents json config file
 type Configuration struct {
 	CoinName                 string `json:"coin_name"`
@@ -44,10 +52,14 @@ type Configuration struct {
 	RPCTimeout               int    `json:"rpc_timeout"`
 	Parse                    bool   `json:"parse"`
 	MessageQueueBinding      string `json:"message_queue_binding"`
+	MessageQueueRawPayloads  bool   `json:"message_queue_raw_payloads,omitempty"`
 	Subversion               string `json:"subversion"`
 	BlockAddressesToKeep     int    `json:"block_addresses_to_keep"`
 	MempoolWorkers           int    `json:"mempool_workers"`
 	MempoolSubWorkers        int    `json:"mempool_sub_workers"`
+	MempoolTxTimeoutHours    int    `json:"mempool_tx_timeout_hours,omitempty"`
+	MempoolMaxTransactions   int    `json:"mempool_max_transactions,omitempty"`
+	MempoolMaxMemoryMB       int    `json:"mempool_max_memory_mb,omitempty"`
 	AddressFormat            string `json:"address_format"`
 	SupportsEstimateFee      bool   `json:"supports_estimate_fee"`
 	SupportsEstimateSmartFee bool   `json:"supports_estimate_smart_fee"`
@@ -96,6 +108,7 @@ func NewBitcoinRPC(config json.RawMessage, pushHandler func(bchain.NotificationT
 		ChainConfig:  &c,
 		pushHandler:  pushHandler,
 		RPCMarshaler: JSONMarshalerV2{},
+		parsedBlocks: make(map[string]*bchain.Block),
 	}
 
 	return s, nil
@@ -112,14 +125,15 @@ func (b *BitcoinRPC) GetChainInfoAndInitializeMempool(bc bchain.BlockChain) (str
 	}
 	chainName := ci.Chain
 
-	mq, err := bchain.NewMQ(b.ChainConfig.MessageQueueBinding, b.pushHandler)
+	mq, err := bchain.NewMQ(b.ChainConfig.MessageQueueBinding, b.pushHandler, b.mqRawCallback())
 	if err != nil {
 		glog.Error("mq: ", err)
 		return "", err
 	}
 	b.mq = mq
 
-	b.Mempool = bchain.NewMempoolBitcoinType(bc, b.ChainConfig.MempoolWorkers, b.ChainConfig.MempoolSubWorkers)
+	b.Mempool = bchain.NewMempoolBitcoinType(bc, b.ChainConfig.MempoolWorkers, b.ChainConfig.MempoolSubWorkers,
+		b.ChainConfig.MempoolTxTimeoutHours, b.ChainConfig.MempoolMaxTransactions, b.ChainConfig.MempoolMaxMemoryMB)
 
 	return chainName, nil
 }
@@ -157,20 +171,22 @@ func (b *BitcoinRPC) Initialize() error {
 // CreateMempool creates mempool if not already created, however does not initialize it
 func (b *BitcoinRPC) CreateMempool(chain bchain.BlockChain) (bchain.Mempool, error) {
 	if b.Mempool == nil {
-		b.Mempool = bchain.NewMempoolBitcoinType(chain, b.ChainConfig.MempoolWorkers, b.ChainConfig.MempoolSubWorkers)
+		b.Mempool = bchain.NewMempoolBitcoinType(chain, b.ChainConfig.MempoolWorkers, b.ChainConfig.MempoolSubWorkers,
+			b.ChainConfig.MempoolTxTimeoutHours, b.ChainConfig.MempoolMaxTransactions, b.ChainConfig.MempoolMaxMemoryMB)
 	}
 	return b.Mempool, nil
 }
 
-// InitializeMempool creates ZeroMQ subscription and sets AddrDescForOutpointFunc to the Mempool
-func (b *BitcoinRPC) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc) error {
+// InitializeMempool creates ZeroMQ subscription and sets AddrDescForOutpointFunc and notification callbacks to the Mempool
+func (b *BitcoinRPC) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, onTxReplaced bchain.OnTxReplacedFunc) error {
 	if b.Mempool == nil {
 		return errors.New("Mempool not created")
 	}
 	b.Mempool.AddrDescForOutpoint = addrDescForOutpoint
 	b.Mempool.OnNewTxAddr = onNewTxAddr
+	b.Mempool.OnTxReplaced = onTxReplaced
 	if b.mq == nil {
-		mq, err := bchain.NewMQ(b.ChainConfig.MessageQueueBinding, b.pushHandler)
+		mq, err := bchain.NewMQ(b.ChainConfig.MessageQueueBinding, b.pushHandler, b.mqRawCallback())
 		if err != nil {
 			glog.Error("mq: ", err)
 			return err
@@ -180,6 +196,73 @@ func (b *BitcoinRPC) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOut
 	return nil
 }
 
+// mqRawCallback returns the handler of raw payloads from ZeroMQ if they are enabled in the configuration
+func (b *BitcoinRPC) mqRawCallback() bchain.MQRawCallback {
+	if !b.ChainConfig.MessageQueueRawPayloads {
+		return nil
+	}
+	return b.onRawMessage
+}
+
+// onRawMessage parses raw transaction or block received from ZeroMQ and passes it to the mempool or the sync,
+// the payloads which cannot be parsed are ignored, the data are then fetched using RPC
+func (b *BitcoinRPC) onRawMessage(nt bchain.NotificationType, hash string, data []byte) {
+	switch nt {
+	case bchain.NotificationNewTx:
+		if b.Mempool == nil {
+			return
+		}
+		tx, err := b.Parser.ParseTx(data)
+		if err != nil {
+			glog.V(1).Info("mq: rawtx parse error ", err)
+			return
+		}
+		b.Mempool.AddParsedTx(tx)
+	case bchain.NotificationNewBlock:
+		if !b.ParseBlocks {
+			return
+		}
+		block, err := b.Parser.ParseBlock(data)
+		if err != nil {
+			glog.V(1).Info("mq: rawblock ", hash, " parse error ", err)
+			return
+		}
+		b.addParsedBlock(hash, block)
+	}
+}
+
+func (b *BitcoinRPC) addParsedBlock(hash string, block *bchain.Block) {
+	b.parsedBlocksMux.Lock()
+	defer b.parsedBlocksMux.Unlock()
+	if _, found := b.parsedBlocks[hash]; found {
+		return
+	}
+	if len(b.parsedBlockHashes) >= maxParsedBlocks {
+		delete(b.parsedBlocks, b.parsedBlockHashes[0])
+		b.parsedBlockHashes = b.parsedBlockHashes[1:]
+	}
+	b.parsedBlocks[hash] = block
+	b.parsedBlockHashes = append(b.parsedBlockHashes, hash)
+}
+
+// popParsedBlock returns and removes the block received from ZeroMQ, nil if there is no such block
+func (b *BitcoinRPC) popParsedBlock(hash string) *bchain.Block {
+	b.parsedBlocksMux.Lock()
+	defer b.parsedBlocksMux.Unlock()
+	block, found := b.parsedBlocks[hash]
+	if !found {
+		return nil
+	}
+	delete(b.parsedBlocks, hash)
+	for i := range b.parsedBlockHashes {
+		if b.parsedBlockHashes[i] == hash {
+			b.parsedBlockHashes = append(b.parsedBlockHashes[:i], b.parsedBlockHashes[i+1:]...)
+			break
+		}
+	}
+	return block
+}
+
 // Shutdown ZeroMQ and other resources
 func (b *BitcoinRPC) Shutdown(ctx context.Context) error {
 	if b.mq != nil {
@@ -538,6 +621,33 @@ func (b *BitcoinRPC) GetBlockHeader(hash string) (*bchain.BlockHeader, error) {
 	return &res.Result, nil
 }
 
+type ResGetBlockHeaderRaw struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result string           `json:"result"`
+}
+
+// GetBlockHeaderRaw returns serialized header of block with given hash as hex string.
+func (b *BitcoinRPC) GetBlockHeaderRaw(hash string) (string, error) {
+	glog.V(1).Info("rpc: getblockheader (verbose=false) ", hash)
+
+	res := ResGetBlockHeaderRaw{}
+	req := CmdGetBlockHeader{Method: "getblockheader"}
+	req.Params.BlockHash = hash
+	req.Params.Verbose = false
+	err := b.Call(&req, &res)
+
+	if err != nil {
+		return "", errors.Annotatef(err, "hash %v", hash)
+	}
+	if res.Error != nil {
+		if IsErrBlockNotFound(res.Error) {
+			return "", bchain.ErrBlockNotFound
+		}
+		return "", errors.Annotatef(res.Error, "hash %v", hash)
+	}
+	return res.Result, nil
+}
+
 // GetBlock returns block with given hash.
 func (b *BitcoinRPC) GetBlock(hash string, height uint32) (*bchain.Block, error) {
 	var err error
@@ -558,13 +668,16 @@ func (b *BitcoinRPC) GetBlock(hash string, height uint32) (*bchain.Block, error)
 	if err != nil {
 		return nil, err
 	}
-	data, err := b.GetBlockRaw(hash)
-	if err != nil {
-		return nil, err
-	}
-	block, err := b.Parser.ParseBlock(data)
-	if err != nil {
-		return nil, errors.Annotatef(err, "hash %v", hash)
+	block := b.popParsedBlock(hash)
+	if block == nil {
+		data, err := b.GetBlockRaw(hash)
+		if err != nil {
+			return nil, err
+		}
+		block, err = b.Parser.ParseBlock(data)
+		if err != nil {
+			return nil, errors.Annotatef(err, "hash %v", hash)
+		}
 	}
 	block.BlockHeader = *header
 	return block, nil
@@ -595,13 +708,16 @@ func (b *BitcoinRPC) GetBlockInfo(hash string) (*bchain.BlockInfo, error) {
 // GetBlockWithoutHeader is an optimization - it does not call GetBlockHeader to get prev, next hashes
 // instead it sets to header only block hash and height passed in parameters
 func (b *BitcoinRPC) GetBlockWithoutHeader(hash string, height uint32) (*bchain.Block, error) {
-	data, err := b.GetBlockRaw(hash)
-	if err != nil {
-		return nil, err
-	}
-	block, err := b.Parser.ParseBlock(data)
-	if err != nil {
-		return nil, errors.Annotatef(err, "%v %v", height, hash)
+	block := b.popParsedBlock(hash)
+	if block == nil {
+		data, err := b.GetBlockRaw(hash)
+		if err != nil {
+			return nil, err
+		}
+		block, err = b.Parser.ParseBlock(data)
+		if err != nil {
+			return nil, errors.Annotatef(err, "%v %v", height, hash)
+		}
 	}
 	block.BlockHeader.Hash = hash
 	block.BlockHeader.Height = height
@@ -0,0 +1,75 @@
+// +build unittest
+
+package btc
+
+import (
+	"blockbook/bchain"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestBitcoinRPC_parsedBlocks(t *testing.T) {
+	block := func(i int) *bchain.Block {
+		return &bchain.Block{BlockHeader: bchain.BlockHeader{Hash: fmt.Sprint("hash", i), Height: uint32(i)}}
+	}
+	tests := []struct {
+		name       string
+		add        []int
+		pop        []string
+		wantPopped []bool
+		wantHashes []string
+	}{
+		{
+			name:       "add and pop",
+			add:        []int{1, 2},
+			pop:        []string{"hash1", "hash1", "hash3"},
+			wantPopped: []bool{true, false, false},
+			wantHashes: []string{"hash2"},
+		},
+		{
+			name:       "add the same block twice",
+			add:        []int{1, 1, 2},
+			pop:        []string{},
+			wantPopped: []bool{},
+			wantHashes: []string{"hash1", "hash2"},
+		},
+		{
+			name:       "evict the oldest blocks",
+			add:        []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17},
+			pop:        []string{"hash0", "hash1", "hash2"},
+			wantPopped: []bool{false, false, true},
+			wantHashes: []string{"hash3", "hash4", "hash5", "hash6", "hash7", "hash8", "hash9", "hash10", "hash11", "hash12", "hash13", "hash14", "hash15", "hash16", "hash17"},
+		},
+		{
+			name:       "pop from the middle",
+			add:        []int{1, 2, 3},
+			pop:        []string{"hash2"},
+			wantPopped: []bool{true},
+			wantHashes: []string{"hash1", "hash3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &BitcoinRPC{parsedBlocks: make(map[string]*bchain.Block)}
+			for _, i := range tt.add {
+				b.addParsedBlock(fmt.Sprint("hash", i), block(i))
+			}
+			for i, hash := range tt.pop {
+				got := b.popParsedBlock(hash)
+				if (got != nil) != tt.wantPopped[i] {
+					t.Errorf("popParsedBlock(%v) = %+v, want found %v", hash, got, tt.wantPopped[i])
+				}
+				if got != nil && got.Hash != hash {
+					t.Errorf("popParsedBlock(%v) returned block %v", hash, got.Hash)
+				}
+			}
+			if !reflect.DeepEqual(b.parsedBlockHashes, tt.wantHashes) {
+				t.Errorf("parsedBlockHashes = %v, want %v", b.parsedBlockHashes, tt.wantHashes)
+			}
+			if len(b.parsedBlocks) != len(tt.wantHashes) {
+				t.Errorf("len(parsedBlocks) = %v, want %v", len(b.parsedBlocks), len(tt.wantHashes))
+			}
+		})
+	}
+}
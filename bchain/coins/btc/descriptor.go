@@ -0,0 +1,356 @@
+package btc
+
+import (
+	"blockbook/bchain"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcutil"
+	"github.com/martinboehm/btcutil/hdkeychain"
+	"github.com/martinboehm/btcutil/txscript"
+)
+
+// output descriptor script types supported for account queries
+const (
+	descriptorPkh         = "pkh"
+	descriptorShWpkh      = "sh(wpkh)"
+	descriptorWpkh        = "wpkh"
+	descriptorShMulti     = "sh(multi)"
+	descriptorWshMulti    = "wsh(multi)"
+	descriptorShWshMulti  = "sh(wsh(multi))"
+	maxShMultisigKeys     = 15
+	maxWshMultisigKeys    = 20
+	descriptorChecksumLen = 8
+)
+
+const descriptorInputCharset = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+const descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// descriptorKey is an extended public key in an output descriptor with the derivation steps following it,
+// the key must be followed by one step selecting the chain (possibly a multipath step <a;b;...>) and by /*
+type descriptorKey struct {
+	extKey *hdkeychain.ExtendedKey
+	origin string
+	chains []uint32
+}
+
+// outputDescriptor is a parsed ranged output descriptor
+type outputDescriptor struct {
+	scriptType string
+	threshold  int
+	sorted     bool
+	keys       []descriptorKey
+}
+
+// isOutputDescriptor returns true if the xpub parameter of account queries is an output descriptor
+func isOutputDescriptor(s string) bool {
+	return strings.IndexByte(s, '(') > 0
+}
+
+func descriptorPolymod(c uint64, val int) uint64 {
+	c0 := c >> 35
+	c = ((c & 0x7ffffffff) << 5) ^ uint64(val)
+	if c0&1 != 0 {
+		c ^= 0xf5dee51989
+	}
+	if c0&2 != 0 {
+		c ^= 0xa9fdca3312
+	}
+	if c0&4 != 0 {
+		c ^= 0x1bab10e32d
+	}
+	if c0&8 != 0 {
+		c ^= 0x3706b1677a
+	}
+	if c0&16 != 0 {
+		c ^= 0x644d626ffd
+	}
+	return c
+}
+
+// descriptorChecksum computes the checksum of the output descriptor as defined in BIP380
+func descriptorChecksum(s string) (string, error) {
+	c := uint64(1)
+	cls := 0
+	clscount := 0
+	for i := 0; i < len(s); i++ {
+		pos := strings.IndexByte(descriptorInputCharset, s[i])
+		if pos < 0 {
+			return "", errors.Errorf("Invalid character '%c' in descriptor", s[i])
+		}
+		c = descriptorPolymod(c, pos&31)
+		cls = cls*3 + (pos >> 5)
+		clscount++
+		if clscount == 3 {
+			c = descriptorPolymod(c, cls)
+			cls = 0
+			clscount = 0
+		}
+	}
+	if clscount > 0 {
+		c = descriptorPolymod(c, cls)
+	}
+	for i := 0; i < descriptorChecksumLen; i++ {
+		c = descriptorPolymod(c, 0)
+	}
+	c ^= 1
+	r := make([]byte, descriptorChecksumLen)
+	for i := range r {
+		r[i] = descriptorChecksumCharset[(c>>(5*(7-uint(i))))&31]
+	}
+	return string(r), nil
+}
+
+// unwrapDescriptorFunction returns the argument of the function fn if s is fn(argument)
+func unwrapDescriptorFunction(s string, fn string) (string, bool) {
+	if strings.HasPrefix(s, fn+"(") && strings.HasSuffix(s, ")") {
+		return s[len(fn)+1 : len(s)-1], true
+	}
+	return "", false
+}
+
+// parseDerivationStep parses non hardened derivation step
+func parseDerivationStep(s string) (uint32, error) {
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || n >= hdkeychain.HardenedKeyStart {
+		return 0, errors.Errorf("Invalid derivation step '%v', only unhardened steps are supported after the key", s)
+	}
+	return uint32(n), nil
+}
+
+// parseOrigin parses the key origin [fingerprint/path] and returns the path in the form m/a'/b'
+func parseOrigin(s string) (string, error) {
+	steps := strings.Split(s, "/")
+	if len(steps[0]) != 8 {
+		return "", errors.Errorf("Invalid key origin fingerprint '%v'", steps[0])
+	}
+	if _, err := hex.DecodeString(steps[0]); err != nil {
+		return "", errors.Errorf("Invalid key origin fingerprint '%v'", steps[0])
+	}
+	path := "m"
+	for _, step := range steps[1:] {
+		hardened := ""
+		if strings.HasSuffix(step, "'") || strings.HasSuffix(step, "h") {
+			hardened = "'"
+			step = step[:len(step)-1]
+		}
+		if _, err := strconv.ParseUint(step, 10, 31); err != nil {
+			return "", errors.Errorf("Invalid key origin path step '%v'", step)
+		}
+		path += "/" + step + hardened
+	}
+	return path, nil
+}
+
+func (p *BitcoinParser) parseDescriptorKey(s string) (*descriptorKey, error) {
+	var k descriptorKey
+	if strings.HasPrefix(s, "[") {
+		i := strings.IndexByte(s, ']')
+		if i < 0 {
+			return nil, errors.New("Missing ']' in key origin")
+		}
+		origin, err := parseOrigin(s[1:i])
+		if err != nil {
+			return nil, err
+		}
+		k.origin = origin
+		s = s[i+1:]
+	}
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 || parts[2] != "*" {
+		return nil, errors.Errorf("Unsupported key '%v', the key must be followed by /<chain>/* or /<receive;change>/*", s)
+	}
+	extKey, err := hdkeychain.NewKeyFromString(parts[0], p.Params.Base58CksumHasher)
+	if err != nil {
+		return nil, errors.Annotatef(err, "key %v", parts[0])
+	}
+	if extKey.IsPrivate() {
+		return nil, errors.New("Private keys are not supported")
+	}
+	k.extKey = extKey
+	chains := parts[1]
+	if strings.HasPrefix(chains, "<") && strings.HasSuffix(chains, ">") {
+		chains = chains[1 : len(chains)-1]
+	}
+	for _, c := range strings.Split(chains, ";") {
+		n, err := parseDerivationStep(c)
+		if err != nil {
+			return nil, err
+		}
+		k.chains = append(k.chains, n)
+	}
+	return &k, nil
+}
+
+func (p *BitcoinParser) parseMultisigDescriptor(s string, d *outputDescriptor, maxKeys int) error {
+	args, ok := unwrapDescriptorFunction(s, "multi")
+	if !ok {
+		if args, ok = unwrapDescriptorFunction(s, "sortedmulti"); !ok {
+			return errors.Errorf("Unsupported descriptor '%v'", s)
+		}
+		d.sorted = true
+	}
+	parts := strings.Split(args, ",")
+	if len(parts) < 2 {
+		return errors.New("Missing multisig keys")
+	}
+	threshold, err := strconv.Atoi(parts[0])
+	if err != nil || threshold < 1 || threshold > len(parts)-1 {
+		return errors.Errorf("Invalid multisig threshold '%v'", parts[0])
+	}
+	if len(parts)-1 > maxKeys {
+		return errors.Errorf("Too many multisig keys, maximum is %d", maxKeys)
+	}
+	d.threshold = threshold
+	for _, ks := range parts[1:] {
+		k, err := p.parseDescriptorKey(ks)
+		if err != nil {
+			return err
+		}
+		d.keys = append(d.keys, *k)
+	}
+	return nil
+}
+
+// parseOutputDescriptor parses the ranged output descriptor, optionally with checksum
+func (p *BitcoinParser) parseOutputDescriptor(s string) (*outputDescriptor, error) {
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		checksum, err := descriptorChecksum(s[:i])
+		if err != nil {
+			return nil, err
+		}
+		if s[i+1:] != checksum {
+			return nil, errors.Errorf("Invalid descriptor checksum '%v'", s[i+1:])
+		}
+		s = s[:i]
+	}
+	var d outputDescriptor
+	var err error
+	if inner, ok := unwrapDescriptorFunction(s, "pkh"); ok {
+		d.scriptType = descriptorPkh
+		err = p.parseSingleKeyDescriptor(inner, &d)
+	} else if inner, ok := unwrapDescriptorFunction(s, "wpkh"); ok {
+		d.scriptType = descriptorWpkh
+		err = p.parseSingleKeyDescriptor(inner, &d)
+	} else if inner, ok := unwrapDescriptorFunction(s, "wsh"); ok {
+		d.scriptType = descriptorWshMulti
+		err = p.parseMultisigDescriptor(inner, &d, maxWshMultisigKeys)
+	} else if inner, ok := unwrapDescriptorFunction(s, "sh"); ok {
+		if inner2, ok := unwrapDescriptorFunction(inner, "wpkh"); ok {
+			d.scriptType = descriptorShWpkh
+			err = p.parseSingleKeyDescriptor(inner2, &d)
+		} else if inner2, ok := unwrapDescriptorFunction(inner, "wsh"); ok {
+			d.scriptType = descriptorShWshMulti
+			err = p.parseMultisigDescriptor(inner2, &d, maxWshMultisigKeys)
+		} else {
+			d.scriptType = descriptorShMulti
+			err = p.parseMultisigDescriptor(inner, &d, maxShMultisigKeys)
+		}
+	} else {
+		return nil, errors.Errorf("Unsupported descriptor '%v'", s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(d.keys); i++ {
+		if len(d.keys[i].chains) != len(d.keys[0].chains) {
+			return nil, errors.New("All keys of the descriptor must have the same number of derivation paths")
+		}
+	}
+	return &d, nil
+}
+
+func (p *BitcoinParser) parseSingleKeyDescriptor(s string, d *outputDescriptor) error {
+	k, err := p.parseDescriptorKey(s)
+	if err != nil {
+		return err
+	}
+	d.keys = []descriptorKey{*k}
+	return nil
+}
+
+// deriveDescriptorAddresses derives address descriptors for the indexes of the chain given by its position in the descriptor
+func (p *BitcoinParser) deriveDescriptorAddresses(d *outputDescriptor, chain uint32, indexes []uint32) ([]bchain.AddressDescriptor, error) {
+	if int(chain) >= len(d.keys[0].chains) {
+		return nil, errors.Errorf("Descriptor does not have derivation path %d", chain)
+	}
+	chainKeys := make([]*hdkeychain.ExtendedKey, len(d.keys))
+	for i := range d.keys {
+		var err error
+		if chainKeys[i], err = d.keys[i].extKey.Child(d.keys[i].chains[chain]); err != nil {
+			return nil, err
+		}
+	}
+	ad := make([]bchain.AddressDescriptor, len(indexes))
+	pubKeys := make([][]byte, len(d.keys))
+	for i, index := range indexes {
+		for j := range chainKeys {
+			indexKey, err := chainKeys[j].Child(index)
+			if err != nil {
+				return nil, err
+			}
+			pubKeys[j] = indexKey.PubKeyBytes()
+		}
+		var err error
+		if ad[i], err = p.descriptorOutputScript(d, pubKeys); err != nil {
+			return nil, err
+		}
+	}
+	return ad, nil
+}
+
+// descriptorOutputScript creates the output script (address descriptor) of the descriptor from derived public keys
+func (p *BitcoinParser) descriptorOutputScript(d *outputDescriptor, pubKeys [][]byte) (bchain.AddressDescriptor, error) {
+	var a btcutil.Address
+	var script []byte
+	var err error
+	switch d.scriptType {
+	case descriptorPkh:
+		a, err = btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKeys[0]), p.Params)
+	case descriptorWpkh:
+		a, err = btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKeys[0]), p.Params)
+	case descriptorShWpkh:
+		// redeemScript <witness version: OP_0><len pubKeyHash: 20><20-byte-pubKeyHash>
+		if script, err = txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(btcutil.Hash160(pubKeys[0])).Script(); err != nil {
+			return nil, err
+		}
+		a, err = btcutil.NewAddressScriptHashFromHash(btcutil.Hash160(script), p.Params)
+	default:
+		keys := pubKeys
+		if d.sorted {
+			keys = make([][]byte, len(pubKeys))
+			copy(keys, pubKeys)
+			sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+		}
+		b := txscript.NewScriptBuilder().AddInt64(int64(d.threshold))
+		for _, k := range keys {
+			b.AddData(k)
+		}
+		if script, err = b.AddInt64(int64(len(keys))).AddOp(txscript.OP_CHECKMULTISIG).Script(); err != nil {
+			return nil, err
+		}
+		switch d.scriptType {
+		case descriptorShMulti:
+			a, err = btcutil.NewAddressScriptHashFromHash(btcutil.Hash160(script), p.Params)
+		case descriptorWshMulti:
+			h := sha256.Sum256(script)
+			a, err = btcutil.NewAddressWitnessScriptHash(h[:], p.Params)
+		case descriptorShWshMulti:
+			// the witness script is nested in P2SH redeemScript <OP_0><len: 32><32-byte-sha256(multisig script)>
+			h := sha256.Sum256(script)
+			if script, err = txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(h[:]).Script(); err != nil {
+				return nil, err
+			}
+			a, err = btcutil.NewAddressScriptHashFromHash(btcutil.Hash160(script), p.Params)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(a)
+}
@@ -0,0 +1,257 @@
+// +build unittest
+
+package btc
+
+import (
+	"reflect"
+	"testing"
+)
+
+const (
+	testDescriptorXpub = "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj"
+	testDescriptorYpub = "ypub6Ww3ibxVfGzLrAH1PNcjyAWenMTbbAosGNB6VvmSEgytSER9azLDWCxoJwW7Ke7icmizBMXrzBx9979FfaHxHcrArf3zbeJJJUZPf663zsP"
+	testDescriptorZpub = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	testDescriptorWsh  = "wsh(sortedmulti(2,[d34db33f/48'/0'/0'/2']" + testDescriptorXpub + "/<0;1>/*," + testDescriptorYpub + "/<0;1>/*," + testDescriptorZpub + "/<0;1>/*))"
+)
+
+func Test_descriptorChecksum(t *testing.T) {
+	tests := []struct {
+		descriptor string
+		want       string
+	}{
+		{"raw(deadbeef)", "89f8spxm"},
+		{testDescriptorWsh, "m883ccfr"},
+	}
+	for _, tt := range tests {
+		got, err := descriptorChecksum(tt.descriptor)
+		if err != nil {
+			t.Errorf("descriptorChecksum(%v) error %v", tt.descriptor, err)
+		}
+		if got != tt.want {
+			t.Errorf("descriptorChecksum(%v) = %v, want %v", tt.descriptor, got, tt.want)
+		}
+	}
+}
+
+func TestBitcoinParser_ParseXpub(t *testing.T) {
+	btcMainParser := NewBitcoinParser(GetChainParams("main"), &Configuration{XPubMagic: 76067358, XPubMagicSegwitP2sh: 77429938, XPubMagicSegwitNative: 78792518})
+	tests := []struct {
+		name              string
+		xpub              string
+		wantType          string
+		wantChangeIndexes []uint32
+		wantErr           bool
+	}{
+		{
+			name:              "xpub",
+			xpub:              testDescriptorXpub,
+			wantType:          "pkh",
+			wantChangeIndexes: []uint32{0, 1},
+		},
+		{
+			name:              "ypub",
+			xpub:              testDescriptorYpub,
+			wantType:          "sh(wpkh)",
+			wantChangeIndexes: []uint32{0, 1},
+		},
+		{
+			name:              "wpkh single path",
+			xpub:              "wpkh([d34db33f/84h/0h/0h]" + testDescriptorZpub + "/7/*)",
+			wantType:          "wpkh",
+			wantChangeIndexes: []uint32{7},
+		},
+		{
+			name:              "wsh sortedmulti with checksum",
+			xpub:              testDescriptorWsh + "#m883ccfr",
+			wantType:          "wsh(multi)",
+			wantChangeIndexes: []uint32{0, 1},
+		},
+		{
+			name:    "invalid checksum",
+			xpub:    testDescriptorWsh + "#m883ccfq",
+			wantErr: true,
+		},
+		{
+			name:    "hardened wildcard",
+			xpub:    "pkh(" + testDescriptorXpub + "/0/*h)",
+			wantErr: true,
+		},
+		{
+			name:    "missing wildcard",
+			xpub:    "pkh(" + testDescriptorXpub + "/0/1)",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported script",
+			xpub:    "tr(" + testDescriptorXpub + "/0/*)",
+			wantErr: true,
+		},
+		{
+			name:    "threshold over number of keys",
+			xpub:    "sh(multi(3," + testDescriptorXpub + "/0/*," + testDescriptorYpub + "/0/*))",
+			wantErr: true,
+		},
+		{
+			name:    "different number of paths",
+			xpub:    "sh(multi(1," + testDescriptorXpub + "/<0;1>/*," + testDescriptorYpub + "/0/*))",
+			wantErr: true,
+		},
+		{
+			name:    "invalid xpub",
+			xpub:    "xpub123",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := btcMainParser.ParseXpub(tt.xpub)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseXpub() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got.Type != tt.wantType {
+				t.Errorf("ParseXpub() Type = %v, want %v", got.Type, tt.wantType)
+			}
+			if !reflect.DeepEqual(got.ChangeIndexes, tt.wantChangeIndexes) {
+				t.Errorf("ParseXpub() ChangeIndexes = %v, want %v", got.ChangeIndexes, tt.wantChangeIndexes)
+			}
+		})
+	}
+}
+
+func TestDeriveAddressDescriptorsFromDescriptor(t *testing.T) {
+	btcMainParser := NewBitcoinParser(GetChainParams("main"), &Configuration{XPubMagic: 76067358, XPubMagicSegwitP2sh: 77429938, XPubMagicSegwitNative: 78792518})
+	type args struct {
+		descriptor string
+		change     uint32
+		fromIndex  uint32
+		toIndex    uint32
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "pkh",
+			args: args{
+				descriptor: "pkh([d34db33f/44'/0'/0']" + testDescriptorXpub + "/0/*)",
+				fromIndex:  0,
+				toIndex:    1,
+			},
+			want: []string{"1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA"},
+		},
+		{
+			name: "sh(wpkh)",
+			args: args{
+				descriptor: "sh(wpkh(" + testDescriptorYpub + "/0/*))",
+				fromIndex:  0,
+				toIndex:    1,
+			},
+			want: []string{"37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"},
+		},
+		{
+			name: "wpkh",
+			args: args{
+				descriptor: "wpkh(" + testDescriptorZpub + "/<0;1>/*)",
+				fromIndex:  0,
+				toIndex:    1,
+			},
+			want: []string{"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"},
+		},
+		{
+			name: "sh(multi)",
+			args: args{
+				descriptor: "sh(multi(2," + testDescriptorXpub + "/0/*," + testDescriptorYpub + "/0/*," + testDescriptorZpub + "/0/*))",
+				fromIndex:  0,
+				toIndex:    2,
+			},
+			want: []string{"3A8dFYbd2ha6EthBc2JrwX8UE8JozFFBFE", "3PCknU9JvHdeQfYT5pbj4P4sLNcp12MvvC"},
+		},
+		{
+			name: "wsh(sortedmulti) receive",
+			args: args{
+				descriptor: testDescriptorWsh,
+				change:     0,
+				fromIndex:  0,
+				toIndex:    2,
+			},
+			want: []string{"bc1qkfhl4j640ujzldlt5ppylyp2f8h343xf79z5qh9kfgw2x0ejlvuqkd26cl", "bc1q0ndnght75tl36md24w9u7ur8n8urzxxw2ak86u7l9rkkmfyc2q9q5qqn38"},
+		},
+		{
+			name: "wsh(sortedmulti) change",
+			args: args{
+				descriptor: testDescriptorWsh,
+				change:     1,
+				fromIndex:  0,
+				toIndex:    2,
+			},
+			want: []string{"bc1q7s0ftueakqpu6qr2ueycnle0g5jm96u7wf3uvtvxgh8kmyrts4lsrnv2ar", "bc1q982fsm0f785j40auwrh28lm4reh0ut0ywtrs02dtp6l5m077wd9qtqmhfl"},
+		},
+		{
+			name: "sh(wsh(sortedmulti))",
+			args: args{
+				descriptor: "sh(wsh(sortedmulti(2," + testDescriptorZpub + "/1/*," + testDescriptorXpub + "/1/*," + testDescriptorYpub + "/1/*)))",
+				fromIndex:  0,
+				toIndex:    2,
+			},
+			want: []string{"3CP9cbWc1JLc8u6NHEbyPKqBinVB6vP4nX", "3AyavDfJGLneMkPwDS6gANwgFwaAYCkwHb"},
+		},
+		{
+			name: "missing derivation path",
+			args: args{
+				descriptor: "wpkh(" + testDescriptorZpub + "/0/*)",
+				change:     1,
+				fromIndex:  0,
+				toIndex:    1,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := btcMainParser.DeriveAddressDescriptorsFromTo(tt.args.descriptor, tt.args.change, tt.args.fromIndex, tt.args.toIndex)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DeriveAddressDescriptorsFromTo() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			gotAddresses := make([]string, len(got))
+			for i, ad := range got {
+				aa, _, err := btcMainParser.GetAddressesFromAddrDesc(ad)
+				if err != nil || len(aa) != 1 {
+					t.Errorf("DeriveAddressDescriptorsFromTo() got incorrect address descriptor %v, error %v", ad, err)
+					return
+				}
+				gotAddresses[i] = aa[0]
+			}
+			if !tt.wantErr && !reflect.DeepEqual(gotAddresses, tt.want) {
+				t.Errorf("DeriveAddressDescriptorsFromTo() = %v, want %v", gotAddresses, tt.want)
+			}
+		})
+	}
+}
+
+func TestBitcoinParser_DerivationBasePathDescriptor(t *testing.T) {
+	btcMainParser := NewBitcoinParser(GetChainParams("main"), &Configuration{XPubMagic: 76067358, XPubMagicSegwitP2sh: 77429938, XPubMagicSegwitNative: 78792518})
+	tests := []struct {
+		descriptor string
+		want       string
+	}{
+		{testDescriptorWsh, "m/48'/0'/0'/2'"},
+		{"wpkh([d34db33f/84h/0h/0h]" + testDescriptorZpub + "/0/*)", "m/84'/0'/0'"},
+		{"pkh(" + testDescriptorXpub + "/0/*)", "unknown"},
+	}
+	for _, tt := range tests {
+		got, err := btcMainParser.DerivationBasePath(tt.descriptor)
+		if err != nil {
+			t.Errorf("DerivationBasePath(%v) error %v", tt.descriptor, err)
+		}
+		if got != tt.want {
+			t.Errorf("DerivationBasePath(%v) = %v, want %v", tt.descriptor, got, tt.want)
+		}
+	}
+}
@@ -1,63 +1,130 @@
 package bchain
 
 import (
+	"encoding/hex"
+	"math/big"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
 )
 
+// parsedTxRetention is the time for which the transactions received from the message queue are kept for resync
+const parsedTxRetention = time.Minute
+
+type parsedTx struct {
+	tx   *Tx
+	time time.Time
+}
+
+// resolvedInput is the address and value of the output spent by a mempool transaction input
+type resolvedInput struct {
+	addrIndex
+	valueSat big.Int
+}
+
 // MempoolBitcoinType is mempool handle.
 type MempoolBitcoinType struct {
 	BaseMempool
 	chanTxid            chan string
 	chanAddrIndex       chan txidio
 	AddrDescForOutpoint AddrDescForOutpointFunc
+	parsedTxs           map[string]parsedTx
+	parsedTxsMux        sync.Mutex
+	txTimeout           uint32
+	maxTransactions     int
+	maxMemory           int64
+	// evicted contains txids evicted by Blockbook which are still in the backend mempool, they are not fetched again
+	evicted map[string]struct{}
 }
 
 // NewMempoolBitcoinType creates new mempool handler.
+// The transactions older than txTimeoutHours are evicted from the mempool, if the number of transactions exceeds
+// maxTransactions or the estimated memory usage exceeds maxMemoryMB, the transactions with the lowest fee rate are evicted.
+// Zero value of a limit means no limit.
 // For now there is no cleanup of sync routines, the expectation is that the mempool is created only once per process
-func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int) *MempoolBitcoinType {
+func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int, txTimeoutHours int, maxTransactions int, maxMemoryMB int) *MempoolBitcoinType {
 	m := &MempoolBitcoinType{
 		BaseMempool: BaseMempool{
-			chain:        chain,
-			txEntries:    make(map[string]txEntry),
-			addrDescToTx: make(map[string][]Outpoint),
+			chain:          chain,
+			txEntries:      make(map[string]txEntry),
+			addrDescToTx:   make(map[string][]Outpoint),
+			spentOutpoints: make(map[Outpoint]string),
+			replacements:   make(map[string]txReplacement),
+			childTxs:       make(map[string][]string),
 		},
-		chanTxid:      make(chan string, 1),
-		chanAddrIndex: make(chan txidio, 1),
+		chanTxid:        make(chan string, 1),
+		chanAddrIndex:   make(chan txidio, 1),
+		parsedTxs:       make(map[string]parsedTx),
+		txTimeout:       uint32(txTimeoutHours) * 3600,
+		maxTransactions: maxTransactions,
+		maxMemory:       int64(maxMemoryMB) * 1024 * 1024,
+		evicted:         make(map[string]struct{}),
 	}
 	for i := 0; i < workers; i++ {
 		go func(i int) {
 			chanInput := make(chan Outpoint, 1)
-			chanResult := make(chan *addrIndex, 1)
+			chanResult := make(chan *resolvedInput, 1)
 			for j := 0; j < subworkers; j++ {
 				go func(j int) {
 					for input := range chanInput {
-						ai := m.getInputAddress(input)
-						chanResult <- ai
+						ri := m.getInputAddress(input)
+						chanResult <- ri
 					}
 				}(j)
 			}
 			for txid := range m.chanTxid {
-				io, ok := m.getTxAddrs(txid, chanInput, chanResult)
+				tio, ok := m.getTxAddrs(txid, chanInput, chanResult)
 				if !ok {
-					io = []addrIndex{}
+					tio = txidio{txid: txid, io: []addrIndex{}}
 				}
-				m.chanAddrIndex <- txidio{txid, io}
+				m.chanAddrIndex <- tio
 			}
 		}(i)
 	}
-	glog.Info("mempool: starting with ", workers, "*", subworkers, " sync workers")
+	glog.Info("mempool: starting with ", workers, "*", subworkers, " sync workers, limits: tx timeout ", txTimeoutHours,
+		" hours, max transactions ", maxTransactions, ", max memory ", maxMemoryMB, " MB")
 	return m
 }
 
-func (m *MempoolBitcoinType) getInputAddress(input Outpoint) *addrIndex {
+// AddParsedTx stores transaction received from the message queue, it is used by the following resync instead of the RPC call
+func (m *MempoolBitcoinType) AddParsedTx(tx *Tx) {
+	m.parsedTxsMux.Lock()
+	m.parsedTxs[tx.Txid] = parsedTx{tx: tx, time: time.Now()}
+	m.parsedTxsMux.Unlock()
+}
+
+// getTransactionForMempool returns transaction received from the message queue or gets it from the backend
+func (m *MempoolBitcoinType) getTransactionForMempool(txid string) (*Tx, error) {
+	m.parsedTxsMux.Lock()
+	ptx, found := m.parsedTxs[txid]
+	m.parsedTxsMux.Unlock()
+	if found {
+		return ptx.tx, nil
+	}
+	return m.chain.GetTransactionForMempool(txid)
+}
+
+// pruneParsedTxs removes transactions which are already in mempool or are too old
+func (m *MempoolBitcoinType) pruneParsedTxs() {
+	limit := time.Now().Add(-parsedTxRetention)
+	m.parsedTxsMux.Lock()
+	defer m.parsedTxsMux.Unlock()
+	for txid, ptx := range m.parsedTxs {
+		if _, exists := m.txEntries[txid]; exists || ptx.time.Before(limit) {
+			delete(m.parsedTxs, txid)
+		}
+	}
+}
+
+func (m *MempoolBitcoinType) getInputAddress(input Outpoint) *resolvedInput {
 	var addrDesc AddressDescriptor
+	var valueSat *big.Int
 	if m.AddrDescForOutpoint != nil {
-		addrDesc = m.AddrDescForOutpoint(input)
+		addrDesc, valueSat = m.AddrDescForOutpoint(input)
 	}
 	if addrDesc == nil {
-		itx, err := m.chain.GetTransactionForMempool(input.Txid)
+		itx, err := m.getTransactionForMempool(input.Txid)
 		if err != nil {
 			glog.Error("cannot get transaction ", input.Txid, ": ", err)
 			return nil
@@ -71,20 +138,35 @@ func (m *MempoolBitcoinType) getInputAddress(input Outpoint) *addrIndex {
 			glog.Error("error in addrDesc in ", input.Txid, " ", input.Vout, ": ", err)
 			return nil
 		}
+		valueSat = &itx.Vout[input.Vout].ValueSat
 	}
-	return &addrIndex{string(addrDesc), ^input.Vout}
-
+	ri := &resolvedInput{addrIndex: addrIndex{string(addrDesc), ^input.Vout}}
+	if valueSat != nil {
+		ri.valueSat.Set(valueSat)
+	}
+	return ri
 }
 
-func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan Outpoint, chanResult chan *addrIndex) ([]addrIndex, bool) {
-	tx, err := m.chain.GetTransactionForMempool(txid)
+func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan Outpoint, chanResult chan *resolvedInput) (txidio, bool) {
+	tx, err := m.getTransactionForMempool(txid)
 	if err != nil {
 		glog.Error("cannot get transaction ", txid, ": ", err)
-		return nil, false
+		return txidio{}, false
 	}
 	glog.V(2).Info("mempool: gettxaddrs ", txid, ", ", len(tx.Vin), " inputs")
 	io := make([]addrIndex, 0, len(tx.Vout)+len(tx.Vin))
+	// the fee is computed from the values of the resolved inputs, it is known only if all inputs are resolved
+	var valInSat, valOutSat big.Int
+	resolved := 0
+	addResolved := func(ri *resolvedInput) {
+		if ri != nil {
+			io = append(io, ri.addrIndex)
+			valInSat.Add(&valInSat, &ri.valueSat)
+			resolved++
+		}
+	}
 	for _, output := range tx.Vout {
+		valOutSat.Add(&valOutSat, &output.ValueSat)
 		addrDesc, err := m.chain.GetChainParser().GetAddrDescFromVout(&output)
 		if err != nil {
 			glog.Error("error in addrDesc in ", txid, " ", output.N, ": ", err)
@@ -98,19 +180,19 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan Outpoint, ch
 		}
 	}
 	dispatched := 0
+	inputs := make([]Outpoint, 0, len(tx.Vin))
 	for _, input := range tx.Vin {
 		if input.Coinbase != "" {
 			continue
 		}
 		o := Outpoint{input.Txid, int32(input.Vout)}
+		inputs = append(inputs, o)
 	loop:
 		for {
 			select {
 			// store as many processed results as possible
-			case ai := <-chanResult:
-				if ai != nil {
-					io = append(io, *ai)
-				}
+			case ri := <-chanResult:
+				addResolved(ri)
 				dispatched--
 			// send input to be processed
 			case chanInput <- o:
@@ -120,12 +202,81 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan Outpoint, ch
 		}
 	}
 	for i := 0; i < dispatched; i++ {
-		ai := <-chanResult
-		if ai != nil {
-			io = append(io, *ai)
+		addResolved(<-chanResult)
+	}
+	tio := txidio{txid: txid, io: io, inputs: inputs, rbf: IsRbfSignaled(tx)}
+	if resolved == len(inputs) && len(inputs) > 0 {
+		tio.fee.Sub(&valInSat, &valOutSat)
+		if tio.fee.Sign() >= 0 {
+			tio.vsize = txVSize(tx)
+		} else {
+			// the values are not consistent, leave the fee unknown
+			tio.fee.SetInt64(0)
 		}
 	}
-	return io, true
+	return tio, true
+}
+
+// txVSize returns the virtual size of the transaction computed from its serialization, 0 if the serialization is not available;
+// the witness data of a segwit transaction count by a quarter of their size
+func txVSize(tx *Tx) uint32 {
+	b, err := hex.DecodeString(tx.Hex)
+	if err != nil || len(b) == 0 {
+		return 0
+	}
+	size := len(b)
+	// the segwit serialization has the marker 0 and flag 1 after the version
+	if size < 6 || b[4] != 0 || b[5] != 1 {
+		return uint32(size)
+	}
+	p := 6
+	readVarInt := func() int {
+		if p >= size {
+			p = size + 1
+			return 0
+		}
+		v, l := uint64(b[p]), 1
+		switch b[p] {
+		case 0xfd:
+			l = 3
+		case 0xfe:
+			l = 5
+		case 0xff:
+			l = 9
+		}
+		if l > 1 {
+			if p+l > size {
+				p = size + 1
+				return 0
+			}
+			v = 0
+			for i := l - 1; i > 0; i-- {
+				v = v<<8 | uint64(b[p+i])
+			}
+		}
+		p += l
+		if v > uint64(size) {
+			p = size + 1
+			return 0
+		}
+		return int(v)
+	}
+	// skip the inputs (outpoint, script, sequence) and outputs (value, script) to the witness data
+	for n := readVarInt(); n > 0 && p <= size; n-- {
+		p += 36
+		p += readVarInt() + 4
+	}
+	for n := readVarInt(); n > 0 && p <= size; n-- {
+		p += 8
+		p += readVarInt()
+	}
+	if p > size-4 {
+		// malformed data, use the full size
+		return uint32(size)
+	}
+	// the witness data including the marker and flag, the locktime follows the witness data
+	witness := size - 4 - p + 2
+	return uint32((4*size - 3*witness + 3) / 4)
 }
 
 // Resync gets mempool transactions and maps outputs to transactions.
@@ -139,14 +290,22 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 		return 0, err
 	}
 	glog.V(2).Info("mempool: resync ", len(txs), " txs")
-	onNewEntry := func(txid string, entry txEntry) {
-		if len(entry.addrIndexes) > 0 {
+	onNewEntry := func(tio *txidio, txTime uint32) {
+		if len(tio.io) > 0 {
 			m.mux.Lock()
-			m.txEntries[txid] = entry
-			for _, si := range entry.addrIndexes {
-				m.addrDescToTx[si.addrDesc] = append(m.addrDescToTx[si.addrDesc], Outpoint{txid, si.n})
-			}
+			// the transactions spending the same outpoints were replaced (RBF) or double spent by the new transaction
+			replaced := m.removeConflictingEntries(tio.txid, tio.inputs, txTime)
+			m.addEntryToMempool(tio.txid, txEntry{
+				addrIndexes: tio.io,
+				time:        txTime,
+				fee:         tio.fee,
+				vsize:       tio.vsize,
+				feeRate:     computeFeeRate(&tio.fee, tio.vsize),
+				inputs:      tio.inputs,
+				rbf:         tio.rbf,
+			})
 			m.mux.Unlock()
+			m.notifyReplacedEntries(replaced, tio.txid)
 		}
 	}
 	txsMap := make(map[string]struct{}, len(txs))
@@ -156,13 +315,13 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 	for _, txid := range txs {
 		txsMap[txid] = struct{}{}
 		_, exists := m.txEntries[txid]
-		if !exists {
+		if _, evicted := m.evicted[txid]; !exists && !evicted {
 		loop:
 			for {
 				select {
 				// store as many processed transactions as possible
 				case tio := <-m.chanAddrIndex:
-					onNewEntry(tio.txid, txEntry{tio.io, txTime})
+					onNewEntry(&tio, txTime)
 					dispatched--
 				// send transaction to be processed
 				case m.chanTxid <- txid:
@@ -174,7 +333,7 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 	}
 	for i := 0; i < dispatched; i++ {
 		tio := <-m.chanAddrIndex
-		onNewEntry(tio.txid, txEntry{tio.io, txTime})
+		onNewEntry(&tio, txTime)
 	}
 
 	for txid, entry := range m.txEntries {
@@ -184,6 +343,22 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 			m.mux.Unlock()
 		}
 	}
+	m.mux.Lock()
+	m.pruneReplacements(txTime)
+	evicted := m.evictEntries(txTime, m.txTimeout, m.maxTransactions, m.maxMemory)
+	m.mux.Unlock()
+	m.pruneParsedTxs()
+	for _, txid := range evicted {
+		m.evicted[txid] = struct{}{}
+	}
+	for txid := range m.evicted {
+		if _, exists := txsMap[txid]; !exists {
+			delete(m.evicted, txid)
+		}
+	}
+	if len(evicted) > 0 {
+		glog.Info("mempool: evicted ", len(evicted), " transactions, ", len(m.evicted), " evicted transactions still in backend mempool")
+	}
 	glog.Info("mempool: resync finished in ", time.Since(start), ", ", len(m.txEntries), " transactions in mempool")
 	return len(m.txEntries), nil
 }
@@ -0,0 +1,125 @@
+package bchain
+
+import (
+	"encoding/hex"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_txVSize(t *testing.T) {
+	outpoint := strings.Repeat("11", 32) + "01000000"
+	output := "e803000000000000" + "16" + "0014" + strings.Repeat("22", 20)
+	witness := "02" + "48" + strings.Repeat("33", 72) + "21" + strings.Repeat("44", 33)
+	tests := []struct {
+		name string
+		hex  string
+		want uint32
+	}{
+		{
+			name: "empty",
+			hex:  "",
+			want: 0,
+		},
+		{
+			// 4 version, 1+41 input, 1+31 output, 4 locktime
+			name: "legacy",
+			hex:  "02000000" + "01" + outpoint + "00" + "ffffffff" + "01" + output + "00000000",
+			want: 82,
+		},
+		{
+			// the stripped size is 82, the witness with the marker and flag is 110 bytes, weight 3*82+192
+			name: "segwit",
+			hex:  "02000000" + "0001" + "01" + outpoint + "00" + "ffffffff" + "01" + output + witness + "00000000",
+			want: 110,
+		},
+		{
+			name: "truncated segwit",
+			hex:  "02000000" + "0001" + "01" + outpoint,
+			want: 43,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := txVSize(&Tx{Hex: tt.hex}); got != tt.want {
+				b, _ := hex.DecodeString(tt.hex)
+				t.Errorf("txVSize() = %v, want %v (size %v)", got, tt.want, len(b))
+			}
+		})
+	}
+}
+
+func TestMempoolBitcoinType_parsedTxs(t *testing.T) {
+	m := &MempoolBitcoinType{
+		BaseMempool: BaseMempool{
+			txEntries: map[string]txEntry{"tx2": {time: 1000}},
+		},
+		parsedTxs: make(map[string]parsedTx),
+	}
+	for _, txid := range []string{"tx1", "tx2", "tx3", "tx4"} {
+		m.AddParsedTx(&Tx{Txid: txid})
+	}
+	// a transaction received again replaces the older one
+	m.AddParsedTx(&Tx{Txid: "tx4", Hex: "04"})
+	if len(m.parsedTxs) != 4 {
+		t.Fatalf("len(parsedTxs) = %v, want 4", len(m.parsedTxs))
+	}
+	tx, err := m.getTransactionForMempool("tx4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Hex != "04" {
+		t.Errorf("getTransactionForMempool(tx4) = %+v, want the last received tx", tx)
+	}
+
+	// tx2 is already in mempool, tx3 is older than parsedTxRetention
+	ptx := m.parsedTxs["tx3"]
+	ptx.time = time.Now().Add(-parsedTxRetention - time.Second)
+	m.parsedTxs["tx3"] = ptx
+	ptx = m.parsedTxs["tx1"]
+	ptx.time = time.Now().Add(-parsedTxRetention + 10*time.Second)
+	m.parsedTxs["tx1"] = ptx
+
+	tests := []struct {
+		name  string
+		setup func()
+		want  []string
+	}{
+		{
+			name:  "evict in mempool and expired",
+			setup: func() {},
+			want:  []string{"tx1", "tx4"},
+		},
+		{
+			name: "evict added to mempool",
+			setup: func() {
+				m.txEntries["tx4"] = txEntry{time: 1001}
+			},
+			want: []string{"tx1"},
+		},
+		{
+			name: "evict all expired",
+			setup: func() {
+				ptx := m.parsedTxs["tx1"]
+				ptx.time = time.Now().Add(-2 * parsedTxRetention)
+				m.parsedTxs["tx1"] = ptx
+			},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			m.pruneParsedTxs()
+			got := make([]string, 0, len(m.parsedTxs))
+			for txid := range m.parsedTxs {
+				got = append(got, txid)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("parsedTxs after pruneParsedTxs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -21,9 +21,12 @@ func NewMempoolEthereumType(chain BlockChain, mempoolTxTimeoutHours int, queryBa
 	mempoolTimeoutTime := time.Duration(mempoolTxTimeoutHours) * time.Hour
 	return &MempoolEthereumType{
 		BaseMempool: BaseMempool{
-			chain:        chain,
-			txEntries:    make(map[string]txEntry),
-			addrDescToTx: make(map[string][]Outpoint),
+			chain:          chain,
+			txEntries:      make(map[string]txEntry),
+			addrDescToTx:   make(map[string][]Outpoint),
+			spentOutpoints: make(map[Outpoint]string),
+			replacements:   make(map[string]txReplacement),
+			childTxs:       make(map[string][]string),
 		},
 		mempoolTimeoutTime:   mempoolTimeoutTime,
 		queryBackendOnResync: queryBackendOnResync,
@@ -111,6 +114,7 @@ func (m *MempoolEthereumType) Resync() (int, error) {
 		for txid, entry := range m.txEntries {
 			if time.Unix(int64(entry.time), 0).Before(threshold) {
 				m.removeEntryFromMempool(txid, entry)
+				m.evictions.Age++
 			}
 		}
 		removed := entries - len(m.txEntries)
@@ -137,10 +141,7 @@ func (m *MempoolEthereumType) AddTransactionToMempool(txid string) {
 			return
 		}
 		m.mux.Lock()
-		m.txEntries[txid] = entry
-		for _, si := range entry.addrIndexes {
-			m.addrDescToTx[si.addrDesc] = append(m.addrDescToTx[si.addrDesc], Outpoint{txid, si.n})
-		}
+		m.addEntryToMempool(txid, entry)
 		m.mux.Unlock()
 	}
 }
@@ -0,0 +1,76 @@
+package bchain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/juju/errors"
+)
+
+func doubleSha256(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+	return h[:]
+}
+
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+// GetMerkleBranch returns the merkle branch of the transaction at position pos in the list of block txids,
+// the hashes in the branch are in the same byte order as txids (reversed hex), from the leaf level up to the root
+func GetMerkleBranch(txids []string, pos int) ([]string, error) {
+	if pos < 0 || pos >= len(txids) {
+		return nil, errors.Errorf("Position %v out of range of %v transactions", pos, len(txids))
+	}
+	level := make([][]byte, len(txids))
+	for i, txid := range txids {
+		b, err := hex.DecodeString(txid)
+		if err != nil || len(b) != 32 {
+			return nil, errors.Errorf("Invalid txid %v", txid)
+		}
+		level[i] = reverseBytes(b)
+	}
+	var branch []string
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		branch = append(branch, hex.EncodeToString(reverseBytes(level[pos^1])))
+		next := make([][]byte, len(level)/2)
+		for i := range next {
+			next[i] = doubleSha256(append(append([]byte{}, level[2*i]...), level[2*i+1]...))
+		}
+		level = next
+		pos /= 2
+	}
+	return branch, nil
+}
+
+// GetMerkleRoot computes the merkle root of the transaction at position pos from its txid and merkle branch,
+// the returned root is in the reversed hex format as the merkleroot of the block header
+func GetMerkleRoot(txid string, branch []string, pos int) (string, error) {
+	h, err := hex.DecodeString(txid)
+	if err != nil || len(h) != 32 {
+		return "", errors.Errorf("Invalid txid %v", txid)
+	}
+	h = reverseBytes(h)
+	for _, s := range branch {
+		b, err := hex.DecodeString(s)
+		if err != nil || len(b) != 32 {
+			return "", errors.Errorf("Invalid branch hash %v", s)
+		}
+		b = reverseBytes(b)
+		if pos&1 == 0 {
+			h = doubleSha256(append(h, b...))
+		} else {
+			h = doubleSha256(append(b, h...))
+		}
+		pos >>= 1
+	}
+	return hex.EncodeToString(reverseBytes(h)), nil
+}
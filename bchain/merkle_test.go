@@ -0,0 +1,102 @@
+package bchain
+
+import (
+	"reflect"
+	"testing"
+)
+
+// transactions of bitcoin block 100000
+var merkleTxids = []string{
+	"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+	"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+	"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+	"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+}
+
+const merkleRoot100000 = "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766"
+
+func TestGetMerkleBranch(t *testing.T) {
+	tests := []struct {
+		name   string
+		txids  []string
+		pos    int
+		want   []string
+		root   string
+		hasErr bool
+	}{
+		{
+			name:  "block 100000, last tx",
+			txids: merkleTxids,
+			pos:   2,
+			want: []string{
+				"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+				"ccdafb73d8dcd0173d5d5c3c9a0770d0b3953db889dab99ef05b1907518cb815",
+			},
+			root: merkleRoot100000,
+		},
+		{
+			name:  "odd number of txs, last tx paired with itself",
+			txids: merkleTxids[:3],
+			pos:   2,
+			want: []string{
+				"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+				"ccdafb73d8dcd0173d5d5c3c9a0770d0b3953db889dab99ef05b1907518cb815",
+			},
+			root: "fa435470825de273081dcc706b25514c936fa6dc80ab965ce6970d68ddd0b553",
+		},
+		{
+			name:  "single tx",
+			txids: merkleTxids[:1],
+			pos:   0,
+			want:  nil,
+			root:  merkleTxids[0],
+		},
+		{
+			name:   "position out of range",
+			txids:  merkleTxids,
+			pos:    4,
+			hasErr: true,
+		},
+		{
+			name:   "invalid txid",
+			txids:  []string{merkleTxids[0], "1234"},
+			pos:    0,
+			hasErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetMerkleBranch(tt.txids, tt.pos)
+			if (err != nil) != tt.hasErr {
+				t.Fatalf("GetMerkleBranch() error = %v, hasErr %v", err, tt.hasErr)
+			}
+			if tt.hasErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetMerkleBranch() = %v, want %v", got, tt.want)
+			}
+			root, err := GetMerkleRoot(tt.txids[tt.pos], got, tt.pos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if root != tt.root {
+				t.Errorf("GetMerkleRoot() = %v, want %v", root, tt.root)
+			}
+		})
+	}
+	// the branch of every tx leads to the root of the block
+	for pos := range merkleTxids {
+		branch, err := GetMerkleBranch(merkleTxids, pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := GetMerkleRoot(merkleTxids[pos], branch, pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root != merkleRoot100000 {
+			t.Errorf("pos %d: GetMerkleRoot() = %v, want %v", pos, root, merkleRoot100000)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package bchain
 import (
 	"context"
 	"encoding/binary"
+	"encoding/hex"
 	"time"
 
 	"github.com/golang/glog"
@@ -11,13 +12,22 @@ import (
 
 // MQ is message queue listener handle
 type MQ struct {
-	context   *zmq.Context
-	socket    *zmq.Socket
-	isRunning bool
-	finished  chan error
-	binding   string
+	context          *zmq.Context
+	socket           *zmq.Socket
+	isRunning        bool
+	finished         chan error
+	binding          string
+	subscriptions    []string
+	rawCallback      MQRawCallback
+	lastHashBlock    string
+	lastHashBlockSeq uint32
+	sequences        map[string]uint32
 }
 
+// MQRawCallback receives raw transactions (NotificationNewTx) and raw blocks (NotificationNewBlock) from the message queue,
+// the hash of the block is taken from the hashblock notification with the same sequence number, it is empty for transactions
+type MQRawCallback func(nt NotificationType, hash string, data []byte)
+
 // NotificationType is type of notification
 type NotificationType int
 
@@ -32,7 +42,9 @@ const (
 
 // NewMQ creates new Bitcoind ZeroMQ listener
 // callback function receives messages
-func NewMQ(binding string, callback func(NotificationType)) (*MQ, error) {
+// if rawCallback is not nil, raw transactions and blocks are subscribed and passed to rawCallback before the callback is called;
+// the notifications are still only hints, skipped or lost raw payloads are fetched using RPC by sync or syncmempool
+func NewMQ(binding string, callback func(NotificationType), rawCallback MQRawCallback) (*MQ, error) {
 	context, err := zmq.NewContext()
 	if err != nil {
 		return nil, err
@@ -41,24 +53,31 @@ func NewMQ(binding string, callback func(NotificationType)) (*MQ, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = socket.SetSubscribe("hashblock")
-	if err != nil {
-		return nil, err
+	subscriptions := []string{"hashblock", "hashtx"}
+	if rawCallback != nil {
+		subscriptions = []string{"hashblock", "rawblock", "rawtx"}
 	}
-	err = socket.SetSubscribe("hashtx")
-	if err != nil {
-		return nil, err
+	for _, s := range subscriptions {
+		err = socket.SetSubscribe(s)
+		if err != nil {
+			return nil, err
+		}
 	}
-	// for now do not use raw subscriptions - we would have to handle skipped/lost notifications from zeromq
-	// on each notification we do sync or syncmempool respectively
-	// socket.SetSubscribe("rawblock")
-	// socket.SetSubscribe("rawtx")
 	err = socket.Connect(binding)
 	if err != nil {
 		return nil, err
 	}
-	glog.Info("MQ listening to ", binding)
-	mq := &MQ{context, socket, true, make(chan error), binding}
+	glog.Info("MQ listening to ", binding, ", subscriptions ", subscriptions)
+	mq := &MQ{
+		context:       context,
+		socket:        socket,
+		isRunning:     true,
+		finished:      make(chan error),
+		binding:       binding,
+		subscriptions: subscriptions,
+		rawCallback:   rawCallback,
+		sequences:     make(map[string]uint32),
+	}
 	go mq.run(callback)
 	return mq, nil
 }
@@ -83,28 +102,69 @@ func (mq *MQ) run(callback func(NotificationType)) {
 			time.Sleep(100 * time.Millisecond)
 		}
 		if msg != nil && len(msg) >= 3 {
-			var nt NotificationType
-			switch string(msg[0]) {
-			case "hashblock":
-				nt = NotificationNewBlock
-				break
-			case "hashtx":
-				nt = NotificationNewTx
-				break
-			default:
-				nt = NotificationUnknown
-				glog.Infof("MQ: NotificationUnknown %v", string(msg[0]))
-			}
-			if glog.V(2) {
-				sequence := uint32(0)
-				if len(msg[len(msg)-1]) == 4 {
-					sequence = binary.LittleEndian.Uint32(msg[len(msg)-1])
-				}
-				glog.Infof("MQ: %v %s-%d", nt, string(msg[0]), sequence)
-			}
-			callback(nt)
+			mq.handleMessage(msg, callback)
+		}
+	}
+}
+
+// checkSequence records the sequence number of the message of the topic,
+// it returns false if some messages of the topic were lost or the message came out of order
+func (mq *MQ) checkSequence(topic string, sequence uint32) bool {
+	last, found := mq.sequences[topic]
+	mq.sequences[topic] = sequence
+	if !found || sequence == last+1 {
+		return true
+	}
+	if sequence <= last {
+		glog.Warning("MQ: ", topic, "-", sequence, " out of order, last ", topic, "-", last)
+	} else {
+		glog.Warning("MQ: ", topic, "-", sequence, " lost ", sequence-last-1, " messages")
+	}
+	return false
+}
+
+func (mq *MQ) handleMessage(msg [][]byte, callback func(NotificationType)) {
+	var nt NotificationType
+	topic := string(msg[0])
+	sequence := uint32(0)
+	inSequence := true
+	if len(msg[len(msg)-1]) == 4 {
+		sequence = binary.LittleEndian.Uint32(msg[len(msg)-1])
+		inSequence = mq.checkSequence(topic, sequence)
+	}
+	switch topic {
+	case "hashblock":
+		nt = NotificationNewBlock
+		mq.lastHashBlock = hex.EncodeToString(msg[1])
+		mq.lastHashBlockSeq = sequence
+		break
+	case "hashtx":
+		nt = NotificationNewTx
+		break
+	case "rawblock":
+		// the backend sends hashblock and rawblock of the same block with the same sequence number,
+		// the sync is already triggered by the hashblock notification
+		if mq.rawCallback != nil && inSequence && mq.lastHashBlock != "" && mq.lastHashBlockSeq == sequence {
+			mq.rawCallback(NotificationNewBlock, mq.lastHashBlock, msg[1])
+		} else {
+			glog.Warning("MQ: rawblock-", sequence, " does not match hashblock-", mq.lastHashBlockSeq)
+		}
+		return
+	case "rawtx":
+		// the lost transactions are fetched using RPC by the resync triggered by the callback
+		nt = NotificationNewTx
+		if mq.rawCallback != nil {
+			mq.rawCallback(nt, "", msg[1])
 		}
+		break
+	default:
+		nt = NotificationUnknown
+		glog.Infof("MQ: NotificationUnknown %v", topic)
 	}
+	if glog.V(2) {
+		glog.Infof("MQ: %v %s-%d", nt, topic, sequence)
+	}
+	callback(nt)
 }
 
 // Shutdown stops listening to the ZeroMQ and closes the connection
@@ -113,13 +173,11 @@ func (mq *MQ) Shutdown(ctx context.Context) error {
 	if mq.isRunning {
 		go func() {
 			// if errors in the closing sequence, let it close ungracefully
-			if err := mq.socket.SetUnsubscribe("hashtx"); err != nil {
-				mq.finished <- err
-				return
-			}
-			if err := mq.socket.SetUnsubscribe("hashblock"); err != nil {
-				mq.finished <- err
-				return
+			for _, s := range mq.subscriptions {
+				if err := mq.socket.SetUnsubscribe(s); err != nil {
+					mq.finished <- err
+					return
+				}
 			}
 			if err := mq.socket.Unbind(mq.binding); err != nil {
 				mq.finished <- err
@@ -0,0 +1,137 @@
+package bchain
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func mqMessage(topic string, body []byte, sequence uint32) [][]byte {
+	seq := make([]byte, 4)
+	binary.LittleEndian.PutUint32(seq, sequence)
+	return [][]byte{[]byte(topic), body, seq}
+}
+
+func TestMQ_checkSequence(t *testing.T) {
+	type message struct {
+		topic    string
+		sequence uint32
+		want     bool
+	}
+	tests := []struct {
+		name     string
+		messages []message
+	}{
+		{
+			name: "in order",
+			messages: []message{
+				{"rawtx", 5, true},
+				{"rawtx", 6, true},
+				{"rawtx", 7, true},
+			},
+		},
+		{
+			name: "topics have own sequences",
+			messages: []message{
+				{"rawtx", 10, true},
+				{"hashblock", 2, true},
+				{"rawtx", 11, true},
+				{"hashblock", 3, true},
+			},
+		},
+		{
+			name: "missing sequences",
+			messages: []message{
+				{"rawtx", 1, true},
+				{"rawtx", 4, false},
+				{"rawtx", 5, true},
+			},
+		},
+		{
+			name: "out of order",
+			messages: []message{
+				{"rawtx", 1, true},
+				{"rawtx", 3, false},
+				{"rawtx", 2, false},
+				{"rawtx", 3, true},
+			},
+		},
+		{
+			name: "repeated sequence",
+			messages: []message{
+				{"hashblock", 8, true},
+				{"hashblock", 8, false},
+			},
+		},
+		{
+			name: "wrap around",
+			messages: []message{
+				{"rawtx", 0xffffffff, true},
+				{"rawtx", 0, true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq := &MQ{sequences: make(map[string]uint32)}
+			for i, m := range tt.messages {
+				if got := mq.checkSequence(m.topic, m.sequence); got != m.want {
+					t.Errorf("message %d checkSequence(%v, %v) = %v, want %v", i, m.topic, m.sequence, got, m.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMQ_handleMessage(t *testing.T) {
+	type raw struct {
+		nt   NotificationType
+		hash string
+		data string
+	}
+	var raws []raw
+	var notifications []NotificationType
+	mq := &MQ{
+		sequences: make(map[string]uint32),
+		rawCallback: func(nt NotificationType, hash string, data []byte) {
+			raws = append(raws, raw{nt, hash, string(data)})
+		},
+	}
+	callback := func(nt NotificationType) {
+		notifications = append(notifications, nt)
+	}
+	messages := [][][]byte{
+		mqMessage("rawtx", []byte("tx1"), 1),
+		mqMessage("hashblock", []byte{0xab, 0xcd}, 1),
+		mqMessage("rawblock", []byte("block1"), 1),
+		// a lost transaction is only notified, the raw data still passed to the mempool
+		mqMessage("rawtx", []byte("tx2"), 3),
+		// the rawblock does not match the hashblock
+		mqMessage("hashblock", []byte{0x01}, 2),
+		mqMessage("rawblock", []byte("block3"), 3),
+		// the rawblock came out of order
+		mqMessage("hashblock", []byte{0x02}, 3),
+		mqMessage("rawblock", []byte("block2"), 2),
+	}
+	for _, m := range messages {
+		mq.handleMessage(m, callback)
+	}
+	wantRaws := []raw{
+		{NotificationNewTx, "", "tx1"},
+		{NotificationNewBlock, "abcd", "block1"},
+		{NotificationNewTx, "", "tx2"},
+	}
+	if !reflect.DeepEqual(raws, wantRaws) {
+		t.Errorf("raw callbacks = %+v, want %+v", raws, wantRaws)
+	}
+	wantNotifications := []NotificationType{
+		NotificationNewTx,
+		NotificationNewBlock,
+		NotificationNewTx,
+		NotificationNewBlock,
+		NotificationNewBlock,
+	}
+	if !reflect.DeepEqual(notifications, wantNotifications) {
+		t.Errorf("notifications = %v, want %v", notifications, wantNotifications)
+	}
+}
@@ -130,6 +130,7 @@ type MempoolEntry struct {
 	ModifiedFee     json.Number `json:"modifiedfee"`
 	Time            uint64      `json:"time"`
 	Height          uint32      `json:"height"`
+	VSize           uint32      `json:"vsize"`
 	DescendantCount uint32      `json:"descendantcount"`
 	DescendantSize  uint32      `json:"descendantsize"`
 	DescendantFees  uint32      `json:"descendantfees"`
@@ -210,14 +211,82 @@ type MempoolTxidEntry struct {
 // MempoolTxidEntries is array of MempoolTxidEntry
 type MempoolTxidEntries []MempoolTxidEntry
 
+// MempoolAddrIndex is address descriptor of a mempool transaction
+// together with the output index (or binary complement of the input index)
+type MempoolAddrIndex struct {
+	AddrDesc AddressDescriptor
+	N        int32
+}
+
+// MempoolStoredEntry contains data about a mempool transaction which are persisted across restarts
+type MempoolStoredEntry struct {
+	Txid        string
+	Time        uint32
+	AddrIndexes []MempoolAddrIndex
+	Fee         big.Int
+	VSize       uint32
+	Inputs      []Outpoint
+	Rbf         bool
+}
+
+// MempoolFeeEntry contains fee, virtual size and fee rate (in satoshi per vbyte) of a mempool transaction
+type MempoolFeeEntry struct {
+	Fee     big.Int
+	VSize   uint32
+	FeeRate float64
+}
+
+// MempoolTxPackage contains in-mempool dependencies (CPFP package) of a mempool transaction
+// with aggregated fees and virtual sizes of its ancestors and descendants, fee rates are in satoshi per vbyte
+type MempoolTxPackage struct {
+	Txid             string
+	Fee              big.Int
+	VSize            uint32
+	FeeRate          float64
+	Parents          []string
+	Children         []string
+	AncestorCount    int
+	AncestorFee      big.Int
+	AncestorVSize    int64
+	DescendantCount  int
+	DescendantFee    big.Int
+	DescendantVSize  int64
+	AncestorFeeRate  float64
+	PackageFeeRate   float64
+	EffectiveFeeRate float64
+}
+
+// MempoolEvictionStats contains the cumulative numbers of transactions evicted from the mempool by Blockbook
+// (not by the backend) by the reason of the eviction and the estimated memory used by the mempool in bytes
+type MempoolEvictionStats struct {
+	Age         uint64
+	Count       uint64
+	Memory      uint64
+	MemoryUsage int64
+}
+
+// XpubDescriptor contains information about an xpub or an output descriptor describing an account,
+// ChangeIndexes are the values of the derivation step preceding the address index,
+// i.e. [0, 1] for receiving and change addresses of a BIP44 like account;
+// the change parameter of the Derive methods is the position in ChangeIndexes
+type XpubDescriptor struct {
+	XpubDescriptor string
+	Type           string
+	ChangeIndexes  []uint32
+}
+
 // OnNewBlockFunc is used to send notification about a new block
 type OnNewBlockFunc func(hash string, height uint32)
 
 // OnNewTxAddrFunc is used to send notification about a new transaction/address
 type OnNewTxAddrFunc func(tx *Tx, desc AddressDescriptor)
 
-// AddrDescForOutpointFunc defines function that returns address descriptorfor given outpoint or nil if outpoint not found
-type AddrDescForOutpointFunc func(outpoint Outpoint) AddressDescriptor
+// OnTxReplacedFunc is used to send notification about a mempool transaction replaced (RBF or double spend) by another transaction,
+// the notification is sent for each address of the replaced transaction
+type OnTxReplacedFunc func(txid string, replacedBy string, rbf bool, desc AddressDescriptor)
+
+// AddrDescForOutpointFunc defines function that returns address descriptor and value for given outpoint or nil if outpoint not found
+type AddrDescForOutpointFunc func(outpoint Outpoint) (AddressDescriptor, *big.Int)
 
 // BlockChain defines common interface to block chain daemon
 type BlockChain interface {
@@ -227,7 +296,7 @@ type BlockChain interface {
 	// create mempool but do not initialize it
 	CreateMempool(BlockChain) (Mempool, error)
 	// initialize mempool, create ZeroMQ (or other) subscription
-	InitializeMempool(AddrDescForOutpointFunc, OnNewTxAddrFunc) error
+	InitializeMempool(AddrDescForOutpointFunc, OnNewTxAddrFunc, OnTxReplacedFunc) error
 	// shutdown mempool, ZeroMQ and block chain connections
 	Shutdown(ctx context.Context) error
 	// chain info
@@ -241,6 +310,7 @@ type BlockChain interface {
 	GetBestBlockHeight() (uint32, error)
 	GetBlockHash(height uint32) (string, error)
 	GetBlockHeader(hash string) (*BlockHeader, error)
+	GetBlockHeaderRaw(hash string) (string, error)
 	GetBlock(hash string, height uint32) (*Block, error)
 	GetBlockInfo(hash string) (*BlockInfo, error)
 	GetMempoolTransactions() ([]string, error)
@@ -294,7 +364,8 @@ type BlockChainParser interface {
 	PackBlockHash(hash string) ([]byte, error)
 	UnpackBlockHash(buf []byte) (string, error)
 	ParseBlock(b []byte) (*Block, error)
-	// xpub
+	// xpub, the xpub parameter can be also an output descriptor if the parser supports it
+	ParseXpub(xpub string) (*XpubDescriptor, error)
 	DerivationBasePath(xpub string) (string, error)
 	DeriveAddressDescriptors(xpub string, change uint32, indexes []uint32) ([]AddressDescriptor, error)
 	DeriveAddressDescriptorsFromTo(xpub string, change uint32, fromIndex uint32, toIndex uint32) ([]AddressDescriptor, error)
@@ -309,4 +380,10 @@ type Mempool interface {
 	GetAddrDescTransactions(addrDesc AddressDescriptor) ([]Outpoint, error)
 	GetAllEntries() MempoolTxidEntries
 	GetTransactionTime(txid string) uint32
+	GetStoredEntries() []MempoolStoredEntry
+	RestoreStoredEntries(entries []MempoolStoredEntry) int
+	GetFeeEntries() []MempoolFeeEntry
+	GetReplacedBy(txid string) string
+	GetTxPackage(txid string) *MempoolTxPackage
+	GetEvictionStats() MempoolEvictionStats
 }
@@ -6,9 +6,13 @@ import (
 	"blockbook/bchain/coins"
 	"blockbook/common"
 	"blockbook/db"
+	"blockbook/fiat"
+	"blockbook/mq"
 	"blockbook/server"
 	"context"
+	"encoding/json"
 	"flag"
+	"io/ioutil"
 	"log"
 	"math/rand"
 	"net/http"
@@ -39,6 +43,7 @@ var (
 	dbPath         = flag.String("datadir", "./data", "path to database directory")
 	dbCache        = flag.Int("dbcache", 1<<29, "size of the rocksdb cache")
 	dbMaxOpenFiles = flag.Int("dbmaxopenfiles", 1<<14, "max open files by rocksdb")
+	dbXpubStates   = flag.Int("dbxpubstates", 100000, "max number of xpubs with derived addresses persisted in rocksdb")
 
 	blockFrom      = flag.Int("blockheight", -1, "height of the starting block")
 	blockUntil     = flag.Int("blockuntil", -1, "height of the final block")
@@ -56,14 +61,25 @@ var (
 
 	internalBinding = flag.String("internal", "", "internal http server binding [address]:port, (default no internal server)")
 
+	internalAPIKeyFile = flag.String("internalapikey", "", "path to file with the key required by the internal API in header 'Authorization: Bearer <key>' (default internal API disabled)")
+
 	publicBinding = flag.String("public", "", "public http server binding [address]:port[/path] (default no public server)")
 
+	electrumBinding = flag.String("electrum", "", "electrum protocol server binding [address]:port, with SSL if certfile is specified (default no electrum server)")
+
+	grpcBinding = flag.String("grpc", "", "grpc server binding [address]:port, with TLS if certfile is specified (default no grpc server)")
+
+	blockFilters = flag.Bool("blockfilters", false, "build BIP158 block filters of connected blocks, supported only for Bitcoin type coins")
+
 	certFiles = flag.String("certfile", "", "to enable SSL specify path to certificate files without extension, expecting <certfile>.crt and <certfile>.key (default no SSL)")
 
 	explorerURL = flag.String("explorer", "", "address of blockchain explorer")
 
 	noTxCache = flag.Bool("notxcache", false, "disable tx cache")
 
+	mqURL     = flag.String("mq", "", "url of the message broker to publish blockchain events to, e.g. nats://localhost:4222 (default no publishing)")
+	mqSubject = flag.String("mqsubject", "blockbook", "subject prefix of the messages published to the message broker")
+
 	computeColumnStats = flag.Bool("computedbstats", false, "compute column stats and exit")
 	dbStatsPeriodHours = flag.Int("dbstatsperiod", 24, "period of db stats collection in hours, 0 disables stats collection")
 
@@ -90,6 +106,12 @@ var (
 	internalState              *common.InternalState
 	callbacksOnNewBlock        []bchain.OnNewBlockFunc
 	callbacksOnNewTxAddr       []bchain.OnNewTxAddrFunc
+	callbacksOnTxReplaced      []bchain.OnTxReplacedFunc
+	callbacksOnMempoolResync   []func()
+	eventPublisher             *mq.Publisher
+	fiatRatesDownloader        *fiat.RatesDownloader
+	electrumServer             *server.ElectrumServer
+	grpcServer                 *server.GrpcServer
 	chanOsSignal               chan os.Signal
 	inShutdown                 int32
 )
@@ -155,6 +177,10 @@ func main() {
 		return
 	}
 	defer index.Close()
+	if err = index.SetMaxXpubStates(*dbXpubStates); err != nil {
+		glog.Error("rocksDB: ", err)
+		return
+	}
 
 	internalState, err = newInternalState(coin, coinShortcut, coinLabel, index)
 	if err != nil {
@@ -185,6 +211,8 @@ func main() {
 		glog.Errorf("NewSyncWorker %v", err)
 		return
 	}
+	// stop the background sync of headers before the db is closed
+	defer syncWorker.Close()
 
 	// set the DbState to open at this moment, after all important workers are initialized
 	internalState.DbState = common.DbStateOpen
@@ -194,6 +222,18 @@ func main() {
 		return
 	}
 
+	if err = initScripthashIndex(); err != nil {
+		glog.Error("scripthashIndex: ", err)
+		return
+	}
+
+	if *blockFilters {
+		if err = index.SetBlockFilters(true); err != nil {
+			glog.Error("blockFilters: ", err)
+			return
+		}
+	}
+
 	if *rollbackHeight >= 0 {
 		performRollback()
 		return
@@ -239,11 +279,14 @@ func main() {
 		if chain.GetChainParser().GetChainType() == bchain.ChainBitcoinType {
 			addrDescForOutpoint = index.AddrDescForOutpoint
 		}
-		err = chain.InitializeMempool(addrDescForOutpoint, onNewTxAddr)
+		err = chain.InitializeMempool(addrDescForOutpoint, onNewTxAddr, onTxReplaced)
 		if err != nil {
 			glog.Error("initializeMempool ", err)
 			return
 		}
+		if chain.GetChainParser().GetChainType() == bchain.ChainBitcoinType {
+			restoreMempool()
+		}
 		var mempoolCount int
 		if mempoolCount, err = mempool.Resync(); err != nil {
 			glog.Error("resyncMempool ", err)
@@ -256,13 +299,63 @@ func main() {
 	}
 	go storeInternalStateLoop()
 
+	if internalServer != nil {
+		callbacksOnNewBlock = append(callbacksOnNewBlock, internalServer.OnNewBlock)
+		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, internalServer.OnNewTxAddr)
+		callbacksOnMempoolResync = append(callbacksOnMempoolResync, internalServer.OnMempoolResync)
+	}
+
+	if *electrumBinding != "" {
+		electrumServer, err = startElectrumServer()
+		if err != nil {
+			glog.Error("electrum server: ", err)
+			return
+		}
+		callbacksOnNewBlock = append(callbacksOnNewBlock, electrumServer.OnNewBlock)
+		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, electrumServer.OnNewTxAddr)
+		callbacksOnMempoolResync = append(callbacksOnMempoolResync, electrumServer.OnMempoolResync)
+	}
+
+	if *grpcBinding != "" {
+		grpcServer, err = startGrpcServer()
+		if err != nil {
+			glog.Error("grpc server: ", err)
+			return
+		}
+		callbacksOnNewBlock = append(callbacksOnNewBlock, grpcServer.OnNewBlock)
+		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, grpcServer.OnNewTxAddr)
+	}
+
+	if *mqURL != "" {
+		broker, err := mq.NewNATSBroker(*mqURL)
+		if err != nil {
+			glog.Error("mq: ", err)
+			return
+		}
+		eventPublisher = mq.NewPublisher(broker, *mqSubject, index, chain, mempool, internalState)
+		callbacksOnNewBlock = append(callbacksOnNewBlock, eventPublisher.OnNewBlock)
+		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, eventPublisher.OnNewTxAddr)
+		callbacksOnMempoolResync = append(callbacksOnMempoolResync, eventPublisher.OnMempoolResync)
+		eventPublisher.Run()
+	}
+
 	if publicServer != nil {
 		// start full public interface
 		callbacksOnNewBlock = append(callbacksOnNewBlock, publicServer.OnNewBlock)
 		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, publicServer.OnNewTxAddr)
+		callbacksOnTxReplaced = append(callbacksOnTxReplaced, publicServer.OnTxReplaced)
+		callbacksOnMempoolResync = append(callbacksOnMempoolResync, publicServer.OnMempoolResync)
 		publicServer.ConnectFullPublicInterface()
 	}
 
+	if *synchronize {
+		fiatRatesDownloader, err = startFiatRatesDownloader(publicServer)
+		if err != nil {
+			glog.Error("fiat rates: ", err)
+			return
+		}
+	}
+
 	if *blockFrom >= 0 {
 		if *blockUntil < 0 {
 			*blockUntil = *blockFrom
@@ -282,6 +375,26 @@ func main() {
 		waitForSignalAndShutdown(internalServer, publicServer, chain, 10*time.Second)
 	}
 
+	if electrumServer != nil {
+		if err = electrumServer.Close(); err != nil {
+			glog.Error("electrum server: close error: ", err)
+		}
+	}
+
+	if grpcServer != nil {
+		if err = grpcServer.Close(); err != nil {
+			glog.Error("grpc server: close error: ", err)
+		}
+	}
+
+	if eventPublisher != nil {
+		eventPublisher.Close()
+	}
+
+	if fiatRatesDownloader != nil {
+		fiatRatesDownloader.Close()
+	}
+
 	if *synchronize {
 		close(chanSyncIndex)
 		close(chanSyncMempool)
@@ -289,6 +402,28 @@ func main() {
 		<-chanSyncIndexDone
 		<-chanSyncMempoolDone
 		<-chanStoreInternalStateDone
+		if chain.GetChainParser().GetChainType() == bchain.ChainBitcoinType {
+			storeMempool()
+		}
+	}
+}
+
+// restoreMempool loads the mempool entries persisted at the last shutdown,
+// they are reconciled with the backend by the following mempool resync
+func restoreMempool() {
+	entries, err := index.LoadMempool()
+	if err != nil {
+		glog.Error("restoreMempool ", err)
+		return
+	}
+	if len(entries) > 0 {
+		glog.Info("restoreMempool: restored ", mempool.RestoreStoredEntries(entries), " of ", len(entries), " stored mempool entries")
+	}
+}
+
+func storeMempool() {
+	if err := index.StoreMempool(mempool.GetStoredEntries()); err != nil {
+		glog.Error("storeMempool ", err)
 	}
 }
 
@@ -317,7 +452,17 @@ func getBlockChainWithRetry(coin string, configfile string, pushHandler func(bch
 }
 
 func startInternalServer() (*server.InternalServer, error) {
-	internalServer, err := server.NewInternalServer(*internalBinding, *certFiles, index, chain, mempool, txCache, internalState)
+	var apiKey string
+	if *internalAPIKeyFile != "" {
+		key, err := ioutil.ReadFile(*internalAPIKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if apiKey = strings.TrimSpace(string(key)); apiKey == "" {
+			return nil, errors.New("Empty internal API key")
+		}
+	}
+	internalServer, err := server.NewInternalServer(*internalBinding, *certFiles, index, chain, mempool, txCache, metrics, internalState, apiKey)
 	if err != nil {
 		return nil, err
 	}
@@ -355,6 +500,77 @@ func startPublicServer() (*server.PublicServer, error) {
 	return publicServer, err
 }
 
+// initScripthashIndex switches on and completes the scripthash index required by the electrum server,
+// without the electrum server the index is switched off and marked for rebuild
+func initScripthashIndex() error {
+	if *electrumBinding == "" {
+		if chain.GetChainParser().GetChainType() != bchain.ChainBitcoinType {
+			return nil
+		}
+		return index.SetScripthashIndex(false)
+	}
+	if err := index.SetScripthashIndex(true); err != nil {
+		return err
+	}
+	return index.BuildScripthashIndex(chanOsSignal)
+}
+
+func startElectrumServer() (*server.ElectrumServer, error) {
+	electrumServer, err := server.NewElectrumServer(*electrumBinding, *certFiles, index, chain, mempool, txCache, metrics, internalState)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := electrumServer.Run(); err != nil {
+			glog.Error("electrum server: ", err)
+		}
+	}()
+	return electrumServer, nil
+}
+
+func startGrpcServer() (*server.GrpcServer, error) {
+	grpcServer, err := server.NewGrpcServer(*grpcBinding, *certFiles, index, chain, mempool, txCache, metrics, internalState)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := grpcServer.Run(); err != nil {
+			glog.Error("grpc server: ", err)
+		}
+	}()
+	return grpcServer, nil
+}
+
+// startFiatRatesDownloader starts the download of the fiat rates if the provider is set in the blockchain configuration
+func startFiatRatesDownloader(publicServer *server.PublicServer) (*fiat.RatesDownloader, error) {
+	data, err := ioutil.ReadFile(*blockchain)
+	if err != nil {
+		return nil, errors.Annotatef(err, "Error reading file %v", *blockchain)
+	}
+	var config struct {
+		FiatRates       string `json:"fiat_rates"`
+		FiatRatesParams string `json:"fiat_rates_params"`
+	}
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, errors.Annotatef(err, "Error parsing file %v", *blockchain)
+	}
+	if config.FiatRates == "" {
+		glog.Info("fiat rates: not configured")
+		return nil, nil
+	}
+	var onNewTicker fiat.OnNewFiatRatesTicker
+	if publicServer != nil {
+		onNewTicker = publicServer.OnNewFiatRatesTicker
+	}
+	rd, err := fiat.NewRatesDownloader(index, config.FiatRates, config.FiatRatesParams, onNewTicker)
+	if err != nil {
+		return nil, err
+	}
+	rd.Run()
+	glog.Info("fiat rates: downloading from ", config.FiatRates)
+	return rd, nil
+}
+
 func performRollback() {
 	bestHeight, bestHash, err := index.GetBestBlock()
 	if err != nil {
@@ -486,12 +702,18 @@ func syncMempoolLoop() {
 			glog.Error("syncMempoolLoop ", errors.ErrorStack(err))
 		} else {
 			internalState.FinishedMempoolSync(count)
-
+			onMempoolResync()
 		}
 	})
 	glog.Info("syncMempoolLoop stopped")
 }
 
+func onMempoolResync() {
+	for _, c := range callbacksOnMempoolResync {
+		c()
+	}
+}
+
 func storeInternalStateLoop() {
 	stopCompute := make(chan os.Signal)
 	defer func() {
@@ -541,6 +763,12 @@ func onNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
 	}
 }
 
+func onTxReplaced(txid string, replacedBy string, rbf bool, desc bchain.AddressDescriptor) {
+	for _, c := range callbacksOnTxReplaced {
+		c(txid, replacedBy, rbf, desc)
+	}
+}
+
 func pushSynchronizationHandler(nt bchain.NotificationType) {
 	glog.V(1).Info("MQ: notification ", nt)
 	if atomic.LoadInt32(&inShutdown) != 0 {
@@ -6,6 +6,9 @@ import (
 	"time"
 )
 
+// syncProgressPeriod is the minimal period over which the sync speed is measured
+const syncProgressPeriod = 10 * time.Second
+
 const (
 	// DbStateClosed means db was closed gracefully
 	DbStateClosed = uint32(iota)
@@ -46,6 +49,16 @@ type InternalState struct {
 	BestHeight     uint32    `json:"bestHeight"`
 	LastSync       time.Time `json:"lastSync"`
 
+	BackendBestHeight   uint32    `json:"backendBestHeight"`
+	SyncBlocksPerSecond float64   `json:"syncBlocksPerSecond"`
+	SyncTxsPerSecond    float64   `json:"syncTxsPerSecond"`
+	SyncETA             time.Time `json:"syncEta"`
+
+	// start of the current sync speed measurement
+	progressTime   time.Time
+	progressHeight uint32
+	progressTxs    int
+
 	IsMempoolSynchronized bool      `json:"isMempoolSynchronized"`
 	MempoolSize           int       `json:"mempoolSize"`
 	LastMempoolSync       time.Time `json:"lastMempoolSync"`
@@ -58,6 +71,9 @@ func (is *InternalState) StartedSync() {
 	is.mux.Lock()
 	defer is.mux.Unlock()
 	is.IsSynchronized = false
+	is.progressTime = time.Now()
+	is.progressHeight = is.BestHeight
+	is.progressTxs = 0
 }
 
 // FinishedSync marks end of synchronization, bestHeight specifies new best block height
@@ -67,6 +83,7 @@ func (is *InternalState) FinishedSync(bestHeight uint32) {
 	is.IsSynchronized = true
 	is.BestHeight = bestHeight
 	is.LastSync = time.Now()
+	is.SyncETA = time.Time{}
 }
 
 // UpdateBestHeight sets new best height, without changing IsSynchronized flag
@@ -82,6 +99,55 @@ func (is *InternalState) FinishedSyncNoChange() {
 	is.mux.Lock()
 	defer is.mux.Unlock()
 	is.IsSynchronized = true
+	is.SyncETA = time.Time{}
+}
+
+// UpdateBackendBestHeight sets the best height reported by the backend
+func (is *InternalState) UpdateBackendBestHeight(height uint32) {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	is.BackendBestHeight = height
+}
+
+// UpdateSyncProgress records connected block of given height with txs transactions.
+// Once in syncProgressPeriod it recomputes the sync speed and the estimated time of the sync finish
+// and returns true, otherwise it returns false.
+func (is *InternalState) UpdateSyncProgress(height uint32, txs int) bool {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	now := time.Now()
+	if is.progressTime.IsZero() {
+		is.progressTime = now
+		is.progressHeight = height
+		is.progressTxs = 0
+		return false
+	}
+	is.progressTxs += txs
+	d := now.Sub(is.progressTime)
+	if d < syncProgressPeriod || height <= is.progressHeight {
+		return false
+	}
+	s := d.Seconds()
+	is.SyncBlocksPerSecond = float64(height-is.progressHeight) / s
+	is.SyncTxsPerSecond = float64(is.progressTxs) / s
+	if is.BackendBestHeight > height {
+		remaining := float64(is.BackendBestHeight-height) / is.SyncBlocksPerSecond
+		is.SyncETA = now.Add(time.Duration(remaining * float64(time.Second)))
+	} else {
+		is.SyncETA = time.Time{}
+	}
+	is.progressTime = now
+	is.progressHeight = height
+	is.progressTxs = 0
+	return true
+}
+
+// GetSyncProgress returns the backend best height, the sync speed in blocks and transactions per second
+// and the estimated time of the sync finish (zero time if not syncing)
+func (is *InternalState) GetSyncProgress() (uint32, float64, float64, time.Time) {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	return is.BackendBestHeight, is.SyncBlocksPerSecond, is.SyncTxsPerSecond, is.SyncETA
 }
 
 // GetSyncState gets the state of synchronization
@@ -0,0 +1,63 @@
+// +build unittest
+
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInternalState_UpdateSyncProgress(t *testing.T) {
+	is := &InternalState{BackendBestHeight: 1300}
+
+	// the first call only starts the measurement
+	if is.UpdateSyncProgress(1000, 10) {
+		t.Fatal("UpdateSyncProgress() of the first block = true, want false")
+	}
+	if is.progressHeight != 1000 || is.progressTxs != 0 || is.progressTime.IsZero() {
+		t.Fatalf("progress after the first block = %v, %v, %v", is.progressHeight, is.progressTxs, is.progressTime)
+	}
+
+	// within syncProgressPeriod the transactions are only counted
+	if is.UpdateSyncProgress(1001, 20) {
+		t.Fatal("UpdateSyncProgress() within the period = true, want false")
+	}
+	if is.progressTxs != 20 {
+		t.Fatalf("progressTxs = %v, want 20", is.progressTxs)
+	}
+
+	// after the period the speed and ETA are computed and the measurement restarts
+	is.progressTime = time.Now().Add(-10 * time.Second)
+	if !is.UpdateSyncProgress(1100, 80) {
+		t.Fatal("UpdateSyncProgress() after the period = false, want true")
+	}
+	best, blocks, txs, eta := is.GetSyncProgress()
+	if best != 1300 {
+		t.Errorf("BackendBestHeight = %v, want 1300", best)
+	}
+	if blocks < 9 || blocks > 10.1 {
+		t.Errorf("SyncBlocksPerSecond = %v, want about 10", blocks)
+	}
+	if txs < 9 || txs > 10.1 {
+		t.Errorf("SyncTxsPerSecond = %v, want about 10", txs)
+	}
+	// 200 remaining blocks at about 10 blocks per second
+	if d := time.Until(eta); d < 19*time.Second || d > 23*time.Second {
+		t.Errorf("SyncETA in %v, want about 20s", d)
+	}
+	if is.progressHeight != 1100 || is.progressTxs != 0 {
+		t.Errorf("progress after the period = %v, %v, want 1100, 0", is.progressHeight, is.progressTxs)
+	}
+
+	// a block not higher than the last measured one (e.g. after a rollback) does not recompute the speed
+	is.progressTime = time.Now().Add(-10 * time.Second)
+	if is.UpdateSyncProgress(1100, 5) {
+		t.Error("UpdateSyncProgress() of the same height = true, want false")
+	}
+
+	// the ETA is cleared when the backend best block is reached
+	is.UpdateSyncProgress(1300, 5)
+	if _, _, _, eta = is.GetSyncProgress(); !eta.IsZero() {
+		t.Errorf("SyncETA at the best block = %v, want zero", eta)
+	}
+}
@@ -22,11 +22,24 @@ type Metrics struct {
 	RPCLatency            *prometheus.HistogramVec
 	IndexResyncErrors     *prometheus.CounterVec
 	IndexDBSize           prometheus.Gauge
+	IndexSyncBlocksSpeed  prometheus.Gauge
+	IndexSyncTxsSpeed     prometheus.Gauge
+	IndexSyncETA          prometheus.Gauge
+	BackendBestHeight     prometheus.Gauge
 	ExplorerViews         *prometheus.CounterVec
 	MempoolSize           prometheus.Gauge
+	MempoolMemory         prometheus.Gauge
+	MempoolEvictions      *prometheus.CounterVec
 	DbColumnRows          *prometheus.GaugeVec
 	DbColumnSize          *prometheus.GaugeVec
 	BlockbookAppInfo      *prometheus.GaugeVec
+	WebhookDeliveries     *prometheus.CounterVec
+	WebhookDuration       *prometheus.HistogramVec
+	WebhookPending        *prometheus.GaugeVec
+	ElectrumRequests      *prometheus.CounterVec
+	ElectrumClients       prometheus.Gauge
+	GrpcRequests          *prometheus.CounterVec
+	GrpcSubscriptions     *prometheus.GaugeVec
 }
 
 // Labels represents a collection of label name -> value mappings.
@@ -148,6 +161,34 @@ func GetMetrics(coin string) (*Metrics, error) {
 			ConstLabels: Labels{"coin": coin},
 		},
 	)
+	metrics.IndexSyncBlocksSpeed = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_index_sync_blocks_per_second",
+			Help:        "Speed of index synchronization (in blocks per second)",
+			ConstLabels: Labels{"coin": coin},
+		},
+	)
+	metrics.IndexSyncTxsSpeed = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_index_sync_txs_per_second",
+			Help:        "Speed of index synchronization (in transactions per second)",
+			ConstLabels: Labels{"coin": coin},
+		},
+	)
+	metrics.IndexSyncETA = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_index_sync_eta",
+			Help:        "Estimated time to finish index synchronization (in seconds)",
+			ConstLabels: Labels{"coin": coin},
+		},
+	)
+	metrics.BackendBestHeight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_backend_best_height",
+			Help:        "Best block height reported by the backend",
+			ConstLabels: Labels{"coin": coin},
+		},
+	)
 	metrics.ExplorerViews = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name:        "blockbook_explorer_views",
@@ -163,6 +204,21 @@ func GetMetrics(coin string) (*Metrics, error) {
 			ConstLabels: Labels{"coin": coin},
 		},
 	)
+	metrics.MempoolMemory = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_mempool_memory",
+			Help:        "Estimated memory used by mempool (in bytes)",
+			ConstLabels: Labels{"coin": coin},
+		},
+	)
+	metrics.MempoolEvictions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_mempool_evictions",
+			Help:        "Total number of transactions evicted from mempool by reason",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"reason"},
+	)
 	metrics.DbColumnRows = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name:        "blockbook_dbcolumn_rows",
@@ -187,6 +243,62 @@ func GetMetrics(coin string) (*Metrics, error) {
 		},
 		[]string{"blockbook_version", "blockbook_commit", "blockbook_buildtime", "backend_version", "backend_subversion", "backend_protocol_version"},
 	)
+	metrics.WebhookDeliveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_webhook_deliveries",
+			Help:        "Total number of webhook delivery attempts by webhook and status",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"webhook", "status"},
+	)
+	metrics.WebhookDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:        "blockbook_webhook_duration",
+			Help:        "Duration of webhook delivery by webhook (in milliseconds)",
+			Buckets:     []float64{10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000},
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"webhook"},
+	)
+	metrics.WebhookPending = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_webhook_pending",
+			Help:        "Number of events waiting in the outbox for the delivery by webhook",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"webhook"},
+	)
+	metrics.ElectrumRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_electrum_requests",
+			Help:        "Total number of electrum protocol requests by method and status",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"method", "status"},
+	)
+	metrics.ElectrumClients = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_electrum_clients",
+			Help:        "Number of currently connected electrum protocol clients",
+			ConstLabels: Labels{"coin": coin},
+		},
+	)
+	metrics.GrpcRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_grpc_requests",
+			Help:        "Total number of grpc requests by method and status",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"method", "status"},
+	)
+	metrics.GrpcSubscriptions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_grpc_subscriptions",
+			Help:        "Number of currently open grpc subscription streams by method",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"method"},
+	)
 
 	v := reflect.ValueOf(metrics)
 	for i := 0; i < v.NumField(); i++ {
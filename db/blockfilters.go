@@ -0,0 +1,223 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"bytes"
+	"encoding/hex"
+	"sync/atomic"
+
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// length of the filter header stored before the filter, unknown header is stored as zero bytes
+const blockFilterHeaderLen = 32
+
+var unknownBlockFilterHeader = make([]byte, blockFilterHeaderLen)
+
+// BlockFilter is the BIP158 basic filter of a block with its BIP157 filter header,
+// the header is nil if it is not known because the filter of a previous block is missing
+type BlockFilter struct {
+	Filter []byte
+	Header []byte
+}
+
+// SetBlockFilters switches building of the BIP158 block filters during the connect of blocks
+func (d *RocksDB) SetBlockFilters(enabled bool) error {
+	if !enabled {
+		atomic.StoreInt32(&d.blockFilters, 0)
+		return nil
+	}
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return errors.New("Unsupported chain type")
+	}
+	atomic.StoreInt32(&d.blockFilters, 1)
+	return nil
+}
+
+func (d *RocksDB) blockFiltersEnabled() bool {
+	return atomic.LoadInt32(&d.blockFilters) != 0
+}
+
+// buildBlockFilter builds the basic filter of the block from the addresses of its inputs and outputs
+// and from the output scripts too long to be indexed as addresses
+func buildBlockFilter(hash string, addresses addressesMap, longScripts [][]byte) ([]byte, error) {
+	scripts := make(map[string]struct{}, len(addresses)+len(longScripts))
+	for ad := range addresses {
+		scripts[ad] = struct{}{}
+	}
+	for _, s := range longScripts {
+		scripts[string(s)] = struct{}{}
+	}
+	return buildBlockFilterFromScripts(hash, scripts)
+}
+
+// buildBlockFilterFromScripts builds the basic filter of the block from the set of scripts,
+// the OP_RETURN outputs are not part of the filter
+func buildBlockFilterFromScripts(hash string, scripts map[string]struct{}) ([]byte, error) {
+	r := make([][]byte, 0, len(scripts))
+	for s := range scripts {
+		if len(s) == 0 || s[0] == 0x6a {
+			continue
+		}
+		r = append(r, []byte(s))
+	}
+	return bchain.BuildBasicBlockFilter(hash, r)
+}
+
+// longOutputScripts returns the output scripts of the block longer than maxAddrDescLen,
+// they are not in the address index but they are part of the block filter
+func longOutputScripts(block *bchain.Block) [][]byte {
+	var r [][]byte
+	for i := range block.Txs {
+		for j := range block.Txs[i].Vout {
+			h := block.Txs[i].Vout[j].ScriptPubKey.Hex
+			if len(h) > 2*maxAddrDescLen {
+				if s, err := hex.DecodeString(h); err == nil {
+					r = append(r, s)
+				}
+			}
+		}
+	}
+	return r
+}
+
+// storeBlockFilter builds and stores the filter of the block and returns its filter header,
+// prevHeader is the filter header of the previous block, nil if unknown
+func (d *RocksDB) storeBlockFilter(wb *gorocksdb.WriteBatch, height uint32, hash string, addresses addressesMap, longScripts [][]byte, prevHeader []byte) ([]byte, error) {
+	filter, err := buildBlockFilter(hash, addresses, longScripts)
+	if err != nil {
+		return nil, err
+	}
+	if height == 0 {
+		prevHeader = unknownBlockFilterHeader
+	}
+	var header []byte
+	if prevHeader != nil {
+		header = bchain.GetBlockFilterHeader(filter, prevHeader)
+	}
+	d.putBlockFilter(wb, height, filter, header)
+	return header, nil
+}
+
+func (d *RocksDB) putBlockFilter(wb *gorocksdb.WriteBatch, height uint32, filter []byte, header []byte) {
+	val := make([]byte, 0, blockFilterHeaderLen+len(filter))
+	if header != nil {
+		val = append(val, header...)
+	} else {
+		val = append(val, unknownBlockFilterHeader...)
+	}
+	val = append(val, filter...)
+	wb.PutCF(d.cfh[cfBlockFilters], packUint(height), val)
+}
+
+func unpackBlockFilter(buf []byte) (*BlockFilter, error) {
+	if len(buf) < blockFilterHeaderLen+1 {
+		return nil, errors.New("Invalid block filter")
+	}
+	bf := &BlockFilter{Filter: append([]byte(nil), buf[blockFilterHeaderLen:]...)}
+	if !bytes.Equal(buf[:blockFilterHeaderLen], unknownBlockFilterHeader) {
+		bf.Header = append([]byte(nil), buf[:blockFilterHeaderLen]...)
+	}
+	return bf, nil
+}
+
+// GetBlockFilter returns the filter of the block at given height or nil if the filter is not stored
+func (d *RocksDB) GetBlockFilter(height uint32) (*BlockFilter, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return nil, nil
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfBlockFilters], packUint(height))
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	if len(val.Data()) == 0 {
+		return nil, nil
+	}
+	return unpackBlockFilter(val.Data())
+}
+
+// getBlockFilterHeader returns the filter header of the block at given height, nil if it is not known
+func (d *RocksDB) getBlockFilterHeader(height uint32) ([]byte, error) {
+	bf, err := d.GetBlockFilter(height)
+	if err != nil || bf == nil {
+		return nil, err
+	}
+	return bf.Header, nil
+}
+
+// GetBlockFilterHeaders returns up to count filter headers of consecutive blocks starting at given height,
+// the result ends before the first block without a known filter header
+func (d *RocksDB) GetBlockFilterHeaders(start uint32, count int) ([][]byte, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType || count <= 0 {
+		return nil, nil
+	}
+	headers := make([][]byte, 0, count)
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfBlockFilters])
+	defer it.Close()
+	height := start
+	for it.Seek(packUint(start)); it.Valid() && len(headers) < count; it.Next() {
+		if unpackUint(it.Key().Data()) != height {
+			break
+		}
+		bf, err := unpackBlockFilter(it.Value().Data())
+		if err != nil {
+			return nil, err
+		}
+		if bf.Header == nil {
+			break
+		}
+		headers = append(headers, bf.Header)
+		height++
+	}
+	return headers, nil
+}
+
+// firstUnknownBlockFilterHeader returns the lowest height from which the filter headers are not known,
+// either because the filter is missing or because it was built without the header of the previous block
+func (d *RocksDB) firstUnknownBlockFilterHeader() (uint32, error) {
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+	ro.SetFillCache(false)
+	it := d.db.NewIteratorCF(ro, d.cfh[cfBlockFilters])
+	defer it.Close()
+	var height uint32
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		if unpackUint(it.Key().Data()) != height {
+			break
+		}
+		val := it.Value().Data()
+		if len(val) < blockFilterHeaderLen || bytes.Equal(val[:blockFilterHeaderLen], unknownBlockFilterHeader) {
+			break
+		}
+		height++
+	}
+	return height, it.Err()
+}
+
+// getBlockFilterScripts returns the scripts of the block filter of an already indexed block, the inputs are taken from the index,
+// the outputs from the block, only the inputs spending the outputs too long to be indexed are missing
+func (d *RocksDB) getBlockFilterScripts(block *bchain.Block) (map[string]struct{}, error) {
+	scripts := make(map[string]struct{})
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		ta, err := d.GetTxAddresses(tx.Txid)
+		if err != nil {
+			return nil, err
+		}
+		if ta != nil {
+			for j := range ta.Inputs {
+				scripts[string(ta.Inputs[j].AddrDesc)] = struct{}{}
+			}
+		}
+		for j := range tx.Vout {
+			s, err := hex.DecodeString(tx.Vout[j].ScriptPubKey.Hex)
+			if err != nil {
+				return nil, errors.Annotatef(err, "tx %v vout %v", tx.Txid, j)
+			}
+			scripts[string(s)] = struct{}{}
+		}
+	}
+	return scripts, nil
+}
@@ -0,0 +1,148 @@
+//go:build unittest
+// +build unittest
+
+package db
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"blockbook/tests/dbtestdata"
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestRocksDB_BlockFilters(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	expectedFilter := func(hash string, addrs ...string) []byte {
+		scripts := make([][]byte, len(addrs))
+		for i, a := range addrs {
+			scripts[i] = addressToAddrDesc(a, d.chainParser)
+		}
+		f, err := bchain.BuildBasicBlockFilter(hash, scripts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	checkFilter := func(height uint32, filter []byte, header []byte) {
+		bf, err := d.GetBlockFilter(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bf == nil {
+			t.Fatalf("GetBlockFilter(%v) not found", height)
+		}
+		if !bytes.Equal(bf.Filter, filter) {
+			t.Errorf("GetBlockFilter(%v).Filter = %x, want %x", height, bf.Filter, filter)
+		}
+		if !bytes.Equal(bf.Header, header) {
+			t.Errorf("GetBlockFilter(%v).Header = %x, want %x", height, bf.Header, header)
+		}
+	}
+
+	if err := d.SetBlockFilters(true); err != nil {
+		t.Fatal(err)
+	}
+	// filter header of the block before the first connected block, so that the header chain is known
+	prevHeader := bytes.Repeat([]byte{0x11}, blockFilterHeaderLen)
+	if err := d.db.PutCF(d.wo, d.cfh[cfBlockFilters], packUint(225492), append(append([]byte{}, prevHeader...), 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	block1 := dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)
+	if err := d.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	f1 := expectedFilter(block1.Hash, dbtestdata.Addr1, dbtestdata.Addr2, dbtestdata.Addr3, dbtestdata.Addr4, dbtestdata.Addr5)
+	h1 := bchain.GetBlockFilterHeader(f1, prevHeader)
+	checkFilter(225493, f1, h1)
+
+	// the filter contains also the addresses of the spent outputs, the output without address is skipped
+	block2 := dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)
+	if err := d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	f2 := expectedFilter(block2.Hash, dbtestdata.Addr2, dbtestdata.Addr3, dbtestdata.Addr4, dbtestdata.Addr5, dbtestdata.Addr6,
+		dbtestdata.Addr7, dbtestdata.Addr8, dbtestdata.Addr9, dbtestdata.AddrA)
+	h2 := bchain.GetBlockFilterHeader(f2, h1)
+	checkFilter(225494, f2, h2)
+
+	headers, err := d.GetBlockFilterHeaders(225492, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]byte{prevHeader, h1, h2}; !reflect.DeepEqual(headers, want) {
+		t.Errorf("GetBlockFilterHeaders() = %x, want %x", headers, want)
+	}
+
+	// the filter of the disconnected block is removed
+	if err := d.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+		t.Fatal(err)
+	}
+	if bf, err := d.GetBlockFilter(225494); err != nil || bf != nil {
+		t.Errorf("GetBlockFilter(225494) after disconnect = %+v, %v, want nil", bf, err)
+	}
+
+	// without the filter of the previous block the header is not known
+	if err := d.db.DeleteCF(d.wo, d.cfh[cfBlockFilters], packUint(225493)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	checkFilter(225494, f2, nil)
+	if headers, err = d.GetBlockFilterHeaders(225494, 10); err != nil || len(headers) != 0 {
+		t.Errorf("GetBlockFilterHeaders(225494) = %x, %v, want empty", headers, err)
+	}
+}
+
+func Test_buildBlockFilter(t *testing.T) {
+	hash := "000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943"
+	script := []byte{0x00, 0x14, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}
+	want, err := bchain.BuildBasicBlockFilter(hash, [][]byte{script})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// OP_RETURN output is not part of the filter
+	got, err := buildBlockFilter(hash, addressesMap{string(script): nil, "\x6a\x04test": nil}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("buildBlockFilter() = %x, want %x", got, want)
+	}
+}
+
+func Test_longOutputScripts(t *testing.T) {
+	hash := "000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943"
+	script := []byte{0x00, 0x14, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}
+	long := bytes.Repeat([]byte{0x51}, maxAddrDescLen+1)
+	block := &bchain.Block{Txs: []bchain.Tx{{
+		Vout: []bchain.Vout{
+			{ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(script)}},
+			{ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(long)}},
+		},
+	}}}
+	got := longOutputScripts(block)
+	if want := [][]byte{long}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("longOutputScripts() = %x, want %x", got, want)
+	}
+	// the long script not indexed as address is part of the filter, duplicates are counted once
+	want, err := bchain.BuildBasicBlockFilter(hash, [][]byte{script, long})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := buildBlockFilter(hash, addressesMap{string(script): nil}, [][]byte{long, long})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(f, want) {
+		t.Errorf("buildBlockFilter() = %x, want %x", f, want)
+	}
+}
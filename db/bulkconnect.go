@@ -17,6 +17,8 @@ import (
 type bulkAddresses struct {
 	bi        BlockInfo
 	addresses addressesMap
+	// output scripts of the block not indexed as addresses, used by the block filter
+	longScripts [][]byte
 }
 
 // BulkConnect is used to connect blocks in bulk, faster but if interrupted inconsistent way
@@ -29,6 +31,10 @@ type BulkConnect struct {
 	balances           map[string]*AddrBalance
 	addressContracts   map[string]*AddrContracts
 	height             uint32
+	// filter header of the last block with stored filter, it may not be written to the db yet
+	filterHeader       []byte
+	filterHeaderHeight uint32
+	filterHeaderValid  bool
 }
 
 const (
@@ -163,12 +169,35 @@ func (b *BulkConnect) storeBulkAddresses(wb *gorocksdb.WriteBatch) error {
 		if err := b.d.writeHeight(wb, ba.bi.Height, &ba.bi, opInsert); err != nil {
 			return err
 		}
+		if b.d.blockFiltersEnabled() {
+			if err := b.storeBlockFilter(wb, &ba.bi, ba.addresses, ba.longScripts); err != nil {
+				return err
+			}
+		}
 	}
 	b.bulkAddressesCount = 0
 	b.bulkAddresses = b.bulkAddresses[:0]
 	return nil
 }
 
+func (b *BulkConnect) storeBlockFilter(wb *gorocksdb.WriteBatch, bi *BlockInfo, addresses addressesMap, longScripts [][]byte) error {
+	var prevHeader []byte
+	if b.filterHeaderValid && b.filterHeaderHeight+1 == bi.Height {
+		prevHeader = b.filterHeader
+	} else if bi.Height > 0 {
+		var err error
+		if prevHeader, err = b.d.getBlockFilterHeader(bi.Height - 1); err != nil {
+			return err
+		}
+	}
+	header, err := b.d.storeBlockFilter(wb, bi.Height, bi.Hash, addresses, longScripts, prevHeader)
+	if err != nil {
+		return err
+	}
+	b.filterHeader, b.filterHeaderHeight, b.filterHeaderValid = header, bi.Height, true
+	return nil
+}
+
 func (b *BulkConnect) connectBlockBitcoinType(block *bchain.Block, storeBlockTxs bool) error {
 	addresses := make(addressesMap)
 	if err := b.d.processAddressesBitcoinType(block, addresses, b.txAddressesMap, b.balances); err != nil {
@@ -187,7 +216,7 @@ func (b *BulkConnect) connectBlockBitcoinType(block *bchain.Block, storeBlockTxs
 			go b.parallelStoreBalances(storeBalancesChan, false)
 		}
 	}
-	b.bulkAddresses = append(b.bulkAddresses, bulkAddresses{
+	ba := bulkAddresses{
 		bi: BlockInfo{
 			Hash:   block.Hash,
 			Time:   block.Time,
@@ -196,7 +225,11 @@ func (b *BulkConnect) connectBlockBitcoinType(block *bchain.Block, storeBlockTxs
 			Height: block.Height,
 		},
 		addresses: addresses,
-	})
+	}
+	if b.d.blockFiltersEnabled() {
+		ba.longScripts = longOutputScripts(block)
+	}
+	b.bulkAddresses = append(b.bulkAddresses, ba)
 	b.bulkAddressesCount += len(addresses)
 	// open WriteBatch only if going to write
 	if sa || b.bulkAddressesCount > maxBulkAddresses || storeBlockTxs {
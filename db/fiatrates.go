@@ -0,0 +1,83 @@
+package db
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// Resolutions of the stored fiat rates tickers, used as the prefix of the key
+const (
+	FiatRatesDaily  = byte('d')
+	FiatRatesHourly = byte('h')
+)
+
+// CurrencyRatesTicker contains the exchange rates of the coin to other currencies (lowercase codes) at the unix time Timestamp
+type CurrencyRatesTicker struct {
+	Timestamp int64              `json:"timestamp"`
+	Rates     map[string]float64 `json:"rates"`
+}
+
+func packFiatRatesKey(resolution byte, timestamp int64) []byte {
+	return append([]byte{resolution}, packUint(uint32(timestamp))...)
+}
+
+func unpackFiatRatesTicker(key, val []byte) (*CurrencyRatesTicker, error) {
+	var t CurrencyRatesTicker
+	if err := json.Unmarshal(val, &t); err != nil {
+		return nil, errors.Annotatef(err, "fiat rates ticker %x", key)
+	}
+	return &t, nil
+}
+
+// FiatRatesStoreTickers stores the tickers of the given resolution, a ticker with the same timestamp is replaced
+func (d *RocksDB) FiatRatesStoreTickers(resolution byte, tickers []CurrencyRatesTicker) error {
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	for i := range tickers {
+		buf, err := json.Marshal(&tickers[i])
+		if err != nil {
+			return err
+		}
+		wb.PutCF(d.cfh[cfFiatRates], packFiatRatesKey(resolution, tickers[i].Timestamp), buf)
+	}
+	return d.db.Write(d.wo, wb)
+}
+
+// FiatRatesFindTicker returns the newest ticker of the given resolution with timestamp not after the given time or nil if there is none
+func (d *RocksDB) FiatRatesFindTicker(resolution byte, timestamp int64) (*CurrencyRatesTicker, error) {
+	if timestamp < 0 {
+		return nil, nil
+	}
+	// the timestamp is stored as uint32
+	if timestamp > math.MaxUint32 {
+		timestamp = math.MaxUint32
+	}
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfFiatRates])
+	defer it.Close()
+	key := packFiatRatesKey(resolution, timestamp)
+	it.Seek(key)
+	if it.Valid() {
+		if k := it.Key().Data(); len(k) == len(key) && k[0] == resolution && unpackUint(k[1:]) == uint32(timestamp) {
+			return unpackFiatRatesTicker(k, it.Value().Data())
+		}
+		it.Prev()
+	} else {
+		it.SeekToLast()
+	}
+	if !it.Valid() {
+		return nil, nil
+	}
+	k := it.Key().Data()
+	if len(k) != len(key) || k[0] != resolution {
+		return nil, nil
+	}
+	return unpackFiatRatesTicker(k, it.Value().Data())
+}
+
+// FiatRatesGetLastTicker returns the newest stored ticker of the given resolution or nil if there is none
+func (d *RocksDB) FiatRatesGetLastTicker(resolution byte) (*CurrencyRatesTicker, error) {
+	return d.FiatRatesFindTicker(resolution, math.MaxUint32)
+}
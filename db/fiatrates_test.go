@@ -0,0 +1,84 @@
+//go:build unittest
+// +build unittest
+
+package db
+
+import (
+	"blockbook/bchain/coins/btc"
+	"reflect"
+	"testing"
+)
+
+func TestRocksDB_FiatRates(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	last, err := d.FiatRatesGetLastTicker(FiatRatesHourly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != nil {
+		t.Errorf("FiatRatesGetLastTicker() in empty db = %+v, want nil", last)
+	}
+	daily := []CurrencyRatesTicker{
+		{Timestamp: 1574294400, Rates: map[string]float64{"usd": 0.11, "btc": 0.000015}},
+		{Timestamp: 1574380800, Rates: map[string]float64{"usd": 0.12, "btc": 0.000016}},
+	}
+	hourly := []CurrencyRatesTicker{
+		{Timestamp: 1574290800, Rates: map[string]float64{"usd": 0.105}},
+		{Timestamp: 1574294400, Rates: map[string]float64{"usd": 0.115}},
+		{Timestamp: 1574298000, Rates: map[string]float64{"usd": 0.125}},
+	}
+	if err = d.FiatRatesStoreTickers(FiatRatesDaily, daily); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.FiatRatesStoreTickers(FiatRatesHourly, hourly); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		resolution byte
+		timestamp  int64
+		want       *CurrencyRatesTicker
+	}{
+		{name: "daily before first", resolution: FiatRatesDaily, timestamp: 1574294399, want: nil},
+		{name: "daily exact", resolution: FiatRatesDaily, timestamp: 1574294400, want: &daily[0]},
+		{name: "daily between", resolution: FiatRatesDaily, timestamp: 1574380799, want: &daily[0]},
+		{name: "daily after last", resolution: FiatRatesDaily, timestamp: 1600000000, want: &daily[1]},
+		{name: "hourly before first", resolution: FiatRatesHourly, timestamp: 1574290000, want: nil},
+		{name: "hourly between", resolution: FiatRatesHourly, timestamp: 1574297999, want: &hourly[1]},
+		{name: "hourly after last", resolution: FiatRatesHourly, timestamp: 1600000000, want: &hourly[2]},
+		{name: "hourly after uint32 range", resolution: FiatRatesHourly, timestamp: 22549400002, want: &hourly[2]},
+		{name: "negative", resolution: FiatRatesHourly, timestamp: -1, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.FiatRatesFindTicker(tt.resolution, tt.timestamp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FiatRatesFindTicker() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	// a ticker with the same timestamp is replaced
+	replaced := []CurrencyRatesTicker{{Timestamp: 1574298000, Rates: map[string]float64{"usd": 0.13, "eur": 0.12}}}
+	if err = d.FiatRatesStoreTickers(FiatRatesHourly, replaced); err != nil {
+		t.Fatal(err)
+	}
+	if last, err = d.FiatRatesGetLastTicker(FiatRatesHourly); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(last, &replaced[0]) {
+		t.Errorf("FiatRatesGetLastTicker() = %+v, want %+v", last, replaced[0])
+	}
+	if last, err = d.FiatRatesGetLastTicker(FiatRatesDaily); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(last, &daily[1]) {
+		t.Errorf("FiatRatesGetLastTicker(daily) = %+v, want %+v", last, daily[1])
+	}
+}
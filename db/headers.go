@@ -0,0 +1,70 @@
+package db
+
+import (
+	"blockbook/bchain"
+
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// StoreBlockHeaders stores the raw headers of consecutive blocks starting at given height
+func (d *RocksDB) StoreBlockHeaders(start uint32, headers [][]byte) error {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return errors.New("Unsupported chain type")
+	}
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	for i := range headers {
+		wb.PutCF(d.cfh[cfHeaders], packUint(start+uint32(i)), headers[i])
+	}
+	return d.db.Write(d.wo, wb)
+}
+
+// GetLastBlockHeaderHeight returns the height of the last stored block header, ok is false if no header is stored
+func (d *RocksDB) GetLastBlockHeaderHeight() (height uint32, ok bool, err error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return 0, false, nil
+	}
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfHeaders])
+	defer it.Close()
+	if it.SeekToLast(); it.Valid() {
+		return unpackUint(it.Key().Data()), true, nil
+	}
+	return 0, false, nil
+}
+
+// GetBlockHeaderRaw returns the raw header of the block at given height or nil if it is not stored
+func (d *RocksDB) GetBlockHeaderRaw(height uint32) ([]byte, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return nil, nil
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfHeaders], packUint(height))
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	if len(val.Data()) == 0 {
+		return nil, nil
+	}
+	return append([]byte(nil), val.Data()...), nil
+}
+
+// GetBlockHeadersRaw returns up to count raw headers of consecutive blocks starting at given height,
+// the result ends before the first header which is not stored
+func (d *RocksDB) GetBlockHeadersRaw(start uint32, count int) ([][]byte, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType || count <= 0 {
+		return nil, nil
+	}
+	headers := make([][]byte, 0, count)
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfHeaders])
+	defer it.Close()
+	height := start
+	for it.Seek(packUint(start)); it.Valid() && len(headers) < count; it.Next() {
+		if unpackUint(it.Key().Data()) != height {
+			break
+		}
+		headers = append(headers, append([]byte(nil), it.Value().Data()...))
+		height++
+	}
+	return headers, nil
+}
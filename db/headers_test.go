@@ -0,0 +1,77 @@
+//go:build unittest
+// +build unittest
+
+package db
+
+import (
+	"blockbook/bchain/coins/btc"
+	"blockbook/tests/dbtestdata"
+	"reflect"
+	"testing"
+)
+
+func TestRocksDB_BlockHeaders(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	checkLast := func(want uint32, wantOk bool) {
+		got, ok, err := d.GetLastBlockHeaderHeight()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != wantOk || got != want {
+			t.Errorf("GetLastBlockHeaderHeight() = %v, %v, want %v, %v", got, ok, want, wantOk)
+		}
+	}
+	checkHeaders := func(start uint32, count int, want [][]byte) {
+		got, err := d.GetBlockHeadersRaw(start, count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetBlockHeadersRaw(%v, %v) = %x, want %x", start, count, got, want)
+		}
+	}
+
+	checkLast(0, false)
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	h1, h2, h4 := []byte{1, 2, 3}, []byte{4, 5, 6}, []byte{7, 8, 9}
+	if err := d.StoreBlockHeaders(225493, [][]byte{h1, h2}); err != nil {
+		t.Fatal(err)
+	}
+	// header after a gap
+	if err := d.StoreBlockHeaders(225496, [][]byte{h4}); err != nil {
+		t.Fatal(err)
+	}
+	checkLast(225496, true)
+	h, err := d.GetBlockHeaderRaw(225494)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(h, h2) {
+		t.Errorf("GetBlockHeaderRaw(225494) = %x, want %x", h, h2)
+	}
+	if h, err = d.GetBlockHeaderRaw(225495); err != nil || h != nil {
+		t.Errorf("GetBlockHeaderRaw(225495) = %x, %v, want nil", h, err)
+	}
+	checkHeaders(225493, 1, [][]byte{h1})
+	checkHeaders(225493, 10, [][]byte{h1, h2})
+	checkHeaders(225492, 10, [][]byte{})
+	checkHeaders(225496, 10, [][]byte{h4})
+
+	// the headers of the disconnected blocks are removed
+	if err := d.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+		t.Fatal(err)
+	}
+	checkHeaders(225493, 10, [][]byte{h1})
+	if h, err = d.GetBlockHeaderRaw(225494); err != nil || h != nil {
+		t.Errorf("GetBlockHeaderRaw(225494) after disconnect = %x, %v, want nil", h, err)
+	}
+}
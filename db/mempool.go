@@ -0,0 +1,168 @@
+package db
+
+import (
+	"blockbook/bchain"
+
+	vlq "github.com/bsm/go-vlq"
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+const mempoolKey = "mempool"
+
+// mempoolFormatVersion prefixes the stored mempool, it must be increased on any change of the packed format
+const mempoolFormatVersion = 2
+
+// StoreMempool persists the mempool entries so that they can be restored after restart
+func (d *RocksDB) StoreMempool(entries []bchain.MempoolStoredEntry) error {
+	buf, err := d.packMempoolEntries(entries)
+	if err != nil {
+		return err
+	}
+	glog.Info("rocksdb: storing ", len(entries), " mempool entries, ", len(buf), " bytes")
+	return d.db.PutCF(d.wo, d.cfh[cfDefault], []byte(mempoolKey), buf)
+}
+
+// LoadMempool loads the mempool entries stored by StoreMempool and removes them from db,
+// the entries are restored only once to avoid use of stale data after ungraceful shutdown.
+// Entries stored in a different format version are discarded, the mempool is then filled by the normal resync.
+func (d *RocksDB) LoadMempool() ([]bchain.MempoolStoredEntry, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfDefault], []byte(mempoolKey))
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	data := val.Data()
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if err = d.db.DeleteCF(d.wo, d.cfh[cfDefault], []byte(mempoolKey)); err != nil {
+		return nil, err
+	}
+	if data[0] != mempoolFormatVersion {
+		glog.Warning("rocksdb: discarding stored mempool in format version ", data[0], ", required version ", mempoolFormatVersion)
+		return nil, nil
+	}
+	return d.unpackMempoolEntries(data[1:])
+}
+
+func (d *RocksDB) packMempoolEntries(entries []bchain.MempoolStoredEntry) ([]byte, error) {
+	buf := make([]byte, 0, 64*len(entries)+vlq.MaxLen64+1)
+	buf = append(buf, mempoolFormatVersion)
+	varBuf := make([]byte, maxPackedBigintBytes)
+	l := packVaruint(uint(len(entries)), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	for i := range entries {
+		e := &entries[i]
+		btxID, err := d.chainParser.PackTxid(e.Txid)
+		if err != nil {
+			return nil, errors.Annotatef(err, "PackTxid %v", e.Txid)
+		}
+		buf = append(buf, btxID...)
+		buf = append(buf, packUint(e.Time)...)
+		l = packVaruint(uint(len(e.AddrIndexes)), varBuf)
+		buf = append(buf, varBuf[:l]...)
+		for j := range e.AddrIndexes {
+			ai := &e.AddrIndexes[j]
+			l = packVaruint(uint(len(ai.AddrDesc)), varBuf)
+			buf = append(buf, varBuf[:l]...)
+			buf = append(buf, ai.AddrDesc...)
+			l = packVarint32(ai.N, varBuf)
+			buf = append(buf, varBuf[:l]...)
+		}
+		l = packBigint(&e.Fee, varBuf)
+		buf = append(buf, varBuf[:l]...)
+		l = packVaruint(uint(e.VSize), varBuf)
+		buf = append(buf, varBuf[:l]...)
+		// the rbf flag is stored as the complement of the number of inputs
+		ni := len(e.Inputs)
+		if e.Rbf {
+			ni = ^ni
+		}
+		l = packVarint(ni, varBuf)
+		buf = append(buf, varBuf[:l]...)
+		for j := range e.Inputs {
+			btxID, err := d.chainParser.PackTxid(e.Inputs[j].Txid)
+			if err != nil {
+				return nil, errors.Annotatef(err, "PackTxid %v", e.Inputs[j].Txid)
+			}
+			buf = append(buf, btxID...)
+			l = packVarint32(e.Inputs[j].Vout, varBuf)
+			buf = append(buf, varBuf[:l]...)
+		}
+	}
+	return buf, nil
+}
+
+func (d *RocksDB) unpackMempoolEntries(buf []byte) ([]bchain.MempoolStoredEntry, error) {
+	txidLen := d.chainParser.PackedTxidLen()
+	n, p := unpackVaruint(buf)
+	// the counts are checked against the remaining data before allocation, each item takes at least its minimal packed size
+	if n > uint((len(buf)-p)/(txidLen+4)) {
+		return nil, errors.New("Invalid mempool data")
+	}
+	entries := make([]bchain.MempoolStoredEntry, n)
+	for i := range entries {
+		if len(buf) < p+txidLen+4 {
+			return nil, errors.New("Invalid mempool data")
+		}
+		txid, err := d.chainParser.UnpackTxid(buf[p : p+txidLen])
+		if err != nil {
+			return nil, err
+		}
+		p += txidLen
+		e := &entries[i]
+		e.Txid = txid
+		e.Time = unpackUint(buf[p:])
+		p += 4
+		na, l := unpackVaruint(buf[p:])
+		p += l
+		if na > uint((len(buf)-p)/2) {
+			return nil, errors.New("Invalid mempool data")
+		}
+		e.AddrIndexes = make([]bchain.MempoolAddrIndex, na)
+		for j := range e.AddrIndexes {
+			al, l := unpackVaruint(buf[p:])
+			p += l
+			if len(buf) < p+int(al)+1 {
+				return nil, errors.New("Invalid mempool data")
+			}
+			ai := &e.AddrIndexes[j]
+			ai.AddrDesc = append(bchain.AddressDescriptor(nil), buf[p:p+int(al)]...)
+			p += int(al)
+			ai.N, l = unpackVarint32(buf[p:])
+			p += l
+		}
+		if len(buf) < p+1 || len(buf) < p+int(buf[p])+2 {
+			return nil, errors.New("Invalid mempool data")
+		}
+		e.Fee, l = unpackBigint(buf[p:])
+		p += l
+		vsize, l := unpackVaruint(buf[p:])
+		e.VSize = uint32(vsize)
+		p += l
+		ni, l := unpackVarint(buf[p:])
+		p += l
+		if ni < 0 {
+			e.Rbf = true
+			ni = ^ni
+		}
+		if ni > (len(buf)-p)/(txidLen+1) {
+			return nil, errors.New("Invalid mempool data")
+		}
+		e.Inputs = make([]bchain.Outpoint, ni)
+		for j := range e.Inputs {
+			if len(buf) < p+txidLen+1 {
+				return nil, errors.New("Invalid mempool data")
+			}
+			e.Inputs[j].Txid, err = d.chainParser.UnpackTxid(buf[p : p+txidLen])
+			if err != nil {
+				return nil, err
+			}
+			p += txidLen
+			e.Inputs[j].Vout, l = unpackVarint32(buf[p:])
+			p += l
+		}
+	}
+	return entries, nil
+}
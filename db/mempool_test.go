@@ -0,0 +1,135 @@
+//go:build unittest
+// +build unittest
+
+package db
+
+import (
+	"blockbook/bchain"
+	"blockbook/tests/dbtestdata"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestRocksDB_StoreMempool_LoadMempool(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	entries := []bchain.MempoolStoredEntry{
+		{
+			Txid: dbtestdata.TxidB2T1,
+			Time: 1554043080,
+			AddrIndexes: []bchain.MempoolAddrIndex{
+				{AddrDesc: addressToAddrDesc(dbtestdata.Addr3, d.chainParser), N: 0},
+				{AddrDesc: addressToAddrDesc(dbtestdata.Addr4, d.chainParser), N: 1},
+				{AddrDesc: addressToAddrDesc(dbtestdata.Addr1, d.chainParser), N: ^0},
+			},
+			Fee:   *big.NewInt(22600),
+			VSize: 226,
+			Inputs: []bchain.Outpoint{
+				{Txid: dbtestdata.TxidB1T2, Vout: 0},
+				{Txid: dbtestdata.TxidB1T1, Vout: 2},
+			},
+			Rbf: true,
+		},
+		{
+			Txid:        dbtestdata.TxidB2T2,
+			Time:        1554043088,
+			AddrIndexes: []bchain.MempoolAddrIndex{},
+			Inputs:      []bchain.Outpoint{},
+		},
+	}
+	if err := d.StoreMempool(entries); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.LoadMempool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("LoadMempool() = %+v, want %+v", got, entries)
+	}
+	// the stored mempool can be loaded only once
+	got, err = d.LoadMempool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("LoadMempool() = %+v, want nil", got)
+	}
+	// the mempool stored in a different format version is discarded
+	buf, err := d.packMempoolEntries(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf[0] = mempoolFormatVersion - 1
+	if err := d.db.PutCF(d.wo, d.cfh[cfDefault], []byte(mempoolKey), buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err = d.LoadMempool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("LoadMempool() = %+v, want nil", got)
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfDefault], []byte(mempoolKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer val.Free()
+	if val.Size() != 0 {
+		t.Error("the stored mempool in a different format version was not removed")
+	}
+}
+
+func TestRocksDB_unpackMempoolEntries_invalid(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	entries := []bchain.MempoolStoredEntry{
+		{
+			Txid: dbtestdata.TxidB2T1,
+			Time: 1554043080,
+			AddrIndexes: []bchain.MempoolAddrIndex{
+				{AddrDesc: addressToAddrDesc(dbtestdata.Addr3, d.chainParser), N: 0},
+			},
+			Inputs: []bchain.Outpoint{
+				{Txid: dbtestdata.TxidB1T2, Vout: 0},
+			},
+		},
+	}
+	valid, err := d.packMempoolEntries(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// skip the format version, it is checked by LoadMempool
+	valid = valid[1:]
+	varBuf := make([]byte, maxPackedBigintBytes)
+	huge := func(prefix []byte, count uint) []byte {
+		l := packVaruint(count, varBuf)
+		return append(append(append([]byte(nil), prefix...), varBuf[:l]...), make([]byte, 40)...)
+	}
+	txidLen := d.chainParser.PackedTxidLen()
+	// offset of the number of address indexes in the valid data: count, txid, time
+	naOffset := 1 + txidLen + 4
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{name: "huge number of entries", buf: huge(nil, 1<<40)},
+		{name: "huge number of address indexes", buf: huge(valid[:naOffset], 1<<40)},
+		{name: "truncated", buf: valid[:len(valid)-1]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := d.unpackMempoolEntries(tt.buf); err == nil {
+				t.Errorf("unpackMempoolEntries() = %+v, want error", got)
+			}
+		})
+	}
+}
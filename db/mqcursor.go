@@ -0,0 +1,46 @@
+package db
+
+import (
+	"encoding/json"
+)
+
+const mqCursorKey = "mqCursor"
+
+// MQCursorBlock is a block published to the message queue
+type MQCursorBlock struct {
+	Height uint32 `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// MQCursor is the position of the message queue publisher, the last published blocks are ordered by height,
+// the older blocks are kept to detect the disconnected blocks after restart
+type MQCursor struct {
+	Blocks []MQCursorBlock `json:"blocks"`
+}
+
+// GetMQCursor returns the stored message queue cursor or nil if nothing was published yet
+func (d *RocksDB) GetMQCursor() (*MQCursor, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfDefault], []byte(mqCursorKey))
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	data := val.Data()
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var c MQCursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// StoreMQCursor stores the message queue cursor
+func (d *RocksDB) StoreMQCursor(c *MQCursor) error {
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return d.db.PutCF(d.wo, d.cfh[cfDefault], []byte(mqCursorKey), buf)
+}
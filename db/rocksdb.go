@@ -11,6 +11,8 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	vlq "github.com/bsm/go-vlq"
@@ -55,6 +57,17 @@ type RocksDB struct {
 	cache        *gorocksdb.Cache
 	maxOpenFiles int
 	cbs          connectBlockStats
+	xpubsStored  int32
+	// xpubsMux serializes the updates of the xpub states by the sync and by the api
+	xpubsMux sync.Mutex
+	// registeredXpubs caches the descriptors of the watchlist items and the xpubs of the webhooks, nil if not loaded
+	registeredXpubs map[string]struct{}
+	// maxXpubStates is the maximum number of persisted xpub states
+	maxXpubStates int
+	// scripthashIndex is 1 if the scripthash index is maintained
+	scripthashIndex int32
+	// blockFilters is 1 if the block filters are built
+	blockFilters int32
 }
 
 const (
@@ -63,18 +76,27 @@ const (
 	cfAddresses
 	cfBlockTxs
 	cfTransactions
+	cfWatchlists
+	cfWebhooks
+	cfWebhookOutbox
+	cfFiatRates
 	// BitcoinType
 	cfAddressBalance
 	cfTxAddresses
+	cfXpubs
+	cfXpubAddresses
+	cfScripthashes
+	cfHeaders
+	cfBlockFilters
 	// EthereumType
 	cfAddressContracts = cfAddressBalance
 )
 
 // common columns
-var cfNames = []string{"default", "height", "addresses", "blockTxs", "transactions"}
+var cfNames = []string{"default", "height", "addresses", "blockTxs", "transactions", "watchlists", "webhooks", "webhookOutbox", "fiatRates"}
 
 // type specific columns
-var cfNamesBitcoinType = []string{"addressBalance", "txAddresses"}
+var cfNamesBitcoinType = []string{"addressBalance", "txAddresses", "xpubs", "xpubAddresses", "scripthashes", "headers", "blockFilters"}
 var cfNamesEthereumType = []string{"addressContracts"}
 
 func openDB(path string, c *gorocksdb.Cache, openFiles int) (*gorocksdb.DB, []*gorocksdb.ColumnFamilyHandle, error) {
@@ -118,7 +140,7 @@ func NewRocksDB(path string, cacheSize, maxOpenFiles int, parser bchain.BlockCha
 	}
 	wo := gorocksdb.NewDefaultWriteOptions()
 	ro := gorocksdb.NewDefaultReadOptions()
-	return &RocksDB{path, db, wo, ro, cfh, parser, nil, metrics, c, maxOpenFiles, connectBlockStats{}}, nil
+	return &RocksDB{path, db, wo, ro, cfh, parser, nil, metrics, c, maxOpenFiles, connectBlockStats{}, -1, sync.Mutex{}, nil, defaultMaxXpubStates, 0, 0}, nil
 }
 
 func (d *RocksDB) closeDB() error {
@@ -288,6 +310,9 @@ const (
 func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 	wb := gorocksdb.NewWriteBatch()
 	defer wb.Destroy()
+	// the xpub states read by updateXpubs must not change until the batch is written
+	d.xpubsMux.Lock()
+	defer d.xpubsMux.Unlock()
 
 	if glog.V(2) {
 		glog.Infof("rocksdb: insert %d %s", block.Height, block.Hash)
@@ -314,6 +339,21 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 		if err := d.storeAndCleanupBlockTxs(wb, block); err != nil {
 			return err
 		}
+		if err := d.updateXpubs(wb, block, addresses); err != nil {
+			return err
+		}
+		if d.blockFiltersEnabled() {
+			var prevHeader []byte
+			if block.Height > 0 {
+				var err error
+				if prevHeader, err = d.getBlockFilterHeader(block.Height - 1); err != nil {
+					return err
+				}
+			}
+			if _, err := d.storeBlockFilter(wb, block.Height, block.Hash, addresses, longOutputScripts(block), prevHeader); err != nil {
+				return err
+			}
+		}
 	} else if chainType == bchain.ChainEthereumType {
 		addressContracts := make(map[string]*AddrContracts)
 		blockTxs, err := d.processAddressesEthereumType(block, addresses, addressContracts)
@@ -388,6 +428,8 @@ type AddrBalance struct {
 	Txs        uint32
 	SentSat    big.Int
 	BalanceSat big.Int
+	// added is set if the address was not in db before the connect of the block
+	added bool
 }
 
 // ReceivedSat computes received amount from total balance and sent amount
@@ -458,7 +500,7 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 					return err
 				}
 				if ab == nil {
-					ab = &AddrBalance{}
+					ab = &AddrBalance{added: true}
 				}
 				balances[strAddrDesc] = ab
 				d.cbs.balancesMiss++
@@ -533,7 +575,7 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 					return err
 				}
 				if ab == nil {
-					ab = &AddrBalance{}
+					ab = &AddrBalance{added: true}
 				}
 				balances[strAddrDesc] = ab
 				d.cbs.balancesMiss++
@@ -611,6 +653,10 @@ func (d *RocksDB) storeBalances(wb *gorocksdb.WriteBatch, abm map[string]*AddrBa
 			ll = packBigint(&ab.BalanceSat, buf[l:])
 			l += ll
 			wb.PutCF(d.cfh[cfAddressBalance], bchain.AddressDescriptor(addrDesc), buf[:l])
+			// the scripthash of an existing address is already in the index
+			if ab.added && atomic.LoadInt32(&d.scripthashIndex) != 0 {
+				d.storeScripthash(wb, bchain.AddressDescriptor(addrDesc))
+			}
 		}
 	}
 	return nil
@@ -703,6 +749,25 @@ func (d *RocksDB) getBlockTxs(height uint32) ([]blockTxs, error) {
 	return bt, nil
 }
 
+// GetBlockTxids returns the txids of the block at given height from the blockTxs column,
+// the column keeps only the last blocks given by BlockAddressesToKeep, nil is returned for the older blocks
+func (d *RocksDB) GetBlockTxids(height uint32) ([]string, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return nil, errors.New("Unsupported chain type")
+	}
+	bt, err := d.getBlockTxs(height)
+	if err != nil || len(bt) == 0 {
+		return nil, err
+	}
+	txids := make([]string, len(bt))
+	for i := range bt {
+		if txids[i], err = d.chainParser.UnpackTxid(bt[i].btxID); err != nil {
+			return nil, err
+		}
+	}
+	return txids, nil
+}
+
 // GetAddrDescBalance returns AddrBalance for given addrDesc
 func (d *RocksDB) GetAddrDescBalance(addrDesc bchain.AddressDescriptor) (*AddrBalance, error) {
 	val, err := d.db.GetCF(d.ro, d.cfh[cfAddressBalance], addrDesc)
@@ -757,23 +822,23 @@ func (d *RocksDB) GetTxAddresses(txid string) (*TxAddresses, error) {
 	return d.getTxAddresses(btxID)
 }
 
-// AddrDescForOutpoint defines function that returns address descriptorfor given outpoint or nil if outpoint not found
-func (d *RocksDB) AddrDescForOutpoint(outpoint bchain.Outpoint) bchain.AddressDescriptor {
+// AddrDescForOutpoint defines function that returns address descriptor and value for given outpoint or nil if outpoint not found
+func (d *RocksDB) AddrDescForOutpoint(outpoint bchain.Outpoint) (bchain.AddressDescriptor, *big.Int) {
 	ta, err := d.GetTxAddresses(outpoint.Txid)
 	if err != nil || ta == nil {
-		return nil
+		return nil, nil
 	}
 	if outpoint.Vout < 0 {
 		vin := ^outpoint.Vout
 		if len(ta.Inputs) <= int(vin) {
-			return nil
+			return nil, nil
 		}
-		return ta.Inputs[vin].AddrDesc
+		return ta.Inputs[vin].AddrDesc, &ta.Inputs[vin].ValueSat
 	}
 	if len(ta.Outputs) <= int(outpoint.Vout) {
-		return nil
+		return nil, nil
 	}
-	return ta.Outputs[outpoint.Vout].AddrDesc
+	return ta.Outputs[outpoint.Vout].AddrDesc, &ta.Outputs[outpoint.Vout].ValueSat
 }
 
 func packTxAddresses(ta *TxAddresses, buf []byte, varBuf []byte) []byte {
@@ -1136,6 +1201,8 @@ func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) e
 	}
 	wb := gorocksdb.NewWriteBatch()
 	defer wb.Destroy()
+	d.xpubsMux.Lock()
+	defer d.xpubsMux.Unlock()
 	txAddressesToUpdate := make(map[string]*TxAddresses)
 	txsToDelete := make(map[string]struct{})
 	balances := make(map[string]*AddrBalance)
@@ -1165,9 +1232,14 @@ func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) e
 		key := packUint(height)
 		wb.DeleteCF(d.cfh[cfBlockTxs], key)
 		wb.DeleteCF(d.cfh[cfHeight], key)
+		wb.DeleteCF(d.cfh[cfHeaders], key)
+		wb.DeleteCF(d.cfh[cfBlockFilters], key)
 	}
 	d.storeTxAddresses(wb, txAddressesToUpdate)
 	d.storeBalances(wb, balances)
+	if err := d.rollbackXpubs(wb, lower); err != nil {
+		return err
+	}
 	for s := range txsToDelete {
 		b := []byte(s)
 		wb.DeleteCF(d.cfh[cfTransactions], b)
@@ -1318,6 +1390,7 @@ func (d *RocksDB) LoadInternalState(rpcCoin string) (*common.InternalState, erro
 	is.IsMempoolSynchronized = false
 	var t time.Time
 	is.LastMempoolSync = t
+	is.SyncETA = t
 	is.SyncMode = false
 	return is, nil
 }
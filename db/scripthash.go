@@ -0,0 +1,136 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"crypto/sha256"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// scripthashIndexKey marks in the default column that the scripthash index contains all addresses
+const scripthashIndexKey = "scripthashIndex"
+
+// number of addresses written to the scripthash index in one batch by BuildScripthashIndex
+const scripthashIndexBatch = 100000
+
+// Scripthash returns the sha256 hash of the address descriptor (output script), used as a key of the scripthash index
+func Scripthash(addrDesc bchain.AddressDescriptor) []byte {
+	h := sha256.Sum256(addrDesc)
+	return h[:]
+}
+
+func (d *RocksDB) storeScripthash(wb *gorocksdb.WriteBatch, addrDesc bchain.AddressDescriptor) {
+	wb.PutCF(d.cfh[cfScripthashes], Scripthash(addrDesc), addrDesc)
+}
+
+// SetScripthashIndex switches maintenance of the scripthash index of addresses during the connect of blocks,
+// if the index is switched off, it is marked as incomplete and must be rebuilt by BuildScripthashIndex
+func (d *RocksDB) SetScripthashIndex(enabled bool) error {
+	if enabled {
+		if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+			return errors.New("Unsupported chain type")
+		}
+		atomic.StoreInt32(&d.scripthashIndex, 1)
+		return nil
+	}
+	atomic.StoreInt32(&d.scripthashIndex, 0)
+	return d.db.DeleteCF(d.wo, d.cfh[cfDefault], []byte(scripthashIndexKey))
+}
+
+// IsScripthashIndexComplete returns true if the scripthash index contains all addresses in the db
+func (d *RocksDB) IsScripthashIndexComplete() (bool, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfDefault], []byte(scripthashIndexKey))
+	if err != nil {
+		return false, err
+	}
+	defer val.Free()
+	return len(val.Data()) > 0, nil
+}
+
+// BuildScripthashIndex adds all addresses from the addressBalance column to the scripthash index,
+// the maintenance of the index must be switched on by SetScripthashIndex before the build
+// can be slow operation, it is skipped if the index was already completed
+func (d *RocksDB) BuildScripthashIndex(stop chan os.Signal) error {
+	if atomic.LoadInt32(&d.scripthashIndex) == 0 {
+		return errors.New("Scripthash index not enabled")
+	}
+	complete, err := d.IsScripthashIndexComplete()
+	if err != nil || complete {
+		return err
+	}
+	start := time.Now()
+	glog.Info("db: BuildScripthashIndex start")
+	var rows int64
+	var seekKey []byte
+	ro := gorocksdb.NewDefaultReadOptions()
+	defer ro.Destroy()
+	ro.SetFillCache(false)
+	for {
+		var key []byte
+		it := d.db.NewIteratorCF(ro, d.cfh[cfAddressBalance])
+		if rows == 0 {
+			it.SeekToFirst()
+		} else {
+			glog.Info("db: BuildScripthashIndex ", rows, " addresses, in progress...")
+			it.Seek(seekKey)
+			it.Next()
+		}
+		wb := gorocksdb.NewWriteBatch()
+		for count := 0; it.Valid() && count < refreshIterator; it.Next() {
+			select {
+			case <-stop:
+				wb.Destroy()
+				it.Close()
+				return errors.New("Interrupted")
+			default:
+			}
+			key = it.Key().Data()
+			d.storeScripthash(wb, key)
+			count++
+			rows++
+			if count%scripthashIndexBatch == 0 {
+				if err = d.db.Write(d.wo, wb); err != nil {
+					wb.Destroy()
+					it.Close()
+					return err
+				}
+				wb.Clear()
+			}
+		}
+		err = d.db.Write(d.wo, wb)
+		wb.Destroy()
+		seekKey = append([]byte{}, key...)
+		valid := it.Valid()
+		it.Close()
+		if err != nil {
+			return err
+		}
+		if !valid {
+			break
+		}
+	}
+	if err = d.db.PutCF(d.wo, d.cfh[cfDefault], []byte(scripthashIndexKey), []byte{1}); err != nil {
+		return err
+	}
+	glog.Info("db: BuildScripthashIndex finished, ", rows, " addresses, in ", time.Since(start))
+	return nil
+}
+
+// GetScripthashAddrDesc returns the address descriptor with given scripthash or nil if it is not in the index
+func (d *RocksDB) GetScripthashAddrDesc(scripthash []byte) (bchain.AddressDescriptor, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfScripthashes], scripthash)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	return append(bchain.AddressDescriptor(nil), buf...), nil
+}
@@ -0,0 +1,73 @@
+//go:build unittest
+// +build unittest
+
+package db
+
+import (
+	"blockbook/bchain/coins/btc"
+	"blockbook/tests/dbtestdata"
+	"bytes"
+	"testing"
+)
+
+func TestRocksDB_ScripthashIndex(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	checkAddrDesc := func(addr string, found bool) {
+		ad := addressToAddrDesc(addr, d.chainParser)
+		got, err := d.GetScripthashAddrDesc(Scripthash(ad))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found && !bytes.Equal(got, ad) {
+			t.Errorf("GetScripthashAddrDesc(%v) = %x, want %x", addr, got, ad)
+		} else if !found && got != nil {
+			t.Errorf("GetScripthashAddrDesc(%v) = %x, want nil", addr, got)
+		}
+	}
+	checkComplete := func(want bool) {
+		got, err := d.IsScripthashIndexComplete()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("IsScripthashIndexComplete() = %v, want %v", got, want)
+		}
+	}
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	checkAddrDesc(dbtestdata.Addr1, false)
+	if err := d.BuildScripthashIndex(nil); err == nil {
+		t.Error("BuildScripthashIndex() without SetScripthashIndex, expected error")
+	}
+
+	// the addresses of the already connected block are added by the build
+	if err := d.SetScripthashIndex(true); err != nil {
+		t.Fatal(err)
+	}
+	checkComplete(false)
+	if err := d.BuildScripthashIndex(nil); err != nil {
+		t.Fatal(err)
+	}
+	checkComplete(true)
+	checkAddrDesc(dbtestdata.Addr1, true)
+	checkAddrDesc(dbtestdata.Addr5, true)
+	checkAddrDesc(dbtestdata.Addr9, false)
+
+	// the addresses of the newly connected block are added during the connect
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	checkAddrDesc(dbtestdata.Addr9, true)
+
+	// switched off index is not complete anymore
+	if err := d.SetScripthashIndex(false); err != nil {
+		t.Fatal(err)
+	}
+	checkComplete(false)
+}
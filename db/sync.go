@@ -3,6 +3,8 @@ package db
 import (
 	"blockbook/bchain"
 	"blockbook/common"
+	"bytes"
+	"encoding/hex"
 	"os"
 	"sync"
 	"sync/atomic"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/golang/glog"
 	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
 )
 
 // SyncWorker is handle to SyncWorker
@@ -23,6 +26,13 @@ type SyncWorker struct {
 	chanOsSignal           chan os.Signal
 	metrics                *common.Metrics
 	is                     *common.InternalState
+	// headersSync is 1 while the background sync of headers runs
+	headersSync int32
+	headersStop chan struct{}
+	headersDone sync.WaitGroup
+	closeOnce   sync.Once
+	// filtersSynced is set when all block filter headers are known, accessed only by the background sync
+	filtersSynced bool
 }
 
 // NewSyncWorker creates new SyncWorker and returns its handle
@@ -40,6 +50,7 @@ func NewSyncWorker(db *RocksDB, chain bchain.BlockChain, syncWorkers, syncChunk
 		chanOsSignal: chanOsSignal,
 		metrics:      metrics,
 		is:           is,
+		headersStop:  make(chan struct{}),
 	}, nil
 }
 
@@ -59,9 +70,11 @@ func (w *SyncWorker) ResyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 		glog.Info("resync: finished in ", d)
 		w.metrics.IndexResyncDuration.Observe(float64(d) / 1e6) // in milliseconds
 		w.metrics.IndexDBSize.Set(float64(w.db.DatabaseSizeOnDisk()))
+		w.metrics.IndexSyncETA.Set(0)
 		bh, _, err := w.db.GetBestBlock()
 		if err == nil {
 			w.is.FinishedSync(bh)
+			w.syncHeaders()
 		}
 		return err
 	case errSynced:
@@ -72,6 +85,7 @@ func (w *SyncWorker) ResyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 			d := time.Since(start)
 			glog.Info("resync: finished in ", d)
 		}
+		w.syncHeaders()
 		return nil
 	}
 
@@ -92,8 +106,14 @@ func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 	// If the locally indexed block is the same as the best block on the network, we're done.
 	if localBestHash == remoteBestHash {
 		glog.Infof("resync: synced at %d %s", localBestHeight, localBestHash)
+		w.updateBackendBestHeight(localBestHeight)
 		return errSynced
 	}
+	remoteBestHeight, err := w.chain.GetBestBlockHeight()
+	if err != nil {
+		return err
+	}
+	w.updateBackendBestHeight(remoteBestHeight)
 	if localBestHash != "" {
 		remoteHash, err := w.chain.GetBlockHash(localBestHeight)
 		// for some coins (eth) remote can be at lower best height after rollback
@@ -119,10 +139,6 @@ func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 	// use parallel routine to load majority of blocks
 	// use parallel sync only in case of initial sync because it puts the db to inconsistent state
 	if w.syncWorkers > 1 && initialSync {
-		remoteBestHeight, err := w.chain.GetBestBlockHeight()
-		if err != nil {
-			return err
-		}
 		if remoteBestHeight < w.startHeight {
 			glog.Error("resync: error - remote best height ", remoteBestHeight, " less than sync start height ", w.startHeight)
 			return errors.New("resync: remote best height error")
@@ -187,6 +203,9 @@ func (w *SyncWorker) connectBlocks(onNewBlock bchain.OnNewBlockFunc, initialSync
 		if err != nil {
 			return err
 		}
+		w.updateSyncProgress(res.block)
+		// store the header before the notification, the subscribers of headers read it from the db
+		w.storeBlockHeader(res.block)
 		if onNewBlock != nil {
 			onNewBlock(res.block.Hash, res.block.Height)
 		}
@@ -230,6 +249,405 @@ func (w *SyncWorker) connectBlocks(onNewBlock bchain.OnNewBlockFunc, initialSync
 	return nil
 }
 
+// number of block headers stored in one batch by SyncHeaders
+const syncHeadersBatch = 1000
+
+// syncHeaders starts SyncHeaders and SyncBlockFilters in background after the sync of the index unless they are already running,
+// the sync of the index does not wait for them, the error is only logged and the missing headers are synced in the next run
+func (w *SyncWorker) syncHeaders() {
+	if w.chain.GetChainParser().GetChainType() != bchain.ChainBitcoinType {
+		return
+	}
+	select {
+	case <-w.headersStop:
+		return
+	default:
+	}
+	if !atomic.CompareAndSwapInt32(&w.headersSync, 0, 1) {
+		return
+	}
+	w.headersDone.Add(1)
+	go func() {
+		defer func() {
+			atomic.StoreInt32(&w.headersSync, 0)
+			w.headersDone.Done()
+		}()
+		if err := w.SyncHeaders(w.headersStop); err != nil {
+			glog.Error("resync: SyncHeaders ", err)
+		}
+		// once the filter headers are complete, they are kept complete by the connect of blocks
+		if w.db.blockFiltersEnabled() && !w.filtersSynced {
+			if err := w.SyncBlockFilters(w.headersStop); err != nil {
+				glog.Error("resync: SyncBlockFilters ", err)
+			} else {
+				w.filtersSynced = true
+			}
+		}
+	}()
+}
+
+// Close stops the background sync of headers and waits until it finishes
+func (w *SyncWorker) Close() {
+	w.closeOnce.Do(func() { close(w.headersStop) })
+	w.headersDone.Wait()
+}
+
+// SyncHeaders stores the raw block headers missing in the db up to the best indexed block,
+// it is a header-only sync, the blocks are not fetched from the backend
+// the headers of a batch are requested in parallel by syncWorkers goroutines, it stops when stop is closed;
+// a batch changed by a chain reorganization during the fetch is fetched again
+func (w *SyncWorker) SyncHeaders(stop chan struct{}) error {
+	if w.chain.GetChainParser().GetChainType() != bchain.ChainBitcoinType {
+		return nil
+	}
+	start := time.Now()
+	var stored uint32
+	for {
+		select {
+		case <-stop:
+			return errors.New("SyncHeaders interrupted")
+		default:
+		}
+		// the best block and the last header are read again for each batch, the index is synced concurrently
+		bestHeight, bestHash, err := w.db.GetBestBlock()
+		if err != nil || bestHash == "" {
+			return err
+		}
+		last, ok, err := w.db.GetLastBlockHeaderHeight()
+		if err != nil {
+			return err
+		}
+		var from uint32
+		if ok {
+			from = last + 1
+		}
+		if from > bestHeight {
+			break
+		}
+		if stored == 0 {
+			glog.Info("sync headers: blocks ", from, "-", bestHeight)
+		}
+		to := bestHeight
+		if to-from >= syncHeadersBatch {
+			to = from + syncHeadersBatch - 1
+		}
+		headers, hashes, err := w.getBlockHeaders(from, to)
+		if err != nil {
+			return err
+		}
+		// the blocks could have been disconnected in the meantime, the headers of the orphaned blocks must not be stored
+		hash, err := w.db.GetBlockHash(to)
+		if err != nil {
+			return err
+		}
+		if (hash != "" && hash != hashes[len(hashes)-1]) || !blockHeadersLinked(headers, hashes) {
+			glog.Warning("sync headers: blocks ", from, "-", to, " changed during the sync, fetching them again")
+			continue
+		}
+		if ok {
+			linked, err := w.blockHeaderLinksStored(from, headers[0])
+			if err != nil {
+				return err
+			}
+			if !linked {
+				// the stored header of the previous block is of an orphaned block, it is fetched again
+				glog.Warning("sync headers: header ", last, " is not the parent of block ", from, ", fetching it again")
+				if err = w.db.db.DeleteCF(w.db.wo, w.db.cfh[cfHeaders], packUint(last)); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err = w.db.StoreBlockHeaders(from, headers); err != nil {
+			return err
+		}
+		stored += uint32(len(headers))
+		if stored%(100*syncHeadersBatch) == 0 {
+			glog.Info("sync headers: stored up to ", to)
+		}
+	}
+	if stored > 0 {
+		glog.Info("sync headers: finished, ", stored, " headers in ", time.Since(start))
+	}
+	return nil
+}
+
+// number of block filters stored in one batch by SyncBlockFilters
+const syncBlockFiltersBatch = 100
+
+// SyncBlockFilters builds the block filters missing in the db and computes the unknown filter headers,
+// so that the chain of filter headers is complete also in the db which did not build filters from the genesis block,
+// the missing filters are built from the blocks requested in parallel by syncWorkers goroutines, it stops when stop is closed
+func (w *SyncWorker) SyncBlockFilters(stop chan struct{}) error {
+	if w.chain.GetChainParser().GetChainType() != bchain.ChainBitcoinType {
+		return nil
+	}
+	from, err := w.db.firstUnknownBlockFilterHeader()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	var stored uint32
+	for {
+		select {
+		case <-stop:
+			return errors.New("SyncBlockFilters interrupted")
+		default:
+		}
+		bestHeight, bestHash, err := w.db.GetBestBlock()
+		if err != nil || bestHash == "" {
+			return err
+		}
+		if from > bestHeight {
+			break
+		}
+		if stored == 0 {
+			glog.Info("sync block filters: blocks ", from, "-", bestHeight)
+		}
+		to := bestHeight
+		if to-from >= syncBlockFiltersBatch {
+			to = from + syncBlockFiltersBatch - 1
+		}
+		if err = w.syncBlockFilters(from, to); err != nil {
+			return err
+		}
+		stored += to - from + 1
+		if stored%(100*syncBlockFiltersBatch) == 0 {
+			glog.Info("sync block filters: stored up to ", to)
+		}
+		from = to + 1
+	}
+	if stored > 0 {
+		glog.Info("sync block filters: finished, ", stored, " filters in ", time.Since(start))
+	}
+	return nil
+}
+
+// syncBlockFilters stores the filters of the blocks from..to with their headers, the filter header of the block from-1 must be known
+func (w *SyncWorker) syncBlockFilters(from, to uint32) error {
+	prevHeader := unknownBlockFilterHeader
+	if from > 0 {
+		var err error
+		if prevHeader, err = w.db.getBlockFilterHeader(from - 1); err != nil {
+			return err
+		}
+		if prevHeader == nil {
+			return errors.Errorf("Unknown filter header of block %d", from-1)
+		}
+	}
+	hashes := make([]string, to-from+1)
+	filters := make([][]byte, to-from+1)
+	var missing []int
+	for i := range hashes {
+		height := from + uint32(i)
+		var err error
+		if hashes[i], err = w.db.GetBlockHash(height); err != nil {
+			return err
+		}
+		if hashes[i] == "" {
+			return errors.Errorf("Block %d not indexed", height)
+		}
+		bf, err := w.db.GetBlockFilter(height)
+		if err != nil {
+			return err
+		}
+		if bf != nil {
+			filters[i] = bf.Filter
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if err := w.buildBlockFilters(from, hashes, filters, missing); err != nil {
+		return err
+	}
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	for i := range filters {
+		prevHeader = bchain.GetBlockFilterHeader(filters[i], prevHeader)
+		w.db.putBlockFilter(wb, from+uint32(i), filters[i], prevHeader)
+	}
+	// the blocks could have been disconnected in the meantime, their filters must not be stored
+	if hash, err := w.db.GetBlockHash(to); err != nil || hash != hashes[len(hashes)-1] {
+		if err == nil {
+			err = errors.Errorf("Block %d changed during SyncBlockFilters", to)
+		}
+		return err
+	}
+	return w.db.db.Write(w.db.wo, wb)
+}
+
+// buildBlockFilters builds the filters of the blocks with given indexes, fetching the blocks by syncWorkers goroutines
+func (w *SyncWorker) buildBlockFilters(from uint32, hashes []string, filters [][]byte, missing []int) error {
+	workers := w.syncWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(missing) {
+		workers = len(missing)
+	}
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for k := 0; k < workers; k++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			for m := k; m < len(missing); m += workers {
+				i := missing[m]
+				block, err := w.chain.GetBlock(hashes[i], from+uint32(i))
+				if err != nil {
+					errs[k] = errors.Annotatef(err, "GetBlock %v", hashes[i])
+					return
+				}
+				scripts, err := w.db.getBlockFilterScripts(block)
+				if err == nil {
+					filters[i], err = buildBlockFilterFromScripts(hashes[i], scripts)
+				}
+				if err != nil {
+					errs[k] = err
+					return
+				}
+			}
+		}(k)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getBlockHeaders returns the raw headers of the blocks from..to from the backend together with the block hashes
+// used to request them, using syncWorkers goroutines
+func (w *SyncWorker) getBlockHeaders(from, to uint32) ([][]byte, []string, error) {
+	headers := make([][]byte, to-from+1)
+	hashes := make([]string, len(headers))
+	workers := w.syncWorkers
+	if workers > len(headers) {
+		workers = len(headers)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for k := 0; k < workers; k++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			for i := k; i < len(headers); i += workers {
+				hash, err := w.getBlockHash(from + uint32(i))
+				if err != nil {
+					errs[k] = err
+					return
+				}
+				h, err := w.getBlockHeader(from+uint32(i), hash)
+				if err != nil {
+					errs[k] = err
+					return
+				}
+				headers[i] = h
+				hashes[i] = hash
+			}
+		}(k)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return headers, hashes, nil
+}
+
+// getBlockHash returns the hash of the block from the db or from the backend for the blocks below the start of the index
+func (w *SyncWorker) getBlockHash(height uint32) (string, error) {
+	hash, err := w.db.GetBlockHash(height)
+	if err != nil {
+		return "", err
+	}
+	if hash == "" {
+		if hash, err = w.chain.GetBlockHash(height); err != nil {
+			return "", errors.Annotatef(err, "GetBlockHash %v", height)
+		}
+	}
+	return hash, nil
+}
+
+// blockHeaderPrevHash returns the hash of the previous block stored in the raw header,
+// ok is false if the header is too short to contain it
+func blockHeaderPrevHash(header []byte) (hash string, ok bool) {
+	// the header starts with 4 bytes of version followed by the hash of the previous block in reversed byte order
+	if len(header) < 36 {
+		return "", false
+	}
+	b := make([]byte, 32)
+	for i := range b {
+		b[i] = header[35-i]
+	}
+	return hex.EncodeToString(b), true
+}
+
+// blockHeadersLinked checks that each header refers to the block of the preceding header
+func blockHeadersLinked(headers [][]byte, hashes []string) bool {
+	for i := 1; i < len(headers); i++ {
+		if prev, ok := blockHeaderPrevHash(headers[i]); ok && prev != hashes[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+// blockHeaderLinksStored checks that the stored header of the block height-1 is the header of the parent of the given header
+func (w *SyncWorker) blockHeaderLinksStored(height uint32, header []byte) (bool, error) {
+	prev, ok := blockHeaderPrevHash(header)
+	if !ok || height == 0 {
+		return true, nil
+	}
+	stored, err := w.db.GetBlockHeaderRaw(height - 1)
+	if err != nil || stored == nil {
+		return true, err
+	}
+	parent, err := w.getBlockHeader(height-1, prev)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(parent, stored), nil
+}
+
+// getBlockHeader returns the raw header of the block with given hash from the backend
+func (w *SyncWorker) getBlockHeader(height uint32, hash string) ([]byte, error) {
+	h, err := w.chain.GetBlockHeaderRaw(hash)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlockHeaderRaw %v", hash)
+	}
+	return hex.DecodeString(h)
+}
+
+// storeBlockHeader stores the header of a newly connected block if the stored headers continue up to the previous block,
+// the gaps (for example after the parallel sync) are filled by SyncHeaders
+func (w *SyncWorker) storeBlockHeader(block *bchain.Block) {
+	if w.chain.GetChainParser().GetChainType() != bchain.ChainBitcoinType {
+		return
+	}
+	last, ok, err := w.db.GetLastBlockHeaderHeight()
+	if err != nil {
+		glog.Error("storeBlockHeader ", block.Height, ": ", err)
+		return
+	}
+	if (ok && last+1 != block.Height) || (!ok && block.Height != 0) {
+		return
+	}
+	header, err := w.getBlockHeader(block.Height, block.Hash)
+	if err == nil {
+		err = w.db.StoreBlockHeaders(block.Height, [][]byte{header})
+	}
+	if err != nil {
+		glog.Error("storeBlockHeader ", block.Height, ": ", err)
+	}
+}
+
 // ConnectBlocksParallel uses parallel goroutines to get data from blockchain daemon
 func (w *SyncWorker) ConnectBlocksParallel(lower, higher uint32) error {
 	type hashHeight struct {
@@ -270,6 +688,7 @@ func (w *SyncWorker) ConnectBlocksParallel(lower, higher uint32) error {
 				if err != nil {
 					glog.Fatal("writeBlockWorker ", b.Height, " ", b.Hash, " error ", err)
 				}
+				w.updateSyncProgress(b)
 				lastBlock = b.Height
 			case <-terminating:
 				break WriteBlockLoop
@@ -362,6 +781,25 @@ ConnectLoop:
 	return err
 }
 
+func (w *SyncWorker) updateBackendBestHeight(height uint32) {
+	w.is.UpdateBackendBestHeight(height)
+	w.metrics.BackendBestHeight.Set(float64(height))
+}
+
+// updateSyncProgress records the connected block and, when the sync speed is recomputed, exports it to metrics
+func (w *SyncWorker) updateSyncProgress(block *bchain.Block) {
+	if w.is.UpdateSyncProgress(block.Height, len(block.Txs)) {
+		_, bps, tps, eta := w.is.GetSyncProgress()
+		w.metrics.IndexSyncBlocksSpeed.Set(bps)
+		w.metrics.IndexSyncTxsSpeed.Set(tps)
+		var etaSeconds float64
+		if !eta.IsZero() {
+			etaSeconds = time.Until(eta).Seconds()
+		}
+		w.metrics.IndexSyncETA.Set(etaSeconds)
+	}
+}
+
 type blockResult struct {
 	block *bchain.Block
 	err   error
@@ -0,0 +1,279 @@
+//go:build unittest
+// +build unittest
+
+package db
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"blockbook/tests/dbtestdata"
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestSyncWorker_SyncHeaders(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	chain, err := dbtestdata.NewFakeBlockChain(d.chainParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewSyncWorker(d, chain, 8, 0, 0, false, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	// the fake chain has only the two test blocks, the headers below them are already stored
+	if err := d.StoreBlockHeaders(225492, [][]byte{{0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the interrupted sync stores nothing
+	stop := make(chan struct{})
+	close(stop)
+	if err := w.SyncHeaders(stop); err == nil {
+		t.Error("SyncHeaders() with closed stop, expected error")
+	}
+	if last, _, err := d.GetLastBlockHeaderHeight(); err != nil || last != 225492 {
+		t.Errorf("GetLastBlockHeaderHeight() after interrupted sync = %v, %v, want 225492", last, err)
+	}
+
+	// the background sync stores the missing headers, Close waits for it
+	w.syncHeaders()
+	w.Close()
+	got, err := d.GetBlockHeadersRaw(225492, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]byte{{0}, {1, 0, 0, 0, 1}, {1, 0, 0, 0, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetBlockHeadersRaw() = %x, want %x", got, want)
+	}
+
+	// a closed worker does not start the sync again
+	w.syncHeaders()
+	if w.headersSync != 0 {
+		t.Error("syncHeaders() started after Close")
+	}
+}
+
+func TestSyncWorker_getBlockHeaders(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	chain, err := dbtestdata.NewFakeBlockChain(d.chainParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{0, 1, 8} {
+		w, err := NewSyncWorker(d, chain, workers, 0, 0, false, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, hashes, err := w.getBlockHeaders(225493, 225494)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := [][]byte{{1, 0, 0, 0, 1}, {1, 0, 0, 0, 2}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("getBlockHeaders() with %v workers = %x, want %x", workers, got, want)
+		}
+		if want := []string{dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser).Hash, dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser).Hash}; !reflect.DeepEqual(hashes, want) {
+			t.Errorf("getBlockHeaders() with %v workers hashes = %v, want %v", workers, hashes, want)
+		}
+		// the error of any block is returned
+		if _, _, err = w.getBlockHeaders(225493, 225495); err == nil {
+			t.Errorf("getBlockHeaders() with %v workers of unknown block, expected error", workers)
+		}
+	}
+}
+
+// reorgHeadersChain serves the raw headers and the hashes of the blocks below the index, hook is called on each request of a header
+type reorgHeadersChain struct {
+	bchain.BlockChain
+	headers map[string][]byte
+	hashes  map[uint32]string
+	hook    func(hash string)
+}
+
+func (c *reorgHeadersChain) GetBlockHash(height uint32) (string, error) {
+	if hash, found := c.hashes[height]; found {
+		return hash, nil
+	}
+	return c.BlockChain.GetBlockHash(height)
+}
+
+func (c *reorgHeadersChain) GetBlockHeaderRaw(hash string) (string, error) {
+	if c.hook != nil {
+		c.hook(hash)
+	}
+	h, found := c.headers[hash]
+	if !found {
+		return "", bchain.ErrBlockNotFound
+	}
+	return hex.EncodeToString(h), nil
+}
+
+// testBlockHeader returns a raw header referring to the previous block
+func testBlockHeader(prevHash string, nonce byte) []byte {
+	h := make([]byte, 80)
+	h[0] = 1
+	prev, _ := hex.DecodeString(prevHash)
+	for i := range prev {
+		h[35-i] = prev[i]
+	}
+	h[79] = nonce
+	return h
+}
+
+func TestSyncWorker_SyncHeaders_reorg(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	fake, err := dbtestdata.NewFakeBlockChain(d.chainParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block1 := dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)
+	block2 := dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)
+	fork := dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)
+	fork.Hash = "00000000000000000000000000000000000000000000000000000000000000f2"
+	grandparentHash := "00000000000000000000000000000000000000000000000000000000000000ef"
+	grandparent := testBlockHeader("00000000000000000000000000000000000000000000000000000000000000ee", 0)
+	parentHash := "00000000000000000000000000000000000000000000000000000000000000f0"
+	parent := testBlockHeader(grandparentHash, 0)
+	chain := &reorgHeadersChain{
+		BlockChain: fake,
+		headers: map[string][]byte{
+			grandparentHash: grandparent,
+			parentHash:      parent,
+			block1.Hash:     testBlockHeader(parentHash, 1),
+			block2.Hash:     testBlockHeader(block1.Hash, 2),
+			fork.Hash:       testBlockHeader(block1.Hash, 3),
+		},
+		hashes: map[uint32]string{225491: grandparentHash, 225492: parentHash},
+	}
+	w, err := NewSyncWorker(d, chain, 1, 0, 0, false, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := d.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	// the header of the block 225492 below the index was stored from an orphaned chain
+	if err := d.StoreBlockHeaders(225491, [][]byte{grandparent, testBlockHeader(grandparentHash, 9)}); err != nil {
+		t.Fatal(err)
+	}
+	// the block 225494 is replaced while its header is fetched
+	chain.hook = func(hash string) {
+		if hash != block2.Hash {
+			return
+		}
+		chain.hook = nil
+		if err := d.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.ConnectBlock(fork); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.SyncHeaders(make(chan struct{})); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.GetBlockHeadersRaw(225491, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the batch with the orphaned block is fetched again and the orphaned header of the block 225492 is replaced
+	want := [][]byte{grandparent, parent, chain.headers[block1.Hash], chain.headers[fork.Hash]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetBlockHeadersRaw() = %x, want %x", got, want)
+	}
+}
+
+func TestSyncWorker_SyncBlockFilters(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	chain, err := dbtestdata.NewFakeBlockChain(d.chainParser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewSyncWorker(d, chain, 8, 0, 0, false, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// the test blocks are connected as the first two blocks of the chain, the fake chain returns them by hash,
+	// the filters are switched on only after the first block
+	block1 := dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)
+	block1.Height = 0
+	block2 := dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)
+	block2.Height = 1
+	if err := d.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetBlockFilters(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	if from, err := d.firstUnknownBlockFilterHeader(); err != nil || from != 0 {
+		t.Fatalf("firstUnknownBlockFilterHeader() = %v, %v, want 0", from, err)
+	}
+	bf2, err := d.GetBlockFilter(1)
+	if err != nil || bf2 == nil || bf2.Header != nil {
+		t.Fatalf("GetBlockFilter(1) = %+v, %v, want filter with unknown header", bf2, err)
+	}
+
+	if err := w.SyncBlockFilters(make(chan struct{})); err != nil {
+		t.Fatal(err)
+	}
+	scripts := make([][]byte, 0, 5)
+	for _, a := range []string{dbtestdata.Addr1, dbtestdata.Addr2, dbtestdata.Addr3, dbtestdata.Addr4, dbtestdata.Addr5} {
+		scripts = append(scripts, addressToAddrDesc(a, d.chainParser))
+	}
+	f1, err := bchain.BuildBasicBlockFilter(block1.Hash, scripts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h1 := bchain.GetBlockFilterHeader(f1, unknownBlockFilterHeader)
+	h2 := bchain.GetBlockFilterHeader(bf2.Filter, h1)
+	bf1, err := d.GetBlockFilter(0)
+	if err != nil || bf1 == nil {
+		t.Fatalf("GetBlockFilter(0) = %+v, %v", bf1, err)
+	}
+	if !bytes.Equal(bf1.Filter, f1) {
+		t.Errorf("GetBlockFilter(0).Filter = %x, want %x", bf1.Filter, f1)
+	}
+	headers, err := d.GetBlockFilterHeaders(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]byte{h1, h2}; !reflect.DeepEqual(headers, want) {
+		t.Errorf("GetBlockFilterHeaders() = %x, want %x", headers, want)
+	}
+	if from, err := d.firstUnknownBlockFilterHeader(); err != nil || from != 2 {
+		t.Errorf("firstUnknownBlockFilterHeader() after sync = %v, %v, want 2", from, err)
+	}
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"encoding/json"
+
+	"github.com/juju/errors"
+)
+
+// WatchlistItem is an address or xpub in a watchlist with an optional label
+type WatchlistItem struct {
+	Descriptor string `json:"descriptor"`
+	Label      string `json:"label,omitempty"`
+}
+
+// Watchlist is a named set of addresses or xpubs
+type Watchlist struct {
+	Name  string          `json:"name"`
+	Items []WatchlistItem `json:"items"`
+}
+
+// GetWatchlists returns all stored watchlists ordered by name
+func (d *RocksDB) GetWatchlists() ([]Watchlist, error) {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfWatchlists])
+	defer it.Close()
+	r := make([]Watchlist, 0)
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		var wl Watchlist
+		if err := json.Unmarshal(it.Value().Data(), &wl); err != nil {
+			return nil, errors.Annotatef(err, "watchlist %v", string(it.Key().Data()))
+		}
+		r = append(r, wl)
+	}
+	return r, nil
+}
+
+// GetWatchlist returns the watchlist of the given name or nil if it does not exist
+func (d *RocksDB) GetWatchlist(name string) (*Watchlist, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfWatchlists], []byte(name))
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	data := val.Data()
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var wl Watchlist
+	if err = json.Unmarshal(data, &wl); err != nil {
+		return nil, errors.Annotatef(err, "watchlist %v", name)
+	}
+	return &wl, nil
+}
+
+// StoreWatchlist creates or replaces the watchlist
+func (d *RocksDB) StoreWatchlist(wl *Watchlist) error {
+	if wl.Name == "" {
+		return errors.New("Missing watchlist name")
+	}
+	buf, err := json.Marshal(wl)
+	if err != nil {
+		return err
+	}
+	if err = d.db.PutCF(d.wo, d.cfh[cfWatchlists], []byte(wl.Name), buf); err != nil {
+		return err
+	}
+	d.resetRegisteredXpubs()
+	return nil
+}
+
+// DeleteWatchlist removes the watchlist
+func (d *RocksDB) DeleteWatchlist(name string) error {
+	if err := d.db.DeleteCF(d.wo, d.cfh[cfWatchlists], []byte(name)); err != nil {
+		return err
+	}
+	d.resetRegisteredXpubs()
+	return nil
+}
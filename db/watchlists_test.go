@@ -0,0 +1,59 @@
+//go:build unittest
+// +build unittest
+
+package db
+
+import (
+	"blockbook/bchain/coins/btc"
+	"reflect"
+	"testing"
+)
+
+func TestRocksDB_Watchlists(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	wls, err := d.GetWatchlists()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wls) != 0 {
+		t.Fatalf("GetWatchlists() = %+v, want empty", wls)
+	}
+	cold := Watchlist{Name: "cold", Items: []WatchlistItem{{Descriptor: "mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz", Label: "vault"}}}
+	hot := Watchlist{Name: "hot", Items: []WatchlistItem{{Descriptor: "mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"}}}
+	for _, wl := range []*Watchlist{&hot, &cold} {
+		if err := d.StoreWatchlist(wl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.StoreWatchlist(&Watchlist{}); err == nil {
+		t.Error("StoreWatchlist() without name, expected error")
+	}
+	wls, err = d.GetWatchlists()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Watchlist{cold, hot}; !reflect.DeepEqual(wls, want) {
+		t.Errorf("GetWatchlists() = %+v, want %+v", wls, want)
+	}
+	wl, err := d.GetWatchlist("cold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(wl, &cold) {
+		t.Errorf("GetWatchlist(cold) = %+v, want %+v", wl, cold)
+	}
+	if err := d.DeleteWatchlist("cold"); err != nil {
+		t.Fatal(err)
+	}
+	wl, err = d.GetWatchlist("cold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wl != nil {
+		t.Errorf("GetWatchlist(cold) after delete = %+v, want nil", wl)
+	}
+}
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// Webhook is a registered HTTP callback receiving the events about its addresses, xpubs or all blocks
+type Webhook struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	Xpubs     []string `json:"xpubs,omitempty"`
+	Blocks    bool     `json:"blocks,omitempty"`
+}
+
+// WebhookDelivery is an event waiting in the outbox for the delivery to a webhook
+type WebhookDelivery struct {
+	ID          uint64          `json:"-"`
+	Webhook     string          `json:"webhook"`
+	Event       string          `json:"event"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt int64           `json:"nextAttempt"`
+	LastError   string          `json:"lastError,omitempty"`
+}
+
+// GetWebhooks returns all registered webhooks ordered by id
+func (d *RocksDB) GetWebhooks() ([]Webhook, error) {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfWebhooks])
+	defer it.Close()
+	r := make([]Webhook, 0)
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		var wh Webhook
+		if err := json.Unmarshal(it.Value().Data(), &wh); err != nil {
+			return nil, errors.Annotatef(err, "webhook %v", string(it.Key().Data()))
+		}
+		r = append(r, wh)
+	}
+	return r, nil
+}
+
+// GetWebhook returns the webhook of the given id or nil if it does not exist
+func (d *RocksDB) GetWebhook(id string) (*Webhook, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfWebhooks], []byte(id))
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	data := val.Data()
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var wh Webhook
+	if err = json.Unmarshal(data, &wh); err != nil {
+		return nil, errors.Annotatef(err, "webhook %v", id)
+	}
+	return &wh, nil
+}
+
+// StoreWebhook creates or replaces the webhook
+func (d *RocksDB) StoreWebhook(wh *Webhook) error {
+	if wh.ID == "" {
+		return errors.New("Missing webhook id")
+	}
+	buf, err := json.Marshal(wh)
+	if err != nil {
+		return err
+	}
+	if err = d.db.PutCF(d.wo, d.cfh[cfWebhooks], []byte(wh.ID), buf); err != nil {
+		return err
+	}
+	d.resetRegisteredXpubs()
+	return nil
+}
+
+// DeleteWebhook removes the webhook and its pending deliveries
+func (d *RocksDB) DeleteWebhook(id string) error {
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	wb.DeleteCF(d.cfh[cfWebhooks], []byte(id))
+	prefix := packWebhookDeliveryPrefix(id)
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfWebhookOutbox])
+	defer it.Close()
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		if !bytes.HasPrefix(key, prefix) {
+			break
+		}
+		wb.DeleteCF(d.cfh[cfWebhookOutbox], append([]byte(nil), key...))
+	}
+	if err := d.db.Write(d.wo, wb); err != nil {
+		return err
+	}
+	d.resetRegisteredXpubs()
+	return nil
+}
+
+// packWebhookDeliveryPrefix returns the common prefix of the outbox keys of the webhook,
+// the length of the id is part of the prefix so that an id cannot be a prefix of another one
+func packWebhookDeliveryPrefix(webhook string) []byte {
+	varBuf := make([]byte, maxPackedBigintBytes)
+	l := packVaruint(uint(len(webhook)), varBuf)
+	return append(varBuf[:l:l], webhook...)
+}
+
+// packWebhookDeliveryKey returns the outbox key, the deliveries of a webhook are ordered by id
+func packWebhookDeliveryKey(webhook string, id uint64) []byte {
+	key := packWebhookDeliveryPrefix(webhook)
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, id)
+	return append(key, buf...)
+}
+
+func unpackWebhookDeliveryKey(key []byte) (string, uint64, error) {
+	l, p := unpackVaruint(key)
+	if len(key) != p+int(l)+8 {
+		return "", 0, errors.New("Invalid webhook delivery key")
+	}
+	return string(key[p : p+int(l)]), binary.BigEndian.Uint64(key[p+int(l):]), nil
+}
+
+// GetLastWebhookDeliveryID returns the highest id of the deliveries in the outbox or 0 if the outbox is empty
+func (d *RocksDB) GetLastWebhookDeliveryID() (uint64, error) {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfWebhookOutbox])
+	defer it.Close()
+	var last uint64
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		_, id, err := unpackWebhookDeliveryKey(it.Key().Data())
+		if err != nil {
+			return 0, err
+		}
+		if id > last {
+			last = id
+		}
+	}
+	return last, it.Err()
+}
+
+// GetWebhookDeliveries returns at most limit oldest deliveries of the webhook from the outbox, in the order of ids
+func (d *RocksDB) GetWebhookDeliveries(webhook string, limit int) ([]WebhookDelivery, error) {
+	prefix := packWebhookDeliveryPrefix(webhook)
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfWebhookOutbox])
+	defer it.Close()
+	r := make([]WebhookDelivery, 0)
+	for it.Seek(prefix); it.Valid() && len(r) < limit; it.Next() {
+		key := it.Key().Data()
+		if !bytes.HasPrefix(key, prefix) {
+			break
+		}
+		_, id, err := unpackWebhookDeliveryKey(key)
+		if err != nil {
+			return nil, err
+		}
+		var wd WebhookDelivery
+		if err := json.Unmarshal(it.Value().Data(), &wd); err != nil {
+			return nil, errors.Annotatef(err, "webhook delivery %x", key)
+		}
+		wd.ID = id
+		r = append(r, wd)
+	}
+	return r, nil
+}
+
+// CountWebhookDeliveries returns the number of deliveries of the webhook in the outbox
+func (d *RocksDB) CountWebhookDeliveries(webhook string) (int, error) {
+	prefix := packWebhookDeliveryPrefix(webhook)
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfWebhookOutbox])
+	defer it.Close()
+	n := 0
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		if !bytes.HasPrefix(it.Key().Data(), prefix) {
+			break
+		}
+		n++
+	}
+	return n, it.Err()
+}
+
+// StoreWebhookDeliveries stores the deliveries to the outbox, an existing delivery of the same webhook and id is replaced
+func (d *RocksDB) StoreWebhookDeliveries(deliveries []WebhookDelivery) error {
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	for i := range deliveries {
+		buf, err := json.Marshal(&deliveries[i])
+		if err != nil {
+			return err
+		}
+		wb.PutCF(d.cfh[cfWebhookOutbox], packWebhookDeliveryKey(deliveries[i].Webhook, deliveries[i].ID), buf)
+	}
+	return d.db.Write(d.wo, wb)
+}
+
+// DeleteWebhookDelivery removes the delivery from the outbox
+func (d *RocksDB) DeleteWebhookDelivery(webhook string, id uint64) error {
+	return d.db.DeleteCF(d.wo, d.cfh[cfWebhookOutbox], packWebhookDeliveryKey(webhook, id))
+}
@@ -0,0 +1,103 @@
+//go:build unittest
+// +build unittest
+
+package db
+
+import (
+	"blockbook/bchain/coins/btc"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRocksDB_Webhooks(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	wh := Webhook{ID: "wallet", URL: "https://example.com/hook", Secret: "s3cr3t", Addresses: []string{"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"}}
+	if err := d.StoreWebhook(&wh); err != nil {
+		t.Fatal(err)
+	}
+	list, err := d.GetWebhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Webhook{wh}; !reflect.DeepEqual(list, want) {
+		t.Errorf("GetWebhooks() = %+v, want %+v", list, want)
+	}
+	if err := d.DeleteWebhook("wallet"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.GetWebhook("wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("GetWebhook(wallet) after delete = %+v, want nil", got)
+	}
+
+	last, err := d.GetLastWebhookDeliveryID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != 0 {
+		t.Errorf("GetLastWebhookDeliveryID() = %v, want 0", last)
+	}
+	deliveries := []WebhookDelivery{
+		{ID: 1, Webhook: "wallet", Event: "block", Payload: json.RawMessage(`{"height":1}`)},
+		{ID: 2, Webhook: "wallet2", Event: "block", Payload: json.RawMessage(`{"height":1}`)},
+		{ID: 3, Webhook: "wallet", Event: "block", Payload: json.RawMessage(`{"height":2}`)},
+		{ID: 256, Webhook: "wallet", Event: "tx", Payload: json.RawMessage(`{"txid":"abcd"}`), Attempts: 1, NextAttempt: 1234, LastError: "HTTP status 500"},
+	}
+	if err := d.StoreWebhookDeliveries(deliveries); err != nil {
+		t.Fatal(err)
+	}
+	if last, err = d.GetLastWebhookDeliveryID(); err != nil {
+		t.Fatal(err)
+	}
+	if last != 256 {
+		t.Errorf("GetLastWebhookDeliveryID() = %v, want 256", last)
+	}
+	walletDeliveries := []WebhookDelivery{deliveries[0], deliveries[2], deliveries[3]}
+	gotDeliveries, err := d.GetWebhookDeliveries("wallet", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotDeliveries, walletDeliveries) {
+		t.Errorf("GetWebhookDeliveries(wallet, 10) = %+v, want %+v", gotDeliveries, walletDeliveries)
+	}
+	n, err := d.CountWebhookDeliveries("wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("CountWebhookDeliveries(wallet) = %v, want 3", n)
+	}
+	if err := d.DeleteWebhookDelivery("wallet", 1); err != nil {
+		t.Fatal(err)
+	}
+	if gotDeliveries, err = d.GetWebhookDeliveries("wallet", 1); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotDeliveries, deliveries[2:3]) {
+		t.Errorf("GetWebhookDeliveries(wallet, 1) = %+v, want %+v", gotDeliveries, deliveries[2:3])
+	}
+	// deleting the webhook removes its pending deliveries only
+	if err := d.DeleteWebhook("wallet"); err != nil {
+		t.Fatal(err)
+	}
+	if gotDeliveries, err = d.GetWebhookDeliveries("wallet", 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(gotDeliveries) != 0 {
+		t.Errorf("GetWebhookDeliveries(wallet, 10) after DeleteWebhook = %+v, want empty", gotDeliveries)
+	}
+	if gotDeliveries, err = d.GetWebhookDeliveries("wallet2", 10); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotDeliveries, deliveries[1:2]) {
+		t.Errorf("GetWebhookDeliveries(wallet2, 10) = %+v, want %+v", gotDeliveries, deliveries[1:2])
+	}
+}
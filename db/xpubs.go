@@ -0,0 +1,427 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"bytes"
+	"sort"
+	"sync/atomic"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// XpubChain is the persisted state of one derivation chain (receiving or change addresses) of an xpub
+type XpubChain struct {
+	LastUsedIndex int
+	AddrDescs     []bchain.AddressDescriptor
+}
+
+// defaultMaxXpubStates is the default maximum number of persisted xpub states,
+// the states updated at the lowest height are evicted to make place for new ones
+const defaultMaxXpubStates = 100000
+
+// XpubState is the persisted state of the addresses derived from an xpub (or output descriptor),
+// Height is the block height to which the state is valid, an older state never overwrites a newer one
+type XpubState struct {
+	Gap    int
+	Height uint32
+	Chains []XpubChain
+}
+
+// SetMaxXpubStates sets the maximum number of persisted xpub states, the excess states are evicted by the next store of a new xpub
+func (d *RocksDB) SetMaxXpubStates(max int) error {
+	if max <= 0 {
+		return errors.New("Invalid maximum number of xpub states")
+	}
+	d.xpubsMux.Lock()
+	d.maxXpubStates = max
+	d.xpubsMux.Unlock()
+	return nil
+}
+
+// GetXpubState returns the persisted state of the xpub or nil if the xpub was not stored
+func (d *RocksDB) GetXpubState(xpub string) (*XpubState, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfXpubs], []byte(xpub))
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	return unpackXpubState(buf)
+}
+
+// StoreXpubState persists the state of the xpub together with the index of its derived addresses,
+// the addresses already stored in the previous state of the xpub are not indexed again.
+// One state is kept for an xpub, the one derived with the widest gap. The state is persisted only if it is not older
+// and not derived with a smaller gap than the stored one, the returned value signals if the state was stored.
+func (d *RocksDB) StoreXpubState(xpub string, s *XpubState) (bool, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return false, errors.New("Unsupported chain type")
+	}
+	d.xpubsMux.Lock()
+	defer d.xpubsMux.Unlock()
+	old, err := d.GetXpubState(xpub)
+	if err != nil {
+		return false, err
+	}
+	if old != nil && (old.Height > s.Height || old.Gap > s.Gap) {
+		return false, nil
+	}
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	if old == nil {
+		if err = d.evictXpubStates(wb, d.maxXpubStates-1); err != nil {
+			return false, err
+		}
+	} else {
+		// remove the addresses not present in the new state from the index
+		for c := range old.Chains {
+			from := 0
+			if c < len(s.Chains) {
+				from = len(s.Chains[c].AddrDescs)
+			}
+			for i := from; i < len(old.Chains[c].AddrDescs); i++ {
+				wb.DeleteCF(d.cfh[cfXpubAddresses], packXpubAddressKey(old.Chains[c].AddrDescs[i], xpub))
+			}
+		}
+	}
+	d.storeXpubState(wb, xpub, s, xpubIndexedAddresses(old))
+	if err = d.db.Write(d.wo, wb); err != nil {
+		return false, err
+	}
+	atomic.StoreInt32(&d.xpubsStored, 1)
+	return true, nil
+}
+
+// isRegisteredXpub returns true if the xpub is an item of some watchlist or is subscribed by some webhook,
+// the caller must hold xpubsMux
+func (d *RocksDB) isRegisteredXpub(xpub string) (bool, error) {
+	if d.registeredXpubs == nil {
+		watchlists, err := d.GetWatchlists()
+		if err != nil {
+			return false, err
+		}
+		webhooks, err := d.GetWebhooks()
+		if err != nil {
+			return false, err
+		}
+		registered := make(map[string]struct{})
+		for i := range watchlists {
+			for _, item := range watchlists[i].Items {
+				registered[item.Descriptor] = struct{}{}
+			}
+		}
+		for i := range webhooks {
+			for _, x := range webhooks[i].Xpubs {
+				registered[x] = struct{}{}
+			}
+		}
+		d.registeredXpubs = registered
+	}
+	_, found := d.registeredXpubs[xpub]
+	return found, nil
+}
+
+// resetRegisteredXpubs drops the cached registered xpubs after a change of the watchlists or webhooks
+func (d *RocksDB) resetRegisteredXpubs() {
+	d.xpubsMux.Lock()
+	d.registeredXpubs = nil
+	d.xpubsMux.Unlock()
+}
+
+// evictXpubStates deletes the states updated at the lowest heights so that at most keep states remain,
+// the states of the xpubs registered in a watchlist or a webhook are never evicted as the sync matches their transactions,
+// the caller must hold xpubsMux
+func (d *RocksDB) evictXpubStates(wb *gorocksdb.WriteBatch, keep int) error {
+	type xpubHeight struct {
+		xpub   string
+		height uint32
+	}
+	var states []xpubHeight
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfXpubs])
+	defer it.Close()
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		buf := it.Value().Data()
+		_, p := unpackVaruint(buf)
+		if len(buf) < p+4 {
+			return errors.New("Invalid xpub data")
+		}
+		states = append(states, xpubHeight{string(it.Key().Data()), unpackUint(buf[p:])})
+	}
+	if len(states) <= keep {
+		return nil
+	}
+	sort.SliceStable(states, func(i, j int) bool { return states[i].height < states[j].height })
+	evict := len(states) - keep
+	for _, e := range states {
+		if evict == 0 {
+			break
+		}
+		registered, err := d.isRegisteredXpub(e.xpub)
+		if err != nil {
+			return err
+		}
+		if registered {
+			continue
+		}
+		evict--
+		s, err := d.GetXpubState(e.xpub)
+		if err != nil {
+			return err
+		}
+		if s != nil {
+			d.deleteXpubState(wb, e.xpub, s)
+			glog.Info("rocksdb: evicted state of xpub ", e.xpub, " updated at height ", e.height)
+		}
+	}
+	return nil
+}
+
+// deleteXpubState deletes the state of the xpub and the index of its derived addresses
+func (d *RocksDB) deleteXpubState(wb *gorocksdb.WriteBatch, xpub string, s *XpubState) {
+	wb.DeleteCF(d.cfh[cfXpubs], []byte(xpub))
+	for c := range s.Chains {
+		for _, ad := range s.Chains[c].AddrDescs {
+			wb.DeleteCF(d.cfh[cfXpubAddresses], packXpubAddressKey(ad, xpub))
+		}
+	}
+}
+
+// rollbackXpubs moves the states updated by the disconnected blocks below the lowest disconnected block,
+// the derived addresses stay valid and the last used indexes are corrected by the next request of the xpub,
+// the caller must hold xpubsMux
+func (d *RocksDB) rollbackXpubs(wb *gorocksdb.WriteBatch, lower uint32) error {
+	if !d.hasXpubs() {
+		return nil
+	}
+	height := uint32(0)
+	if lower > 0 {
+		height = lower - 1
+	}
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfXpubs])
+	defer it.Close()
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		s, err := unpackXpubState(it.Value().Data())
+		if err != nil {
+			return errors.Annotatef(err, "xpub %v", string(it.Key().Data()))
+		}
+		if s.Height >= lower {
+			s.Height = height
+			wb.PutCF(d.cfh[cfXpubs], append([]byte(nil), it.Key().Data()...), packXpubState(s))
+		}
+	}
+	return nil
+}
+
+// xpubIndexedAddresses returns the number of addresses of each chain of the state, which are already in the reverse index
+func xpubIndexedAddresses(s *XpubState) []int {
+	if s == nil {
+		return nil
+	}
+	indexed := make([]int, len(s.Chains))
+	for c := range s.Chains {
+		indexed[c] = len(s.Chains[c].AddrDescs)
+	}
+	return indexed
+}
+
+func (d *RocksDB) storeXpubState(wb *gorocksdb.WriteBatch, xpub string, s *XpubState, indexed []int) {
+	wb.PutCF(d.cfh[cfXpubs], []byte(xpub), packXpubState(s))
+	varBuf := make([]byte, maxPackedBigintBytes)
+	for c := range s.Chains {
+		from := 0
+		if c < len(indexed) {
+			from = indexed[c]
+		}
+		for i := from; i < len(s.Chains[c].AddrDescs); i++ {
+			l := packVaruint(uint(c), varBuf)
+			l += packVaruint(uint(i), varBuf[l:])
+			wb.PutCF(d.cfh[cfXpubAddresses], packXpubAddressKey(s.Chains[c].AddrDescs[i], xpub), append([]byte(nil), varBuf[:l]...))
+		}
+	}
+}
+
+// packXpubAddressKey creates the key of the reverse index of xpub addresses,
+// the length of the address descriptor is part of the key so that a descriptor cannot be a prefix of another one
+func packXpubAddressKey(addrDesc bchain.AddressDescriptor, xpub string) []byte {
+	varBuf := make([]byte, maxPackedBigintBytes)
+	l := packVaruint(uint(len(addrDesc)), varBuf)
+	key := make([]byte, 0, l+len(addrDesc)+len(xpub))
+	key = append(key, varBuf[:l]...)
+	key = append(key, addrDesc...)
+	return append(key, xpub...)
+}
+
+func packXpubState(s *XpubState) []byte {
+	varBuf := make([]byte, maxPackedBigintBytes)
+	buf := make([]byte, 0, 64)
+	l := packVaruint(uint(s.Gap), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	buf = append(buf, packUint(s.Height)...)
+	l = packVaruint(uint(len(s.Chains)), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	for i := range s.Chains {
+		c := &s.Chains[i]
+		l = packVaruint(uint(c.LastUsedIndex), varBuf)
+		buf = append(buf, varBuf[:l]...)
+		l = packVaruint(uint(len(c.AddrDescs)), varBuf)
+		buf = append(buf, varBuf[:l]...)
+		for _, ad := range c.AddrDescs {
+			l = packVaruint(uint(len(ad)), varBuf)
+			buf = append(buf, varBuf[:l]...)
+			buf = append(buf, ad...)
+		}
+	}
+	return buf
+}
+
+func unpackXpubState(buf []byte) (*XpubState, error) {
+	var s XpubState
+	gap, p := unpackVaruint(buf)
+	s.Gap = int(gap)
+	if len(buf) < p+4 {
+		return nil, errors.New("Invalid xpub data")
+	}
+	s.Height = unpackUint(buf[p:])
+	p += 4
+	nc, l := unpackVaruint(buf[p:])
+	p += l
+	s.Chains = make([]XpubChain, nc)
+	for i := range s.Chains {
+		c := &s.Chains[i]
+		lu, l := unpackVaruint(buf[p:])
+		c.LastUsedIndex = int(lu)
+		p += l
+		na, l := unpackVaruint(buf[p:])
+		p += l
+		c.AddrDescs = make([]bchain.AddressDescriptor, na)
+		for j := range c.AddrDescs {
+			al, l := unpackVaruint(buf[p:])
+			p += l
+			if len(buf) < p+int(al) {
+				return nil, errors.New("Invalid xpub data")
+			}
+			c.AddrDescs[j] = append(bchain.AddressDescriptor(nil), buf[p:p+int(al)]...)
+			p += int(al)
+		}
+	}
+	return &s, nil
+}
+
+// hasXpubs returns true if there is any xpub state stored in db, the result is cached
+func (d *RocksDB) hasXpubs() bool {
+	s := atomic.LoadInt32(&d.xpubsStored)
+	if s < 0 {
+		s = 0
+		it := d.db.NewIteratorCF(d.ro, d.cfh[cfXpubs])
+		it.SeekToFirst()
+		if it.Valid() {
+			s = 1
+		}
+		it.Close()
+		atomic.StoreInt32(&d.xpubsStored, s)
+	}
+	return s > 0
+}
+
+// GetAddressXpubs returns the stored xpubs from which the address descriptor was derived
+func (d *RocksDB) GetAddressXpubs(addrDesc bchain.AddressDescriptor) ([]string, error) {
+	if !d.hasXpubs() {
+		return nil, nil
+	}
+	var xpubs []string
+	prefix := packXpubAddressKey(addrDesc, "")
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfXpubAddresses])
+	defer it.Close()
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		if !bytes.HasPrefix(key, prefix) {
+			break
+		}
+		xpubs = append(xpubs, string(key[len(prefix):]))
+	}
+	return xpubs, it.Err()
+}
+
+type xpubAddressRef struct {
+	chain int
+	index int
+}
+
+// updateXpubs updates the persisted state of the xpubs having addresses in the connected block,
+// the last used indexes are moved and new addresses are derived to keep the gap of unused addresses,
+// the caller must hold xpubsMux until the batch is written
+func (d *RocksDB) updateXpubs(wb *gorocksdb.WriteBatch, block *bchain.Block, addresses addressesMap) error {
+	if !d.hasXpubs() {
+		return nil
+	}
+	affected := make(map[string][]xpubAddressRef)
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfXpubAddresses])
+	defer it.Close()
+	for addrDesc := range addresses {
+		prefix := packXpubAddressKey(bchain.AddressDescriptor(addrDesc), "")
+		for it.Seek(prefix); it.Valid(); it.Next() {
+			key := it.Key().Data()
+			if !bytes.HasPrefix(key, prefix) {
+				break
+			}
+			val := it.Value().Data()
+			c, l := unpackVaruint(val)
+			i, _ := unpackVaruint(val[l:])
+			xpub := string(key[len(prefix):])
+			affected[xpub] = append(affected[xpub], xpubAddressRef{chain: int(c), index: int(i)})
+		}
+	}
+	for xpub, refs := range affected {
+		s, err := d.GetXpubState(xpub)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			continue
+		}
+		indexed := xpubIndexedAddresses(s)
+		for _, r := range refs {
+			if r.chain < len(s.Chains) && r.index > s.Chains[r.chain].LastUsedIndex {
+				s.Chains[r.chain].LastUsedIndex = r.index
+			}
+		}
+		for c := range s.Chains {
+			if err = d.deriveXpubChain(xpub, s, c, addresses); err != nil {
+				// the derivation is completed by the next request of the xpub
+				glog.Warning("rocksdb: xpub ", xpub, " derivation error ", err)
+				break
+			}
+		}
+		s.Height = block.Height
+		d.storeXpubState(wb, xpub, s, indexed)
+	}
+	if len(affected) > 0 && glog.V(1) {
+		glog.Info("rocksdb: block ", block.Height, " updated ", len(affected), " xpubs")
+	}
+	return nil
+}
+
+// deriveXpubChain derives new addresses of the chain so that there is gap of unused addresses after the last used one,
+// the newly derived addresses are checked against the addresses of the block
+func (d *RocksDB) deriveXpubChain(xpub string, s *XpubState, c int, addresses addressesMap) error {
+	chain := &s.Chains[c]
+	for len(chain.AddrDescs)-chain.LastUsedIndex < s.Gap {
+		from := len(chain.AddrDescs)
+		descriptors, err := d.chainParser.DeriveAddressDescriptorsFromTo(xpub, uint32(c), uint32(from), uint32(chain.LastUsedIndex+s.Gap))
+		if err != nil {
+			return err
+		}
+		for i, ad := range descriptors {
+			if _, used := addresses[string(ad)]; used {
+				chain.LastUsedIndex = from + i
+			}
+		}
+		chain.AddrDescs = append(chain.AddrDescs, descriptors...)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+//go:build unittest
+// +build unittest
+
+package db
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"blockbook/tests/dbtestdata"
+	"reflect"
+	"testing"
+)
+
+func TestRocksDB_XpubState(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{
+			BlockAddressesToKeep:  1,
+			XPubMagic:             70617039,
+			XPubMagicSegwitP2sh:   71979618,
+			XPubMagicSegwitNative: 73342198,
+		}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	derive := func(change uint32, to uint32) []bchain.AddressDescriptor {
+		ad, err := d.chainParser.DeriveAddressDescriptorsFromTo(dbtestdata.Xpub, change, 0, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ad
+	}
+
+	if d.hasXpubs() {
+		t.Fatal("hasXpubs() = true in empty db")
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	state := &XpubState{
+		Gap:    3,
+		Height: 225493,
+		Chains: []XpubChain{
+			{LastUsedIndex: 0, AddrDescs: derive(0, 3)},
+			{LastUsedIndex: 0, AddrDescs: derive(1, 4)},
+		},
+	}
+	stored, err := d.StoreXpubState(dbtestdata.Xpub, state)
+	if err != nil || !stored {
+		t.Fatalf("StoreXpubState() = %v, %v, want true, nil", stored, err)
+	}
+	got, err := d.GetXpubState(dbtestdata.Xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, state) {
+		t.Errorf("GetXpubState() = %+v, want %+v", got, state)
+	}
+	if !d.hasXpubs() {
+		t.Fatal("hasXpubs() = false after StoreXpubState")
+	}
+
+	// block 2 pays to the address m/49'/1'/33'/1/3, the change chain must be derived further to keep the gap
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	got, err = d.GetXpubState(dbtestdata.Xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &XpubState{
+		Gap:    3,
+		Height: 225494,
+		Chains: []XpubChain{
+			{LastUsedIndex: 0, AddrDescs: derive(0, 3)},
+			{LastUsedIndex: 3, AddrDescs: derive(1, 6)},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetXpubState() = %+v, want %+v", got, want)
+	}
+
+	// a state computed before the last block does not overwrite the state updated by the block
+	if stored, err = d.StoreXpubState(dbtestdata.Xpub, state); err != nil || stored {
+		t.Fatalf("StoreXpubState() of older state = %v, %v, want false, nil", stored, err)
+	}
+	if got, err = d.GetXpubState(dbtestdata.Xpub); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("GetXpubState() after older state = %+v, %v, want %+v", got, err, want)
+	}
+	// a state derived with a smaller gap does not overwrite the wider state
+	narrow := &XpubState{
+		Gap:    2,
+		Height: 225494,
+		Chains: []XpubChain{
+			{LastUsedIndex: 0, AddrDescs: derive(0, 2)},
+			{LastUsedIndex: 3, AddrDescs: derive(1, 5)},
+		},
+	}
+	if stored, err = d.StoreXpubState(dbtestdata.Xpub, narrow); err != nil || stored {
+		t.Fatalf("StoreXpubState() of narrower state = %v, %v, want false, nil", stored, err)
+	}
+	if got, err = d.GetXpubState(dbtestdata.Xpub); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("GetXpubState() after narrower state = %+v, %v, want %+v", got, err, want)
+	}
+
+	// the disconnected block rolls back the height of the state, the derived addresses are kept
+	if err := d.DisconnectBlockRangeBitcoinType(225494, 225494); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = d.GetXpubState(dbtestdata.Xpub); err != nil {
+		t.Fatal(err)
+	}
+	want.Height = 225493
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetXpubState() after disconnect = %+v, want %+v", got, want)
+	}
+	if stored, err = d.StoreXpubState(dbtestdata.Xpub, state); err != nil || !stored {
+		t.Fatalf("StoreXpubState() after disconnect = %v, %v, want true, nil", stored, err)
+	}
+	if got, err = d.GetXpubState(dbtestdata.Xpub); err != nil || !reflect.DeepEqual(got, state) {
+		t.Errorf("GetXpubState() after disconnect and store = %+v, %v, want %+v", got, err, state)
+	}
+	// the addresses not present in the new state are removed from the index
+	if xpubs, err := d.GetAddressXpubs(want.Chains[1].AddrDescs[5]); err != nil || len(xpubs) != 0 {
+		t.Errorf("GetAddressXpubs() of removed address = %v, %v, want none", xpubs, err)
+	}
+	if xpubs, err := d.GetAddressXpubs(state.Chains[1].AddrDescs[3]); err != nil || !reflect.DeepEqual(xpubs, []string{dbtestdata.Xpub}) {
+		t.Errorf("GetAddressXpubs() = %v, %v, want %v", xpubs, err, dbtestdata.Xpub)
+	}
+
+	got, err = d.GetXpubState("unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("GetXpubState(unknown) = %+v, want nil", got)
+	}
+}
+
+func TestRocksDB_XpubState_eviction(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1}),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	if err := d.SetMaxXpubStates(0); err == nil {
+		t.Error("SetMaxXpubStates(0) accepted")
+	}
+	if err := d.SetMaxXpubStates(2); err != nil {
+		t.Fatal(err)
+	}
+
+	state := func(height uint32, addrDesc string) *XpubState {
+		return &XpubState{Gap: 21, Height: height, Chains: []XpubChain{{AddrDescs: []bchain.AddressDescriptor{bchain.AddressDescriptor(addrDesc)}}}}
+	}
+	store := func(xpub string, height uint32) {
+		if stored, err := d.StoreXpubState(xpub, state(height, "ad-"+xpub)); err != nil || !stored {
+			t.Fatalf("StoreXpubState(%v) = %v, %v, want true, nil", xpub, stored, err)
+		}
+	}
+	stateExists := func(xpub string) bool {
+		s, err := d.GetXpubState(xpub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		xpubs, err := d.GetAddressXpubs(bchain.AddressDescriptor("ad-" + xpub))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (s != nil) != (len(xpubs) == 1) {
+			t.Errorf("state of %v is not consistent with the address index %v", xpub, xpubs)
+		}
+		return s != nil
+	}
+	if err := d.StoreWebhook(&Webhook{ID: "wh", URL: "http://localhost", Xpubs: []string{"xpub2"}}); err != nil {
+		t.Fatal(err)
+	}
+	store("xpub1", 100)
+	store("xpub2", 90)
+	store("xpub3", 110)
+	// the state updated at the lowest height is evicted when the limit is reached, except the states of registered xpubs
+	if stateExists("xpub1") || !stateExists("xpub2") || !stateExists("xpub3") {
+		t.Error("xpub1 was not evicted")
+	}
+
+	// the state of the xpub removed from the webhook is kept until it is evicted
+	if err := d.DeleteWebhook("wh"); err != nil {
+		t.Fatal(err)
+	}
+	if !stateExists("xpub2") {
+		t.Error("state of xpub2 deleted with the webhook")
+	}
+	if err := d.StoreWatchlist(&Watchlist{Name: "wl", Items: []WatchlistItem{{Descriptor: "xpub3"}}}); err != nil {
+		t.Fatal(err)
+	}
+	store("xpub4", 120)
+	if stateExists("xpub2") || !stateExists("xpub3") || !stateExists("xpub4") {
+		t.Error("xpub2 was not evicted")
+	}
+}
@@ -0,0 +1,112 @@
+package fiat
+
+import (
+	"blockbook/db"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// CoinGeckoProvider downloads the rates from the CoinGecko API v3 or from a local service with a compatible interface
+type CoinGeckoProvider struct {
+	url        string
+	coin       string
+	currencies []string
+	httpClient *http.Client
+}
+
+// NewCoinGeckoProvider creates the provider of the rates of the coin (CoinGecko coin id) to the currencies
+func NewCoinGeckoProvider(url string, coin string, currencies []string, timeout time.Duration) *CoinGeckoProvider {
+	return &CoinGeckoProvider{
+		url:        strings.TrimSuffix(url, "/"),
+		coin:       coin,
+		currencies: currencies,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *CoinGeckoProvider) get(path string, query url.Values, v interface{}) error {
+	u := p.url + path + "?" + query.Encode()
+	resp, err := p.httpClient.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("%v returned status %v: %v", u, resp.StatusCode, string(body))
+	}
+	if err = json.Unmarshal(body, v); err != nil {
+		return errors.Annotatef(err, "%v", u)
+	}
+	return nil
+}
+
+// CurrentTicker returns the current rates
+func (p *CoinGeckoProvider) CurrentTicker() (*db.CurrencyRatesTicker, error) {
+	var prices map[string]map[string]float64
+	query := url.Values{
+		"ids":           {p.coin},
+		"vs_currencies": {strings.Join(p.currencies, ",")},
+	}
+	if err := p.get("/simple/price", query, &prices); err != nil {
+		return nil, err
+	}
+	rates, found := prices[p.coin]
+	if !found || len(rates) == 0 {
+		return nil, errors.Errorf("no rates of coin %v", p.coin)
+	}
+	return &db.CurrencyRatesTicker{Timestamp: time.Now().Unix(), Rates: rates}, nil
+}
+
+// HistoricalTickers returns the tickers of the given resolution in the time range from - to; the provider returns the prices
+// in a granularity depending on the length of the range, the first price in each day or hour is taken as its ticker
+func (p *CoinGeckoProvider) HistoricalTickers(resolution byte, from, to time.Time) ([]db.CurrencyRatesTicker, error) {
+	period := int64(tickerPeriod(resolution) / time.Second)
+	buckets := make(map[int64]map[string]float64)
+	for _, currency := range p.currencies {
+		var chart struct {
+			Prices [][2]float64 `json:"prices"`
+		}
+		query := url.Values{
+			"vs_currency": {currency},
+			"from":        {strconv.FormatInt(from.Unix(), 10)},
+			"to":          {strconv.FormatInt(to.Unix(), 10)},
+		}
+		if err := p.get("/coins/"+p.coin+"/market_chart/range", query, &chart); err != nil {
+			return nil, err
+		}
+		for _, price := range chart.Prices {
+			ts := int64(price[0]) / 1000
+			// only the periods which start in the range are complete
+			bucket := ts - ts%period
+			if bucket < from.Unix() {
+				continue
+			}
+			rates, found := buckets[bucket]
+			if !found {
+				rates = make(map[string]float64)
+				buckets[bucket] = rates
+			}
+			if _, found = rates[currency]; !found {
+				rates[currency] = price[1]
+			}
+		}
+	}
+	tickers := make([]db.CurrencyRatesTicker, 0, len(buckets))
+	for ts, rates := range buckets {
+		tickers = append(tickers, db.CurrencyRatesTicker{Timestamp: ts, Rates: rates})
+	}
+	sort.Slice(tickers, func(i, j int) bool { return tickers[i].Timestamp < tickers[j].Timestamp })
+	return tickers, nil
+}
@@ -0,0 +1,209 @@
+package fiat
+
+import (
+	"blockbook/db"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+const (
+	defaultPeriodSeconds = 900
+	defaultHourlyDays    = 90
+	defaultTimeout       = 30 * time.Second
+)
+
+// RatesProvider is the source of the exchange rates of the coin
+type RatesProvider interface {
+	// CurrentTicker returns the current rates
+	CurrentTicker() (*db.CurrencyRatesTicker, error)
+	// HistoricalTickers returns the tickers of the given resolution (db.FiatRatesDaily or db.FiatRatesHourly)
+	// starting in the time range from - to, ordered by time
+	HistoricalTickers(resolution byte, from, to time.Time) ([]db.CurrencyRatesTicker, error)
+}
+
+// OnNewFiatRatesTicker is called when the current rates are downloaded
+type OnNewFiatRatesTicker func(ticker *db.CurrencyRatesTicker)
+
+// RatesDownloaderParams are the parameters of the downloader, passed in the blockchain configuration as "fiat_rates_params"
+type RatesDownloaderParams struct {
+	// URL of the provider API, a local service with the same interface can be used instead of the public one
+	URL string `json:"url"`
+	// Coin is the id of the coin by the provider
+	Coin string `json:"coin"`
+	// Currencies are the lowercase codes of the fiat currencies and coins (e.g. btc) to download the rates to
+	Currencies []string `json:"currencies"`
+	// PeriodSeconds is the period of the download of the current rates
+	PeriodSeconds int `json:"periodSeconds"`
+	// StartDate (YYYY-MM-DD) is the date of the first downloaded daily ticker
+	StartDate string `json:"startDate"`
+	// HourlyDays is the number of days of the downloaded history of hourly tickers
+	HourlyDays int `json:"hourlyDays"`
+}
+
+// RatesDownloader periodically downloads the current rates and completes the stored history of daily and hourly tickers;
+// the current rates are stored as hourly tickers with their exact time, the history of hourly tickers is downloaded
+// only after an outage of the downloader
+type RatesDownloader struct {
+	db          *db.RocksDB
+	provider    RatesProvider
+	period      time.Duration
+	startTime   time.Time
+	hourlyDays  int
+	onNewTicker OnNewFiatRatesTicker
+	now         func() time.Time
+	done        chan struct{}
+	finished    chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewRatesDownloader creates the downloader of the rates from the provider of the given type configured by the json params
+func NewRatesDownloader(d *db.RocksDB, providerType string, params string, onNewTicker OnNewFiatRatesTicker) (*RatesDownloader, error) {
+	var rdp RatesDownloaderParams
+	if err := json.Unmarshal([]byte(params), &rdp); err != nil {
+		return nil, errors.Annotatef(err, "fiat_rates_params")
+	}
+	if rdp.Coin == "" || len(rdp.Currencies) == 0 {
+		return nil, errors.New("Missing coin or currencies in fiat_rates_params")
+	}
+	for i := range rdp.Currencies {
+		rdp.Currencies[i] = strings.ToLower(rdp.Currencies[i])
+	}
+	var provider RatesProvider
+	switch providerType {
+	case "coingecko":
+		if rdp.URL == "" {
+			rdp.URL = "https://api.coingecko.com/api/v3"
+		}
+		provider = NewCoinGeckoProvider(rdp.URL, rdp.Coin, rdp.Currencies, defaultTimeout)
+	default:
+		return nil, errors.Errorf("Unsupported fiat rates provider %v", providerType)
+	}
+	if rdp.PeriodSeconds <= 0 {
+		rdp.PeriodSeconds = defaultPeriodSeconds
+	}
+	if rdp.HourlyDays <= 0 {
+		rdp.HourlyDays = defaultHourlyDays
+	}
+	var startTime time.Time
+	if rdp.StartDate != "" {
+		var err error
+		if startTime, err = time.Parse("2006-01-02", rdp.StartDate); err != nil {
+			return nil, errors.Annotatef(err, "fiat_rates_params startDate")
+		}
+	}
+	return newRatesDownloader(d, provider, time.Duration(rdp.PeriodSeconds)*time.Second, startTime, rdp.HourlyDays, onNewTicker), nil
+}
+
+func newRatesDownloader(d *db.RocksDB, provider RatesProvider, period time.Duration, startTime time.Time, hourlyDays int, onNewTicker OnNewFiatRatesTicker) *RatesDownloader {
+	return &RatesDownloader{
+		db:          d,
+		provider:    provider,
+		period:      period,
+		startTime:   startTime,
+		hourlyDays:  hourlyDays,
+		onNewTicker: onNewTicker,
+		now:         time.Now,
+		done:        make(chan struct{}),
+		finished:    make(chan struct{}),
+	}
+}
+
+// Run starts the download loop
+func (rd *RatesDownloader) Run() {
+	go rd.loop()
+}
+
+// Close stops the download loop and waits until it finishes
+func (rd *RatesDownloader) Close() {
+	rd.closeOnce.Do(func() {
+		close(rd.done)
+		<-rd.finished
+	})
+}
+
+func (rd *RatesDownloader) loop() {
+	defer close(rd.finished)
+	ticker := time.NewTicker(rd.period)
+	defer ticker.Stop()
+	for {
+		if err := rd.sync(); err != nil {
+			glog.Error("fiat rates: ", err)
+		}
+		select {
+		case <-rd.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func tickerPeriod(resolution byte) time.Duration {
+	if resolution == db.FiatRatesDaily {
+		return 24 * time.Hour
+	}
+	return time.Hour
+}
+
+// syncHistory downloads the tickers of the resolution after the last stored ticker, but not before the time start
+func (rd *RatesDownloader) syncHistory(resolution byte, start time.Time, now time.Time) error {
+	last, err := rd.db.FiatRatesGetLastTicker(resolution)
+	if err != nil {
+		return err
+	}
+	from := start
+	if last != nil && last.Timestamp >= start.Unix() {
+		from = time.Unix(last.Timestamp+1, 0)
+	}
+	// download only if a new period started after the last ticker
+	period := tickerPeriod(resolution)
+	next := from.Truncate(period)
+	if next.Before(from) {
+		next = next.Add(period)
+	}
+	if next.After(now) {
+		return nil
+	}
+	tickers, err := rd.provider.HistoricalTickers(resolution, from, now)
+	if err != nil {
+		return errors.Annotatef(err, "HistoricalTickers %c", resolution)
+	}
+	if len(tickers) > 0 {
+		glog.Infof("fiat rates: downloaded %d tickers of resolution %c from %v", len(tickers), resolution, from.UTC())
+	}
+	return rd.db.FiatRatesStoreTickers(resolution, tickers)
+}
+
+// sync completes the stored history and stores and announces the current ticker
+func (rd *RatesDownloader) sync() error {
+	now := rd.now()
+	if err := rd.syncHistory(db.FiatRatesDaily, rd.startTime, now); err != nil {
+		return err
+	}
+	last, err := rd.db.FiatRatesGetLastTicker(db.FiatRatesHourly)
+	if err != nil {
+		return err
+	}
+	if last == nil || now.Sub(time.Unix(last.Timestamp, 0)) > 2*rd.period {
+		if err = rd.syncHistory(db.FiatRatesHourly, now.Add(-time.Duration(rd.hourlyDays)*24*time.Hour), now); err != nil {
+			return err
+		}
+	}
+	ticker, err := rd.provider.CurrentTicker()
+	if err != nil {
+		return errors.Annotatef(err, "CurrentTicker")
+	}
+	// the ticker is stored with the time of the download to be consistent with the history
+	ticker.Timestamp = now.Unix()
+	if err = rd.db.FiatRatesStoreTickers(db.FiatRatesHourly, []db.CurrencyRatesTicker{*ticker}); err != nil {
+		return err
+	}
+	if rd.onNewTicker != nil {
+		rd.onNewTicker(ticker)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// +build unittest
+
+package fiat
+
+import (
+	"blockbook/bchain/coins/btc"
+	"blockbook/db"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// coinGeckoStandIn is a local service with the interface of the CoinGecko API, the prices are generated from the time
+type coinGeckoStandIn struct {
+	t        *testing.T
+	requests []string
+}
+
+func price(currency string, ts int64) float64 {
+	if currency == "btc" {
+		return float64(ts) / 1e15
+	}
+	return float64(ts) / 1e9
+}
+
+func (s *coinGeckoStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.requests = append(s.requests, r.URL.Path+" "+q.Get("vs_currency")+q.Get("vs_currencies")+" "+q.Get("from")+"-"+q.Get("to"))
+	var v interface{}
+	switch r.URL.Path {
+	case "/simple/price":
+		if q.Get("ids") != "nix-platform" {
+			http.Error(w, `{"error":"invalid coin"}`, http.StatusNotFound)
+			return
+		}
+		v = map[string]map[string]float64{"nix-platform": {"usd": 0.5, "btc": 0.00005}}
+	case "/coins/nix-platform/market_chart/range":
+		from, _ := strconv.ParseInt(q.Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(q.Get("to"), 10, 64)
+		// like CoinGecko, return daily prices for ranges longer than 90 days, hourly otherwise, not aligned to the period start
+		step := int64(3600)
+		if to-from > 90*86400 {
+			step = 86400
+		}
+		prices := [][2]float64{}
+		for ts := from - from%step + 60; ts <= to; ts += step {
+			if ts >= from {
+				prices = append(prices, [2]float64{float64(ts * 1000), price(q.Get("vs_currency"), ts)})
+			}
+		}
+		v = map[string]interface{}{"prices": prices}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+func TestCoinGeckoProvider(t *testing.T) {
+	standIn := &coinGeckoStandIn{t: t}
+	ts := httptest.NewServer(standIn)
+	defer ts.Close()
+	p := NewCoinGeckoProvider(ts.URL+"/", "nix-platform", []string{"usd", "btc"}, time.Second)
+
+	ticker, err := p.CurrentTicker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]float64{"usd": 0.5, "btc": 0.00005}; !reflect.DeepEqual(ticker.Rates, want) {
+		t.Errorf("CurrentTicker() rates = %v, want %v", ticker.Rates, want)
+	}
+
+	// the range starts in the middle of an hour, the first hour is not complete
+	from := time.Unix(1574290800+1800, 0)
+	to := time.Unix(1574290800+3*3600+1800, 0)
+	tickers, err := p.HistoricalTickers(db.FiatRatesHourly, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []db.CurrencyRatesTicker
+	for _, h := range []int64{1574294400, 1574298000, 1574301600} {
+		want = append(want, db.CurrencyRatesTicker{Timestamp: h, Rates: map[string]float64{"usd": price("usd", h+60), "btc": price("btc", h+60)}})
+	}
+	if !reflect.DeepEqual(tickers, want) {
+		t.Errorf("HistoricalTickers() = %+v, want %+v", tickers, want)
+	}
+
+	bad := NewCoinGeckoProvider(ts.URL, "unknown", []string{"usd"}, time.Second)
+	if _, err = bad.CurrentTicker(); err == nil {
+		t.Error("CurrentTicker() of unknown coin did not fail")
+	}
+}
+
+func TestRatesDownloader_sync(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "testdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	d, err := db.NewRocksDB(tmp, 100000, -1, btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	standIn := &coinGeckoStandIn{t: t}
+	ts := httptest.NewServer(standIn)
+	defer ts.Close()
+	var announced []*db.CurrencyRatesTicker
+	rd, err := NewRatesDownloader(d, "coingecko", fmt.Sprintf(`{"url":%q,"coin":"nix-platform","currencies":["USD","btc"],"periodSeconds":600,"startDate":"2019-06-01","hourlyDays":2}`, ts.URL),
+		func(ticker *db.CurrencyRatesTicker) {
+			announced = append(announced, ticker)
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2019, 11, 21, 10, 30, 0, 0, time.UTC)
+	rd.now = func() time.Time { return now }
+
+	// the first sync downloads the daily history from the start date, two days of hourly history and the current ticker
+	if err = rd.sync(); err != nil {
+		t.Fatal(err)
+	}
+	wantRequests := []string{
+		"/coins/nix-platform/market_chart/range usd 1559347200-1574332200",
+		"/coins/nix-platform/market_chart/range btc 1559347200-1574332200",
+		"/coins/nix-platform/market_chart/range usd 1574159400-1574332200",
+		"/coins/nix-platform/market_chart/range btc 1574159400-1574332200",
+		"/simple/price usd,btc -",
+	}
+	if !reflect.DeepEqual(standIn.requests, wantRequests) {
+		t.Errorf("requests = %q, want %q", standIn.requests, wantRequests)
+	}
+	daily, err := d.FiatRatesGetLastTicker(db.FiatRatesDaily)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&db.CurrencyRatesTicker{Timestamp: 1574294400, Rates: map[string]float64{"usd": price("usd", 1574294460), "btc": price("btc", 1574294460)}}); !reflect.DeepEqual(daily, want) {
+		t.Errorf("last daily ticker = %+v, want %+v", daily, want)
+	}
+	first, err := d.FiatRatesFindTicker(db.FiatRatesDaily, 1559347200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == nil || first.Timestamp != 1559347200 {
+		t.Errorf("first daily ticker = %+v, want at 1559347200", first)
+	}
+	hourly, err := d.FiatRatesFindTicker(db.FiatRatesHourly, 1574330400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hourly == nil || hourly.Timestamp != 1574330400 {
+		t.Errorf("hourly ticker = %+v, want at 1574330400", hourly)
+	}
+	if len(announced) != 1 || !reflect.DeepEqual(announced[0].Rates, map[string]float64{"usd": 0.5, "btc": 0.00005}) {
+		t.Fatalf("announced = %+v", announced)
+	}
+	current, err := d.FiatRatesGetLastTicker(db.FiatRatesHourly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Timestamp != now.Unix() || !reflect.DeepEqual(current, announced[0]) {
+		t.Errorf("last hourly ticker = %+v, want the current ticker %+v", current, announced[0])
+	}
+
+	// the next sync during the same day, shortly after the previous one, downloads only the current ticker
+	standIn.requests = nil
+	now = now.Add(10 * time.Minute)
+	if err = rd.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/simple/price usd,btc -"}; !reflect.DeepEqual(standIn.requests, want) {
+		t.Errorf("requests = %q, want %q", standIn.requests, want)
+	}
+}
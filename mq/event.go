@@ -0,0 +1,31 @@
+package mq
+
+// SchemaVersion is the version of the schema of the published events,
+// it is increased on any incompatible change of the Event structure
+const SchemaVersion = 1
+
+// Types of the published events, the type is also the suffix of the subject of the message
+const (
+	EventBlockConnected    = "block.connected"
+	EventBlockDisconnected = "block.disconnected"
+	EventMempoolAdded      = "mempool.added"
+	EventMempoolRemoved    = "mempool.removed"
+	EventAddressTx         = "address.tx"
+)
+
+// Event is the message published to the message broker as JSON
+//
+// block.connected and block.disconnected have Height and Hash, block.connected also Time and Txs,
+// mempool.added and mempool.removed have Txid, mempool.added also Time,
+// address.tx has Address and Txid, and Height and Hash if the transaction is in a block
+type Event struct {
+	Version int    `json:"version"`
+	Type    string `json:"type"`
+	Coin    string `json:"coin"`
+	Height  uint32 `json:"height,omitempty"`
+	Hash    string `json:"hash,omitempty"`
+	Time    int64  `json:"time,omitempty"`
+	Txs     int    `json:"txs,omitempty"`
+	Txid    string `json:"txid,omitempty"`
+	Address string `json:"address,omitempty"`
+}
@@ -0,0 +1,166 @@
+package mq
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+const natsTimeout = 10 * time.Second
+
+// Broker is a connection to the message broker
+type Broker interface {
+	// Publish sends the message, the message is not guaranteed to be received by the broker until Flush returns without error
+	Publish(subject string, data []byte) error
+	// Flush waits until the broker acknowledges all the messages published since the last Flush
+	Flush() error
+	Close() error
+}
+
+type natsConnectOptions struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+}
+
+// NATSBroker publishes messages to NATS server using its text protocol,
+// the connection is closed on any error and reestablished by the next Publish
+type NATSBroker struct {
+	url  *url.URL
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// NewNATSBroker creates the broker for NATS server given by url nats://[user:password@]host:port,
+// the connection is established by the first Publish
+func NewNATSBroker(rawurl string) (*NATSBroker, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "nats" || u.Host == "" {
+		return nil, errors.Errorf("Invalid NATS url %v", rawurl)
+	}
+	return &NATSBroker{url: u}, nil
+}
+
+func (b *NATSBroker) connect() error {
+	conn, err := net.DialTimeout("tcp", b.url.Host, natsTimeout)
+	if err != nil {
+		return err
+	}
+	b.conn = conn
+	b.r = bufio.NewReader(conn)
+	b.w = bufio.NewWriter(conn)
+	conn.SetReadDeadline(time.Now().Add(natsTimeout))
+	line, err := b.r.ReadString('\n')
+	if err != nil {
+		b.disconnect()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		err = errors.Errorf("Unexpected NATS greeting %q", line)
+		b.disconnect()
+		return err
+	}
+	opts := natsConnectOptions{Name: "blockbook", Lang: "go", Version: strconv.Itoa(SchemaVersion)}
+	if b.url.User != nil {
+		opts.User = b.url.User.Username()
+		opts.Pass, _ = b.url.User.Password()
+	}
+	buf, err := json.Marshal(&opts)
+	if err != nil {
+		b.disconnect()
+		return err
+	}
+	b.w.WriteString("CONNECT ")
+	b.w.Write(buf)
+	b.w.WriteString("\r\n")
+	// the server responds to PING only if CONNECT was accepted
+	return b.Flush()
+}
+
+func (b *NATSBroker) disconnect() {
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+	}
+}
+
+// Publish sends the message to the subject
+func (b *NATSBroker) Publish(subject string, data []byte) error {
+	if b.conn == nil {
+		if err := b.connect(); err != nil {
+			return err
+		}
+	}
+	b.conn.SetWriteDeadline(time.Now().Add(natsTimeout))
+	b.w.WriteString("PUB ")
+	b.w.WriteString(subject)
+	b.w.WriteString(" ")
+	b.w.WriteString(strconv.Itoa(len(data)))
+	b.w.WriteString("\r\n")
+	b.w.Write(data)
+	if _, err := b.w.WriteString("\r\n"); err != nil {
+		b.disconnect()
+		return err
+	}
+	return nil
+}
+
+// Flush sends PING and waits for PONG, which the server sends after processing all previous messages
+func (b *NATSBroker) Flush() error {
+	if b.conn == nil {
+		return errors.New("Not connected")
+	}
+	b.conn.SetWriteDeadline(time.Now().Add(natsTimeout))
+	b.w.WriteString("PING\r\n")
+	if err := b.w.Flush(); err != nil {
+		b.disconnect()
+		return err
+	}
+	b.conn.SetReadDeadline(time.Now().Add(natsTimeout))
+	for {
+		line, err := b.r.ReadString('\n')
+		if err != nil {
+			b.disconnect()
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			b.w.WriteString("PONG\r\n")
+			if err = b.w.Flush(); err != nil {
+				b.disconnect()
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			b.disconnect()
+			return errors.Errorf("NATS %v", line)
+		}
+		// +OK and INFO messages are ignored
+	}
+}
+
+// Close closes the connection to the server
+func (b *NATSBroker) Close() error {
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn = nil
+	return err
+}
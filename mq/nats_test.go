@@ -0,0 +1,111 @@
+// +build unittest
+
+package mq
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type natsMessage struct {
+	subject string
+	data    string
+}
+
+// fakeNATSServer implements the subset of the NATS protocol used by NATSBroker
+type fakeNATSServer struct {
+	listener net.Listener
+	mux      sync.Mutex
+	connect  string
+	messages []natsMessage
+}
+
+func newFakeNATSServer(t *testing.T) *fakeNATSServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeNATSServer{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeNATSServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	conn.Write([]byte("INFO {\"server_id\":\"fake\"}\r\n"))
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			s.mux.Lock()
+			s.connect = line[8:]
+			s.mux.Unlock()
+		case strings.HasPrefix(line, "PUB "):
+			f := strings.Fields(line)
+			n, _ := strconv.Atoi(f[len(f)-1])
+			buf := make([]byte, n+2)
+			if _, err = io.ReadFull(r, buf); err != nil {
+				return
+			}
+			s.mux.Lock()
+			s.messages = append(s.messages, natsMessage{subject: f[1], data: string(buf[:n])})
+			s.mux.Unlock()
+		case line == "PING":
+			conn.Write([]byte("PONG\r\n"))
+		}
+	}
+}
+
+func TestNATSBroker(t *testing.T) {
+	s := newFakeNATSServer(t)
+	defer s.listener.Close()
+
+	if _, err := NewNATSBroker("http://" + s.listener.Addr().String()); err == nil {
+		t.Error("NewNATSBroker with http url, expected error")
+	}
+	b, err := NewNATSBroker("nats://user:pass@" + s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if err = b.Publish("test.block.connected", []byte(`{"height":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Publish("test.mempool.added", []byte(`{"txid":"abcd"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	want := []natsMessage{
+		{subject: "test.block.connected", data: `{"height":1}`},
+		{subject: "test.mempool.added", data: `{"txid":"abcd"}`},
+	}
+	if !reflect.DeepEqual(s.messages, want) {
+		t.Errorf("messages = %+v, want %+v", s.messages, want)
+	}
+	if !strings.Contains(s.connect, `"user":"user","pass":"pass"`) {
+		t.Errorf("CONNECT %v, missing credentials", s.connect)
+	}
+}
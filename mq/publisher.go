@@ -0,0 +1,355 @@
+package mq
+
+import (
+	"blockbook/bchain"
+	"blockbook/common"
+	"blockbook/db"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+const (
+	// period of the check for new blocks and of the retry after a failure of the broker
+	publishPeriod = 10 * time.Second
+	// number of the last published blocks kept in the cursor to detect disconnected blocks
+	cursorBlocks = 100
+	// maximum number of mempool events waiting for publishing, the oldest events are dropped over the limit
+	maxMempoolEvents = 100000
+)
+
+// Publisher publishes the connected and disconnected blocks, mempool changes and address transactions to the message broker
+//
+// The blocks are published with at-least-once guarantee, the db cursor with the last published blocks is updated
+// only after the broker acknowledged all events of a block and after restart the publishing continues from the cursor.
+// The mempool events are kept only in memory and are retried until the broker acknowledges them.
+type Publisher struct {
+	broker         Broker
+	subject        string
+	coin           string
+	db             *db.RocksDB
+	chain          bchain.BlockChain
+	chainParser    bchain.BlockChainParser
+	mempool        bchain.Mempool
+	notify         chan struct{}
+	done           chan struct{}
+	finished       chan struct{}
+	closeOnce      sync.Once
+	mempoolMux     sync.Mutex
+	mempoolEvents  []*Event
+	mempoolTxs     map[string]struct{}
+	mempoolDropped int
+}
+
+// NewPublisher creates the publisher of events to the broker, the subject of a message is subject prefix followed by the event type
+func NewPublisher(broker Broker, subject string, d *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, is *common.InternalState) *Publisher {
+	return &Publisher{
+		broker:      broker,
+		subject:     subject,
+		coin:        is.Coin,
+		db:          d,
+		chain:       chain,
+		chainParser: chain.GetChainParser(),
+		mempool:     mempool,
+		notify:      make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		finished:    make(chan struct{}),
+	}
+}
+
+// Run starts the publishing loop
+func (p *Publisher) Run() {
+	go p.loop()
+}
+
+// Close stops the publishing loop and waits until it finishes
+func (p *Publisher) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		<-p.finished
+		if err := p.broker.Close(); err != nil {
+			glog.Error("mq: close ", err)
+		}
+	})
+}
+
+func (p *Publisher) loop() {
+	defer close(p.finished)
+	ticker := time.NewTicker(publishPeriod)
+	defer ticker.Stop()
+	for {
+		if err := p.publishBlocks(); err != nil {
+			glog.Error("mq: publishBlocks ", err)
+		} else if err = p.publishMempool(); err != nil {
+			glog.Error("mq: publishMempool ", err)
+		}
+		select {
+		case <-p.done:
+			return
+		case <-p.notify:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Publisher) wakeUp() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// OnNewBlock wakes up the publishing loop, the blocks themselves are read from db
+func (p *Publisher) OnNewBlock(hash string, height uint32) {
+	p.wakeUp()
+}
+
+// OnNewTxAddr queues the address event of the new mempool transaction
+func (p *Publisher) OnNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
+	a, _, err := p.chainParser.GetAddressesFromAddrDesc(desc)
+	if err != nil || len(a) == 0 {
+		return
+	}
+	p.queueMempoolEvents([]*Event{p.newEvent(EventAddressTx, &Event{Txid: tx.Txid, Address: a[0]})})
+}
+
+// OnMempoolResync queues the events about the transactions added and removed from mempool since the last resync
+func (p *Publisher) OnMempoolResync() {
+	entries := p.mempool.GetAllEntries()
+	txs := make(map[string]struct{}, len(entries))
+	var events []*Event
+	p.mempoolMux.Lock()
+	for _, e := range entries {
+		txs[e.Txid] = struct{}{}
+		if _, found := p.mempoolTxs[e.Txid]; !found {
+			events = append(events, p.newEvent(EventMempoolAdded, &Event{Txid: e.Txid, Time: int64(e.Time)}))
+		}
+	}
+	for txid := range p.mempoolTxs {
+		if _, found := txs[txid]; !found {
+			events = append(events, p.newEvent(EventMempoolRemoved, &Event{Txid: txid}))
+		}
+	}
+	p.mempoolTxs = txs
+	p.mempoolMux.Unlock()
+	p.queueMempoolEvents(events)
+	p.wakeUp()
+}
+
+func (p *Publisher) newEvent(t string, e *Event) *Event {
+	e.Version = SchemaVersion
+	e.Type = t
+	e.Coin = p.coin
+	return e
+}
+
+func (p *Publisher) queueMempoolEvents(events []*Event) {
+	if len(events) == 0 {
+		return
+	}
+	p.mempoolMux.Lock()
+	p.mempoolEvents = append(p.mempoolEvents, events...)
+	if over := len(p.mempoolEvents) - maxMempoolEvents; over > 0 {
+		p.mempoolEvents = p.mempoolEvents[over:]
+		p.mempoolDropped += over
+	}
+	p.mempoolMux.Unlock()
+}
+
+func (p *Publisher) publish(e *Event) error {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return p.broker.Publish(p.subject+"."+e.Type, buf)
+}
+
+func (p *Publisher) publishEvents(events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	for _, e := range events {
+		if err := p.publish(e); err != nil {
+			return err
+		}
+	}
+	return p.broker.Flush()
+}
+
+// publishMempool publishes the queued mempool events, the events are returned to the queue if the broker fails
+func (p *Publisher) publishMempool() error {
+	p.mempoolMux.Lock()
+	events := p.mempoolEvents
+	p.mempoolEvents = nil
+	if p.mempoolDropped > 0 {
+		glog.Warning("mq: dropped ", p.mempoolDropped, " mempool events over limit")
+		p.mempoolDropped = 0
+	}
+	p.mempoolMux.Unlock()
+	if err := p.publishEvents(events); err != nil {
+		p.mempoolMux.Lock()
+		p.mempoolEvents = append(events, p.mempoolEvents...)
+		p.mempoolMux.Unlock()
+		return err
+	}
+	return nil
+}
+
+// publishBlocks publishes the blocks disconnected and connected since the last published block stored in the cursor
+func (p *Publisher) publishBlocks() error {
+	cursor, err := p.db.GetMQCursor()
+	if err != nil {
+		return err
+	}
+	if cursor == nil || len(cursor.Blocks) == 0 {
+		// the first run, the publishing starts from the current best block
+		height, hash, err := p.db.GetBestBlock()
+		if err != nil || hash == "" {
+			return err
+		}
+		glog.Info("mq: starting to publish after block ", height)
+		return p.db.StoreMQCursor(&db.MQCursor{Blocks: []db.MQCursorBlock{{Height: height, Hash: hash}}})
+	}
+	oldest := cursor.Blocks[0].Height
+	// disconnected blocks are published from the highest one
+	for len(cursor.Blocks) > 0 {
+		last := cursor.Blocks[len(cursor.Blocks)-1]
+		bi, err := p.db.GetBlockInfo(last.Height)
+		if err != nil {
+			return err
+		}
+		if bi != nil && bi.Hash == last.Hash {
+			break
+		}
+		if err = p.publishEvents([]*Event{p.newEvent(EventBlockDisconnected, &Event{Height: last.Height, Hash: last.Hash})}); err != nil {
+			return err
+		}
+		cursor.Blocks = cursor.Blocks[:len(cursor.Blocks)-1]
+		if err = p.db.StoreMQCursor(cursor); err != nil {
+			return err
+		}
+	}
+	bestHeight, _, err := p.db.GetBestBlock()
+	if err != nil {
+		return err
+	}
+	if len(cursor.Blocks) == 0 {
+		// the fork point is below the blocks kept in the cursor, the blocks are republished from the oldest height of the cursor
+		base := oldest
+		if base > 0 {
+			base--
+		}
+		if base > bestHeight {
+			base = bestHeight
+		}
+		bi, err := p.db.GetBlockInfo(base)
+		if err != nil {
+			return err
+		}
+		if bi == nil {
+			return errors.Errorf("mq: missing block %v", base)
+		}
+		glog.Warning("mq: fork deeper than ", cursorBlocks, " published blocks, republishing from block ", base+1)
+		cursor.Blocks = []db.MQCursorBlock{{Height: base, Hash: bi.Hash}}
+		if err = p.db.StoreMQCursor(cursor); err != nil {
+			return err
+		}
+	}
+	for height := cursor.Blocks[len(cursor.Blocks)-1].Height + 1; height <= bestHeight; height++ {
+		select {
+		case <-p.done:
+			return nil
+		default:
+		}
+		bi, err := p.db.GetBlockInfo(height)
+		if err != nil {
+			return err
+		}
+		if bi == nil {
+			// the block was disconnected in the meantime
+			break
+		}
+		events, err := p.blockEvents(height, bi)
+		if err != nil {
+			return err
+		}
+		if err = p.publishEvents(events); err != nil {
+			return err
+		}
+		cursor.Blocks = append(cursor.Blocks, db.MQCursorBlock{Height: height, Hash: bi.Hash})
+		if len(cursor.Blocks) > cursorBlocks {
+			cursor.Blocks = cursor.Blocks[len(cursor.Blocks)-cursorBlocks:]
+		}
+		if err = p.db.StoreMQCursor(cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockEvents returns the event of the connected block followed by the address events of its transactions
+func (p *Publisher) blockEvents(height uint32, bi *db.BlockInfo) ([]*Event, error) {
+	block, err := p.chain.GetBlock(bi.Hash, height)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlock %v %v", height, bi.Hash)
+	}
+	events := []*Event{p.newEvent(EventBlockConnected, &Event{Height: height, Hash: bi.Hash, Time: bi.Time, Txs: len(block.Txs)})}
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		addrDescs, err := p.txAddrDescs(tx)
+		if err != nil {
+			return nil, err
+		}
+		unique := make(map[string]struct{}, len(addrDescs))
+		for _, ad := range addrDescs {
+			a, _, err := p.chainParser.GetAddressesFromAddrDesc(ad)
+			if err != nil || len(a) == 0 {
+				continue
+			}
+			if _, found := unique[a[0]]; found {
+				continue
+			}
+			unique[a[0]] = struct{}{}
+			events = append(events, p.newEvent(EventAddressTx, &Event{Height: height, Hash: bi.Hash, Txid: tx.Txid, Address: a[0]}))
+		}
+	}
+	return events, nil
+}
+
+// txAddrDescs returns address descriptors of the inputs and outputs of the transaction,
+// for Bitcoin type coins the input addresses are read from the stored tx addresses
+func (p *Publisher) txAddrDescs(tx *bchain.Tx) ([]bchain.AddressDescriptor, error) {
+	var r []bchain.AddressDescriptor
+	if p.chainParser.GetChainType() == bchain.ChainBitcoinType {
+		ta, err := p.db.GetTxAddresses(tx.Txid)
+		if err != nil {
+			return nil, err
+		}
+		if ta != nil {
+			for i := range ta.Inputs {
+				r = append(r, ta.Inputs[i].AddrDesc)
+			}
+			for i := range ta.Outputs {
+				r = append(r, ta.Outputs[i].AddrDesc)
+			}
+			return r, nil
+		}
+	} else {
+		for i := range tx.Vin {
+			for _, a := range tx.Vin[i].Addresses {
+				if ad, err := p.chainParser.GetAddrDescFromAddress(a); err == nil {
+					r = append(r, ad)
+				}
+			}
+		}
+	}
+	for i := range tx.Vout {
+		if ad, err := p.chainParser.GetAddrDescFromVout(&tx.Vout[i]); err == nil {
+			r = append(r, ad)
+		}
+	}
+	return r, nil
+}
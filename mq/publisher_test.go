@@ -0,0 +1,187 @@
+// +build unittest
+
+package mq
+
+import (
+	"blockbook/bchain/coins/btc"
+	"blockbook/db"
+	"blockbook/tests/dbtestdata"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/juju/errors"
+)
+
+// fakeBroker is a stand-in of the message broker, the messages are acknowledged by Flush unless fail is set
+type fakeBroker struct {
+	t         *testing.T
+	fail      bool
+	pending   []Event
+	published []Event
+}
+
+func (b *fakeBroker) Publish(subject string, data []byte) error {
+	var e Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		b.t.Fatal(err)
+	}
+	if subject != "test."+e.Type {
+		b.t.Errorf("Publish subject %v, event type %v", subject, e.Type)
+	}
+	b.pending = append(b.pending, e)
+	return nil
+}
+
+func (b *fakeBroker) Flush() error {
+	if b.fail {
+		b.pending = nil
+		return errors.New("broker failure")
+	}
+	b.published = append(b.published, b.pending...)
+	b.pending = nil
+	return nil
+}
+
+func (b *fakeBroker) Close() error {
+	return nil
+}
+
+func (b *fakeBroker) take() []Event {
+	r := b.published
+	b.published = nil
+	return r
+}
+
+func TestPublisher_publishBlocks(t *testing.T) {
+	parser := btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1})
+	tmp, err := ioutil.TempDir("", "testdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	d, err := db.NewRocksDB(tmp, 100000, -1, parser, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	is, err := d.LoadInternalState("fakecoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetInternalState(is)
+	chain, err := dbtestdata.NewFakeBlockChain(parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mempool, err := chain.CreateMempool(chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block1 := dbtestdata.GetTestBitcoinTypeBlock1(parser)
+	block2 := dbtestdata.GetTestBitcoinTypeBlock2(parser)
+	broker := &fakeBroker{t: t}
+	newPublisher := func() *Publisher {
+		return NewPublisher(broker, "test", d, chain, mempool, is)
+	}
+	checkCursor := func(want ...db.MQCursorBlock) {
+		c, err := d.GetMQCursor()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c.Blocks, want) {
+			t.Errorf("cursor = %+v, want %+v", c.Blocks, want)
+		}
+	}
+	b1 := db.MQCursorBlock{Height: block1.Height, Hash: block1.Hash}
+	b2 := db.MQCursorBlock{Height: block2.Height, Hash: block2.Hash}
+
+	if err = d.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	// the first run starts publishing after the best block
+	p := newPublisher()
+	if err = p.publishBlocks(); err != nil {
+		t.Fatal(err)
+	}
+	if got := broker.take(); len(got) != 0 {
+		t.Errorf("first run published %+v", got)
+	}
+	checkCursor(b1)
+
+	if err = d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	// the broker fails, the cursor must stay
+	broker.fail = true
+	if err = p.publishBlocks(); err == nil {
+		t.Error("publishBlocks with failing broker, expected error")
+	}
+	checkCursor(b1)
+
+	// restart of the publisher, the block is published again
+	broker.fail = false
+	p = newPublisher()
+	if err = p.publishBlocks(); err != nil {
+		t.Fatal(err)
+	}
+	got := broker.take()
+	want := []Event{
+		{Version: SchemaVersion, Type: EventBlockConnected, Coin: "fakecoin", Height: block2.Height, Hash: block2.Hash, Time: block2.Time, Txs: 4},
+		{Version: SchemaVersion, Type: EventAddressTx, Coin: "fakecoin", Height: block2.Height, Hash: block2.Hash, Txid: dbtestdata.TxidB2T1, Address: dbtestdata.Addr3},
+		{Version: SchemaVersion, Type: EventAddressTx, Coin: "fakecoin", Height: block2.Height, Hash: block2.Hash, Txid: dbtestdata.TxidB2T1, Address: dbtestdata.Addr2},
+		{Version: SchemaVersion, Type: EventAddressTx, Coin: "fakecoin", Height: block2.Height, Hash: block2.Hash, Txid: dbtestdata.TxidB2T1, Address: dbtestdata.Addr6},
+		{Version: SchemaVersion, Type: EventAddressTx, Coin: "fakecoin", Height: block2.Height, Hash: block2.Hash, Txid: dbtestdata.TxidB2T1, Address: dbtestdata.Addr7},
+	}
+	if len(got) < len(want) || !reflect.DeepEqual(got[:len(want)], want) {
+		t.Errorf("published %+v, want prefix %+v", got, want)
+	}
+	checkCursor(b1, b2)
+
+	// nothing new to publish
+	if err = p.publishBlocks(); err != nil {
+		t.Fatal(err)
+	}
+	if got = broker.take(); len(got) != 0 {
+		t.Errorf("published %+v, want nothing", got)
+	}
+
+	// disconnected block
+	if err = d.DisconnectBlockRangeBitcoinType(block2.Height, block2.Height); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.publishBlocks(); err != nil {
+		t.Fatal(err)
+	}
+	got = broker.take()
+	want = []Event{
+		{Version: SchemaVersion, Type: EventBlockDisconnected, Coin: "fakecoin", Height: block2.Height, Hash: block2.Hash},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("published %+v, want %+v", got, want)
+	}
+	checkCursor(b1)
+
+	// fork deeper than the cursor, the blocks are republished from the oldest height of the cursor
+	if err = d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	forked := db.MQCursorBlock{Height: block2.Height, Hash: "0000000000000000000000000000000000000000000000000000000000000001"}
+	if err = d.StoreMQCursor(&db.MQCursor{Blocks: []db.MQCursorBlock{forked}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.publishBlocks(); err != nil {
+		t.Fatal(err)
+	}
+	got = broker.take()
+	want = []Event{
+		{Version: SchemaVersion, Type: EventBlockDisconnected, Coin: "fakecoin", Height: forked.Height, Hash: forked.Hash},
+		{Version: SchemaVersion, Type: EventBlockConnected, Coin: "fakecoin", Height: block2.Height, Hash: block2.Hash, Time: block2.Time, Txs: 4},
+	}
+	if len(got) < len(want) || !reflect.DeepEqual(got[:len(want)], want) {
+		t.Errorf("published %+v, want prefix %+v", got, want)
+	}
+	checkCursor(b1, b2)
+}
@@ -0,0 +1,1011 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain"
+	"blockbook/common"
+	"blockbook/db"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+const (
+	electrumProtocolVersion = "1.4"
+	// maximum length of a request line, a batch of requests is on one line,
+	// the line fits a hex encoded transaction of the maximum standard weight
+	electrumMaxLineLength = 1024 * 1024
+	// maximum number of requests in a batch
+	electrumMaxBatchRequests = 100
+	// maximum number of connected clients, new connections above the limit are closed
+	electrumMaxClients = 1000
+	// connection without any request for this time is closed, clients send server.ping to keep the connection
+	electrumIdleTimeout  = 10 * time.Minute
+	electrumWriteTimeout = 60 * time.Second
+	// maximum number of headers returned by blockchain.block.headers
+	electrumMaxHeaders = 2016
+	// maximum number of scripthash subscriptions of one client
+	electrumMaxSubscriptions = 50000
+	// addresses seen in mempool but not indexed in a block are forgotten after this time
+	electrumMempoolScripthashExpiry = 14 * 24 * time.Hour
+	// default minimum relay fee of Bitcoin Core in coins per kB, the backend relay fee is not available through bchain.BlockChain
+	electrumRelayFee = 0.00001
+)
+
+// error codes of the JSON-RPC protocol and of the Electrum protocol
+const (
+	electrumErrParse          = -32700
+	electrumErrInvalidRequest = -32600
+	electrumErrMethodNotFound = -32601
+	electrumErrInvalidParams  = -32602
+	electrumErrBadRequest     = 1
+)
+
+type electrumReq struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type electrumError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *electrumError) Error() string {
+	return e.Message
+}
+
+type electrumRes struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *electrumError  `json:"error,omitempty"`
+}
+
+type electrumNotification struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type electrumHeader struct {
+	Height uint32 `json:"height"`
+	Hex    string `json:"hex"`
+}
+
+type electrumClient struct {
+	id        uint64
+	conn      net.Conn
+	out       chan []byte
+	ip        string
+	alive     bool
+	aliveLock sync.Mutex
+	// headers and scripthashes are guarded by subscriptionsLock of the server
+	headers bool
+	// last notified status by subscribed scripthash
+	scripthashes map[string]string
+}
+
+// electrumMempoolScripthash is an address seen in mempool, which may not be in the scripthash index yet
+type electrumMempoolScripthash struct {
+	addrDesc bchain.AddressDescriptor
+	seen     time.Time
+}
+
+// ElectrumServer is a handle to the server of the Electrum protocol
+//
+// The scripthashes of the Electrum protocol are resolved to the address descriptors by the scripthash index in db,
+// the addresses which are only in mempool are resolved by an in-memory map filled from the mempool notifications.
+// The scripthashes of the mempool transactions are remembered so that their subscribers are notified
+// when the transactions leave the mempool, confirmed or not.
+type ElectrumServer struct {
+	binding                 string
+	certFiles               string
+	listener                net.Listener
+	db                      *db.RocksDB
+	txCache                 *db.TxCache
+	chain                   bchain.BlockChain
+	chainParser             bchain.BlockChainParser
+	mempool                 bchain.Mempool
+	metrics                 *common.Metrics
+	is                      *common.InternalState
+	api                     *api.Worker
+	block0hash              string
+	closed                  int32
+	clients                 map[*electrumClient]struct{}
+	subscriptions           map[string]map[*electrumClient]struct{}
+	subscriptionsLock       sync.Mutex
+	mempoolScripthashes     map[string]electrumMempoolScripthash
+	mempoolTxs              map[string][]string
+	mempoolScripthashesLock sync.Mutex
+	pending                 map[string]struct{}
+	pendingLock             sync.Mutex
+	notify                  chan struct{}
+	done                    chan struct{}
+}
+
+// NewElectrumServer creates new Electrum protocol server listening on binding, with TLS if certFiles is set
+func NewElectrumServer(binding, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState) (*ElectrumServer, error) {
+	if chain.GetChainParser().GetChainType() != bchain.ChainBitcoinType {
+		return nil, errors.New("Electrum protocol is supported only for Bitcoin type coins")
+	}
+	api, err := api.NewWorker(db, chain, mempool, txCache, is)
+	if err != nil {
+		return nil, err
+	}
+	b0, err := db.GetBlockHash(0)
+	if err != nil {
+		return nil, err
+	}
+	s := &ElectrumServer{
+		binding:             binding,
+		certFiles:           certFiles,
+		db:                  db,
+		txCache:             txCache,
+		chain:               chain,
+		chainParser:         chain.GetChainParser(),
+		mempool:             mempool,
+		metrics:             metrics,
+		is:                  is,
+		api:                 api,
+		block0hash:          b0,
+		clients:             make(map[*electrumClient]struct{}),
+		subscriptions:       make(map[string]map[*electrumClient]struct{}),
+		mempoolScripthashes: make(map[string]electrumMempoolScripthash),
+		mempoolTxs:          make(map[string][]string),
+		pending:             make(map[string]struct{}),
+		notify:              make(chan struct{}, 1),
+		done:                make(chan struct{}),
+	}
+	return s, nil
+}
+
+// Run starts to accept the connections, it returns after Close
+func (s *ElectrumServer) Run() error {
+	l, err := net.Listen("tcp", s.binding)
+	if err != nil {
+		return err
+	}
+	if s.certFiles != "" {
+		cert, err := tls.LoadX509KeyPair(fmt.Sprint(s.certFiles, ".crt"), fmt.Sprint(s.certFiles, ".key"))
+		if err != nil {
+			l.Close()
+			return err
+		}
+		l = tls.NewListener(l, &tls.Config{Certificates: []tls.Certificate{cert}})
+		glog.Info("electrum server: starting to listen on ssl://", s.binding)
+	} else {
+		glog.Info("electrum server: starting to listen on tcp://", s.binding)
+	}
+	s.subscriptionsLock.Lock()
+	if atomic.LoadInt32(&s.closed) != 0 {
+		s.subscriptionsLock.Unlock()
+		l.Close()
+		return nil
+	}
+	s.listener = l
+	s.subscriptionsLock.Unlock()
+	go s.notifyLoop()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if atomic.LoadInt32(&s.closed) != 0 {
+				return nil
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				glog.Warning("electrum server: accept ", err)
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go s.serve(conn)
+	}
+}
+
+// Close stops the server and closes all client connections
+func (s *ElectrumServer) Close() error {
+	if !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return nil
+	}
+	glog.Infof("electrum server: closing")
+	close(s.done)
+	s.subscriptionsLock.Lock()
+	l := s.listener
+	clients := make([]*electrumClient, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.subscriptionsLock.Unlock()
+	for _, c := range clients {
+		s.closeClient(c)
+	}
+	if l != nil {
+		return l.Close()
+	}
+	return nil
+}
+
+func (s *ElectrumServer) serve(conn net.Conn) {
+	c := &electrumClient{
+		id:           atomic.AddUint64(&connectionCounter, 1),
+		conn:         conn,
+		out:          make(chan []byte, outChannelSize),
+		ip:           conn.RemoteAddr().String(),
+		alive:        true,
+		scripthashes: make(map[string]string),
+	}
+	s.subscriptionsLock.Lock()
+	if len(s.clients) >= electrumMaxClients {
+		s.subscriptionsLock.Unlock()
+		glog.Warning("Electrum client ", c.ip, " rejected, too many clients")
+		conn.Close()
+		return
+	}
+	s.clients[c] = struct{}{}
+	s.subscriptionsLock.Unlock()
+	glog.Info("Electrum client connected ", c.id, ", ", c.ip)
+	s.metrics.ElectrumClients.Inc()
+	go s.outputLoop(c)
+	s.inputLoop(c)
+}
+
+func (s *ElectrumServer) closeClient(c *electrumClient) {
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+	if c.alive {
+		c.conn.Close()
+		c.alive = false
+		close(c.out)
+		s.subscriptionsLock.Lock()
+		for sh := range c.scripthashes {
+			s.unsubscribeScripthashLocked(c, sh)
+		}
+		delete(s.clients, c)
+		s.subscriptionsLock.Unlock()
+		glog.Info("Electrum client disconnected ", c.id, ", ", c.ip)
+		s.metrics.ElectrumClients.Dec()
+	}
+}
+
+// send queues the message to the client, the client which does not read its messages is disconnected
+func (s *ElectrumServer) send(c *electrumClient, m []byte) {
+	c.aliveLock.Lock()
+	if !c.alive {
+		c.aliveLock.Unlock()
+		return
+	}
+	select {
+	case c.out <- m:
+		c.aliveLock.Unlock()
+	default:
+		c.aliveLock.Unlock()
+		glog.Error("Electrum client ", c.id, " output channel full, closing")
+		s.closeClient(c)
+	}
+}
+
+func (s *ElectrumServer) inputLoop(c *electrumClient) {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("recovered from panic: ", r, ", ", c.id)
+			debug.PrintStack()
+		}
+		s.closeClient(c)
+	}()
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), electrumMaxLineLength)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(electrumIdleTimeout))
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				glog.V(1).Info("Electrum client ", c.id, " read error ", err)
+			}
+			return
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if r := s.onLine(c, line); r != nil {
+			s.send(c, append(r, '\n'))
+		}
+	}
+}
+
+func (s *ElectrumServer) outputLoop(c *electrumClient) {
+	w := bufio.NewWriter(c.conn)
+	for m := range c.out {
+		c.conn.SetWriteDeadline(time.Now().Add(electrumWriteTimeout))
+		_, err := w.Write(m)
+		// write out the buffer if there are no more messages waiting
+		if err == nil && len(c.out) == 0 {
+			err = w.Flush()
+		}
+		if err != nil {
+			glog.Error("Error sending message to electrum client ", c.id, ", ", err)
+			s.closeClient(c)
+			// drain the channel until it is closed
+			for range c.out {
+			}
+			return
+		}
+	}
+}
+
+// onLine processes one request or a batch of requests and returns the serialized response
+func (s *ElectrumServer) onLine(c *electrumClient, line []byte) []byte {
+	var buf []byte
+	var err error
+	if line[0] == '[' {
+		var reqs []electrumReq
+		if err = json.Unmarshal(line, &reqs); err != nil {
+			buf, err = json.Marshal(s.errorResponse(nil, &electrumError{Code: electrumErrParse, Message: "Parse error"}))
+		} else if len(reqs) == 0 {
+			buf, err = json.Marshal(s.errorResponse(nil, &electrumError{Code: electrumErrInvalidRequest, Message: "Empty batch"}))
+		} else if len(reqs) > electrumMaxBatchRequests {
+			buf, err = json.Marshal(s.errorResponse(nil, &electrumError{Code: electrumErrInvalidRequest, Message: fmt.Sprintf("Too many requests in batch, maximum is %d", electrumMaxBatchRequests)}))
+		} else {
+			res := make([]*electrumRes, len(reqs))
+			for i := range reqs {
+				res[i] = s.onRequest(c, &reqs[i])
+			}
+			buf, err = json.Marshal(res)
+		}
+	} else {
+		var req electrumReq
+		if err = json.Unmarshal(line, &req); err != nil {
+			buf, err = json.Marshal(s.errorResponse(nil, &electrumError{Code: electrumErrParse, Message: "Parse error"}))
+		} else {
+			buf, err = json.Marshal(s.onRequest(c, &req))
+		}
+	}
+	if err != nil {
+		glog.Error("Electrum client ", c.id, " marshal response error ", err)
+		return nil
+	}
+	return buf
+}
+
+func (s *ElectrumServer) errorResponse(id json.RawMessage, e *electrumError) *electrumRes {
+	return &electrumRes{JSONRPC: "2.0", ID: id, Error: e}
+}
+
+func (s *ElectrumServer) onRequest(c *electrumClient, req *electrumReq) (res *electrumRes) {
+	var err error
+	var data interface{}
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("Electrum client ", c.id, ", onRequest ", req.Method, " recovered from panic: ", r)
+			debug.PrintStack()
+			res = s.errorResponse(req.ID, &electrumError{Code: electrumErrBadRequest, Message: "Internal error"})
+		}
+	}()
+	var params []json.RawMessage
+	if len(req.Params) > 0 && !bytes.Equal(req.Params, []byte("null")) {
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			err = &electrumError{Code: electrumErrInvalidParams, Message: "Params must be an array"}
+		}
+	}
+	if err == nil {
+		f, ok := electrumHandlers[req.Method]
+		if ok {
+			data, err = f(s, c, params)
+		} else {
+			err = &electrumError{Code: electrumErrMethodNotFound, Message: fmt.Sprintf("Unknown method '%v'", req.Method)}
+		}
+	}
+	if err == nil {
+		var result []byte
+		if result, err = json.Marshal(data); err == nil {
+			glog.V(1).Info("Electrum client ", c.id, " onRequest ", req.Method, " success")
+			s.metrics.ElectrumRequests.With(common.Labels{"method": req.Method, "status": "success"}).Inc()
+			return &electrumRes{JSONRPC: "2.0", ID: req.ID, Result: result}
+		}
+	}
+	e, ok := err.(*electrumError)
+	if !ok {
+		glog.Error("Electrum client ", c.id, " onRequest ", req.Method, ": ", errors.ErrorStack(err))
+		e = &electrumError{Code: electrumErrBadRequest, Message: err.Error()}
+	}
+	if e.Code == electrumErrMethodNotFound {
+		// do not create metrics labels from unknown methods
+		s.metrics.ElectrumRequests.With(common.Labels{"method": "unknown", "status": "failure"}).Inc()
+	} else {
+		s.metrics.ElectrumRequests.With(common.Labels{"method": req.Method, "status": "failure"}).Inc()
+	}
+	return s.errorResponse(req.ID, e)
+}
+
+// electrumParam unmarshals the positional parameter i to v, missing optional parameter keeps the value of v
+func electrumParam(params []json.RawMessage, i int, v interface{}, required bool) error {
+	if i >= len(params) || bytes.Equal(params[i], []byte("null")) {
+		if required {
+			return &electrumError{Code: electrumErrInvalidParams, Message: fmt.Sprintf("Missing parameter %d", i)}
+		}
+		return nil
+	}
+	if err := json.Unmarshal(params[i], v); err != nil {
+		return &electrumError{Code: electrumErrInvalidParams, Message: fmt.Sprintf("Invalid parameter %d", i)}
+	}
+	return nil
+}
+
+// parseElectrumScripthash converts the scripthash in the reversed hex format of the Electrum protocol to the key of the scripthash index
+func parseElectrumScripthash(scripthash string) ([]byte, error) {
+	b, err := hex.DecodeString(scripthash)
+	if err != nil || len(b) != sha256.Size {
+		return nil, &electrumError{Code: electrumErrBadRequest, Message: fmt.Sprintf("Invalid scripthash '%v'", scripthash)}
+	}
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b, nil
+}
+
+// electrumScripthash converts the key of the scripthash index to the reversed hex format of the Electrum protocol
+func electrumScripthash(sh []byte) string {
+	b := make([]byte, len(sh))
+	for i := range sh {
+		b[len(sh)-1-i] = sh[i]
+	}
+	return hex.EncodeToString(b)
+}
+
+// electrumStatus computes the status of the scripthash from its history, empty history has null status
+func electrumStatus(history []api.ElectrumHistoryItem) string {
+	if len(history) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for i := range history {
+		fmt.Fprintf(h, "%s:%d:", history[i].Txid, history[i].Height)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func electrumStatusResult(status string) interface{} {
+	if status == "" {
+		return nil
+	}
+	return status
+}
+
+// getAddrDesc returns the address descriptor of the scripthash or nil if the scripthash was never seen
+func (s *ElectrumServer) getAddrDesc(sh []byte) (bchain.AddressDescriptor, error) {
+	addrDesc, err := s.db.GetScripthashAddrDesc(sh)
+	if err != nil || addrDesc != nil {
+		return addrDesc, err
+	}
+	s.mempoolScripthashesLock.Lock()
+	defer s.mempoolScripthashesLock.Unlock()
+	if m, found := s.mempoolScripthashes[string(sh)]; found {
+		return m.addrDesc, nil
+	}
+	return nil, nil
+}
+
+func (s *ElectrumServer) getHistory(sh []byte) ([]api.ElectrumHistoryItem, error) {
+	addrDesc, err := s.getAddrDesc(sh)
+	if err != nil {
+		return nil, err
+	}
+	if addrDesc == nil {
+		return []api.ElectrumHistoryItem{}, nil
+	}
+	return s.api.GetElectrumHistory(addrDesc)
+}
+
+func (s *ElectrumServer) scripthashParam(params []json.RawMessage) ([]byte, error) {
+	var scripthash string
+	if err := electrumParam(params, 0, &scripthash, true); err != nil {
+		return nil, err
+	}
+	return parseElectrumScripthash(scripthash)
+}
+
+// getHeader returns the hex encoded header stored in the db, the backend is used if the header is not stored yet
+func (s *ElectrumServer) getHeader(height uint32) (string, error) {
+	if h, err := s.db.GetBlockHeaderRaw(height); err == nil && h != nil {
+		return hex.EncodeToString(h), nil
+	}
+	hash, err := s.db.GetBlockHash(height)
+	if err != nil {
+		return "", err
+	}
+	if hash == "" {
+		return "", &electrumError{Code: electrumErrBadRequest, Message: fmt.Sprintf("Height %d out of range", height)}
+	}
+	return s.chain.GetBlockHeaderRaw(hash)
+}
+
+func (s *ElectrumServer) getBestHeader() (*electrumHeader, error) {
+	height, _, err := s.db.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+	h, err := s.getHeader(height)
+	if err != nil {
+		return nil, err
+	}
+	return &electrumHeader{Height: height, Hex: h}, nil
+}
+
+// checkpoints are not supported, the only accepted cp_height is 0
+func checkElectrumCpHeight(params []json.RawMessage, i int) error {
+	var cpHeight uint32
+	if err := electrumParam(params, i, &cpHeight, false); err != nil {
+		return err
+	}
+	if cpHeight != 0 {
+		return &electrumError{Code: electrumErrBadRequest, Message: "Checkpoints are not supported"}
+	}
+	return nil
+}
+
+var electrumHandlers = map[string]func(*ElectrumServer, *electrumClient, []json.RawMessage) (interface{}, error){
+	"server.version": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		return []string{"Blockbook " + common.GetVersionInfo().Version, electrumProtocolVersion}, nil
+	},
+	"server.banner": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		return "Blockbook " + common.GetVersionInfo().Version + ", " + s.is.Coin, nil
+	},
+	"server.ping": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		return nil, nil
+	},
+	"server.donation_address": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		return "", nil
+	},
+	"server.peers.subscribe": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		return []interface{}{}, nil
+	},
+	"server.features": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{
+			"genesis_hash":   s.block0hash,
+			"hosts":          map[string]interface{}{},
+			"protocol_max":   electrumProtocolVersion,
+			"protocol_min":   electrumProtocolVersion,
+			"pruning":        nil,
+			"server_version": "Blockbook " + common.GetVersionInfo().Version,
+			"hash_function":  "sha256",
+		}, nil
+	},
+	"blockchain.headers.subscribe": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		h, err := s.getBestHeader()
+		if err != nil {
+			return nil, err
+		}
+		s.subscriptionsLock.Lock()
+		c.headers = true
+		s.subscriptionsLock.Unlock()
+		return h, nil
+	},
+	"blockchain.block.header": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		var height uint32
+		if err := electrumParam(params, 0, &height, true); err != nil {
+			return nil, err
+		}
+		if err := checkElectrumCpHeight(params, 1); err != nil {
+			return nil, err
+		}
+		return s.getHeader(height)
+	},
+	"blockchain.block.headers": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		var start, count uint32
+		if err := electrumParam(params, 0, &start, true); err != nil {
+			return nil, err
+		}
+		if err := electrumParam(params, 1, &count, true); err != nil {
+			return nil, err
+		}
+		if err := checkElectrumCpHeight(params, 2); err != nil {
+			return nil, err
+		}
+		if count > electrumMaxHeaders {
+			count = electrumMaxHeaders
+		}
+		bestHeight, _, err := s.db.GetBestBlock()
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		var n uint32
+		for ; n < count && start+n <= bestHeight; n++ {
+			h, err := s.getHeader(start + n)
+			if err != nil {
+				return nil, err
+			}
+			buf.WriteString(h)
+		}
+		return map[string]interface{}{"count": n, "hex": buf.String(), "max": electrumMaxHeaders}, nil
+	},
+	"blockchain.scripthash.get_balance": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		sh, err := s.scripthashParam(params)
+		if err != nil {
+			return nil, err
+		}
+		addrDesc, err := s.getAddrDesc(sh)
+		if err != nil || addrDesc == nil {
+			return &api.ElectrumBalance{}, err
+		}
+		return s.api.GetElectrumBalance(addrDesc)
+	},
+	"blockchain.scripthash.get_history": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		sh, err := s.scripthashParam(params)
+		if err != nil {
+			return nil, err
+		}
+		return s.getHistory(sh)
+	},
+	"blockchain.scripthash.get_mempool": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		sh, err := s.scripthashParam(params)
+		if err != nil {
+			return nil, err
+		}
+		history, err := s.getHistory(sh)
+		if err != nil {
+			return nil, err
+		}
+		r := make([]api.ElectrumHistoryItem, 0)
+		for i := range history {
+			if history[i].Height <= 0 {
+				r = append(r, history[i])
+			}
+		}
+		return r, nil
+	},
+	"blockchain.scripthash.listunspent": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		sh, err := s.scripthashParam(params)
+		if err != nil {
+			return nil, err
+		}
+		addrDesc, err := s.getAddrDesc(sh)
+		if err != nil || addrDesc == nil {
+			return []api.ElectrumUtxo{}, err
+		}
+		return s.api.GetElectrumUtxo(addrDesc)
+	},
+	"blockchain.scripthash.subscribe": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		sh, err := s.scripthashParam(params)
+		if err != nil {
+			return nil, err
+		}
+		history, err := s.getHistory(sh)
+		if err != nil {
+			return nil, err
+		}
+		status := electrumStatus(history)
+		if err = s.subscribeScripthash(c, sh, status); err != nil {
+			return nil, err
+		}
+		return electrumStatusResult(status), nil
+	},
+	"blockchain.scripthash.unsubscribe": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		sh, err := s.scripthashParam(params)
+		if err != nil {
+			return nil, err
+		}
+		s.subscriptionsLock.Lock()
+		defer s.subscriptionsLock.Unlock()
+		_, found := c.scripthashes[string(sh)]
+		if found {
+			s.unsubscribeScripthashLocked(c, string(sh))
+		}
+		return found, nil
+	},
+	"blockchain.transaction.get": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		var txid string
+		var verbose bool
+		if err := electrumParam(params, 0, &txid, true); err != nil {
+			return nil, err
+		}
+		if err := electrumParam(params, 1, &verbose, false); err != nil {
+			return nil, err
+		}
+		// the verbose transaction as returned by the backend contains also the serialized transaction
+		tx, err := s.chain.GetTransactionSpecific(&bchain.Tx{Txid: txid})
+		if err != nil {
+			if err == bchain.ErrTxNotFound {
+				return nil, &electrumError{Code: electrumErrBadRequest, Message: fmt.Sprintf("Transaction '%v' not found", txid)}
+			}
+			return nil, err
+		}
+		if verbose {
+			return tx, nil
+		}
+		var raw struct {
+			Hex string `json:"hex"`
+		}
+		if err = json.Unmarshal(tx, &raw); err != nil {
+			return nil, err
+		}
+		return raw.Hex, nil
+	},
+	"blockchain.transaction.broadcast": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		var tx string
+		if err := electrumParam(params, 0, &tx, true); err != nil {
+			return nil, err
+		}
+		return s.chain.SendRawTransaction(tx)
+	},
+	"blockchain.transaction.get_merkle": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		var txid string
+		var height uint32
+		if err := electrumParam(params, 0, &txid, true); err != nil {
+			return nil, err
+		}
+		if err := electrumParam(params, 1, &height, true); err != nil {
+			return nil, err
+		}
+		return s.api.GetElectrumMerkle(txid, height)
+	},
+	"blockchain.estimatefee": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		var blocks int
+		if err := electrumParam(params, 0, &blocks, true); err != nil {
+			return nil, err
+		}
+		fee, err := s.chain.EstimateSmartFee(blocks, true)
+		// -1 means that the fee could not be estimated
+		if err != nil || fee.Sign() <= 0 {
+			return -1, nil
+		}
+		return strconv.ParseFloat(s.chainParser.AmountToDecimalString(&fee), 64)
+	},
+	"blockchain.relayfee": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		return electrumRelayFee, nil
+	},
+	"mempool.get_fee_histogram": func(s *ElectrumServer, c *electrumClient, params []json.RawMessage) (interface{}, error) {
+		return s.api.GetElectrumFeeHistogram()
+	},
+}
+
+func (s *ElectrumServer) subscribeScripthash(c *electrumClient, sh []byte, status string) error {
+	s.subscriptionsLock.Lock()
+	defer s.subscriptionsLock.Unlock()
+	if _, found := c.scripthashes[string(sh)]; !found && len(c.scripthashes) >= electrumMaxSubscriptions {
+		return &electrumError{Code: electrumErrBadRequest, Message: fmt.Sprintf("Too many subscriptions, maximum is %d", electrumMaxSubscriptions)}
+	}
+	c.scripthashes[string(sh)] = status
+	cs, found := s.subscriptions[string(sh)]
+	if !found {
+		cs = make(map[*electrumClient]struct{})
+		s.subscriptions[string(sh)] = cs
+	}
+	cs[c] = struct{}{}
+	return nil
+}
+
+func (s *ElectrumServer) unsubscribeScripthashLocked(c *electrumClient, sh string) {
+	delete(c.scripthashes, sh)
+	if cs, found := s.subscriptions[sh]; found {
+		delete(cs, c)
+		if len(cs) == 0 {
+			delete(s.subscriptions, sh)
+		}
+	}
+}
+
+func (s *ElectrumServer) notification(method string, params ...interface{}) []byte {
+	buf, err := json.Marshal(&electrumNotification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		glog.Error("Electrum notification ", method, " marshal error ", err)
+		return nil
+	}
+	return append(buf, '\n')
+}
+
+// markPending schedules the check of the status of the subscribed scripthashes
+func (s *ElectrumServer) markPending(shs []string) {
+	if len(shs) == 0 {
+		return
+	}
+	s.pendingLock.Lock()
+	for _, sh := range shs {
+		s.pending[sh] = struct{}{}
+	}
+	s.pendingLock.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *ElectrumServer) notifyLoop() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.notify:
+		}
+		s.pendingLock.Lock()
+		pending := s.pending
+		s.pending = make(map[string]struct{})
+		s.pendingLock.Unlock()
+		for sh := range pending {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			s.notifyScripthash(sh)
+		}
+	}
+}
+
+// notifyScripthash sends the new status of the scripthash to the subscribed clients with a different last status
+func (s *ElectrumServer) notifyScripthash(sh string) {
+	s.subscriptionsLock.Lock()
+	_, found := s.subscriptions[sh]
+	s.subscriptionsLock.Unlock()
+	if !found {
+		return
+	}
+	history, err := s.getHistory([]byte(sh))
+	if err != nil {
+		glog.Error("Electrum getHistory error ", err, " for ", electrumScripthash([]byte(sh)))
+		return
+	}
+	status := electrumStatus(history)
+	var clients []*electrumClient
+	s.subscriptionsLock.Lock()
+	for c := range s.subscriptions[sh] {
+		if c.scripthashes[sh] != status {
+			c.scripthashes[sh] = status
+			clients = append(clients, c)
+		}
+	}
+	s.subscriptionsLock.Unlock()
+	if len(clients) > 0 {
+		m := s.notification("blockchain.scripthash.subscribe", electrumScripthash([]byte(sh)), electrumStatusResult(status))
+		for _, c := range clients {
+			s.send(c, m)
+		}
+	}
+}
+
+// OnNewBlock is a callback that sends the new header to the subscribed clients
+// and rechecks the subscribed scripthashes of the block transactions and of the transactions which left mempool
+func (s *ElectrumServer) OnNewBlock(hash string, height uint32) {
+	h, err := s.chain.GetBlockHeaderRaw(hash)
+	if err != nil {
+		glog.Error("Electrum GetBlockHeaderRaw error ", err, " for ", hash)
+	}
+	var clients []*electrumClient
+	s.subscriptionsLock.Lock()
+	for c := range s.clients {
+		if c.headers {
+			clients = append(clients, c)
+		}
+	}
+	s.subscriptionsLock.Unlock()
+	if err == nil && len(clients) > 0 {
+		m := s.notification("blockchain.headers.subscribe", &electrumHeader{Height: height, Hex: h})
+		for _, c := range clients {
+			s.send(c, m)
+		}
+	}
+	s.markPending(s.subscribedOnly(append(s.blockScripthashes(height), s.leftMempoolScripthashes()...)))
+	s.pruneMempoolScripthashes()
+}
+
+// blockScripthashes returns the scripthashes of the inputs and outputs of the block transactions,
+// if the transactions of the block are not available, all subscribed scripthashes are returned
+func (s *ElectrumServer) blockScripthashes(height uint32) []string {
+	txids, err := s.db.GetBlockTxids(height)
+	if err != nil {
+		glog.Error("Electrum GetBlockTxids error ", err, " for ", height)
+	}
+	if len(txids) == 0 {
+		s.subscriptionsLock.Lock()
+		defer s.subscriptionsLock.Unlock()
+		shs := make([]string, 0, len(s.subscriptions))
+		for sh := range s.subscriptions {
+			shs = append(shs, sh)
+		}
+		return shs
+	}
+	var shs []string
+	for _, txid := range txids {
+		ta, err := s.db.GetTxAddresses(txid)
+		if err != nil {
+			glog.Error("Electrum GetTxAddresses error ", err, " for ", txid)
+			continue
+		}
+		if ta == nil {
+			continue
+		}
+		for i := range ta.Inputs {
+			if len(ta.Inputs[i].AddrDesc) > 0 {
+				shs = append(shs, string(db.Scripthash(ta.Inputs[i].AddrDesc)))
+			}
+		}
+		for i := range ta.Outputs {
+			if len(ta.Outputs[i].AddrDesc) > 0 {
+				shs = append(shs, string(db.Scripthash(ta.Outputs[i].AddrDesc)))
+			}
+		}
+	}
+	return shs
+}
+
+// leftMempoolScripthashes returns the scripthashes of the remembered mempool transactions which are not in mempool anymore
+// and forgets the transactions
+func (s *ElectrumServer) leftMempoolScripthashes() []string {
+	s.mempoolScripthashesLock.Lock()
+	defer s.mempoolScripthashesLock.Unlock()
+	var shs []string
+	for txid, txShs := range s.mempoolTxs {
+		if s.mempool.GetTransactionTime(txid) == 0 {
+			shs = append(shs, txShs...)
+			delete(s.mempoolTxs, txid)
+		}
+	}
+	return shs
+}
+
+// subscribedOnly filters the scripthashes with at least one subscribed client
+func (s *ElectrumServer) subscribedOnly(shs []string) []string {
+	s.subscriptionsLock.Lock()
+	defer s.subscriptionsLock.Unlock()
+	r := shs[:0]
+	for _, sh := range shs {
+		if _, found := s.subscriptions[sh]; found {
+			r = append(r, sh)
+		}
+	}
+	return r
+}
+
+// pruneMempoolScripthashes forgets the mempool addresses which are already in the scripthash index or expired
+func (s *ElectrumServer) pruneMempoolScripthashes() {
+	s.mempoolScripthashesLock.Lock()
+	defer s.mempoolScripthashesLock.Unlock()
+	expired := time.Now().Add(-electrumMempoolScripthashExpiry)
+	for sh, m := range s.mempoolScripthashes {
+		if m.seen.Before(expired) {
+			delete(s.mempoolScripthashes, sh)
+		} else if addrDesc, err := s.db.GetScripthashAddrDesc([]byte(sh)); err == nil && addrDesc != nil {
+			delete(s.mempoolScripthashes, sh)
+		}
+	}
+}
+
+// OnNewTxAddr is a callback that remembers the scripthash of the mempool address and rechecks it if it is subscribed
+func (s *ElectrumServer) OnNewTxAddr(tx *bchain.Tx, addrDesc bchain.AddressDescriptor) {
+	sh := string(db.Scripthash(addrDesc))
+	s.mempoolScripthashesLock.Lock()
+	s.mempoolScripthashes[sh] = electrumMempoolScripthash{addrDesc: append(bchain.AddressDescriptor(nil), addrDesc...), seen: time.Now()}
+	txShs := s.mempoolTxs[tx.Txid]
+	found := false
+	for _, txSh := range txShs {
+		if txSh == sh {
+			found = true
+			break
+		}
+	}
+	if !found {
+		s.mempoolTxs[tx.Txid] = append(txShs, sh)
+	}
+	s.mempoolScripthashesLock.Unlock()
+	s.markPending(s.subscribedOnly([]string{sh}))
+}
+
+// OnMempoolResync is a callback that rechecks the subscribed scripthashes of the transactions which left mempool,
+// for example were replaced or evicted
+func (s *ElectrumServer) OnMempoolResync() {
+	s.markPending(s.subscribedOnly(s.leftMempoolScripthashes()))
+}
@@ -0,0 +1,258 @@
+// +build unittest
+
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"blockbook/db"
+	"blockbook/tests/dbtestdata"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_electrumScripthash(t *testing.T) {
+	script, _ := hex.DecodeString("76a91477bff20c60e522dfaa3350c39b030a5d004e839a88ac")
+	want := "eafd9bc024177ba93572c1cc3a83f555dadbb81ca94cd9761ef5211ce794cea9"
+	sh := db.Scripthash(script)
+	if got := electrumScripthash(sh); got != want {
+		t.Errorf("electrumScripthash() = %v, want %v", got, want)
+	}
+	parsed, err := parseElectrumScripthash(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(parsed) != string(sh) {
+		t.Errorf("parseElectrumScripthash() = %x, want %x", parsed, sh)
+	}
+	for _, s := range []string{"", "abcd", want[:63], want + "00"} {
+		if _, err := parseElectrumScripthash(s); err == nil {
+			t.Errorf("parseElectrumScripthash(%q), expected error", s)
+		}
+	}
+}
+
+func Test_electrumStatus(t *testing.T) {
+	if got := electrumStatus(nil); got != "" {
+		t.Errorf("electrumStatus(nil) = %v, want empty", got)
+	}
+	if got := electrumStatusResult(""); got != nil {
+		t.Errorf("electrumStatusResult(\"\") = %v, want nil", got)
+	}
+	// sha256 of "a1:100:b2:0:"
+	history := []api.ElectrumHistoryItem{{Txid: "a1", Height: 100}, {Txid: "b2", Height: 0, Fee: 200}}
+	want := "282eda467b027eeb709957fe8e310ffcfb38b3bb849feb9e6d0d171c0eac3fa5"
+	if got := electrumStatus(history); got != want {
+		t.Errorf("electrumStatus() = %v, want %v", got, want)
+	}
+}
+
+func Test_electrumParam(t *testing.T) {
+	var params []json.RawMessage
+	if err := json.Unmarshal([]byte(`["abc", 5, null]`), &params); err != nil {
+		t.Fatal(err)
+	}
+	var s string
+	if err := electrumParam(params, 0, &s, true); err != nil || s != "abc" {
+		t.Errorf("electrumParam(0) = %v, %v", s, err)
+	}
+	var n uint32
+	if err := electrumParam(params, 1, &n, true); err != nil || n != 5 {
+		t.Errorf("electrumParam(1) = %v, %v", n, err)
+	}
+	if err := electrumParam(params, 0, &n, true); err == nil {
+		t.Error("electrumParam(0) to number, expected error")
+	}
+	b := true
+	if err := electrumParam(params, 2, &b, false); err != nil || !b {
+		t.Errorf("electrumParam(2) optional null = %v, %v", b, err)
+	}
+	if err := electrumParam(params, 3, &b, true); err == nil {
+		t.Error("electrumParam(3) missing required, expected error")
+	}
+}
+
+func setupElectrumServer(t *testing.T) (*ElectrumServer, string) {
+	parser := btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1})
+	d, is, path := setupRocksDB(t, parser)
+	if err := d.SetScripthashIndex(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.BuildScripthashIndex(nil); err != nil {
+		t.Fatal(err)
+	}
+	chain, err := dbtestdata.NewFakeBlockChain(parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mempool, err := chain.CreateMempool(chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := getInternalTestMetrics(t)
+	txCache, err := db.NewTxCache(d, chain, metrics, is, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// s.Run is never called, the requests are passed directly to the handlers
+	s, err := NewElectrumServer("localhost:12347", "", d, chain, mempool, txCache, metrics, is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+func electrumTestScripthash(t *testing.T, s *ElectrumServer, address string) string {
+	addrDesc, err := s.chainParser.GetAddrDescFromAddress(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return electrumScripthash(db.Scripthash(addrDesc))
+}
+
+func Test_ElectrumServer_requests(t *testing.T) {
+	s, path := setupElectrumServer(t)
+	defer func() {
+		s.Close()
+		s.db.Close()
+		os.RemoveAll(path)
+	}()
+	c := &electrumClient{id: 1, out: make(chan []byte, outChannelSize), alive: true, scripthashes: make(map[string]string)}
+
+	sh5 := electrumTestScripthash(t, s, dbtestdata.Addr5)
+	sh7 := electrumTestScripthash(t, s, dbtestdata.Addr7)
+	unknown := strings.Repeat("ab", 32)
+	if sh5 != "18789beff0b083eec158e6e5384035b33e34d0bd08aa3cbda50b96f4eac380bb" {
+		t.Fatalf("scripthash of Addr5 = %v", sh5)
+	}
+	tests := []struct {
+		name string
+		req  string
+		want string
+	}{
+		{
+			name: "get_history",
+			req:  `{"id":1,"method":"blockchain.scripthash.get_history","params":["` + sh5 + `"]}`,
+			want: `{"jsonrpc":"2.0","id":1,"result":[{"tx_hash":"` + dbtestdata.TxidB1T2 + `","height":225493},{"tx_hash":"` + dbtestdata.TxidB2T3 + `","height":225494}]}`,
+		},
+		{
+			name: "get_history unknown scripthash",
+			req:  `{"id":2,"method":"blockchain.scripthash.get_history","params":["` + unknown + `"]}`,
+			want: `{"jsonrpc":"2.0","id":2,"result":[]}`,
+		},
+		{
+			name: "get_balance",
+			req:  `{"id":3,"method":"blockchain.scripthash.get_balance","params":["` + sh7 + `"]}`,
+			want: `{"jsonrpc":"2.0","id":3,"result":{"confirmed":917283951061,"unconfirmed":0}}`,
+		},
+		{
+			name: "get_balance unknown scripthash",
+			req:  `{"id":4,"method":"blockchain.scripthash.get_balance","params":["` + unknown + `"]}`,
+			want: `{"jsonrpc":"2.0","id":4,"result":{"confirmed":0,"unconfirmed":0}}`,
+		},
+		{
+			name: "listunspent",
+			req:  `{"id":5,"method":"blockchain.scripthash.listunspent","params":["` + sh5 + `"]}`,
+			want: `{"jsonrpc":"2.0","id":5,"result":[{"tx_hash":"` + dbtestdata.TxidB2T3 + `","tx_pos":0,"height":225494,"value":9000}]}`,
+		},
+		{
+			name: "subscribe",
+			req:  `{"id":6,"method":"blockchain.scripthash.subscribe","params":["` + sh5 + `"]}`,
+			want: `{"jsonrpc":"2.0","id":6,"result":"b71c44c1f97a75fd783dfc9c70d539af5ec79b939348f005170bf0add509e9c4"}`,
+		},
+		{
+			name: "subscribe unknown scripthash",
+			req:  `{"id":7,"method":"blockchain.scripthash.subscribe","params":["` + unknown + `"]}`,
+			want: `{"jsonrpc":"2.0","id":7,"result":null}`,
+		},
+		{
+			name: "invalid scripthash",
+			req:  `{"id":8,"method":"blockchain.scripthash.get_history","params":["abcd"]}`,
+			want: `{"jsonrpc":"2.0","id":8,"error":{"code":1,"message":"Invalid scripthash 'abcd'"}}`,
+		},
+		{
+			name: "unknown method",
+			req:  `{"id":9,"method":"blockchain.unknown","params":[]}`,
+			want: `{"jsonrpc":"2.0","id":9,"error":{"code":-32601,"message":"Unknown method 'blockchain.unknown'"}}`,
+		},
+		{
+			name: "batch",
+			req:  `[{"id":10,"method":"server.ping"},{"id":11,"method":"blockchain.scripthash.get_balance","params":["` + sh5 + `"]}]`,
+			want: `[{"jsonrpc":"2.0","id":10,"result":null},{"jsonrpc":"2.0","id":11,"result":{"confirmed":9000,"unconfirmed":0}}]`,
+		},
+		{
+			name: "batch too large",
+			req:  "[" + strings.Repeat(`{"id":12,"method":"server.ping"},`, electrumMaxBatchRequests) + `{"id":13,"method":"server.ping"}]`,
+			want: `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Too many requests in batch, maximum is 100"}}`,
+		},
+		{
+			name: "parse error",
+			req:  `{"id":14,`,
+			want: `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(s.onLine(c, []byte(tt.req))); got != tt.want {
+				t.Errorf("onLine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the subscriptions of both scripthashes are registered
+	if got, want := len(c.scripthashes), 2; got != want {
+		t.Fatalf("len(scripthashes) = %v, want %v", got, want)
+	}
+	raw5, _ := parseElectrumScripthash(sh5)
+	raw7, _ := parseElectrumScripthash(sh7)
+
+	// only the subscribed scripthashes of the block transactions are rechecked
+	got := s.subscribedOnly(s.blockScripthashes(225494))
+	sort.Strings(got)
+	if want := []string{string(raw5), string(raw5)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("subscribedOnly(blockScripthashes(225494)) = %x, want %x", got, want)
+	}
+
+	// the scripthashes of a transaction which left mempool are rechecked and the transaction is forgotten
+	s.OnNewTxAddr(&bchain.Tx{Txid: "abcd"}, mustAddrDesc(t, s, dbtestdata.Addr7))
+	s.OnNewTxAddr(&bchain.Tx{Txid: "abcd"}, mustAddrDesc(t, s, dbtestdata.Addr7))
+	if got, want := s.leftMempoolScripthashes(), []string{string(raw7)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("leftMempoolScripthashes() = %x, want %x", got, want)
+	}
+	if got := s.leftMempoolScripthashes(); len(got) != 0 {
+		t.Errorf("leftMempoolScripthashes() second call = %x, want empty", got)
+	}
+
+	// the changed status is sent to the subscribed client
+	s.subscriptionsLock.Lock()
+	c.scripthashes[string(raw5)] = "stale"
+	s.subscriptionsLock.Unlock()
+	s.notifyScripthash(string(raw5))
+	select {
+	case m := <-c.out:
+		want := `{"jsonrpc":"2.0","method":"blockchain.scripthash.subscribe","params":["` + sh5 + `","b71c44c1f97a75fd783dfc9c70d539af5ec79b939348f005170bf0add509e9c4"]}` + "\n"
+		if string(m) != want {
+			t.Errorf("notification = %v, want %v", string(m), want)
+		}
+	default:
+		t.Error("notification of the changed status not sent")
+	}
+	s.notifyScripthash(string(raw5))
+	if len(c.out) != 0 {
+		t.Error("notification of the unchanged status sent")
+	}
+}
+
+func mustAddrDesc(t *testing.T, s *ElectrumServer, address string) bchain.AddressDescriptor {
+	addrDesc, err := s.chainParser.GetAddrDescFromAddress(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addrDesc
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/common"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+var exportCSVHeader = []string{"blockTime", "blockHeight", "txid", "direction", "amount", "fee", "balance", "counterparties"}
+
+// exportWriter writes the rows of the exported history in one of the export formats
+type exportWriter interface {
+	writeHeader() error
+	writeRow(row *api.ExportRow) error
+	flush() error
+}
+
+// exportCSVWriter writes the rows as csv, with a column rate_<currency> for each of the requested fiat currencies
+type exportCSVWriter struct {
+	w          *csv.Writer
+	currencies []string
+}
+
+func (e *exportCSVWriter) writeHeader() error {
+	header := append([]string{}, exportCSVHeader...)
+	for _, c := range e.currencies {
+		header = append(header, "rate_"+c)
+	}
+	return e.w.Write(header)
+}
+
+func (e *exportCSVWriter) writeRow(row *api.ExportRow) error {
+	record := []string{
+		strconv.FormatInt(row.Blocktime, 10),
+		strconv.FormatUint(uint64(row.Blockheight), 10),
+		row.Txid,
+		row.Direction,
+		row.AmountSat.String(),
+		row.FeeSat.String(),
+		row.BalanceSat.String(),
+		strings.Join(row.Counterparties, " "),
+	}
+	for _, c := range e.currencies {
+		// the rate is empty if the rates at the time of the block are not available
+		var rate string
+		if r, found := row.Rates[c]; found {
+			rate = strconv.FormatFloat(r, 'f', -1, 64)
+		}
+		record = append(record, rate)
+	}
+	return e.w.Write(record)
+}
+
+func (e *exportCSVWriter) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type exportJSONLinesWriter struct {
+	e *json.Encoder
+}
+
+func (e *exportJSONLinesWriter) writeHeader() error {
+	return nil
+}
+
+func (e *exportJSONLinesWriter) writeRow(row *api.ExportRow) error {
+	return e.e.Encode(row)
+}
+
+func (e *exportJSONLinesWriter) flush() error {
+	return nil
+}
+
+// apiExport streams the history of an address or xpub as csv or json lines, the errors detected before the first row
+// is written are returned as json, the later errors can only interrupt the output
+func (s *PublicServer) apiExport(w http.ResponseWriter, r *http.Request) {
+	var (
+		ew          exportWriter
+		contentType string
+		started     bool
+	)
+	startOutput := func() error {
+		started = true
+		w.Header().Set("Content-Type", contentType)
+		return ew.writeHeader()
+	}
+	err := func() error {
+		descriptor := getPathParamAfter(r, "/export/")
+		if len(descriptor) == 0 {
+			return api.NewAPIError("Missing address or xpub", true)
+		}
+		currencies := getCurrencies(r)
+		switch r.URL.Query().Get("format") {
+		case "", "csv":
+			ew = &exportCSVWriter{w: csv.NewWriter(w), currencies: currencies}
+			contentType = "text/csv; charset=utf-8"
+		case "jsonl":
+			ew = &exportJSONLinesWriter{e: json.NewEncoder(w)}
+			contentType = "application/x-ndjson; charset=utf-8"
+		default:
+			return api.NewAPIError("Invalid format, use csv or jsonl", true)
+		}
+		var params [3]int
+		for i, name := range []string{"from", "to", "gap"} {
+			v, ec := strconv.Atoi(r.URL.Query().Get(name))
+			if ec != nil {
+				continue
+			}
+			if v < 0 {
+				return api.NewAPIError("Parameter '"+name+"' cannot be negative", true)
+			}
+			params[i] = v
+		}
+		from, to, gap := params[0], params[1], params[2]
+		s.metrics.ExplorerViews.With(common.Labels{"action": "api-export"}).Inc()
+		err := s.api.ExportHistory(descriptor, uint32(from), uint32(to), gap, currencies, func(row *api.ExportRow) error {
+			if !started {
+				if err := startOutput(); err != nil {
+					return err
+				}
+			}
+			return ew.writeRow(row)
+		})
+		if err == nil && !started {
+			err = startOutput()
+		}
+		if err == nil {
+			err = ew.flush()
+		}
+		return err
+	}()
+	if err != nil {
+		if !started {
+			s.jsonHandler(func(r *http.Request, apiVersion int) (interface{}, error) {
+				return nil, err
+			}, apiV2)(w, r)
+			return
+		}
+		glog.Warning("apiExport ", r.URL.Path, " interrupted: ", err)
+	}
+}
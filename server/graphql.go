@@ -0,0 +1,857 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/common"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+const (
+	// maximum nesting of the objects in a query, the introspection fields are not counted
+	gqlMaxDepth = 10
+	// maximum estimated cost of a query, see gqlQueryCost
+	gqlMaxCost = 5000
+	// estimated number of items of the lists without paging (vin, vout, utxos etc.)
+	gqlListEstimate = 10
+	// maximum size of the request body
+	gqlMaxRequestSize = 1 << 16
+)
+
+// cost of the fields which load data by the api worker, the other fields are free,
+// in addition each returned object costs 1
+var gqlFieldCosts = map[string]int{
+	"Query.block":                    10,
+	"Query.transaction":              10,
+	"Query.address":                  10,
+	"Query.xpub":                     50,
+	"Query.mempool":                  5,
+	"Block.previousBlock":            10,
+	"Block.nextBlock":                10,
+	"Block.txs":                      10,
+	"Transaction.block":              10,
+	"Vin.previousTransaction":        10,
+	"Vout.spentTransaction":          10,
+	"Address.transactions":           10,
+	"Address.txids":                  5,
+	"Address.utxos":                  10,
+	"Address.nextCursor":             5,
+	"Xpub.transactions":              50,
+	"Xpub.utxos":                     50,
+	"Xpub.nextCursor":                50,
+	"Utxo.transaction":               10,
+	"Mempool.transactions":           5,
+	"MempoolTransaction.transaction": 10,
+}
+
+// the lists with paging, the number of items is given by the argument pageSize
+var gqlPagedLists = map[string]struct{}{
+	"Block.txs":            {},
+	"Address.transactions": {},
+	"Address.txids":        {},
+	"Xpub.transactions":    {},
+	"Mempool.transactions": {},
+}
+
+var gqlAmount = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Amount",
+	Description: "Amount in the base units of the coin (satoshi), serialized as string",
+	Serialize: func(value interface{}) interface{} {
+		if a, ok := value.(*api.Amount); ok && a != nil {
+			return a.String()
+		}
+		return nil
+	},
+})
+
+var gqlPagingArgs = graphql.FieldConfigArgument{
+	"page":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+	"pageSize": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: txsOnPage},
+}
+
+var gqlAddressTxsArgs = graphql.FieldConfigArgument{
+	"page":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+	"pageSize": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: txsOnPage},
+	"from":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+	"to":       &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+	"cursor":   &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: ""},
+}
+
+var gqlUtxoArgs = graphql.FieldConfigArgument{
+	"confirmed": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+}
+
+func gqlIntArg(p graphql.ResolveParams, name string) int {
+	i, _ := p.Args[name].(int)
+	return i
+}
+
+// gqlPageSize limits the pageSize argument in the same way as in the REST API
+func gqlPageSize(pageSize int) int {
+	if pageSize <= 0 || pageSize > txsInAPI {
+		return txsInAPI
+	}
+	return pageSize
+}
+
+// gqlPaging returns page and pageSize arguments limited in the same way as in the REST API
+func gqlPaging(p graphql.ResolveParams) (int, int) {
+	return gqlIntArg(p, "page"), gqlPageSize(gqlIntArg(p, "pageSize"))
+}
+
+func gqlAddressFilter(p graphql.ResolveParams) *api.AddressFilter {
+	filter := &api.AddressFilter{Vout: api.AddressFilterVoutOff}
+	if from := gqlIntArg(p, "from"); from > 0 {
+		filter.FromHeight = uint32(from)
+	}
+	if to := gqlIntArg(p, "to"); to > 0 {
+		filter.ToHeight = uint32(to)
+	}
+	filter.Cursor, _ = p.Args["cursor"].(string)
+	return filter
+}
+
+// gqlXpub is the source of the Xpub type, the gap is needed by the resolvers of the nested fields
+type gqlXpub struct {
+	*api.Address
+	gap int
+}
+
+func blockField(t graphql.Output, f func(b *api.Block) interface{}) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if b, ok := p.Source.(*api.Block); ok && b != nil {
+			return f(b), nil
+		}
+		return nil, nil
+	}}
+}
+
+func txField(t graphql.Output, f func(tx *api.Tx) interface{}) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if tx, ok := p.Source.(*api.Tx); ok && tx != nil {
+			return f(tx), nil
+		}
+		return nil, nil
+	}}
+}
+
+func vinField(t graphql.Output, f func(vin *api.Vin) interface{}) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if vin, ok := p.Source.(*api.Vin); ok && vin != nil {
+			return f(vin), nil
+		}
+		return nil, nil
+	}}
+}
+
+func voutField(t graphql.Output, f func(vout *api.Vout) interface{}) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if vout, ok := p.Source.(*api.Vout); ok && vout != nil {
+			return f(vout), nil
+		}
+		return nil, nil
+	}}
+}
+
+func addressField(t graphql.Output, f func(a *api.Address) interface{}) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		switch a := p.Source.(type) {
+		case *api.Address:
+			return f(a), nil
+		case *gqlXpub:
+			return f(a.Address), nil
+		}
+		return nil, nil
+	}}
+}
+
+func tokenField(t graphql.Output, f func(token *api.Token) interface{}) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if token, ok := p.Source.(*api.Token); ok && token != nil {
+			return f(token), nil
+		}
+		return nil, nil
+	}}
+}
+
+func utxoField(t graphql.Output, f func(u *api.Utxo) interface{}) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if u, ok := p.Source.(*api.Utxo); ok && u != nil {
+			return f(u), nil
+		}
+		return nil, nil
+	}}
+}
+
+func utxoList(utxos api.Utxos, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	r := make([]*api.Utxo, len(utxos))
+	for i := range utxos {
+		r[i] = &utxos[i]
+	}
+	return r, nil
+}
+
+func newGraphQLSchema(w *api.Worker) (graphql.Schema, error) {
+	var blockType, txType, vinType, voutType, addressType, xpubType, utxoType *graphql.Object
+
+	// getBlock and getTransaction return untyped nil if the object does not exist, typed nil pointer would not be null
+	getBlock := func(hash string, page, pageSize int) (interface{}, error) {
+		if hash == "" {
+			return nil, nil
+		}
+		b, err := w.GetBlock(hash, page, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	getTransaction := func(txid string, spendingTxs bool) (interface{}, error) {
+		if txid == "" {
+			return nil, nil
+		}
+		tx, err := w.GetTransaction(txid, spendingTxs, false)
+		if err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}
+
+	blockType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Block",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"hash":              blockField(graphql.String, func(b *api.Block) interface{} { return b.Hash }),
+				"height":            blockField(graphql.Int, func(b *api.Block) interface{} { return b.Height }),
+				"confirmations":     blockField(graphql.Int, func(b *api.Block) interface{} { return b.Confirmations }),
+				"time":              blockField(graphql.Int, func(b *api.Block) interface{} { return b.Time }),
+				"size":              blockField(graphql.Int, func(b *api.Block) interface{} { return b.Size }),
+				"txCount":           blockField(graphql.Int, func(b *api.Block) interface{} { return b.TxCount }),
+				"version":           blockField(graphql.String, func(b *api.Block) interface{} { return string(b.Version) }),
+				"merkleRoot":        blockField(graphql.String, func(b *api.Block) interface{} { return b.MerkleRoot }),
+				"nonce":             blockField(graphql.String, func(b *api.Block) interface{} { return b.Nonce }),
+				"bits":              blockField(graphql.String, func(b *api.Block) interface{} { return b.Bits }),
+				"difficulty":        blockField(graphql.String, func(b *api.Block) interface{} { return b.Difficulty }),
+				"previousBlockHash": blockField(graphql.String, func(b *api.Block) interface{} { return b.Prev }),
+				"nextBlockHash":     blockField(graphql.String, func(b *api.Block) interface{} { return b.Next }),
+				"previousBlock": &graphql.Field{
+					Type: blockType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return getBlock(p.Source.(*api.Block).Prev, 1, 1)
+					},
+				},
+				"nextBlock": &graphql.Field{
+					Type: blockType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return getBlock(p.Source.(*api.Block).Next, 1, 1)
+					},
+				},
+				"txs": &graphql.Field{
+					Type: graphql.NewList(txType),
+					Args: gqlPagingArgs,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						page, pageSize := gqlPaging(p)
+						b, err := w.GetBlock(p.Source.(*api.Block).Hash, page, pageSize)
+						if err != nil {
+							return nil, err
+						}
+						return b.Transactions, nil
+					},
+				},
+			}
+		}),
+	})
+
+	txType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Transaction",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"txid":          txField(graphql.String, func(tx *api.Tx) interface{} { return tx.Txid }),
+				"version":       txField(graphql.Int, func(tx *api.Tx) interface{} { return tx.Version }),
+				"lockTime":      txField(graphql.Float, func(tx *api.Tx) interface{} { return float64(tx.Locktime) }),
+				"blockHash":     txField(graphql.String, func(tx *api.Tx) interface{} { return tx.Blockhash }),
+				"blockHeight":   txField(graphql.Int, func(tx *api.Tx) interface{} { return tx.Blockheight }),
+				"confirmations": txField(graphql.Int, func(tx *api.Tx) interface{} { return tx.Confirmations }),
+				"blockTime":     txField(graphql.Int, func(tx *api.Tx) interface{} { return tx.Blocktime }),
+				"size":          txField(graphql.Int, func(tx *api.Tx) interface{} { return tx.Size }),
+				"value":         txField(gqlAmount, func(tx *api.Tx) interface{} { return tx.ValueOutSat }),
+				"valueIn":       txField(gqlAmount, func(tx *api.Tx) interface{} { return tx.ValueInSat }),
+				"fees":          txField(gqlAmount, func(tx *api.Tx) interface{} { return tx.FeesSat }),
+				"hex":           txField(graphql.String, func(tx *api.Tx) interface{} { return tx.Hex }),
+				"rbf":           txField(graphql.Boolean, func(tx *api.Tx) interface{} { return tx.Rbf }),
+				"replacedBy":    txField(graphql.String, func(tx *api.Tx) interface{} { return tx.ReplacedBy }),
+				"vin": txField(graphql.NewList(vinType), func(tx *api.Tx) interface{} {
+					r := make([]*api.Vin, len(tx.Vin))
+					for i := range tx.Vin {
+						r[i] = &tx.Vin[i]
+					}
+					return r
+				}),
+				"vout": txField(graphql.NewList(voutType), func(tx *api.Tx) interface{} {
+					r := make([]*api.Vout, len(tx.Vout))
+					for i := range tx.Vout {
+						r[i] = &tx.Vout[i]
+					}
+					return r
+				}),
+				"block": &graphql.Field{
+					Type: blockType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return getBlock(p.Source.(*api.Tx).Blockhash, 1, 1)
+					},
+				},
+			}
+		}),
+	})
+
+	vinType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Vin",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"n":         vinField(graphql.Int, func(vin *api.Vin) interface{} { return vin.N }),
+				"txid":      vinField(graphql.String, func(vin *api.Vin) interface{} { return vin.Txid }),
+				"vout":      vinField(graphql.Int, func(vin *api.Vin) interface{} { return vin.Vout }),
+				"sequence":  vinField(graphql.Float, func(vin *api.Vin) interface{} { return float64(vin.Sequence) }),
+				"value":     vinField(gqlAmount, func(vin *api.Vin) interface{} { return vin.ValueSat }),
+				"addresses": vinField(graphql.NewList(graphql.String), func(vin *api.Vin) interface{} { return vin.Addresses }),
+				"hex":       vinField(graphql.String, func(vin *api.Vin) interface{} { return vin.Hex }),
+				"coinbase":  vinField(graphql.String, func(vin *api.Vin) interface{} { return vin.Coinbase }),
+				"previousTransaction": &graphql.Field{
+					Type: txType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return getTransaction(p.Source.(*api.Vin).Txid, false)
+					},
+				},
+			}
+		}),
+	})
+
+	voutType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Vout",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"n":           voutField(graphql.Int, func(vout *api.Vout) interface{} { return vout.N }),
+				"value":       voutField(gqlAmount, func(vout *api.Vout) interface{} { return vout.ValueSat }),
+				"spent":       voutField(graphql.Boolean, func(vout *api.Vout) interface{} { return vout.Spent }),
+				"spentTxId":   voutField(graphql.String, func(vout *api.Vout) interface{} { return vout.SpentTxID }),
+				"spentIndex":  voutField(graphql.Int, func(vout *api.Vout) interface{} { return vout.SpentIndex }),
+				"spentHeight": voutField(graphql.Int, func(vout *api.Vout) interface{} { return vout.SpentHeight }),
+				"addresses":   voutField(graphql.NewList(graphql.String), func(vout *api.Vout) interface{} { return vout.Addresses }),
+				"hex":         voutField(graphql.String, func(vout *api.Vout) interface{} { return vout.Hex }),
+				"type":        voutField(graphql.String, func(vout *api.Vout) interface{} { return vout.Type }),
+				"spentTransaction": &graphql.Field{
+					Type: txType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return getTransaction(p.Source.(*api.Vout).SpentTxID, false)
+					},
+				},
+			}
+		}),
+	})
+
+	utxoType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Utxo",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"txid":          utxoField(graphql.String, func(u *api.Utxo) interface{} { return u.Txid }),
+				"vout":          utxoField(graphql.Int, func(u *api.Utxo) interface{} { return u.Vout }),
+				"value":         utxoField(gqlAmount, func(u *api.Utxo) interface{} { return u.AmountSat }),
+				"height":        utxoField(graphql.Int, func(u *api.Utxo) interface{} { return u.Height }),
+				"confirmations": utxoField(graphql.Int, func(u *api.Utxo) interface{} { return u.Confirmations }),
+				"address":       utxoField(graphql.String, func(u *api.Utxo) interface{} { return u.Address }),
+				"path":          utxoField(graphql.String, func(u *api.Utxo) interface{} { return u.Path }),
+				"transaction": &graphql.Field{
+					Type: txType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return getTransaction(p.Source.(*api.Utxo).Txid, false)
+					},
+				},
+			}
+		}),
+	})
+
+	// fields common to Address and Xpub
+	balanceFields := func(fields graphql.Fields) graphql.Fields {
+		fields["balance"] = addressField(gqlAmount, func(a *api.Address) interface{} { return a.BalanceSat })
+		fields["totalReceived"] = addressField(gqlAmount, func(a *api.Address) interface{} { return a.TotalReceivedSat })
+		fields["totalSent"] = addressField(gqlAmount, func(a *api.Address) interface{} { return a.TotalSentSat })
+		fields["unconfirmedBalance"] = addressField(gqlAmount, func(a *api.Address) interface{} { return a.UnconfirmedBalanceSat })
+		fields["unconfirmedTxs"] = addressField(graphql.Int, func(a *api.Address) interface{} { return a.UnconfirmedTxs })
+		fields["txs"] = addressField(graphql.Int, func(a *api.Address) interface{} { return a.Txs })
+		return fields
+	}
+
+	addressType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Address",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return balanceFields(graphql.Fields{
+				"address": addressField(graphql.String, func(a *api.Address) interface{} { return a.AddrStr }),
+				"transactions": &graphql.Field{
+					Type: graphql.NewList(txType),
+					Args: gqlAddressTxsArgs,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						page, pageSize := gqlPaging(p)
+						a, err := w.GetAddress(p.Source.(*api.Address).AddrStr, page, pageSize, api.AccountDetailsTxHistory, gqlAddressFilter(p))
+						if err != nil {
+							return nil, err
+						}
+						return a.Transactions, nil
+					},
+				},
+				"txids": &graphql.Field{
+					Type: graphql.NewList(graphql.String),
+					Args: gqlAddressTxsArgs,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						page, pageSize := gqlPaging(p)
+						a, err := w.GetAddress(p.Source.(*api.Address).AddrStr, page, pageSize, api.AccountDetailsTxidHistory, gqlAddressFilter(p))
+						if err != nil {
+							return nil, err
+						}
+						return a.Txids, nil
+					},
+				},
+				"nextCursor": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Cursor of the page following the page of transactions given by the same arguments",
+					Args:        gqlAddressTxsArgs,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						page, pageSize := gqlPaging(p)
+						a, err := w.GetAddress(p.Source.(*api.Address).AddrStr, page, pageSize, api.AccountDetailsTxidHistory, gqlAddressFilter(p))
+						if err != nil {
+							return nil, err
+						}
+						return a.NextCursor, nil
+					},
+				},
+				"utxos": &graphql.Field{
+					Type: graphql.NewList(utxoType),
+					Args: gqlUtxoArgs,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return utxoList(w.GetAddressUtxo(p.Source.(*api.Address).AddrStr, p.Args["confirmed"] == true))
+					},
+				},
+			})
+		}),
+	})
+
+	xpubAddressType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "XpubAddress",
+		Fields: graphql.Fields{
+			"address":       tokenField(graphql.String, func(t *api.Token) interface{} { return t.Name }),
+			"path":          tokenField(graphql.String, func(t *api.Token) interface{} { return t.Path }),
+			"txs":           tokenField(graphql.Int, func(t *api.Token) interface{} { return t.Transfers }),
+			"balance":       tokenField(gqlAmount, func(t *api.Token) interface{} { return t.BalanceSat }),
+			"totalReceived": tokenField(gqlAmount, func(t *api.Token) interface{} { return t.TotalReceivedSat }),
+			"totalSent":     tokenField(gqlAmount, func(t *api.Token) interface{} { return t.TotalSentSat }),
+		},
+	})
+
+	xpubType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Xpub",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return balanceFields(graphql.Fields{
+				"xpub": addressField(graphql.String, func(a *api.Address) interface{} { return a.AddrStr }),
+				"usedAddresses": addressField(graphql.NewList(xpubAddressType), func(a *api.Address) interface{} {
+					r := make([]*api.Token, len(a.Tokens))
+					for i := range a.Tokens {
+						r[i] = &a.Tokens[i]
+					}
+					return r
+				}),
+				"transactions": &graphql.Field{
+					Type: graphql.NewList(txType),
+					Args: gqlAddressTxsArgs,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						x := p.Source.(*gqlXpub)
+						page, pageSize := gqlPaging(p)
+						a, err := w.GetXpubAddress(x.AddrStr, page, pageSize, api.AccountDetailsTxHistory, gqlAddressFilter(p), x.gap)
+						if err != nil {
+							return nil, err
+						}
+						return a.Transactions, nil
+					},
+				},
+				"nextCursor": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Cursor of the page following the page of transactions given by the same arguments",
+					Args:        gqlAddressTxsArgs,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						x := p.Source.(*gqlXpub)
+						page, pageSize := gqlPaging(p)
+						a, err := w.GetXpubAddress(x.AddrStr, page, pageSize, api.AccountDetailsTxidHistory, gqlAddressFilter(p), x.gap)
+						if err != nil {
+							return nil, err
+						}
+						return a.NextCursor, nil
+					},
+				},
+				"utxos": &graphql.Field{
+					Type: graphql.NewList(utxoType),
+					Args: gqlUtxoArgs,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						x := p.Source.(*gqlXpub)
+						return utxoList(w.GetXpubUtxo(x.AddrStr, p.Args["confirmed"] == true, x.gap))
+					},
+				},
+			})
+		}),
+	})
+
+	mempoolTxType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MempoolTransaction",
+		Fields: graphql.Fields{
+			"txid": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*api.MempoolTxid).Txid, nil
+			}},
+			"time": &graphql.Field{Type: graphql.Int, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*api.MempoolTxid).Time, nil
+			}},
+			"transaction": &graphql.Field{Type: txType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return getTransaction(p.Source.(*api.MempoolTxid).Txid, false)
+			}},
+		},
+	})
+
+	feeRateBucketType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MempoolFeeRateBucket",
+		Fields: graphql.Fields{
+			"feeRate": &graphql.Field{Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*api.MempoolFeeRateBucket).FeeRate, nil
+			}},
+			"txCount": &graphql.Field{Type: graphql.Int, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*api.MempoolFeeRateBucket).TxCount, nil
+			}},
+			"vsize": &graphql.Field{Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return float64(p.Source.(*api.MempoolFeeRateBucket).VSize), nil
+			}},
+		},
+	})
+
+	projectedBlockType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MempoolProjectedBlock",
+		Fields: graphql.Fields{
+			"txCount": &graphql.Field{Type: graphql.Int, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*api.MempoolProjectedBlock).TxCount, nil
+			}},
+			"vsize": &graphql.Field{Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return float64(p.Source.(*api.MempoolProjectedBlock).VSize), nil
+			}},
+			"totalFees": &graphql.Field{Type: gqlAmount, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*api.MempoolProjectedBlock).TotalFees, nil
+			}},
+			"minFeeRate": &graphql.Field{Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*api.MempoolProjectedBlock).MinFeeRate, nil
+			}},
+			"medianFeeRate": &graphql.Field{Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*api.MempoolProjectedBlock).MedianFeeRate, nil
+			}},
+			"maxFeeRate": &graphql.Field{Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*api.MempoolProjectedBlock).MaxFeeRate, nil
+			}},
+		},
+	})
+
+	mempoolType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mempool",
+		Fields: graphql.Fields{
+			"mempoolSize": &graphql.Field{Type: graphql.Int, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*api.MempoolStats).MempoolSize, nil
+			}},
+			"txCount": &graphql.Field{Type: graphql.Int, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*api.MempoolStats).TxCount, nil
+			}},
+			"totalVSize": &graphql.Field{Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return float64(p.Source.(*api.MempoolStats).TotalVSize), nil
+			}},
+			"totalFees": &graphql.Field{Type: gqlAmount, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*api.MempoolStats).TotalFees, nil
+			}},
+			"feeHistogram": &graphql.Field{Type: graphql.NewList(feeRateBucketType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				h := p.Source.(*api.MempoolStats).FeeHistogram
+				r := make([]*api.MempoolFeeRateBucket, len(h))
+				for i := range h {
+					r[i] = &h[i]
+				}
+				return r, nil
+			}},
+			"projectedBlocks": &graphql.Field{Type: graphql.NewList(projectedBlockType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				b := p.Source.(*api.MempoolStats).ProjectedBlocks
+				r := make([]*api.MempoolProjectedBlock, len(b))
+				for i := range b {
+					r[i] = &b[i]
+				}
+				return r, nil
+			}},
+			"transactions": &graphql.Field{
+				Type: graphql.NewList(mempoolTxType),
+				Args: gqlPagingArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					page, pageSize := gqlPaging(p)
+					m, err := w.GetMempool(page, pageSize, "")
+					if err != nil {
+						return nil, err
+					}
+					r := make([]*api.MempoolTxid, len(m.Mempool))
+					for i := range m.Mempool {
+						r[i] = &m.Mempool[i]
+					}
+					return r, nil
+				},
+			},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"block": &graphql.Field{
+				Type:        blockType,
+				Description: "Block given by height or hash",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return getBlock(p.Args["id"].(string), 1, 1)
+				},
+			},
+			"transaction": &graphql.Field{
+				Type: txType,
+				Args: graphql.FieldConfigArgument{
+					"txid":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"spending": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return getTransaction(p.Args["txid"].(string), p.Args["spending"] == true)
+				},
+			},
+			"address": &graphql.Field{
+				Type: addressType,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					a, err := w.GetAddress(p.Args["address"].(string), 1, txsOnPage, api.AccountDetailsBasic, &api.AddressFilter{Vout: api.AddressFilterVoutOff})
+					if err != nil {
+						return nil, err
+					}
+					return a, nil
+				},
+			},
+			"xpub": &graphql.Field{
+				Type:        xpubType,
+				Description: "Xpub or output descriptor with the derived addresses used on the blockchain",
+				Args: graphql.FieldConfigArgument{
+					"xpub": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"gap":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					gap := gqlIntArg(p, "gap")
+					a, err := w.GetXpubAddress(p.Args["xpub"].(string), 1, txsOnPage, api.AccountDetailsTokenBalances,
+						&api.AddressFilter{Vout: api.AddressFilterVoutOff, TokensToReturn: api.TokensToReturnUsed}, gap)
+					if err != nil {
+						return nil, err
+					}
+					return &gqlXpub{Address: a, gap: gap}, nil
+				},
+			},
+			"mempool": &graphql.Field{
+				Type: mempoolType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					m, err := w.GetMempoolStats()
+					if err != nil {
+						return nil, err
+					}
+					return m, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// gqlCost computes the depth and the estimated cost of a query
+type gqlCost struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	// default values of the variables of the current operation
+	defaults map[string]ast.Value
+	visiting map[string]bool
+	cost     int
+	maxDepth int
+}
+
+// intValue returns the value of the argument, a variable is taken from the request variables or from its default value
+func (c *gqlCost) intValue(value ast.Value, def int) int {
+	switch v := value.(type) {
+	case *ast.IntValue:
+		if i, err := strconv.Atoi(v.Value); err == nil {
+			return i
+		}
+	case *ast.Variable:
+		if v.Name == nil {
+			break
+		}
+		if val, found := c.variables[v.Name.Value]; found {
+			if f, ok := val.(float64); ok {
+				return int(f)
+			}
+			break
+		}
+		if d, found := c.defaults[v.Name.Value]; found {
+			if i, ok := d.(*ast.IntValue); ok {
+				return c.intValue(i, def)
+			}
+		}
+	}
+	return def
+}
+
+func (c *gqlCost) argInt(args []*ast.Argument, name string, def int) int {
+	for _, a := range args {
+		if a.Name != nil && a.Name.Value == name {
+			return c.intValue(a.Value, def)
+		}
+	}
+	return def
+}
+
+func (c *gqlCost) selectionSet(t *graphql.Object, ss *ast.SelectionSet, mult int, depth int) {
+	if ss == nil || c.cost > gqlMaxCost {
+		return
+	}
+	for _, s := range ss.Selections {
+		switch sel := s.(type) {
+		case *ast.Field:
+			if sel.Name == nil || strings.HasPrefix(sel.Name.Value, "__") {
+				continue
+			}
+			fd, ok := t.Fields()[sel.Name.Value]
+			if !ok {
+				continue
+			}
+			key := t.Name() + "." + sel.Name.Value
+			c.cost += mult * gqlFieldCosts[key]
+			child, ok := graphql.GetNamed(fd.Type).(*graphql.Object)
+			if !ok || sel.SelectionSet == nil {
+				continue
+			}
+			m := mult
+			if _, paged := gqlPagedLists[key]; paged {
+				m *= gqlPageSize(c.argInt(sel.Arguments, "pageSize", txsOnPage))
+			} else if _, isList := fd.Type.(*graphql.List); isList {
+				m *= gqlListEstimate
+			}
+			c.cost += m
+			if depth+1 > c.maxDepth {
+				c.maxDepth = depth + 1
+			}
+			c.selectionSet(child, sel.SelectionSet, m, depth+1)
+		case *ast.InlineFragment:
+			c.selectionSet(t, sel.SelectionSet, mult, depth)
+		case *ast.FragmentSpread:
+			if sel.Name == nil || c.visiting[sel.Name.Value] {
+				continue
+			}
+			if f, ok := c.fragments[sel.Name.Value]; ok {
+				c.visiting[sel.Name.Value] = true
+				c.selectionSet(t, f.SelectionSet, mult, depth)
+				delete(c.visiting, sel.Name.Value)
+			}
+		}
+	}
+}
+
+// gqlQueryCost returns the maximum depth and the estimated cost of the operations of the query,
+// the cost is the sum of the costs of the fields loading data multiplied by the number of their parent objects,
+// the size of the lists is given by the argument pageSize or estimated, the errors of parsing are left to the execution
+func gqlQueryCost(schema *graphql.Schema, query string, variables map[string]interface{}) (int, int) {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return 0, 0
+	}
+	c := &gqlCost{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+		visiting:  make(map[string]bool),
+	}
+	for _, d := range doc.Definitions {
+		if f, ok := d.(*ast.FragmentDefinition); ok && f.Name != nil {
+			c.fragments[f.Name.Value] = f
+		}
+	}
+	for _, d := range doc.Definitions {
+		if op, ok := d.(*ast.OperationDefinition); ok && op.Operation == ast.OperationTypeQuery {
+			c.defaults = make(map[string]ast.Value)
+			for _, vd := range op.VariableDefinitions {
+				if vd.Variable != nil && vd.Variable.Name != nil && vd.DefaultValue != nil {
+					c.defaults[vd.Variable.Name.Value] = vd.DefaultValue
+				}
+			}
+			c.selectionSet(schema.QueryType(), op.SelectionSet, 1, 0)
+		}
+	}
+	return c.maxDepth, c.cost
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+func (s *PublicServer) apiGraphQL(r *http.Request, apiVersion int) (interface{}, error) {
+	var req graphQLRequest
+	if r.Method == http.MethodPost {
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, gqlMaxRequestSize))
+		if err != nil {
+			return nil, api.NewAPIError("Missing request body", true)
+		}
+		if err = json.Unmarshal(body, &req); err != nil {
+			return nil, api.NewAPIError("Invalid request, "+err.Error(), true)
+		}
+	} else {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				return nil, api.NewAPIError("Invalid variables, "+err.Error(), true)
+			}
+		}
+	}
+	if req.Query == "" {
+		return nil, api.NewAPIError("Missing query", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-graphql"}).Inc()
+	depth, cost := gqlQueryCost(&s.graphqlSchema, req.Query, req.Variables)
+	if depth > gqlMaxDepth {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{
+			gqlerrors.NewFormattedError(fmt.Sprintf("Query depth %d exceeds the maximum depth %d", depth, gqlMaxDepth)),
+		}}, nil
+	}
+	if cost > gqlMaxCost {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{
+			gqlerrors.NewFormattedError(fmt.Sprintf("Query exceeds the maximum cost %d", gqlMaxCost)),
+		}}, nil
+	}
+	return graphql.Do(graphql.Params{
+		Schema:         s.graphqlSchema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        r.Context(),
+	}), nil
+}
@@ -0,0 +1,571 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain"
+	"blockbook/common"
+	"blockbook/db"
+	"blockbook/server/pb"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"path"
+	"sync"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// number of events queued for a subscription stream, a subscriber which does not read them in time is disconnected
+const grpcSubscriptionQueueSize = 500
+
+// maximum number of fee estimates in one EstimateFee request, each of them is a call to the backend
+const grpcMaxEstimateFeeBlocks = 32
+
+// GrpcServer is a handle to the gRPC interface of Blockbook
+//
+// The unary methods mirror the methods of the websocket interface, the events of new blocks and subscribed addresses
+// are sent by server streams. Unlike the websocket, the events are never dropped silently, a stream whose client
+// does not keep up is closed with the status ResourceExhausted and the client is expected to resubscribe and resync.
+type GrpcServer struct {
+	binding                   string
+	certFiles                 string
+	server                    *grpc.Server
+	db                        *db.RocksDB
+	txCache                   *db.TxCache
+	chain                     bchain.BlockChain
+	chainParser               bchain.BlockChainParser
+	mempool                   bchain.Mempool
+	metrics                   *common.Metrics
+	is                        *common.InternalState
+	api                       *api.Worker
+	block0hash                string
+	newBlockSubscriptions     map[*grpcSubscription]struct{}
+	newBlockSubscriptionsLock sync.Mutex
+	addressSubscriptions      map[string]map[*grpcSubscription]string
+	addressSubscriptionsLock  sync.Mutex
+}
+
+// grpcSubscription is a queue of the events of one subscription stream
+type grpcSubscription struct {
+	out            chan interface{}
+	overflow       chan struct{}
+	overflowSignal sync.Once
+}
+
+func newGrpcSubscription() *grpcSubscription {
+	return &grpcSubscription{
+		out:      make(chan interface{}, grpcSubscriptionQueueSize),
+		overflow: make(chan struct{}),
+	}
+}
+
+// send queues the event without blocking, if the queue is full the subscription is marked as overflowed
+func (sub *grpcSubscription) send(m interface{}) {
+	select {
+	case sub.out <- m:
+	default:
+		sub.overflowSignal.Do(func() { close(sub.overflow) })
+	}
+}
+
+// NewGrpcServer creates new gRPC server listening on binding, with TLS if certFiles is set
+func NewGrpcServer(binding, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState) (*GrpcServer, error) {
+	api, err := api.NewWorker(db, chain, mempool, txCache, is)
+	if err != nil {
+		return nil, err
+	}
+	b0, err := db.GetBlockHash(0)
+	if err != nil {
+		return nil, err
+	}
+	s := &GrpcServer{
+		binding:               binding,
+		certFiles:             certFiles,
+		db:                    db,
+		txCache:               txCache,
+		chain:                 chain,
+		chainParser:           chain.GetChainParser(),
+		mempool:               mempool,
+		metrics:               metrics,
+		is:                    is,
+		api:                   api,
+		block0hash:            b0,
+		newBlockSubscriptions: make(map[*grpcSubscription]struct{}),
+		addressSubscriptions:  make(map[string]map[*grpcSubscription]string),
+	}
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(s.unaryInterceptor)}
+	if certFiles != "" {
+		creds, err := credentials.NewServerTLSFromFile(fmt.Sprint(certFiles, ".crt"), fmt.Sprint(certFiles, ".key"))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	s.server = grpc.NewServer(opts...)
+	pb.RegisterBlockbookServer(s.server, s)
+	return s, nil
+}
+
+// Run starts to serve the requests, it returns after Close
+func (s *GrpcServer) Run() error {
+	l, err := net.Listen("tcp", s.binding)
+	if err != nil {
+		return err
+	}
+	if s.certFiles != "" {
+		glog.Info("grpc server: starting to listen with TLS on ", s.binding)
+	} else {
+		glog.Info("grpc server: starting to listen on ", s.binding)
+	}
+	return s.serve(l)
+}
+
+// serve serves the requests on the listener, it returns after Close
+func (s *GrpcServer) serve(l net.Listener) error {
+	return s.server.Serve(l)
+}
+
+// Close stops the server, closes all connections and subscription streams
+func (s *GrpcServer) Close() error {
+	glog.Infof("grpc server: closing")
+	s.server.Stop()
+	return nil
+}
+
+func (s *GrpcServer) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	method := path.Base(info.FullMethod)
+	resp, err := handler(ctx, req)
+	if err != nil {
+		err = grpcError(method, err)
+	}
+	s.metrics.GrpcRequests.With(common.Labels{"method": method, "status": status.Code(err).String()}).Inc()
+	return resp, err
+}
+
+// grpcError converts the error to the status error, the public api errors are returned as InvalidArgument,
+// the other errors are logged and their details are not returned to the client
+func grpcError(method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if apiErr, ok := err.(*api.APIError); ok && apiErr.Public {
+		return status.Error(codes.InvalidArgument, apiErr.Error())
+	}
+	glog.Error("grpc ", method, " error: ", err)
+	return status.Error(codes.Internal, "Internal server error")
+}
+
+// GetInfo returns the basic information about the coin and the best block
+func (s *GrpcServer) GetInfo(ctx context.Context, req *pb.GetInfoRequest) (*pb.Info, error) {
+	vi := common.GetVersionInfo()
+	height, hash, err := s.db.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Info{
+		Name:       s.is.Coin,
+		Shortcut:   s.is.CoinShortcut,
+		Decimals:   int32(s.chainParser.AmountDecimals()),
+		Version:    vi.Version,
+		BestHeight: height,
+		BestHash:   hash,
+		Block0Hash: s.block0hash,
+		Testnet:    s.chain.IsTestnet(),
+	}, nil
+}
+
+// GetAccountInfo returns the information about an address, xpub or output descriptor
+func (s *GrpcServer) GetAccountInfo(ctx context.Context, req *pb.AccountInfoRequest) (*pb.AccountInfo, error) {
+	var opt api.AccountDetails
+	switch req.Details {
+	case pb.AccountInfoRequest_TOKENS:
+		opt = api.AccountDetailsTokens
+	case pb.AccountInfoRequest_TOKEN_BALANCES:
+		opt = api.AccountDetailsTokenBalances
+	case pb.AccountInfoRequest_TXIDS:
+		opt = api.AccountDetailsTxidHistory
+	case pb.AccountInfoRequest_TXS:
+		opt = api.AccountDetailsTxHistory
+	default:
+		opt = api.AccountDetailsBasic
+	}
+	var tokensToReturn api.TokensToReturn
+	switch req.Tokens {
+	case pb.AccountInfoRequest_USED:
+		tokensToReturn = api.TokensToReturnUsed
+	case pb.AccountInfoRequest_NONZERO:
+		tokensToReturn = api.TokensToReturnNonzeroBalance
+	default:
+		tokensToReturn = api.TokensToReturnDerived
+	}
+	filter := api.AddressFilter{
+		FromHeight:     req.FromHeight,
+		ToHeight:       req.ToHeight,
+		Contract:       req.ContractFilter,
+		Vout:           api.AddressFilterVoutOff,
+		TokensToReturn: tokensToReturn,
+		Cursor:         req.Cursor,
+	}
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = txsOnPage
+	} else if pageSize > txsInAPI {
+		pageSize = txsInAPI
+	}
+	a, err := s.api.GetXpubAddress(req.Account, int(req.Page), pageSize, opt, &filter, 0)
+	if err != nil {
+		a, err = s.api.GetAddress(req.Account, int(req.Page), pageSize, opt, &filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return grpcAccountInfo(a), nil
+}
+
+// GetAccountUtxo returns the unspent outputs of an address, xpub or output descriptor
+func (s *GrpcServer) GetAccountUtxo(ctx context.Context, req *pb.AccountUtxoRequest) (*pb.AccountUtxo, error) {
+	utxos, err := s.api.GetXpubUtxo(req.Account, req.Confirmed, 0)
+	if err != nil {
+		utxos, err = s.api.GetAddressUtxo(req.Account, req.Confirmed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r := &pb.AccountUtxo{Utxos: make([]*pb.Utxo, len(utxos))}
+	for i := range utxos {
+		u := &utxos[i]
+		r.Utxos[i] = &pb.Utxo{
+			Txid:          u.Txid,
+			Vout:          uint32(u.Vout),
+			Value:         grpcAmount(u.AmountSat),
+			Height:        int32(u.Height),
+			Confirmations: int32(u.Confirmations),
+			Address:       u.Address,
+			Path:          u.Path,
+		}
+	}
+	return r, nil
+}
+
+// GetTransaction returns the transaction from the index or from mempool
+func (s *GrpcServer) GetTransaction(ctx context.Context, req *pb.GetTransactionRequest) (*pb.Transaction, error) {
+	tx, err := s.api.GetTransaction(req.Txid, false, false)
+	if err != nil {
+		return nil, err
+	}
+	return grpcTransaction(tx), nil
+}
+
+// SendTransaction broadcasts the raw transaction
+func (s *GrpcServer) SendTransaction(ctx context.Context, req *pb.SendTransactionRequest) (*pb.SendTransactionResponse, error) {
+	if len(req.Hex) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Missing tx")
+	}
+	txid, err := s.chain.SendRawTransaction(hex.EncodeToString(req.Hex))
+	if err != nil {
+		return nil, grpcSendTransactionError(err)
+	}
+	return &pb.SendTransactionResponse{Txid: txid}, nil
+}
+
+// grpcSendTransactionError returns the errors of the backend rejecting the transaction as InvalidArgument,
+// the failures of the call to the backend (connection, timeout etc.) as Unavailable, the client may retry them
+func grpcSendTransactionError(err error) error {
+	switch e := err.(type) {
+	case *bchain.RPCError:
+		return status.Error(codes.InvalidArgument, e.Error())
+	// the json-rpc errors of the ethereum type backends
+	case interface{ ErrorCode() int }:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	glog.Error("grpc SendTransaction error: ", err)
+	return status.Error(codes.Unavailable, "Backend unavailable")
+}
+
+// EstimateFee returns the estimated fees for the given numbers of blocks
+func (s *GrpcServer) EstimateFee(ctx context.Context, req *pb.EstimateFeeRequest) (*pb.EstimateFeeResponse, error) {
+	if len(req.Blocks) > grpcMaxEstimateFeeBlocks {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Too many blocks, maximum is %d", grpcMaxEstimateFeeBlocks))
+	}
+	r := &pb.EstimateFeeResponse{Fees: make([]*pb.EstimateFeeResponse_Fee, len(req.Blocks))}
+	if s.chainParser.GetChainType() == bchain.ChainEthereumType {
+		params := make(map[string]interface{}, len(req.Specific))
+		for k, v := range req.Specific {
+			params[k] = v
+		}
+		gas, err := s.chain.EthereumTypeEstimateGas(params)
+		if err != nil {
+			return nil, err
+		}
+		for i, b := range req.Blocks {
+			fee, err := s.chain.EstimateSmartFee(int(b), true)
+			if err != nil {
+				return nil, err
+			}
+			f := &pb.EstimateFeeResponse_Fee{FeePerUnit: fee.Bytes(), FeeLimit: gas}
+			fee.Mul(&fee, new(big.Int).SetUint64(gas))
+			f.FeePerTx = fee.Bytes()
+			r.Fees[i] = f
+		}
+	} else {
+		for i, b := range req.Blocks {
+			fee, err := s.chain.EstimateSmartFee(int(b), !req.Economical)
+			if err != nil {
+				return nil, err
+			}
+			f := &pb.EstimateFeeResponse_Fee{FeePerUnit: fee.Bytes()}
+			if req.TxSize > 0 {
+				fee.Mul(&fee, big.NewInt(int64(req.TxSize)))
+				fee.Add(&fee, big.NewInt(500))
+				fee.Div(&fee, big.NewInt(1000))
+				f.FeePerTx = fee.Bytes()
+			}
+			r.Fees[i] = f
+		}
+	}
+	return r, nil
+}
+
+// streamSubscription sends the queued events of the subscription until the client cancels the stream,
+// the server is stopped or the queue overflows
+func (s *GrpcServer) streamSubscription(method string, stream grpc.ServerStream, sub *grpcSubscription) error {
+	s.metrics.GrpcSubscriptions.With(common.Labels{"method": method}).Inc()
+	defer s.metrics.GrpcSubscriptions.With(common.Labels{"method": method}).Dec()
+	ctx := stream.Context()
+	for {
+		select {
+		case m := <-sub.out:
+			if err := stream.SendMsg(m); err != nil {
+				return err
+			}
+		case <-sub.overflow:
+			glog.Warning("grpc ", method, ": subscriber does not read the events, closing the stream")
+			return status.Error(codes.ResourceExhausted, "Too many unread events")
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// SubscribeNewBlock streams the height and hash of each new block
+func (s *GrpcServer) SubscribeNewBlock(req *pb.SubscribeNewBlockRequest, stream pb.Blockbook_SubscribeNewBlockServer) error {
+	sub := newGrpcSubscription()
+	s.newBlockSubscriptionsLock.Lock()
+	s.newBlockSubscriptions[sub] = struct{}{}
+	s.newBlockSubscriptionsLock.Unlock()
+	defer func() {
+		s.newBlockSubscriptionsLock.Lock()
+		delete(s.newBlockSubscriptions, sub)
+		s.newBlockSubscriptionsLock.Unlock()
+	}()
+	return s.streamSubscription("SubscribeNewBlock", stream, sub)
+}
+
+// SubscribeAddresses streams the mempool and confirmed transactions of the given addresses
+func (s *GrpcServer) SubscribeAddresses(req *pb.SubscribeAddressesRequest, stream pb.Blockbook_SubscribeAddressesServer) error {
+	if len(req.Addresses) == 0 {
+		return status.Error(codes.InvalidArgument, "Missing addresses")
+	}
+	addrDescs := make([]string, len(req.Addresses))
+	for i, a := range req.Addresses {
+		ad, err := s.chainParser.GetAddrDescFromAddress(a)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, fmt.Sprintf("Invalid address '%v', %v", a, err))
+		}
+		addrDescs[i] = string(ad)
+	}
+	sub := newGrpcSubscription()
+	s.addressSubscriptionsLock.Lock()
+	for i, ads := range addrDescs {
+		as, ok := s.addressSubscriptions[ads]
+		if !ok {
+			as = make(map[*grpcSubscription]string)
+			s.addressSubscriptions[ads] = as
+		}
+		as[sub] = req.Addresses[i]
+	}
+	s.addressSubscriptionsLock.Unlock()
+	defer func() {
+		s.addressSubscriptionsLock.Lock()
+		for _, ads := range addrDescs {
+			if as, ok := s.addressSubscriptions[ads]; ok {
+				delete(as, sub)
+				if len(as) == 0 {
+					delete(s.addressSubscriptions, ads)
+				}
+			}
+		}
+		s.addressSubscriptionsLock.Unlock()
+	}()
+	return s.streamSubscription("SubscribeAddresses", stream, sub)
+}
+
+// OnNewBlock is a callback that sends the new block to the subscribed streams
+func (s *GrpcServer) OnNewBlock(hash string, height uint32) {
+	s.newBlockSubscriptionsLock.Lock()
+	defer s.newBlockSubscriptionsLock.Unlock()
+	for sub := range s.newBlockSubscriptions {
+		sub.send(&pb.NewBlock{Height: height, Hash: hash})
+	}
+}
+
+// OnNewTxAddr is a callback that sends the tx affecting a subscribed address to the subscribed streams
+func (s *GrpcServer) OnNewTxAddr(tx *bchain.Tx, addrDesc bchain.AddressDescriptor) {
+	// check if there is any subscription but release the lock immediately, GetTransactionFromBchainTx may take some time
+	s.addressSubscriptionsLock.Lock()
+	n := len(s.addressSubscriptions[string(addrDesc)])
+	s.addressSubscriptionsLock.Unlock()
+	if n == 0 {
+		return
+	}
+	atx, err := s.api.GetTransactionFromBchainTx(tx, 0, false, false)
+	if err != nil {
+		glog.Error("GetTransactionFromBchainTx error ", err, " for ", tx.Txid)
+		return
+	}
+	ptx := grpcTransaction(atx)
+	s.addressSubscriptionsLock.Lock()
+	defer s.addressSubscriptionsLock.Unlock()
+	for sub, address := range s.addressSubscriptions[string(addrDesc)] {
+		sub.send(&pb.AddressEvent{Address: address, Tx: ptx})
+	}
+}
+
+// grpcAmount returns the absolute value of the amount as big-endian bytes
+func grpcAmount(a *api.Amount) []byte {
+	if a == nil {
+		return nil
+	}
+	b := (*big.Int)(a)
+	if b.Sign() < 0 {
+		return new(big.Int).Neg(b).Bytes()
+	}
+	return b.Bytes()
+}
+
+// grpcHex decodes the hex string, invalid hex is returned as nil
+func grpcHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+func grpcTransaction(tx *api.Tx) *pb.Transaction {
+	r := &pb.Transaction{
+		Txid:          tx.Txid,
+		Version:       tx.Version,
+		LockTime:      tx.Locktime,
+		Vin:           make([]*pb.Transaction_Input, len(tx.Vin)),
+		Vout:          make([]*pb.Transaction_Output, len(tx.Vout)),
+		BlockHash:     tx.Blockhash,
+		BlockHeight:   int32(tx.Blockheight),
+		Confirmations: tx.Confirmations,
+		BlockTime:     tx.Blocktime,
+		Size:          uint32(tx.Size),
+		Value:         grpcAmount(tx.ValueOutSat),
+		ValueIn:       grpcAmount(tx.ValueInSat),
+		Fees:          grpcAmount(tx.FeesSat),
+		Hex:           grpcHex(tx.Hex),
+		Rbf:           tx.Rbf,
+	}
+	for i := range tx.Vin {
+		vin := &tx.Vin[i]
+		r.Vin[i] = &pb.Transaction_Input{
+			N:         uint32(vin.N),
+			Txid:      vin.Txid,
+			Vout:      vin.Vout,
+			Sequence:  uint32(vin.Sequence),
+			Addresses: vin.Addresses,
+			Value:     grpcAmount(vin.ValueSat),
+			Script:    grpcHex(vin.Hex),
+			Coinbase:  vin.Coinbase,
+		}
+	}
+	for i := range tx.Vout {
+		vout := &tx.Vout[i]
+		r.Vout[i] = &pb.Transaction_Output{
+			N:           uint32(vout.N),
+			Value:       grpcAmount(vout.ValueSat),
+			Addresses:   vout.Addresses,
+			Script:      grpcHex(vout.Hex),
+			Type:        vout.Type,
+			Spent:       vout.Spent,
+			SpentTxid:   vout.SpentTxID,
+			SpentIndex:  uint32(vout.SpentIndex),
+			SpentHeight: uint32(vout.SpentHeight),
+		}
+	}
+	if len(tx.TokenTransfers) > 0 {
+		r.TokenTransfers = make([]*pb.TokenTransfer, len(tx.TokenTransfers))
+		for i := range tx.TokenTransfers {
+			t := &tx.TokenTransfers[i]
+			r.TokenTransfers[i] = &pb.TokenTransfer{
+				Type:     string(t.Type),
+				From:     t.From,
+				To:       t.To,
+				Token:    t.Token,
+				Name:     t.Name,
+				Symbol:   t.Symbol,
+				Decimals: int32(t.Decimals),
+				Value:    grpcAmount(t.Value),
+			}
+		}
+	}
+	return r
+}
+
+func grpcAccountInfo(a *api.Address) *pb.AccountInfo {
+	r := &pb.AccountInfo{
+		Address:            a.AddrStr,
+		Balance:            grpcAmount(a.BalanceSat),
+		TotalReceived:      grpcAmount(a.TotalReceivedSat),
+		TotalSent:          grpcAmount(a.TotalSentSat),
+		UnconfirmedBalance: grpcAmount(a.UnconfirmedBalanceSat),
+		UnconfirmedTxs:     int32(a.UnconfirmedTxs),
+		Txs:                int32(a.Txs),
+		NonTokenTxs:        int32(a.NonTokenTxs),
+		Txids:              a.Txids,
+		Nonce:              a.Nonce,
+		TotalTokens:        int32(a.TotalTokens),
+		Page:               int32(a.Page),
+		TotalPages:         int32(a.TotalPages),
+		ItemsOnPage:        int32(a.ItemsOnPage),
+		NextCursor:         a.NextCursor,
+	}
+	if a.UnconfirmedBalanceSat != nil {
+		r.UnconfirmedBalanceNegative = (*big.Int)(a.UnconfirmedBalanceSat).Sign() < 0
+	}
+	if len(a.Transactions) > 0 {
+		r.Transactions = make([]*pb.Transaction, len(a.Transactions))
+		for i, tx := range a.Transactions {
+			r.Transactions[i] = grpcTransaction(tx)
+		}
+	}
+	if len(a.Tokens) > 0 {
+		r.Tokens = make([]*pb.Token, len(a.Tokens))
+		for i := range a.Tokens {
+			t := &a.Tokens[i]
+			r.Tokens[i] = &pb.Token{
+				Type:          string(t.Type),
+				Name:          t.Name,
+				Path:          t.Path,
+				Contract:      t.Contract,
+				Transfers:     int32(t.Transfers),
+				Symbol:        t.Symbol,
+				Decimals:      int32(t.Decimals),
+				Balance:       grpcAmount(t.BalanceSat),
+				TotalReceived: grpcAmount(t.TotalReceivedSat),
+				TotalSent:     grpcAmount(t.TotalSentSat),
+			}
+		}
+	}
+	return r
+}
@@ -0,0 +1,339 @@
+// +build unittest
+
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain/coins/btc"
+	"blockbook/db"
+	"blockbook/server/pb"
+	"blockbook/tests/dbtestdata"
+	"math/big"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func Test_grpcAmount(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *api.Amount
+		want []byte
+	}{
+		{name: "nil", a: nil, want: nil},
+		{name: "zero", a: (*api.Amount)(big.NewInt(0)), want: []byte{}},
+		{name: "small", a: (*api.Amount)(big.NewInt(0x1234)), want: []byte{0x12, 0x34}},
+		{name: "negative", a: (*api.Amount)(big.NewInt(-0x1234)), want: []byte{0x12, 0x34}},
+		{name: "big", a: (*api.Amount)(new(big.Int).Lsh(big.NewInt(1), 72)), want: []byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := grpcAmount(tt.a); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("grpcAmount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_grpcTransaction(t *testing.T) {
+	tx := &api.Tx{
+		Txid:          "7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25",
+		Blockheight:   -1,
+		Confirmations: 0,
+		Hex:           "0100",
+		ValueOutSat:   (*api.Amount)(big.NewInt(300)),
+		Vin: []api.Vin{
+			{N: 0, Txid: "a1", Vout: 2, Sequence: 4294967293, ValueSat: (*api.Amount)(big.NewInt(400)), Hex: "zz"},
+		},
+		Vout: []api.Vout{
+			{N: 0, ValueSat: (*api.Amount)(big.NewInt(300)), Addresses: []string{"addr"}, Hex: "76a9", Spent: true, SpentTxID: "b2", SpentIndex: 1, SpentHeight: 7},
+		},
+	}
+	want := &pb.Transaction{
+		Txid:        tx.Txid,
+		BlockHeight: -1,
+		Hex:         []byte{1, 0},
+		Value:       []byte{1, 44},
+		Vin: []*pb.Transaction_Input{
+			{N: 0, Txid: "a1", Vout: 2, Sequence: 4294967293, Value: []byte{1, 144}},
+		},
+		Vout: []*pb.Transaction_Output{
+			{N: 0, Value: []byte{1, 44}, Addresses: []string{"addr"}, Script: []byte{0x76, 0xa9}, Spent: true, SpentTxid: "b2", SpentIndex: 1, SpentHeight: 7},
+		},
+	}
+	if got := grpcTransaction(tx); !reflect.DeepEqual(got, want) {
+		t.Errorf("grpcTransaction() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_grpcAccountInfo_negativeUnconfirmed(t *testing.T) {
+	a := &api.Address{
+		AddrStr:               "addr",
+		BalanceSat:            (*api.Amount)(big.NewInt(1000)),
+		UnconfirmedBalanceSat: (*api.Amount)(big.NewInt(-300)),
+		UnconfirmedTxs:        1,
+		Txs:                   2,
+	}
+	got := grpcAccountInfo(a)
+	if !reflect.DeepEqual(got.UnconfirmedBalance, []byte{1, 44}) || !got.UnconfirmedBalanceNegative {
+		t.Errorf("grpcAccountInfo() unconfirmed balance = %v, negative %v", got.UnconfirmedBalance, got.UnconfirmedBalanceNegative)
+	}
+	if !reflect.DeepEqual(got.Balance, []byte{3, 232}) || got.UnconfirmedTxs != 1 || got.Txs != 2 {
+		t.Errorf("grpcAccountInfo() = %+v", got)
+	}
+}
+
+func Test_grpcSubscription_overflow(t *testing.T) {
+	sub := newGrpcSubscription()
+	for i := 0; i < grpcSubscriptionQueueSize; i++ {
+		sub.send(&pb.NewBlock{Height: uint32(i)})
+	}
+	select {
+	case <-sub.overflow:
+		t.Fatal("overflow signaled before the queue is full")
+	default:
+	}
+	// the events over the size of the queue are not queued and signal the overflow, repeatedly without panic
+	sub.send(&pb.NewBlock{Height: 1000})
+	sub.send(&pb.NewBlock{Height: 1001})
+	select {
+	case <-sub.overflow:
+	default:
+		t.Fatal("overflow not signaled")
+	}
+	if len(sub.out) != grpcSubscriptionQueueSize {
+		t.Errorf("queued %d events, want %d", len(sub.out), grpcSubscriptionQueueSize)
+	}
+	if m := <-sub.out; m.(*pb.NewBlock).Height != 0 {
+		t.Errorf("first event height %d, want 0", m.(*pb.NewBlock).Height)
+	}
+}
+
+func setupGrpcServer(t *testing.T) (*GrpcServer, string) {
+	parser := btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1})
+	d, is, path := setupRocksDB(t, parser)
+	is.Coin = "Fakecoin"
+	is.CoinShortcut = "FAKE"
+	is.BestHeight = 225494
+	chain, err := dbtestdata.NewFakeBlockChain(parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mempool, err := chain.CreateMempool(chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := getInternalTestMetrics(t)
+	txCache, err := db.NewTxCache(d, chain, metrics, is, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// s.Run is never called, the server is served on an in-memory listener
+	s, err := NewGrpcServer("localhost:12348", "", d, chain, mempool, txCache, metrics, is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+// waitGrpcCondition waits until the condition holds, the streams are registered by the server asynchronously
+func waitGrpcCondition(t *testing.T, name string, cond func() bool) {
+	for i := 0; i < 200; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timeout waiting for ", name)
+}
+
+func grpcTestAmount(b []byte) string {
+	return new(big.Int).SetBytes(b).String()
+}
+
+func Test_GrpcServer_requests(t *testing.T) {
+	s, path := setupGrpcServer(t)
+	l := bufconn.Listen(1 << 20)
+	go s.serve(l)
+	defer func() {
+		s.Close()
+		s.db.Close()
+		os.RemoveAll(path)
+	}()
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(), grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+		return l.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := pb.NewBlockbookClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("GetInfo", func(t *testing.T) {
+		info, err := c.GetInfo(ctx, &pb.GetInfoRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		block2 := dbtestdata.GetTestBitcoinTypeBlock2(s.chainParser)
+		if info.Name != "Fakecoin" || info.Shortcut != "FAKE" || info.BestHeight != block2.Height || info.BestHash != block2.Hash || !info.Testnet {
+			t.Errorf("GetInfo() = %+v", info)
+		}
+	})
+
+	t.Run("GetAccountInfo", func(t *testing.T) {
+		a, err := c.GetAccountInfo(ctx, &pb.AccountInfoRequest{Account: "mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw", Details: pb.AccountInfoRequest_TXIDS})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantTxids := []string{"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25", "effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"}
+		if grpcTestAmount(a.Balance) != "0" || grpcTestAmount(a.TotalReceived) != "1234567890123" || a.Txs != 2 || !reflect.DeepEqual(a.Txids, wantTxids) {
+			t.Errorf("GetAccountInfo() = %+v", a)
+		}
+		_, err = c.GetAccountInfo(ctx, &pb.AccountInfoRequest{Account: "invalid"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("GetAccountInfo(invalid) error = %v, want InvalidArgument", err)
+		}
+	})
+
+	t.Run("GetAccountUtxo", func(t *testing.T) {
+		u, err := c.GetAccountUtxo(ctx, &pb.AccountUtxoRequest{Account: "mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(u.Utxos) != 1 {
+			t.Fatalf("GetAccountUtxo() = %+v", u)
+		}
+		utxo := u.Utxos[0]
+		if utxo.Txid != "7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25" || utxo.Vout != 1 || grpcTestAmount(utxo.Value) != "917283951061" || utxo.Height != 225494 || utxo.Confirmations != 1 {
+			t.Errorf("GetAccountUtxo() = %+v", utxo)
+		}
+	})
+
+	t.Run("GetTransaction", func(t *testing.T) {
+		tx, err := c.GetTransaction(ctx, &pb.GetTransactionRequest{Txid: "05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.BlockHeight != 225494 || grpcTestAmount(tx.Value) != "9000" || grpcTestAmount(tx.Fees) != "876" || len(tx.Vin) != 1 || !reflect.DeepEqual(tx.Vin[0].Addresses, []string{"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"}) {
+			t.Errorf("GetTransaction() = %+v", tx)
+		}
+		_, err = c.GetTransaction(ctx, &pb.GetTransactionRequest{Txid: "0000000000000000000000000000000000000000000000000000000000000000"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("GetTransaction(unknown) error = %v, want InvalidArgument", err)
+		}
+	})
+
+	t.Run("SendTransaction", func(t *testing.T) {
+		r, err := c.SendTransaction(ctx, &pb.SendTransactionRequest{Hex: []byte{0x12, 0x34, 0x56}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Txid != "9876" {
+			t.Errorf("SendTransaction() = %+v", r)
+		}
+		_, err = c.SendTransaction(ctx, &pb.SendTransactionRequest{})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("SendTransaction(empty) error = %v, want InvalidArgument", err)
+		}
+		// the fake backend fails with an error which is not a backend rpc error
+		_, err = c.SendTransaction(ctx, &pb.SendTransactionRequest{Hex: []byte{1}})
+		if status.Code(err) != codes.Unavailable {
+			t.Errorf("SendTransaction(failing) error = %v, want Unavailable", err)
+		}
+	})
+
+	t.Run("EstimateFee", func(t *testing.T) {
+		r, err := c.EstimateFee(ctx, &pb.EstimateFeeRequest{Blocks: []int32{1, 2}, TxSize: 1000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Fees) != 2 || grpcTestAmount(r.Fees[0].FeePerUnit) != "100" || grpcTestAmount(r.Fees[0].FeePerTx) != "100" || grpcTestAmount(r.Fees[1].FeePerUnit) != "200" {
+			t.Errorf("EstimateFee() = %+v", r)
+		}
+		r, err = c.EstimateFee(ctx, &pb.EstimateFeeRequest{Blocks: []int32{1}, Economical: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if grpcTestAmount(r.Fees[0].FeePerUnit) != "99" || len(r.Fees[0].FeePerTx) != 0 {
+			t.Errorf("EstimateFee(economical) = %+v", r)
+		}
+		_, err = c.EstimateFee(ctx, &pb.EstimateFeeRequest{Blocks: make([]int32, grpcMaxEstimateFeeBlocks+1)})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("EstimateFee(too many blocks) error = %v, want InvalidArgument", err)
+		}
+	})
+
+	t.Run("SubscribeNewBlock", func(t *testing.T) {
+		sctx, scancel := context.WithCancel(ctx)
+		stream, err := c.SubscribeNewBlock(sctx, &pb.SubscribeNewBlockRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitGrpcCondition(t, "new block subscription", func() bool {
+			s.newBlockSubscriptionsLock.Lock()
+			defer s.newBlockSubscriptionsLock.Unlock()
+			return len(s.newBlockSubscriptions) == 1
+		})
+		s.OnNewBlock("00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6", 225495)
+		b, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Height != 225495 || b.Hash != "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6" {
+			t.Errorf("SubscribeNewBlock() = %+v", b)
+		}
+		// cancelling the stream removes the subscription
+		scancel()
+		waitGrpcCondition(t, "new block unsubscribe", func() bool {
+			s.newBlockSubscriptionsLock.Lock()
+			defer s.newBlockSubscriptionsLock.Unlock()
+			return len(s.newBlockSubscriptions) == 0
+		})
+	})
+
+	t.Run("SubscribeAddresses", func(t *testing.T) {
+		stream, err := c.SubscribeAddresses(ctx, &pb.SubscribeAddressesRequest{Addresses: []string{"invalid"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = stream.Recv(); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("SubscribeAddresses(invalid) error = %v, want InvalidArgument", err)
+		}
+		address := "2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"
+		stream, err = c.SubscribeAddresses(ctx, &pb.SubscribeAddressesRequest{Addresses: []string{address}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrDesc, err := s.chainParser.GetAddrDescFromAddress(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitGrpcCondition(t, "address subscription", func() bool {
+			s.addressSubscriptionsLock.Lock()
+			defer s.addressSubscriptionsLock.Unlock()
+			return len(s.addressSubscriptions[string(addrDesc)]) == 1
+		})
+		tx, err := s.chain.GetTransaction("05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.OnNewTxAddr(tx, addrDesc)
+		e, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Address != address || e.Tx == nil || e.Tx.Txid != tx.Txid || grpcTestAmount(e.Tx.Value) != "9000" {
+			t.Errorf("SubscribeAddresses() = %+v", e)
+		}
+	})
+}
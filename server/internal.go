@@ -25,11 +25,16 @@ type InternalServer struct {
 	chainParser bchain.BlockChainParser
 	mempool     bchain.Mempool
 	is          *common.InternalState
+	metrics     *common.Metrics
 	api         *api.Worker
+	apiKey      string
+	watchlists  *watchlists
+	webhooks    *webhooks
 }
 
-// NewInternalServer creates new internal http interface to blockbook and returns its handle
-func NewInternalServer(binding, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, is *common.InternalState) (*InternalServer, error) {
+// NewInternalServer creates new internal http interface to blockbook and returns its handle,
+// the internal API (watchlists and webhooks) is enabled only if apiKey is set
+func NewInternalServer(binding, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState, apiKey string) (*InternalServer, error) {
 	api, err := api.NewWorker(db, chain, mempool, txCache, is)
 	if err != nil {
 		return nil, err
@@ -50,12 +55,27 @@ func NewInternalServer(binding, certFiles string, db *db.RocksDB, chain bchain.B
 		chainParser: chain.GetChainParser(),
 		mempool:     mempool,
 		is:          is,
+		metrics:     metrics,
 		api:         api,
+		apiKey:      apiKey,
 	}
 
 	serveMux.Handle(path+"favicon.ico", http.FileServer(http.Dir("./static/")))
 	serveMux.HandleFunc(path+"metrics", promhttp.Handler().ServeHTTP)
 	serveMux.HandleFunc(path, s.index)
+	if apiKey != "" {
+		s.watchlists = newWatchlists(api, db)
+		serveMux.HandleFunc(path+"api/watchlists", s.authenticated(s.jsonHandler(s.apiWatchlists)))
+		serveMux.HandleFunc(path+"api/watchlists/", s.authenticated(s.jsonHandler(s.apiWatchlist)))
+		serveMux.HandleFunc(path+"api/watchlist-events", s.authenticated(s.watchlistEvents))
+		// compute the initial balances of the watchlists
+		s.watchlists.scheduleRefresh()
+		if s.webhooks, err = newWebhooks(db, s.chainParser, metrics); err != nil {
+			return nil, err
+		}
+		serveMux.HandleFunc(path+"api/webhooks", s.authenticated(s.jsonHandler(s.apiWebhooks)))
+		serveMux.HandleFunc(path+"api/webhooks/", s.authenticated(s.jsonHandler(s.apiWebhook)))
+	}
 
 	return s, nil
 }
@@ -73,15 +93,51 @@ func (s *InternalServer) Run() error {
 // Close closes the server
 func (s *InternalServer) Close() error {
 	glog.Infof("internal server: closing")
+	if s.watchlists != nil {
+		s.watchlists.close()
+	}
+	if s.webhooks != nil {
+		s.webhooks.close()
+	}
 	return s.https.Close()
 }
 
 // Shutdown shuts down the server
 func (s *InternalServer) Shutdown(ctx context.Context) error {
 	glog.Infof("internal server: shutdown")
+	if s.watchlists != nil {
+		s.watchlists.close()
+	}
+	if s.webhooks != nil {
+		s.webhooks.close()
+	}
 	return s.https.Shutdown(ctx)
 }
 
+// OnNewBlock recomputes the balances of the watchlists and sends the block to the subscribed webhooks
+func (s *InternalServer) OnNewBlock(hash string, height uint32) {
+	if s.watchlists != nil {
+		s.watchlists.scheduleRefresh()
+	}
+	if s.webhooks != nil {
+		s.webhooks.onNewBlock(hash, height)
+	}
+}
+
+// OnNewTxAddr sends the new transaction to the webhooks subscribed to the address or to its xpub
+func (s *InternalServer) OnNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
+	if s.webhooks != nil {
+		s.webhooks.onNewTxAddr(tx, desc)
+	}
+}
+
+// OnMempoolResync recomputes the balances of the watchlists to include unconfirmed transactions
+func (s *InternalServer) OnMempoolResync() {
+	if s.watchlists != nil {
+		s.watchlists.scheduleRefresh()
+	}
+}
+
 func (s *InternalServer) index(w http.ResponseWriter, r *http.Request) {
 	si, err := s.api.GetSystemInfo(true)
 	if err != nil {
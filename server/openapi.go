@@ -0,0 +1,410 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/common"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// openAPIParam describes a path or query parameter of an api endpoint
+type openAPIParam struct {
+	name        string
+	in          string
+	typ         string
+	enum        []string
+	description string
+}
+
+// openAPIRoute describes one operation of the api,
+// pattern is the pattern of the handler registered in ConnectFullPublicInterface (without the path prefix),
+// one pattern can be described by several routes, for example with and without a path parameter
+type openAPIRoute struct {
+	pattern    string
+	path       string
+	method     string
+	summary    string
+	params     []openAPIParam
+	body       string
+	bodySchema interface{}
+	response   interface{}
+	binaryAlt  bool
+	// contentTypes replace the json response by responses of the given content types and schemas
+	contentTypes map[string]interface{}
+}
+
+func pathParam(name, description string) openAPIParam {
+	return openAPIParam{name: name, in: "path", typ: "string", description: description}
+}
+
+func queryParam(name, typ, description string, enum ...string) openAPIParam {
+	return openAPIParam{name: name, in: "query", typ: typ, enum: enum, description: description}
+}
+
+var openAPIAddressParams = []openAPIParam{
+	queryParam("page", "integer", "page of the returned transactions, starting from 1"),
+	queryParam("pageSize", "integer", "number of transactions on a page, maximum 1000"),
+	queryParam("from", "integer", "only transactions in blocks from this height"),
+	queryParam("to", "integer", "only transactions in blocks up to this height"),
+	queryParam("filter", "string", "'inputs', 'outputs' or the index of the output to filter the transactions by"),
+	queryParam("details", "string", "level of details of the returned data", "basic", "tokens", "tokenBalances", "txids", "txs"),
+	queryParam("cursor", "string", "nextCursor of the previous page, if set, the page parameter is ignored"),
+}
+
+var openAPIXpubParams = append(openAPIAddressParams[:len(openAPIAddressParams):len(openAPIAddressParams)],
+	queryParam("tokens", "string", "which derived addresses are returned in the tokens", "derived", "used", "nonzero"),
+	queryParam("gap", "integer", "gap limit of the address discovery, default 20"),
+)
+
+var openAPIStringArray = []string{}
+
+// openAPIRoutes describes all handlers of the api v2, see Test_openAPIRoutes_complete
+var openAPIRoutes = []openAPIRoute{
+	{pattern: "api/v2/block-index/", path: "/api/v2/block-index/", method: http.MethodGet,
+		summary: "Hash of the best block", response: resultBlockIndex{}},
+	{pattern: "api/v2/block-index/", path: "/api/v2/block-index/{height}", method: http.MethodGet,
+		summary: "Hash of the block at the given height", params: []openAPIParam{pathParam("height", "block height")}, response: resultBlockIndex{}},
+	{pattern: "api/v2/tx-specific/", path: "/api/v2/tx-specific/{txid}", method: http.MethodGet,
+		summary: "Transaction in the coin specific format of the backend", params: []openAPIParam{pathParam("txid", "transaction id")}, response: json.RawMessage{}},
+	{pattern: "api/v2/tx/", path: "/api/v2/tx/{txid}", method: http.MethodGet,
+		summary: "Transaction",
+		params: []openAPIParam{
+			pathParam("txid", "transaction id"),
+			queryParam("spending", "boolean", "return the spending transactions of the outputs"),
+			queryParam("currency", "string", "comma separated list of currencies of the fiat rates at the time of the transaction"),
+		}, response: api.Tx{}},
+	{pattern: "api/v2/txs", path: "/api/v2/txs", method: http.MethodPost,
+		summary: "Batch of transactions", params: []openAPIParam{queryParam("spending", "boolean", "return the spending transactions of the outputs")},
+		body: "application/json", bodySchema: openAPIStringArray, response: []api.TxResult{}},
+	{pattern: "api/v2/address/", path: "/api/v2/address/{address}", method: http.MethodGet,
+		summary: "Balances and transactions of an address", params: append([]openAPIParam{pathParam("address", "address")}, openAPIAddressParams...), response: api.Address{}},
+	{pattern: "api/v2/xpub/", path: "/api/v2/xpub/{xpub}", method: http.MethodGet,
+		summary: "Balances and transactions of an xpub or output descriptor", params: append([]openAPIParam{pathParam("xpub", "xpub or output descriptor")}, openAPIXpubParams...), response: api.Address{}},
+	{pattern: "api/v2/xpub-discovery/", path: "/api/v2/xpub-discovery/{xpub}", method: http.MethodGet,
+		summary: "Diagnostic report of the discovery of the addresses of an xpub",
+		params: []openAPIParam{
+			pathParam("xpub", "xpub or output descriptor"),
+			queryParam("gap", "integer", "gap limit of the address discovery, default 20"),
+			queryParam("probe", "integer", "number of addresses probed beyond the gap limit"),
+		}, response: api.XpubDiscovery{}},
+	{pattern: "api/v2/export/", path: "/api/v2/export/{descriptor}", method: http.MethodGet,
+		summary: "Confirmed transaction history of an address, xpub or output descriptor with the running balance, from the newest transaction",
+		params: []openAPIParam{
+			pathParam("descriptor", "address, xpub or output descriptor"),
+			queryParam("from", "integer", "only transactions in blocks from this height"),
+			queryParam("to", "integer", "only transactions in blocks up to this height"),
+			queryParam("format", "string", "output format, default csv", "csv", "jsonl"),
+			queryParam("gap", "integer", "gap limit of the address discovery, default 20"),
+			queryParam("currency", "string", "comma separated list of currencies of the fiat rates at the time of the block"),
+		}, contentTypes: map[string]interface{}{"text/csv": "", "application/x-ndjson": api.ExportRow{}}},
+	{pattern: "api/v2/balancehistory/", path: "/api/v2/balancehistory/{descriptor}", method: http.MethodGet,
+		summary: "Balance history of an address, xpub or output descriptor in time intervals with confirmed transactions, from the oldest interval",
+		params: []openAPIParam{
+			pathParam("descriptor", "address, xpub or output descriptor"),
+			queryParam("from", "integer", "only transactions in blocks with the unix time from this time"),
+			queryParam("to", "integer", "only transactions in blocks with the unix time up to this time"),
+			queryParam("groupBy", "integer", "length of the interval in seconds, default 3600"),
+			queryParam("gap", "integer", "gap limit of the address discovery, default 20"),
+			queryParam("currency", "string", "comma separated list of currencies of the fiat rates at the start of the interval"),
+		}, response: []api.BalanceHistory{}},
+	{pattern: "api/v2/addresses/", path: "/api/v2/addresses/{addresses}", method: http.MethodGet,
+		summary: "Aggregated balances and transactions of a list of addresses", params: append([]openAPIParam{pathParam("addresses", "comma separated list of addresses")}, openAPIAddressParams...), response: api.Address{}},
+	{pattern: "api/v2/addresses/", path: "/api/v2/addresses/", method: http.MethodPost,
+		summary: "Aggregated balances and transactions of a list of addresses", params: openAPIAddressParams,
+		body: "application/json", bodySchema: openAPIStringArray, response: api.Address{}},
+	{pattern: "api/v2/addresses-utxo/", path: "/api/v2/addresses-utxo/{addresses}", method: http.MethodGet,
+		summary: "Unspent outputs of a list of addresses",
+		params:  []openAPIParam{pathParam("addresses", "comma separated list of addresses"), queryParam("confirmed", "boolean", "return only confirmed outputs")}, response: api.Utxos{}},
+	{pattern: "api/v2/addresses-utxo/", path: "/api/v2/addresses-utxo/", method: http.MethodPost,
+		summary: "Unspent outputs of a list of addresses", params: []openAPIParam{queryParam("confirmed", "boolean", "return only confirmed outputs")},
+		body: "application/json", bodySchema: openAPIStringArray, response: api.Utxos{}},
+	{pattern: "api/v2/utxo/", path: "/api/v2/utxo/{descriptor}", method: http.MethodGet,
+		summary: "Unspent outputs of an address, xpub or output descriptor",
+		params: []openAPIParam{
+			pathParam("descriptor", "address, xpub or output descriptor"),
+			queryParam("confirmed", "boolean", "return only confirmed outputs"),
+			queryParam("gap", "integer", "gap limit of the address discovery, default 20"),
+		}, response: api.Utxos{}},
+	{pattern: "api/v2/block/", path: "/api/v2/block/{block}", method: http.MethodGet,
+		summary: "Block with its transactions", params: []openAPIParam{pathParam("block", "block height or hash"), queryParam("page", "integer", "page of the returned transactions, starting from 1")}, response: api.Block{}},
+	{pattern: "api/v2/sendtx/", path: "/api/v2/sendtx/{hex}", method: http.MethodGet,
+		summary: "Broadcast a transaction", params: []openAPIParam{pathParam("hex", "hex encoded transaction")}, response: resultSendTransaction{}},
+	{pattern: "api/v2/sendtx/", path: "/api/v2/sendtx/", method: http.MethodPost,
+		summary: "Broadcast a transaction", body: "text/plain", bodySchema: "", response: resultSendTransaction{}},
+	{pattern: "api/v2/estimatefee/", path: "/api/v2/estimatefee/{blocks}", method: http.MethodGet,
+		summary: "Estimated fee per kilobyte for the confirmation in the given number of blocks",
+		params:  []openAPIParam{pathParam("blocks", "number of blocks"), queryParam("conservative", "boolean", "conservative estimate, default true")}, response: resultEstimateFeeAsString{}},
+	{pattern: "api/v2/mempool/stats", path: "/api/v2/mempool/stats", method: http.MethodGet,
+		summary: "Statistics of the mempool", response: api.MempoolStats{}},
+	{pattern: "api/v2/mempool/package/", path: "/api/v2/mempool/package/{txid}", method: http.MethodGet,
+		summary: "In-mempool dependencies of a mempool transaction", params: []openAPIParam{pathParam("txid", "transaction id")}, response: api.MempoolTxPackage{}},
+	{pattern: "api/v2/merkleproof/", path: "/api/v2/merkleproof/{txid}", method: http.MethodGet,
+		summary: "Merkle proof of the inclusion of a transaction in a block", params: []openAPIParam{pathParam("txid", "transaction id")}, response: api.MerkleProof{}},
+	{pattern: "api/v2/headers", path: "/api/v2/headers", method: http.MethodGet,
+		summary: "Raw headers of consecutive blocks",
+		params: []openAPIParam{
+			queryParam("start", "integer", "height of the first block"),
+			queryParam("count", "integer", "number of headers"),
+			queryParam("format", "string", "'bin' returns the concatenated binary headers", "bin"),
+		}, response: api.BlockHeaders{}, binaryAlt: true},
+	{pattern: "api/v2/blockfilter/", path: "/api/v2/blockfilter/{block}", method: http.MethodGet,
+		summary: "BIP158 basic filter of a block", params: []openAPIParam{pathParam("block", "block height or hash")}, response: api.BlockFilter{}},
+	{pattern: "api/v2/blockfilter-headers", path: "/api/v2/blockfilter-headers", method: http.MethodGet,
+		summary: "BIP157 filter headers of consecutive blocks",
+		params: []openAPIParam{
+			queryParam("start", "integer", "height of the first block"),
+			queryParam("count", "integer", "number of filter headers"),
+		}, response: api.BlockFilterHeaders{}},
+	{pattern: "api/v2/tickers", path: "/api/v2/tickers", method: http.MethodGet,
+		summary: "Fiat rates of the coin",
+		params: []openAPIParam{
+			queryParam("timestamp", "integer", "unix time of the rates, default the current rates"),
+			queryParam("currency", "string", "comma separated list of currencies, default all available currencies"),
+		}, response: api.FiatTicker{}},
+	{pattern: "api/v2/graphql", path: "/api/v2/graphql", method: http.MethodGet,
+		summary: "GraphQL query",
+		params: []openAPIParam{
+			queryParam("query", "string", "GraphQL query"),
+			queryParam("operationName", "string", "name of the operation to execute"),
+			queryParam("variables", "string", "JSON encoded variables of the query"),
+		}, response: graphql.Result{}},
+	{pattern: "api/v2/graphql", path: "/api/v2/graphql", method: http.MethodPost,
+		summary: "GraphQL query", body: "application/json", bodySchema: graphQLRequest{}, response: graphql.Result{}},
+	{pattern: "api/v2/openapi.json", path: "/api/v2/openapi.json", method: http.MethodGet,
+		summary: "This OpenAPI specification", response: map[string]interface{}{}},
+}
+
+var (
+	openAPIAmountType     = reflect.TypeOf(api.Amount{})
+	openAPIBigIntType     = reflect.TypeOf(big.Int{})
+	openAPITimeType       = reflect.TypeOf(time.Time{})
+	openAPINumberType     = reflect.TypeOf(json.Number(""))
+	openAPIRawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// openAPISchemas generates json schemas of go types, named structs are stored as components
+type openAPISchemas struct {
+	components map[string]interface{}
+	names      map[reflect.Type]string
+}
+
+func (o *openAPISchemas) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case openAPIAmountType:
+		return map[string]interface{}{"type": "string", "description": "amount in the base units of the coin"}
+	case openAPIBigIntType:
+		return map[string]interface{}{"type": "integer"}
+	case openAPITimeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case openAPINumberType:
+		return map[string]interface{}{"type": "number"}
+	case openAPIRawMessageType:
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return o.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": o.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": o.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return o.structSchema(t)
+		}
+		name, found := o.names[t]
+		if !found {
+			name = t.Name()
+			if _, taken := o.components[name]; taken {
+				name = strings.Title(t.PkgPath()[strings.LastIndexByte(t.PkgPath(), '/')+1:]) + name
+			}
+			// register the name before generating the schema to handle recursive types
+			o.names[t] = name
+			o.components[name] = o.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	// interface and other types can hold any value
+	return map[string]interface{}{}
+}
+
+// structSchema returns the schema of a struct as it is serialized by encoding/json,
+// the fields of embedded structs are merged into the struct
+func (o *openAPISchemas) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			if f.Anonymous && tag == "" {
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					addFields(ft)
+					continue
+				}
+			}
+			if f.PkgPath != "" {
+				continue
+			}
+			name := f.Name
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			fs := o.schema(f.Type)
+			omitempty := false
+			for _, p := range parts[1:] {
+				if p == "omitempty" {
+					omitempty = true
+				}
+			}
+			if !omitempty {
+				required = append(required, name)
+				// a nil pointer, slice or map is serialized as null instead of being omitted
+				switch f.Type.Kind() {
+				case reflect.Ptr, reflect.Slice, reflect.Map:
+					fs = nullableSchema(fs)
+				}
+			}
+			properties[name] = fs
+		}
+	}
+	addFields(t)
+	s := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// nullableSchema returns the schema allowing also the null value,
+// a reference cannot have sibling keywords in OpenAPI 3.0 and must be wrapped
+func nullableSchema(s map[string]interface{}) map[string]interface{} {
+	if len(s) == 0 {
+		// schema without type accepts any value including null
+		return s
+	}
+	if _, found := s["$ref"]; found {
+		return map[string]interface{}{"allOf": []interface{}{s}, "nullable": true}
+	}
+	r := make(map[string]interface{}, len(s)+1)
+	for k, v := range s {
+		r[k] = v
+	}
+	r["nullable"] = true
+	return r
+}
+
+func openAPIContent(contentType string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{contentType: map[string]interface{}{"schema": schema}}
+}
+
+// newOpenAPIDocument returns the OpenAPI 3 specification of the api v2 described by openAPIRoutes
+func newOpenAPIDocument(coin string) map[string]interface{} {
+	o := &openAPISchemas{
+		components: make(map[string]interface{}),
+		names:      make(map[reflect.Type]string),
+	}
+	errorSchema := o.schema(reflect.TypeOf(struct {
+		Text string `json:"error"`
+	}{}))
+	o.components["Error"] = errorSchema
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content":     openAPIContent("application/json", map[string]interface{}{"$ref": "#/components/schemas/Error"}),
+		}
+	}
+	paths := make(map[string]interface{})
+	for _, r := range openAPIRoutes {
+		var content map[string]interface{}
+		if r.contentTypes != nil {
+			content = make(map[string]interface{})
+			for ct, schema := range r.contentTypes {
+				content[ct] = map[string]interface{}{"schema": o.schema(reflect.TypeOf(schema))}
+			}
+		} else {
+			content = openAPIContent("application/json", o.schema(reflect.TypeOf(r.response)))
+		}
+		if r.binaryAlt {
+			content["application/octet-stream"] = map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}}
+		}
+		op := map[string]interface{}{
+			"summary": r.summary,
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{"description": "OK", "content": content},
+				"400": errorResponse("Invalid request"),
+				"500": errorResponse("Internal server error"),
+			},
+		}
+		if len(r.params) > 0 {
+			params := make([]interface{}, len(r.params))
+			for i, p := range r.params {
+				schema := map[string]interface{}{"type": p.typ}
+				if len(p.enum) > 0 {
+					schema["enum"] = p.enum
+				}
+				params[i] = map[string]interface{}{
+					"name":        p.name,
+					"in":          p.in,
+					"required":    p.in == "path",
+					"description": p.description,
+					"schema":      schema,
+				}
+			}
+			op["parameters"] = params
+		}
+		if r.body != "" {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  openAPIContent(r.body, o.schema(reflect.TypeOf(r.bodySchema))),
+			}
+		}
+		item, found := paths[r.path].(map[string]interface{})
+		if !found {
+			item = make(map[string]interface{})
+			paths[r.path] = item
+		}
+		item[strings.ToLower(r.method)] = op
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Blockbook " + coin + " API",
+			"version": common.GetVersionInfo().Version,
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": o.components},
+	}
+}
+
+func (s *PublicServer) apiOpenAPI(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-openapi"}).Inc()
+	return newOpenAPIDocument(s.is.Coin), nil
+}
@@ -0,0 +1,164 @@
+// +build unittest
+
+package server
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// registeredAPIv2Patterns returns the patterns of the api v2 handlers registered by the non test sources of the package
+func registeredAPIv2Patterns(t *testing.T) []string {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patterns := make(map[string]struct{})
+	for _, pkg := range pkgs {
+		ast.Inspect(pkg, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || (sel.Sel.Name != "HandleFunc" && sel.Sel.Name != "Handle") {
+				return true
+			}
+			ast.Inspect(call.Args[0], func(n ast.Node) bool {
+				if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					if p, err := strconv.Unquote(lit.Value); err == nil && strings.HasPrefix(p, "api/v2/") {
+						patterns[p] = struct{}{}
+					}
+				}
+				return true
+			})
+			return true
+		})
+	}
+	var r []string
+	for p := range patterns {
+		r = append(r, p)
+	}
+	sort.Strings(r)
+	return r
+}
+
+func Test_openAPIRoutes_complete(t *testing.T) {
+	registered := registeredAPIv2Patterns(t)
+	if len(registered) == 0 {
+		t.Fatal("no api v2 handlers found")
+	}
+	described := make(map[string]struct{})
+	for _, r := range openAPIRoutes {
+		described[r.pattern] = struct{}{}
+	}
+	for _, p := range registered {
+		if _, found := described[p]; !found {
+			t.Errorf("handler %q is not described in openAPIRoutes", p)
+		}
+		delete(described, p)
+	}
+	for p := range described {
+		t.Errorf("openAPIRoutes describe %q which is not a registered handler", p)
+	}
+}
+
+// collectRefs returns all $ref values in the document
+func collectRefs(v interface{}, refs map[string]struct{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if s, ok := e.(string); ok && k == "$ref" {
+				refs[s] = struct{}{}
+			} else {
+				collectRefs(e, refs)
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			collectRefs(e, refs)
+		}
+	}
+}
+
+func Test_newOpenAPIDocument(t *testing.T) {
+	b, err := json.Marshal(newOpenAPIDocument("Fakecoin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// check the document after a round trip through json to see what the clients see
+	var doc map[string]interface{}
+	if err = json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["openapi"] != "3.0.3" {
+		t.Errorf("openapi = %v", doc["openapi"])
+	}
+	paths := doc["paths"].(map[string]interface{})
+	for _, r := range openAPIRoutes {
+		item, ok := paths[r.path].(map[string]interface{})
+		if !ok {
+			t.Errorf("path %q missing", r.path)
+			continue
+		}
+		op, ok := item[strings.ToLower(r.method)].(map[string]interface{})
+		if !ok {
+			t.Errorf("operation %s %q missing", r.method, r.path)
+			continue
+		}
+		// every path parameter of the path template must be described and vice versa
+		var pathParams []string
+		if params, ok := op["parameters"].([]interface{}); ok {
+			for _, p := range params {
+				if p := p.(map[string]interface{}); p["in"] == "path" {
+					pathParams = append(pathParams, p["name"].(string))
+				}
+			}
+		}
+		if strings.Count(r.path, "{") != len(pathParams) {
+			t.Errorf("%s %q has path parameters %v", r.method, r.path, pathParams)
+		}
+		for _, p := range pathParams {
+			if !strings.Contains(r.path, "{"+p+"}") {
+				t.Errorf("%s %q does not contain path parameter %q", r.method, r.path, p)
+			}
+		}
+	}
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	refs := make(map[string]struct{})
+	collectRefs(doc, refs)
+	for ref := range refs {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		if _, found := schemas[name]; !found {
+			t.Errorf("unresolved reference %q", ref)
+		}
+	}
+	// spot check of the schema generated from api types
+	tx := schemas["Tx"].(map[string]interface{})
+	props := tx["properties"].(map[string]interface{})
+	if v := props["value"].(map[string]interface{}); v["type"] != "string" || v["nullable"] != true {
+		t.Errorf("Tx.value = %v, want nullable string", v)
+	}
+	if v := props["txid"].(map[string]interface{}); v["nullable"] != nil {
+		t.Errorf("Tx.txid = %v, want not nullable", v)
+	}
+	if _, found := props["CoinSpecificData"]; found {
+		t.Error("Tx contains field CoinSpecificData excluded from json")
+	}
+	block := schemas["Block"].(map[string]interface{})["properties"].(map[string]interface{})
+	for _, f := range []string{"page", "hash", "height", "merkleroot", "txCount", "txs"} {
+		if _, found := block[f]; !found {
+			t.Errorf("Block is missing embedded field %q", f)
+		}
+	}
+}
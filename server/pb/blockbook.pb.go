@@ -0,0 +1,1690 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: blockbook.proto
+
+/*
+Package pb is a generated protocol buffer package.
+
+It is generated from these files:
+	blockbook.proto
+
+It has these top-level messages:
+	GetInfoRequest
+	Info
+	AccountInfoRequest
+	Token
+	AccountInfo
+	AccountUtxoRequest
+	Utxo
+	AccountUtxo
+	GetTransactionRequest
+	TokenTransfer
+	Transaction
+	SendTransactionRequest
+	SendTransactionResponse
+	EstimateFeeRequest
+	EstimateFeeResponse
+	SubscribeNewBlockRequest
+	NewBlock
+	SubscribeAddressesRequest
+	AddressEvent
+*/
+package pb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type AccountInfoRequest_Details int32
+
+const (
+	AccountInfoRequest_BASIC          AccountInfoRequest_Details = 0
+	AccountInfoRequest_TOKENS         AccountInfoRequest_Details = 1
+	AccountInfoRequest_TOKEN_BALANCES AccountInfoRequest_Details = 2
+	AccountInfoRequest_TXIDS          AccountInfoRequest_Details = 3
+	AccountInfoRequest_TXS            AccountInfoRequest_Details = 4
+)
+
+var AccountInfoRequest_Details_name = map[int32]string{
+	0: "BASIC",
+	1: "TOKENS",
+	2: "TOKEN_BALANCES",
+	3: "TXIDS",
+	4: "TXS",
+}
+var AccountInfoRequest_Details_value = map[string]int32{
+	"BASIC":          0,
+	"TOKENS":         1,
+	"TOKEN_BALANCES": 2,
+	"TXIDS":          3,
+	"TXS":            4,
+}
+
+func (x AccountInfoRequest_Details) String() string {
+	return proto.EnumName(AccountInfoRequest_Details_name, int32(x))
+}
+func (AccountInfoRequest_Details) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{2, 0}
+}
+
+type AccountInfoRequest_Tokens int32
+
+const (
+	AccountInfoRequest_DERIVED AccountInfoRequest_Tokens = 0
+	AccountInfoRequest_USED    AccountInfoRequest_Tokens = 1
+	AccountInfoRequest_NONZERO AccountInfoRequest_Tokens = 2
+)
+
+var AccountInfoRequest_Tokens_name = map[int32]string{
+	0: "DERIVED",
+	1: "USED",
+	2: "NONZERO",
+}
+var AccountInfoRequest_Tokens_value = map[string]int32{
+	"DERIVED": 0,
+	"USED":    1,
+	"NONZERO": 2,
+}
+
+func (x AccountInfoRequest_Tokens) String() string {
+	return proto.EnumName(AccountInfoRequest_Tokens_name, int32(x))
+}
+func (AccountInfoRequest_Tokens) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{2, 1}
+}
+
+type GetInfoRequest struct {
+}
+
+func (m *GetInfoRequest) Reset()                    { *m = GetInfoRequest{} }
+func (m *GetInfoRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetInfoRequest) ProtoMessage()               {}
+func (*GetInfoRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+type Info struct {
+	Name       string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Shortcut   string `protobuf:"bytes,2,opt,name=shortcut" json:"shortcut,omitempty"`
+	Decimals   int32  `protobuf:"varint,3,opt,name=decimals" json:"decimals,omitempty"`
+	Version    string `protobuf:"bytes,4,opt,name=version" json:"version,omitempty"`
+	BestHeight uint32 `protobuf:"varint,5,opt,name=best_height,json=bestHeight" json:"best_height,omitempty"`
+	BestHash   string `protobuf:"bytes,6,opt,name=best_hash,json=bestHash" json:"best_hash,omitempty"`
+	Block0Hash string `protobuf:"bytes,7,opt,name=block0_hash,json=block0Hash" json:"block0_hash,omitempty"`
+	Testnet    bool   `protobuf:"varint,8,opt,name=testnet" json:"testnet,omitempty"`
+}
+
+func (m *Info) Reset()                    { *m = Info{} }
+func (m *Info) String() string            { return proto.CompactTextString(m) }
+func (*Info) ProtoMessage()               {}
+func (*Info) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Info) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Info) GetShortcut() string {
+	if m != nil {
+		return m.Shortcut
+	}
+	return ""
+}
+
+func (m *Info) GetDecimals() int32 {
+	if m != nil {
+		return m.Decimals
+	}
+	return 0
+}
+
+func (m *Info) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *Info) GetBestHeight() uint32 {
+	if m != nil {
+		return m.BestHeight
+	}
+	return 0
+}
+
+func (m *Info) GetBestHash() string {
+	if m != nil {
+		return m.BestHash
+	}
+	return ""
+}
+
+func (m *Info) GetBlock0Hash() string {
+	if m != nil {
+		return m.Block0Hash
+	}
+	return ""
+}
+
+func (m *Info) GetTestnet() bool {
+	if m != nil {
+		return m.Testnet
+	}
+	return false
+}
+
+type AccountInfoRequest struct {
+	// account is an address, xpub or output descriptor
+	Account        string                     `protobuf:"bytes,1,opt,name=account" json:"account,omitempty"`
+	Details        AccountInfoRequest_Details `protobuf:"varint,2,opt,name=details,enum=blockbook.AccountInfoRequest_Details" json:"details,omitempty"`
+	Tokens         AccountInfoRequest_Tokens  `protobuf:"varint,3,opt,name=tokens,enum=blockbook.AccountInfoRequest_Tokens" json:"tokens,omitempty"`
+	Page           int32                      `protobuf:"varint,4,opt,name=page" json:"page,omitempty"`
+	PageSize       int32                      `protobuf:"varint,5,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+	FromHeight     uint32                     `protobuf:"varint,6,opt,name=from_height,json=fromHeight" json:"from_height,omitempty"`
+	ToHeight       uint32                     `protobuf:"varint,7,opt,name=to_height,json=toHeight" json:"to_height,omitempty"`
+	ContractFilter string                     `protobuf:"bytes,8,opt,name=contract_filter,json=contractFilter" json:"contract_filter,omitempty"`
+	// cursor is the next_cursor of the previous page, if set, the page starts at the cursor instead of the page number
+	Cursor string `protobuf:"bytes,9,opt,name=cursor" json:"cursor,omitempty"`
+}
+
+func (m *AccountInfoRequest) Reset()                    { *m = AccountInfoRequest{} }
+func (m *AccountInfoRequest) String() string            { return proto.CompactTextString(m) }
+func (*AccountInfoRequest) ProtoMessage()               {}
+func (*AccountInfoRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *AccountInfoRequest) GetAccount() string {
+	if m != nil {
+		return m.Account
+	}
+	return ""
+}
+
+func (m *AccountInfoRequest) GetDetails() AccountInfoRequest_Details {
+	if m != nil {
+		return m.Details
+	}
+	return AccountInfoRequest_BASIC
+}
+
+func (m *AccountInfoRequest) GetTokens() AccountInfoRequest_Tokens {
+	if m != nil {
+		return m.Tokens
+	}
+	return AccountInfoRequest_DERIVED
+}
+
+func (m *AccountInfoRequest) GetPage() int32 {
+	if m != nil {
+		return m.Page
+	}
+	return 0
+}
+
+func (m *AccountInfoRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *AccountInfoRequest) GetFromHeight() uint32 {
+	if m != nil {
+		return m.FromHeight
+	}
+	return 0
+}
+
+func (m *AccountInfoRequest) GetToHeight() uint32 {
+	if m != nil {
+		return m.ToHeight
+	}
+	return 0
+}
+
+func (m *AccountInfoRequest) GetContractFilter() string {
+	if m != nil {
+		return m.ContractFilter
+	}
+	return ""
+}
+
+func (m *AccountInfoRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+type Token struct {
+	Type          string `protobuf:"bytes,1,opt,name=type" json:"type,omitempty"`
+	Name          string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Path          string `protobuf:"bytes,3,opt,name=path" json:"path,omitempty"`
+	Contract      string `protobuf:"bytes,4,opt,name=contract" json:"contract,omitempty"`
+	Transfers     int32  `protobuf:"varint,5,opt,name=transfers" json:"transfers,omitempty"`
+	Symbol        string `protobuf:"bytes,6,opt,name=symbol" json:"symbol,omitempty"`
+	Decimals      int32  `protobuf:"varint,7,opt,name=decimals" json:"decimals,omitempty"`
+	Balance       []byte `protobuf:"bytes,8,opt,name=balance,proto3" json:"balance,omitempty"`
+	TotalReceived []byte `protobuf:"bytes,9,opt,name=total_received,json=totalReceived,proto3" json:"total_received,omitempty"`
+	TotalSent     []byte `protobuf:"bytes,10,opt,name=total_sent,json=totalSent,proto3" json:"total_sent,omitempty"`
+}
+
+func (m *Token) Reset()                    { *m = Token{} }
+func (m *Token) String() string            { return proto.CompactTextString(m) }
+func (*Token) ProtoMessage()               {}
+func (*Token) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *Token) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Token) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Token) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *Token) GetContract() string {
+	if m != nil {
+		return m.Contract
+	}
+	return ""
+}
+
+func (m *Token) GetTransfers() int32 {
+	if m != nil {
+		return m.Transfers
+	}
+	return 0
+}
+
+func (m *Token) GetSymbol() string {
+	if m != nil {
+		return m.Symbol
+	}
+	return ""
+}
+
+func (m *Token) GetDecimals() int32 {
+	if m != nil {
+		return m.Decimals
+	}
+	return 0
+}
+
+func (m *Token) GetBalance() []byte {
+	if m != nil {
+		return m.Balance
+	}
+	return nil
+}
+
+func (m *Token) GetTotalReceived() []byte {
+	if m != nil {
+		return m.TotalReceived
+	}
+	return nil
+}
+
+func (m *Token) GetTotalSent() []byte {
+	if m != nil {
+		return m.TotalSent
+	}
+	return nil
+}
+
+type AccountInfo struct {
+	Address       string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Balance       []byte `protobuf:"bytes,2,opt,name=balance,proto3" json:"balance,omitempty"`
+	TotalReceived []byte `protobuf:"bytes,3,opt,name=total_received,json=totalReceived,proto3" json:"total_received,omitempty"`
+	TotalSent     []byte `protobuf:"bytes,4,opt,name=total_sent,json=totalSent,proto3" json:"total_sent,omitempty"`
+	// unconfirmed_balance is the absolute value of the change of the balance by mempool transactions
+	UnconfirmedBalance         []byte         `protobuf:"bytes,5,opt,name=unconfirmed_balance,json=unconfirmedBalance,proto3" json:"unconfirmed_balance,omitempty"`
+	UnconfirmedBalanceNegative bool           `protobuf:"varint,6,opt,name=unconfirmed_balance_negative,json=unconfirmedBalanceNegative" json:"unconfirmed_balance_negative,omitempty"`
+	UnconfirmedTxs             int32          `protobuf:"varint,7,opt,name=unconfirmed_txs,json=unconfirmedTxs" json:"unconfirmed_txs,omitempty"`
+	Txs                        int32          `protobuf:"varint,8,opt,name=txs" json:"txs,omitempty"`
+	NonTokenTxs                int32          `protobuf:"varint,9,opt,name=non_token_txs,json=nonTokenTxs" json:"non_token_txs,omitempty"`
+	Transactions               []*Transaction `protobuf:"bytes,10,rep,name=transactions" json:"transactions,omitempty"`
+	Txids                      []string       `protobuf:"bytes,11,rep,name=txids" json:"txids,omitempty"`
+	Nonce                      string         `protobuf:"bytes,12,opt,name=nonce" json:"nonce,omitempty"`
+	TotalTokens                int32          `protobuf:"varint,13,opt,name=total_tokens,json=totalTokens" json:"total_tokens,omitempty"`
+	Tokens                     []*Token       `protobuf:"bytes,14,rep,name=tokens" json:"tokens,omitempty"`
+	Page                       int32          `protobuf:"varint,15,opt,name=page" json:"page,omitempty"`
+	TotalPages                 int32          `protobuf:"varint,16,opt,name=total_pages,json=totalPages" json:"total_pages,omitempty"`
+	ItemsOnPage                int32          `protobuf:"varint,17,opt,name=items_on_page,json=itemsOnPage" json:"items_on_page,omitempty"`
+	NextCursor                 string         `protobuf:"bytes,18,opt,name=next_cursor,json=nextCursor" json:"next_cursor,omitempty"`
+}
+
+func (m *AccountInfo) Reset()                    { *m = AccountInfo{} }
+func (m *AccountInfo) String() string            { return proto.CompactTextString(m) }
+func (*AccountInfo) ProtoMessage()               {}
+func (*AccountInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *AccountInfo) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *AccountInfo) GetBalance() []byte {
+	if m != nil {
+		return m.Balance
+	}
+	return nil
+}
+
+func (m *AccountInfo) GetTotalReceived() []byte {
+	if m != nil {
+		return m.TotalReceived
+	}
+	return nil
+}
+
+func (m *AccountInfo) GetTotalSent() []byte {
+	if m != nil {
+		return m.TotalSent
+	}
+	return nil
+}
+
+func (m *AccountInfo) GetUnconfirmedBalance() []byte {
+	if m != nil {
+		return m.UnconfirmedBalance
+	}
+	return nil
+}
+
+func (m *AccountInfo) GetUnconfirmedBalanceNegative() bool {
+	if m != nil {
+		return m.UnconfirmedBalanceNegative
+	}
+	return false
+}
+
+func (m *AccountInfo) GetUnconfirmedTxs() int32 {
+	if m != nil {
+		return m.UnconfirmedTxs
+	}
+	return 0
+}
+
+func (m *AccountInfo) GetTxs() int32 {
+	if m != nil {
+		return m.Txs
+	}
+	return 0
+}
+
+func (m *AccountInfo) GetNonTokenTxs() int32 {
+	if m != nil {
+		return m.NonTokenTxs
+	}
+	return 0
+}
+
+func (m *AccountInfo) GetTransactions() []*Transaction {
+	if m != nil {
+		return m.Transactions
+	}
+	return nil
+}
+
+func (m *AccountInfo) GetTxids() []string {
+	if m != nil {
+		return m.Txids
+	}
+	return nil
+}
+
+func (m *AccountInfo) GetNonce() string {
+	if m != nil {
+		return m.Nonce
+	}
+	return ""
+}
+
+func (m *AccountInfo) GetTotalTokens() int32 {
+	if m != nil {
+		return m.TotalTokens
+	}
+	return 0
+}
+
+func (m *AccountInfo) GetTokens() []*Token {
+	if m != nil {
+		return m.Tokens
+	}
+	return nil
+}
+
+func (m *AccountInfo) GetPage() int32 {
+	if m != nil {
+		return m.Page
+	}
+	return 0
+}
+
+func (m *AccountInfo) GetTotalPages() int32 {
+	if m != nil {
+		return m.TotalPages
+	}
+	return 0
+}
+
+func (m *AccountInfo) GetItemsOnPage() int32 {
+	if m != nil {
+		return m.ItemsOnPage
+	}
+	return 0
+}
+
+func (m *AccountInfo) GetNextCursor() string {
+	if m != nil {
+		return m.NextCursor
+	}
+	return ""
+}
+
+type AccountUtxoRequest struct {
+	Account   string `protobuf:"bytes,1,opt,name=account" json:"account,omitempty"`
+	Confirmed bool   `protobuf:"varint,2,opt,name=confirmed" json:"confirmed,omitempty"`
+}
+
+func (m *AccountUtxoRequest) Reset()                    { *m = AccountUtxoRequest{} }
+func (m *AccountUtxoRequest) String() string            { return proto.CompactTextString(m) }
+func (*AccountUtxoRequest) ProtoMessage()               {}
+func (*AccountUtxoRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *AccountUtxoRequest) GetAccount() string {
+	if m != nil {
+		return m.Account
+	}
+	return ""
+}
+
+func (m *AccountUtxoRequest) GetConfirmed() bool {
+	if m != nil {
+		return m.Confirmed
+	}
+	return false
+}
+
+type Utxo struct {
+	Txid          string `protobuf:"bytes,1,opt,name=txid" json:"txid,omitempty"`
+	Vout          uint32 `protobuf:"varint,2,opt,name=vout" json:"vout,omitempty"`
+	Value         []byte `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Height        int32  `protobuf:"varint,4,opt,name=height" json:"height,omitempty"`
+	Confirmations int32  `protobuf:"varint,5,opt,name=confirmations" json:"confirmations,omitempty"`
+	Address       string `protobuf:"bytes,6,opt,name=address" json:"address,omitempty"`
+	Path          string `protobuf:"bytes,7,opt,name=path" json:"path,omitempty"`
+}
+
+func (m *Utxo) Reset()                    { *m = Utxo{} }
+func (m *Utxo) String() string            { return proto.CompactTextString(m) }
+func (*Utxo) ProtoMessage()               {}
+func (*Utxo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *Utxo) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *Utxo) GetVout() uint32 {
+	if m != nil {
+		return m.Vout
+	}
+	return 0
+}
+
+func (m *Utxo) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Utxo) GetHeight() int32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *Utxo) GetConfirmations() int32 {
+	if m != nil {
+		return m.Confirmations
+	}
+	return 0
+}
+
+func (m *Utxo) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *Utxo) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+type AccountUtxo struct {
+	Utxos []*Utxo `protobuf:"bytes,1,rep,name=utxos" json:"utxos,omitempty"`
+}
+
+func (m *AccountUtxo) Reset()                    { *m = AccountUtxo{} }
+func (m *AccountUtxo) String() string            { return proto.CompactTextString(m) }
+func (*AccountUtxo) ProtoMessage()               {}
+func (*AccountUtxo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *AccountUtxo) GetUtxos() []*Utxo {
+	if m != nil {
+		return m.Utxos
+	}
+	return nil
+}
+
+type GetTransactionRequest struct {
+	Txid string `protobuf:"bytes,1,opt,name=txid" json:"txid,omitempty"`
+}
+
+func (m *GetTransactionRequest) Reset()                    { *m = GetTransactionRequest{} }
+func (m *GetTransactionRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetTransactionRequest) ProtoMessage()               {}
+func (*GetTransactionRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *GetTransactionRequest) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+type TokenTransfer struct {
+	Type     string `protobuf:"bytes,1,opt,name=type" json:"type,omitempty"`
+	From     string `protobuf:"bytes,2,opt,name=from" json:"from,omitempty"`
+	To       string `protobuf:"bytes,3,opt,name=to" json:"to,omitempty"`
+	Token    string `protobuf:"bytes,4,opt,name=token" json:"token,omitempty"`
+	Name     string `protobuf:"bytes,5,opt,name=name" json:"name,omitempty"`
+	Symbol   string `protobuf:"bytes,6,opt,name=symbol" json:"symbol,omitempty"`
+	Decimals int32  `protobuf:"varint,7,opt,name=decimals" json:"decimals,omitempty"`
+	Value    []byte `protobuf:"bytes,8,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *TokenTransfer) Reset()                    { *m = TokenTransfer{} }
+func (m *TokenTransfer) String() string            { return proto.CompactTextString(m) }
+func (*TokenTransfer) ProtoMessage()               {}
+func (*TokenTransfer) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *TokenTransfer) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *TokenTransfer) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *TokenTransfer) GetTo() string {
+	if m != nil {
+		return m.To
+	}
+	return ""
+}
+
+func (m *TokenTransfer) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *TokenTransfer) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *TokenTransfer) GetSymbol() string {
+	if m != nil {
+		return m.Symbol
+	}
+	return ""
+}
+
+func (m *TokenTransfer) GetDecimals() int32 {
+	if m != nil {
+		return m.Decimals
+	}
+	return 0
+}
+
+func (m *TokenTransfer) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+type Transaction struct {
+	Txid      string                `protobuf:"bytes,1,opt,name=txid" json:"txid,omitempty"`
+	Version   int32                 `protobuf:"varint,2,opt,name=version" json:"version,omitempty"`
+	LockTime  uint32                `protobuf:"varint,3,opt,name=lock_time,json=lockTime" json:"lock_time,omitempty"`
+	Vin       []*Transaction_Input  `protobuf:"bytes,4,rep,name=vin" json:"vin,omitempty"`
+	Vout      []*Transaction_Output `protobuf:"bytes,5,rep,name=vout" json:"vout,omitempty"`
+	BlockHash string                `protobuf:"bytes,6,opt,name=block_hash,json=blockHash" json:"block_hash,omitempty"`
+	// block_height is -1 for mempool transactions
+	BlockHeight    int32            `protobuf:"varint,7,opt,name=block_height,json=blockHeight" json:"block_height,omitempty"`
+	Confirmations  uint32           `protobuf:"varint,8,opt,name=confirmations" json:"confirmations,omitempty"`
+	BlockTime      int64            `protobuf:"varint,9,opt,name=block_time,json=blockTime" json:"block_time,omitempty"`
+	Size           uint32           `protobuf:"varint,10,opt,name=size" json:"size,omitempty"`
+	Value          []byte           `protobuf:"bytes,11,opt,name=value,proto3" json:"value,omitempty"`
+	ValueIn        []byte           `protobuf:"bytes,12,opt,name=value_in,json=valueIn,proto3" json:"value_in,omitempty"`
+	Fees           []byte           `protobuf:"bytes,13,opt,name=fees,proto3" json:"fees,omitempty"`
+	Hex            []byte           `protobuf:"bytes,14,opt,name=hex,proto3" json:"hex,omitempty"`
+	Rbf            bool             `protobuf:"varint,15,opt,name=rbf" json:"rbf,omitempty"`
+	TokenTransfers []*TokenTransfer `protobuf:"bytes,16,rep,name=token_transfers,json=tokenTransfers" json:"token_transfers,omitempty"`
+}
+
+func (m *Transaction) Reset()                    { *m = Transaction{} }
+func (m *Transaction) String() string            { return proto.CompactTextString(m) }
+func (*Transaction) ProtoMessage()               {}
+func (*Transaction) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *Transaction) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *Transaction) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Transaction) GetLockTime() uint32 {
+	if m != nil {
+		return m.LockTime
+	}
+	return 0
+}
+
+func (m *Transaction) GetVin() []*Transaction_Input {
+	if m != nil {
+		return m.Vin
+	}
+	return nil
+}
+
+func (m *Transaction) GetVout() []*Transaction_Output {
+	if m != nil {
+		return m.Vout
+	}
+	return nil
+}
+
+func (m *Transaction) GetBlockHash() string {
+	if m != nil {
+		return m.BlockHash
+	}
+	return ""
+}
+
+func (m *Transaction) GetBlockHeight() int32 {
+	if m != nil {
+		return m.BlockHeight
+	}
+	return 0
+}
+
+func (m *Transaction) GetConfirmations() uint32 {
+	if m != nil {
+		return m.Confirmations
+	}
+	return 0
+}
+
+func (m *Transaction) GetBlockTime() int64 {
+	if m != nil {
+		return m.BlockTime
+	}
+	return 0
+}
+
+func (m *Transaction) GetSize() uint32 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Transaction) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Transaction) GetValueIn() []byte {
+	if m != nil {
+		return m.ValueIn
+	}
+	return nil
+}
+
+func (m *Transaction) GetFees() []byte {
+	if m != nil {
+		return m.Fees
+	}
+	return nil
+}
+
+func (m *Transaction) GetHex() []byte {
+	if m != nil {
+		return m.Hex
+	}
+	return nil
+}
+
+func (m *Transaction) GetRbf() bool {
+	if m != nil {
+		return m.Rbf
+	}
+	return false
+}
+
+func (m *Transaction) GetTokenTransfers() []*TokenTransfer {
+	if m != nil {
+		return m.TokenTransfers
+	}
+	return nil
+}
+
+type Transaction_Input struct {
+	N         uint32   `protobuf:"varint,1,opt,name=n" json:"n,omitempty"`
+	Txid      string   `protobuf:"bytes,2,opt,name=txid" json:"txid,omitempty"`
+	Vout      uint32   `protobuf:"varint,3,opt,name=vout" json:"vout,omitempty"`
+	Sequence  uint32   `protobuf:"varint,4,opt,name=sequence" json:"sequence,omitempty"`
+	Addresses []string `protobuf:"bytes,5,rep,name=addresses" json:"addresses,omitempty"`
+	Value     []byte   `protobuf:"bytes,6,opt,name=value,proto3" json:"value,omitempty"`
+	Script    []byte   `protobuf:"bytes,7,opt,name=script,proto3" json:"script,omitempty"`
+	Coinbase  string   `protobuf:"bytes,8,opt,name=coinbase" json:"coinbase,omitempty"`
+}
+
+func (m *Transaction_Input) Reset()                    { *m = Transaction_Input{} }
+func (m *Transaction_Input) String() string            { return proto.CompactTextString(m) }
+func (*Transaction_Input) ProtoMessage()               {}
+func (*Transaction_Input) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10, 0} }
+
+func (m *Transaction_Input) GetN() uint32 {
+	if m != nil {
+		return m.N
+	}
+	return 0
+}
+
+func (m *Transaction_Input) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *Transaction_Input) GetVout() uint32 {
+	if m != nil {
+		return m.Vout
+	}
+	return 0
+}
+
+func (m *Transaction_Input) GetSequence() uint32 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *Transaction_Input) GetAddresses() []string {
+	if m != nil {
+		return m.Addresses
+	}
+	return nil
+}
+
+func (m *Transaction_Input) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Transaction_Input) GetScript() []byte {
+	if m != nil {
+		return m.Script
+	}
+	return nil
+}
+
+func (m *Transaction_Input) GetCoinbase() string {
+	if m != nil {
+		return m.Coinbase
+	}
+	return ""
+}
+
+type Transaction_Output struct {
+	N           uint32   `protobuf:"varint,1,opt,name=n" json:"n,omitempty"`
+	Value       []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Addresses   []string `protobuf:"bytes,3,rep,name=addresses" json:"addresses,omitempty"`
+	Script      []byte   `protobuf:"bytes,4,opt,name=script,proto3" json:"script,omitempty"`
+	Type        string   `protobuf:"bytes,5,opt,name=type" json:"type,omitempty"`
+	Spent       bool     `protobuf:"varint,6,opt,name=spent" json:"spent,omitempty"`
+	SpentTxid   string   `protobuf:"bytes,7,opt,name=spent_txid,json=spentTxid" json:"spent_txid,omitempty"`
+	SpentIndex  uint32   `protobuf:"varint,8,opt,name=spent_index,json=spentIndex" json:"spent_index,omitempty"`
+	SpentHeight uint32   `protobuf:"varint,9,opt,name=spent_height,json=spentHeight" json:"spent_height,omitempty"`
+}
+
+func (m *Transaction_Output) Reset()                    { *m = Transaction_Output{} }
+func (m *Transaction_Output) String() string            { return proto.CompactTextString(m) }
+func (*Transaction_Output) ProtoMessage()               {}
+func (*Transaction_Output) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10, 1} }
+
+func (m *Transaction_Output) GetN() uint32 {
+	if m != nil {
+		return m.N
+	}
+	return 0
+}
+
+func (m *Transaction_Output) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Transaction_Output) GetAddresses() []string {
+	if m != nil {
+		return m.Addresses
+	}
+	return nil
+}
+
+func (m *Transaction_Output) GetScript() []byte {
+	if m != nil {
+		return m.Script
+	}
+	return nil
+}
+
+func (m *Transaction_Output) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Transaction_Output) GetSpent() bool {
+	if m != nil {
+		return m.Spent
+	}
+	return false
+}
+
+func (m *Transaction_Output) GetSpentTxid() string {
+	if m != nil {
+		return m.SpentTxid
+	}
+	return ""
+}
+
+func (m *Transaction_Output) GetSpentIndex() uint32 {
+	if m != nil {
+		return m.SpentIndex
+	}
+	return 0
+}
+
+func (m *Transaction_Output) GetSpentHeight() uint32 {
+	if m != nil {
+		return m.SpentHeight
+	}
+	return 0
+}
+
+type SendTransactionRequest struct {
+	Hex []byte `protobuf:"bytes,1,opt,name=hex,proto3" json:"hex,omitempty"`
+}
+
+func (m *SendTransactionRequest) Reset()                    { *m = SendTransactionRequest{} }
+func (m *SendTransactionRequest) String() string            { return proto.CompactTextString(m) }
+func (*SendTransactionRequest) ProtoMessage()               {}
+func (*SendTransactionRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *SendTransactionRequest) GetHex() []byte {
+	if m != nil {
+		return m.Hex
+	}
+	return nil
+}
+
+type SendTransactionResponse struct {
+	Txid string `protobuf:"bytes,1,opt,name=txid" json:"txid,omitempty"`
+}
+
+func (m *SendTransactionResponse) Reset()                    { *m = SendTransactionResponse{} }
+func (m *SendTransactionResponse) String() string            { return proto.CompactTextString(m) }
+func (*SendTransactionResponse) ProtoMessage()               {}
+func (*SendTransactionResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *SendTransactionResponse) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+type EstimateFeeRequest struct {
+	Blocks []int32 `protobuf:"varint,1,rep,packed,name=blocks" json:"blocks,omitempty"`
+	// economical switches off the conservative estimate of Bitcoin type coins
+	Economical bool `protobuf:"varint,2,opt,name=economical" json:"economical,omitempty"`
+	// tx_size is the size of the transaction in bytes to compute fee_per_tx of Bitcoin type coins
+	TxSize uint32 `protobuf:"varint,3,opt,name=tx_size,json=txSize" json:"tx_size,omitempty"`
+	// specific are the parameters of the gas estimate of Ethereum type coins
+	Specific map[string]string `protobuf:"bytes,4,rep,name=specific" json:"specific,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *EstimateFeeRequest) Reset()                    { *m = EstimateFeeRequest{} }
+func (m *EstimateFeeRequest) String() string            { return proto.CompactTextString(m) }
+func (*EstimateFeeRequest) ProtoMessage()               {}
+func (*EstimateFeeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *EstimateFeeRequest) GetBlocks() []int32 {
+	if m != nil {
+		return m.Blocks
+	}
+	return nil
+}
+
+func (m *EstimateFeeRequest) GetEconomical() bool {
+	if m != nil {
+		return m.Economical
+	}
+	return false
+}
+
+func (m *EstimateFeeRequest) GetTxSize() uint32 {
+	if m != nil {
+		return m.TxSize
+	}
+	return 0
+}
+
+func (m *EstimateFeeRequest) GetSpecific() map[string]string {
+	if m != nil {
+		return m.Specific
+	}
+	return nil
+}
+
+type EstimateFeeResponse struct {
+	Fees []*EstimateFeeResponse_Fee `protobuf:"bytes,1,rep,name=fees" json:"fees,omitempty"`
+}
+
+func (m *EstimateFeeResponse) Reset()                    { *m = EstimateFeeResponse{} }
+func (m *EstimateFeeResponse) String() string            { return proto.CompactTextString(m) }
+func (*EstimateFeeResponse) ProtoMessage()               {}
+func (*EstimateFeeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *EstimateFeeResponse) GetFees() []*EstimateFeeResponse_Fee {
+	if m != nil {
+		return m.Fees
+	}
+	return nil
+}
+
+type EstimateFeeResponse_Fee struct {
+	FeePerTx   []byte `protobuf:"bytes,1,opt,name=fee_per_tx,json=feePerTx,proto3" json:"fee_per_tx,omitempty"`
+	FeePerUnit []byte `protobuf:"bytes,2,opt,name=fee_per_unit,json=feePerUnit,proto3" json:"fee_per_unit,omitempty"`
+	FeeLimit   uint64 `protobuf:"varint,3,opt,name=fee_limit,json=feeLimit" json:"fee_limit,omitempty"`
+}
+
+func (m *EstimateFeeResponse_Fee) Reset()                    { *m = EstimateFeeResponse_Fee{} }
+func (m *EstimateFeeResponse_Fee) String() string            { return proto.CompactTextString(m) }
+func (*EstimateFeeResponse_Fee) ProtoMessage()               {}
+func (*EstimateFeeResponse_Fee) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14, 0} }
+
+func (m *EstimateFeeResponse_Fee) GetFeePerTx() []byte {
+	if m != nil {
+		return m.FeePerTx
+	}
+	return nil
+}
+
+func (m *EstimateFeeResponse_Fee) GetFeePerUnit() []byte {
+	if m != nil {
+		return m.FeePerUnit
+	}
+	return nil
+}
+
+func (m *EstimateFeeResponse_Fee) GetFeeLimit() uint64 {
+	if m != nil {
+		return m.FeeLimit
+	}
+	return 0
+}
+
+type SubscribeNewBlockRequest struct {
+}
+
+func (m *SubscribeNewBlockRequest) Reset()                    { *m = SubscribeNewBlockRequest{} }
+func (m *SubscribeNewBlockRequest) String() string            { return proto.CompactTextString(m) }
+func (*SubscribeNewBlockRequest) ProtoMessage()               {}
+func (*SubscribeNewBlockRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+type NewBlock struct {
+	Height uint32 `protobuf:"varint,1,opt,name=height" json:"height,omitempty"`
+	Hash   string `protobuf:"bytes,2,opt,name=hash" json:"hash,omitempty"`
+}
+
+func (m *NewBlock) Reset()                    { *m = NewBlock{} }
+func (m *NewBlock) String() string            { return proto.CompactTextString(m) }
+func (*NewBlock) ProtoMessage()               {}
+func (*NewBlock) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *NewBlock) GetHeight() uint32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *NewBlock) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+type SubscribeAddressesRequest struct {
+	Addresses []string `protobuf:"bytes,1,rep,name=addresses" json:"addresses,omitempty"`
+}
+
+func (m *SubscribeAddressesRequest) Reset()                    { *m = SubscribeAddressesRequest{} }
+func (m *SubscribeAddressesRequest) String() string            { return proto.CompactTextString(m) }
+func (*SubscribeAddressesRequest) ProtoMessage()               {}
+func (*SubscribeAddressesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *SubscribeAddressesRequest) GetAddresses() []string {
+	if m != nil {
+		return m.Addresses
+	}
+	return nil
+}
+
+type AddressEvent struct {
+	Address string       `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Tx      *Transaction `protobuf:"bytes,2,opt,name=tx" json:"tx,omitempty"`
+}
+
+func (m *AddressEvent) Reset()                    { *m = AddressEvent{} }
+func (m *AddressEvent) String() string            { return proto.CompactTextString(m) }
+func (*AddressEvent) ProtoMessage()               {}
+func (*AddressEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *AddressEvent) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *AddressEvent) GetTx() *Transaction {
+	if m != nil {
+		return m.Tx
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*GetInfoRequest)(nil), "blockbook.GetInfoRequest")
+	proto.RegisterType((*Info)(nil), "blockbook.Info")
+	proto.RegisterType((*AccountInfoRequest)(nil), "blockbook.AccountInfoRequest")
+	proto.RegisterType((*Token)(nil), "blockbook.Token")
+	proto.RegisterType((*AccountInfo)(nil), "blockbook.AccountInfo")
+	proto.RegisterType((*AccountUtxoRequest)(nil), "blockbook.AccountUtxoRequest")
+	proto.RegisterType((*Utxo)(nil), "blockbook.Utxo")
+	proto.RegisterType((*AccountUtxo)(nil), "blockbook.AccountUtxo")
+	proto.RegisterType((*GetTransactionRequest)(nil), "blockbook.GetTransactionRequest")
+	proto.RegisterType((*TokenTransfer)(nil), "blockbook.TokenTransfer")
+	proto.RegisterType((*Transaction)(nil), "blockbook.Transaction")
+	proto.RegisterType((*Transaction_Input)(nil), "blockbook.Transaction.Input")
+	proto.RegisterType((*Transaction_Output)(nil), "blockbook.Transaction.Output")
+	proto.RegisterType((*SendTransactionRequest)(nil), "blockbook.SendTransactionRequest")
+	proto.RegisterType((*SendTransactionResponse)(nil), "blockbook.SendTransactionResponse")
+	proto.RegisterType((*EstimateFeeRequest)(nil), "blockbook.EstimateFeeRequest")
+	proto.RegisterType((*EstimateFeeResponse)(nil), "blockbook.EstimateFeeResponse")
+	proto.RegisterType((*EstimateFeeResponse_Fee)(nil), "blockbook.EstimateFeeResponse.Fee")
+	proto.RegisterType((*SubscribeNewBlockRequest)(nil), "blockbook.SubscribeNewBlockRequest")
+	proto.RegisterType((*NewBlock)(nil), "blockbook.NewBlock")
+	proto.RegisterType((*SubscribeAddressesRequest)(nil), "blockbook.SubscribeAddressesRequest")
+	proto.RegisterType((*AddressEvent)(nil), "blockbook.AddressEvent")
+	proto.RegisterEnum("blockbook.AccountInfoRequest_Details", AccountInfoRequest_Details_name, AccountInfoRequest_Details_value)
+	proto.RegisterEnum("blockbook.AccountInfoRequest_Tokens", AccountInfoRequest_Tokens_name, AccountInfoRequest_Tokens_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Blockbook service
+
+type BlockbookClient interface {
+	GetInfo(ctx context.Context, in *GetInfoRequest, opts ...grpc.CallOption) (*Info, error)
+	GetAccountInfo(ctx context.Context, in *AccountInfoRequest, opts ...grpc.CallOption) (*AccountInfo, error)
+	GetAccountUtxo(ctx context.Context, in *AccountUtxoRequest, opts ...grpc.CallOption) (*AccountUtxo, error)
+	GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*Transaction, error)
+	SendTransaction(ctx context.Context, in *SendTransactionRequest, opts ...grpc.CallOption) (*SendTransactionResponse, error)
+	EstimateFee(ctx context.Context, in *EstimateFeeRequest, opts ...grpc.CallOption) (*EstimateFeeResponse, error)
+	SubscribeNewBlock(ctx context.Context, in *SubscribeNewBlockRequest, opts ...grpc.CallOption) (Blockbook_SubscribeNewBlockClient, error)
+	SubscribeAddresses(ctx context.Context, in *SubscribeAddressesRequest, opts ...grpc.CallOption) (Blockbook_SubscribeAddressesClient, error)
+}
+
+type blockbookClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewBlockbookClient(cc *grpc.ClientConn) BlockbookClient {
+	return &blockbookClient{cc}
+}
+
+func (c *blockbookClient) GetInfo(ctx context.Context, in *GetInfoRequest, opts ...grpc.CallOption) (*Info, error) {
+	out := new(Info)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/GetInfo", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) GetAccountInfo(ctx context.Context, in *AccountInfoRequest, opts ...grpc.CallOption) (*AccountInfo, error) {
+	out := new(AccountInfo)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/GetAccountInfo", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) GetAccountUtxo(ctx context.Context, in *AccountUtxoRequest, opts ...grpc.CallOption) (*AccountUtxo, error) {
+	out := new(AccountUtxo)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/GetAccountUtxo", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*Transaction, error) {
+	out := new(Transaction)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/GetTransaction", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) SendTransaction(ctx context.Context, in *SendTransactionRequest, opts ...grpc.CallOption) (*SendTransactionResponse, error) {
+	out := new(SendTransactionResponse)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/SendTransaction", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) EstimateFee(ctx context.Context, in *EstimateFeeRequest, opts ...grpc.CallOption) (*EstimateFeeResponse, error) {
+	out := new(EstimateFeeResponse)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/EstimateFee", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) SubscribeNewBlock(ctx context.Context, in *SubscribeNewBlockRequest, opts ...grpc.CallOption) (Blockbook_SubscribeNewBlockClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Blockbook_serviceDesc.Streams[0], c.cc, "/blockbook.Blockbook/SubscribeNewBlock", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &blockbookSubscribeNewBlockClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Blockbook_SubscribeNewBlockClient interface {
+	Recv() (*NewBlock, error)
+	grpc.ClientStream
+}
+
+type blockbookSubscribeNewBlockClient struct {
+	grpc.ClientStream
+}
+
+func (x *blockbookSubscribeNewBlockClient) Recv() (*NewBlock, error) {
+	m := new(NewBlock)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *blockbookClient) SubscribeAddresses(ctx context.Context, in *SubscribeAddressesRequest, opts ...grpc.CallOption) (Blockbook_SubscribeAddressesClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Blockbook_serviceDesc.Streams[1], c.cc, "/blockbook.Blockbook/SubscribeAddresses", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &blockbookSubscribeAddressesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Blockbook_SubscribeAddressesClient interface {
+	Recv() (*AddressEvent, error)
+	grpc.ClientStream
+}
+
+type blockbookSubscribeAddressesClient struct {
+	grpc.ClientStream
+}
+
+func (x *blockbookSubscribeAddressesClient) Recv() (*AddressEvent, error) {
+	m := new(AddressEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for Blockbook service
+
+type BlockbookServer interface {
+	GetInfo(context.Context, *GetInfoRequest) (*Info, error)
+	GetAccountInfo(context.Context, *AccountInfoRequest) (*AccountInfo, error)
+	GetAccountUtxo(context.Context, *AccountUtxoRequest) (*AccountUtxo, error)
+	GetTransaction(context.Context, *GetTransactionRequest) (*Transaction, error)
+	SendTransaction(context.Context, *SendTransactionRequest) (*SendTransactionResponse, error)
+	EstimateFee(context.Context, *EstimateFeeRequest) (*EstimateFeeResponse, error)
+	SubscribeNewBlock(*SubscribeNewBlockRequest, Blockbook_SubscribeNewBlockServer) error
+	SubscribeAddresses(*SubscribeAddressesRequest, Blockbook_SubscribeAddressesServer) error
+}
+
+func RegisterBlockbookServer(s *grpc.Server, srv BlockbookServer) {
+	s.RegisterService(&_Blockbook_serviceDesc, srv)
+}
+
+func _Blockbook_GetInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).GetInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/GetInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).GetInfo(ctx, req.(*GetInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_GetAccountInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).GetAccountInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/GetAccountInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).GetAccountInfo(ctx, req.(*AccountInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_GetAccountUtxo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountUtxoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).GetAccountUtxo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/GetAccountUtxo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).GetAccountUtxo(ctx, req.(*AccountUtxoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_GetTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).GetTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/GetTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).GetTransaction(ctx, req.(*GetTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_SendTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).SendTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/SendTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).SendTransaction(ctx, req.(*SendTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_EstimateFee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EstimateFeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).EstimateFee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/EstimateFee",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).EstimateFee(ctx, req.(*EstimateFeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_SubscribeNewBlock_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeNewBlockRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlockbookServer).SubscribeNewBlock(m, &blockbookSubscribeNewBlockServer{stream})
+}
+
+type Blockbook_SubscribeNewBlockServer interface {
+	Send(*NewBlock) error
+	grpc.ServerStream
+}
+
+type blockbookSubscribeNewBlockServer struct {
+	grpc.ServerStream
+}
+
+func (x *blockbookSubscribeNewBlockServer) Send(m *NewBlock) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Blockbook_SubscribeAddresses_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeAddressesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlockbookServer).SubscribeAddresses(m, &blockbookSubscribeAddressesServer{stream})
+}
+
+type Blockbook_SubscribeAddressesServer interface {
+	Send(*AddressEvent) error
+	grpc.ServerStream
+}
+
+type blockbookSubscribeAddressesServer struct {
+	grpc.ServerStream
+}
+
+func (x *blockbookSubscribeAddressesServer) Send(m *AddressEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Blockbook_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "blockbook.Blockbook",
+	HandlerType: (*BlockbookServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetInfo",
+			Handler:    _Blockbook_GetInfo_Handler,
+		},
+		{
+			MethodName: "GetAccountInfo",
+			Handler:    _Blockbook_GetAccountInfo_Handler,
+		},
+		{
+			MethodName: "GetAccountUtxo",
+			Handler:    _Blockbook_GetAccountUtxo_Handler,
+		},
+		{
+			MethodName: "GetTransaction",
+			Handler:    _Blockbook_GetTransaction_Handler,
+		},
+		{
+			MethodName: "SendTransaction",
+			Handler:    _Blockbook_SendTransaction_Handler,
+		},
+		{
+			MethodName: "EstimateFee",
+			Handler:    _Blockbook_EstimateFee_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeNewBlock",
+			Handler:       _Blockbook_SubscribeNewBlock_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeAddresses",
+			Handler:       _Blockbook_SubscribeAddresses_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "blockbook.proto",
+}
+
+func init() { proto.RegisterFile("blockbook.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 1766 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x58, 0xdd, 0x72, 0x1b, 0x49,
+	0x15, 0xde, 0x91, 0x34, 0xd2, 0xcc, 0xd1, 0x8f, 0xb5, 0x9d, 0xc5, 0x99, 0x88, 0x64, 0xe3, 0x0c,
+	0x09, 0xb8, 0x58, 0x30, 0x21, 0xc0, 0x16, 0x2c, 0x54, 0x81, 0x1d, 0x2b, 0x89, 0x21, 0x65, 0xa7,
+	0x5a, 0x0a, 0x95, 0xda, 0x1b, 0xd5, 0x68, 0xd4, 0x8a, 0xa7, 0x22, 0xf5, 0x88, 0x99, 0x96, 0x51,
+	0xf6, 0x09, 0xb8, 0xe5, 0x4d, 0xe0, 0x8e, 0x2a, 0xae, 0x78, 0x08, 0x1e, 0x81, 0x2b, 0x2e, 0xe0,
+	0x11, 0xa8, 0x73, 0xba, 0x67, 0xd4, 0xb2, 0xe4, 0x2c, 0xb5, 0x57, 0xee, 0xf3, 0xd3, 0x3d, 0xdd,
+	0xdf, 0xf9, 0xce, 0xd7, 0x2d, 0xc3, 0xde, 0x78, 0x96, 0xc6, 0xef, 0xc6, 0x69, 0xfa, 0xee, 0x68,
+	0x91, 0xa5, 0x2a, 0x65, 0x7e, 0xe9, 0x08, 0xbb, 0xd0, 0x79, 0x2e, 0xd4, 0x99, 0x9c, 0xa6, 0x5c,
+	0xfc, 0x61, 0x29, 0x72, 0x15, 0xfe, 0xcb, 0x81, 0x1a, 0xda, 0x8c, 0x41, 0x4d, 0x46, 0x73, 0x11,
+	0x38, 0x07, 0xce, 0xa1, 0xcf, 0x69, 0xcc, 0x7a, 0xe0, 0xe5, 0x97, 0x69, 0xa6, 0xe2, 0xa5, 0x0a,
+	0x2a, 0xe4, 0x2f, 0x6d, 0x8c, 0x4d, 0x44, 0x9c, 0xcc, 0xa3, 0x59, 0x1e, 0x54, 0x0f, 0x9c, 0x43,
+	0x97, 0x97, 0x36, 0x0b, 0xa0, 0x71, 0x25, 0xb2, 0x3c, 0x49, 0x65, 0x50, 0xa3, 0x69, 0x85, 0xc9,
+	0xee, 0x43, 0x73, 0x2c, 0x72, 0x35, 0xba, 0x14, 0xc9, 0xdb, 0x4b, 0x15, 0xb8, 0x07, 0xce, 0x61,
+	0x9b, 0x03, 0xba, 0x5e, 0x90, 0x87, 0x7d, 0x1b, 0x7c, 0x9d, 0x10, 0xe5, 0x97, 0x41, 0x5d, 0x7f,
+	0x93, 0xc2, 0x51, 0x7e, 0x49, 0xb3, 0xf1, 0x2c, 0x8f, 0x75, 0xb8, 0x41, 0x61, 0xd0, 0x2e, 0x4a,
+	0x08, 0xa0, 0xa1, 0x44, 0xae, 0xa4, 0x50, 0x81, 0x77, 0xe0, 0x1c, 0x7a, 0xbc, 0x30, 0xc3, 0x7f,
+	0x56, 0x81, 0x1d, 0xc7, 0x71, 0xba, 0x94, 0xf6, 0xf1, 0x71, 0x42, 0xa4, 0xbd, 0xe6, 0xe0, 0x85,
+	0xc9, 0x7e, 0x0d, 0x8d, 0x89, 0x50, 0x51, 0x32, 0xcb, 0xe9, 0xe8, 0x9d, 0x27, 0x8f, 0x8e, 0xd6,
+	0xc0, 0x6e, 0xaf, 0x74, 0x74, 0xaa, 0x93, 0x79, 0x31, 0x8b, 0xfd, 0x0a, 0xea, 0x2a, 0x7d, 0x27,
+	0xa4, 0x86, 0xa7, 0xf3, 0xe4, 0xe1, 0x87, 0xe7, 0x0f, 0x29, 0x97, 0x9b, 0x39, 0x58, 0x8e, 0x45,
+	0xf4, 0x56, 0x10, 0x7e, 0x2e, 0xa7, 0x31, 0x62, 0x83, 0x7f, 0x47, 0x79, 0xf2, 0x95, 0x20, 0xe8,
+	0x5c, 0xee, 0xa1, 0x63, 0x90, 0x7c, 0x25, 0x10, 0x9b, 0x69, 0x96, 0xce, 0x0b, 0x64, 0xeb, 0x1a,
+	0x59, 0x74, 0xad, 0x91, 0x55, 0x69, 0x11, 0x6e, 0x50, 0xd8, 0x53, 0xa9, 0x09, 0x7e, 0x0f, 0xf6,
+	0xe2, 0x54, 0xaa, 0x2c, 0x8a, 0xd5, 0x68, 0x9a, 0xcc, 0x94, 0xc8, 0x08, 0x40, 0x9f, 0x77, 0x0a,
+	0xf7, 0x33, 0xf2, 0xb2, 0x7d, 0xa8, 0xc7, 0xcb, 0x2c, 0x4f, 0xb3, 0xc0, 0xa7, 0xb8, 0xb1, 0xc2,
+	0x17, 0xd0, 0x30, 0x08, 0x30, 0x1f, 0xdc, 0x93, 0xe3, 0xc1, 0xd9, 0xd3, 0xee, 0x47, 0x0c, 0xa0,
+	0x3e, 0xbc, 0xf8, 0x5d, 0xff, 0x7c, 0xd0, 0x75, 0x18, 0x83, 0x0e, 0x8d, 0x47, 0x27, 0xc7, 0x2f,
+	0x8f, 0xcf, 0x9f, 0xf6, 0x07, 0xdd, 0x0a, 0xa6, 0x0e, 0xdf, 0x9c, 0x9d, 0x0e, 0xba, 0x55, 0xd6,
+	0x80, 0xea, 0xf0, 0xcd, 0xa0, 0x5b, 0x0b, 0x7f, 0x00, 0x75, 0x8d, 0x05, 0x6b, 0x42, 0xe3, 0xb4,
+	0xcf, 0xcf, 0x7e, 0xdf, 0x3f, 0xed, 0x7e, 0xc4, 0x3c, 0xa8, 0xbd, 0x1e, 0xf4, 0x4f, 0xbb, 0x0e,
+	0xba, 0xcf, 0x2f, 0xce, 0xbf, 0xec, 0xf3, 0x8b, 0x6e, 0x25, 0xfc, 0x73, 0x05, 0x5c, 0x4a, 0x47,
+	0xc4, 0xd4, 0xfb, 0x45, 0x49, 0x60, 0x1c, 0x97, 0xa4, 0xae, 0x58, 0xa4, 0x26, 0x64, 0xd5, 0x25,
+	0x55, 0xc5, 0xe7, 0x34, 0x46, 0x32, 0x17, 0xe7, 0x34, 0x8c, 0x2d, 0x6d, 0x76, 0x17, 0x7c, 0x95,
+	0x45, 0x32, 0x9f, 0x8a, 0x2c, 0x37, 0xa8, 0xaf, 0x1d, 0x88, 0x47, 0xfe, 0x7e, 0x3e, 0x4e, 0x67,
+	0x86, 0xac, 0xc6, 0xda, 0x68, 0x8f, 0xc6, 0x76, 0x7b, 0x8c, 0xa3, 0x59, 0x24, 0x63, 0x41, 0x20,
+	0xb7, 0x78, 0x61, 0xb2, 0x47, 0xd0, 0x51, 0xa9, 0x8a, 0x66, 0xa3, 0x4c, 0xc4, 0x22, 0xb9, 0x12,
+	0x13, 0x42, 0xb9, 0xc5, 0xdb, 0xe4, 0xe5, 0xc6, 0xc9, 0xee, 0x01, 0xe8, 0xb4, 0x5c, 0x48, 0x15,
+	0x00, 0xa5, 0xf8, 0xe4, 0x19, 0x08, 0xa9, 0xc2, 0x3f, 0xb9, 0xd0, 0xb4, 0x18, 0x46, 0x24, 0x9f,
+	0x4c, 0x32, 0x91, 0xe7, 0x25, 0xc9, 0xb5, 0x69, 0xef, 0xa4, 0xf2, 0x75, 0x3b, 0xa9, 0x7e, 0xfd,
+	0x4e, 0x6a, 0xd7, 0x76, 0xc2, 0x7e, 0x04, 0xb7, 0x96, 0x32, 0x4e, 0xe5, 0x34, 0xc9, 0xe6, 0x62,
+	0x32, 0x2a, 0xbe, 0xe5, 0x52, 0x1e, 0xb3, 0x42, 0x27, 0xe6, 0xb3, 0xbf, 0x81, 0xbb, 0x3b, 0x26,
+	0x8c, 0xa4, 0x78, 0x1b, 0xa9, 0xe4, 0x4a, 0x10, 0xc8, 0x1e, 0xef, 0x6d, 0xcf, 0x3c, 0x37, 0x19,
+	0xc8, 0x64, 0x7b, 0x05, 0xb5, 0x2a, 0xf0, 0xef, 0x58, 0xee, 0xe1, 0x2a, 0x67, 0x5d, 0xa8, 0x62,
+	0xd0, 0xa3, 0x20, 0x0e, 0x59, 0x08, 0x6d, 0x99, 0xca, 0x11, 0x75, 0x20, 0x4d, 0xf4, 0x29, 0xd6,
+	0x94, 0xa9, 0x24, 0x8a, 0xe1, 0xac, 0x2f, 0xa0, 0x45, 0xc5, 0x8f, 0x62, 0x95, 0xa4, 0x32, 0x0f,
+	0xe0, 0xa0, 0x7a, 0xd8, 0x7c, 0xb2, 0x6f, 0xf5, 0xf6, 0x70, 0x1d, 0xe6, 0x1b, 0xb9, 0xec, 0x13,
+	0x70, 0xd5, 0x2a, 0x99, 0xe4, 0x41, 0xf3, 0xa0, 0x7a, 0xe8, 0x73, 0x6d, 0xa0, 0x57, 0xa6, 0x88,
+	0x4a, 0x8b, 0x6a, 0xa3, 0x0d, 0xf6, 0x00, 0x5a, 0x1a, 0x58, 0xa3, 0x21, 0x6d, 0xbd, 0x15, 0xf2,
+	0x99, 0xf6, 0x38, 0x2c, 0x05, 0xa6, 0x43, 0x9b, 0xe8, 0xda, 0x9b, 0xc0, 0xc0, 0x96, 0x98, 0xec,
+	0x59, 0x62, 0x72, 0x1f, 0xf4, 0x62, 0x23, 0xb4, 0xf2, 0xa0, 0x4b, 0x21, 0x5d, 0xcc, 0x57, 0xe8,
+	0x41, 0x34, 0x12, 0x25, 0xe6, 0xf9, 0x28, 0x95, 0x94, 0x13, 0x7c, 0xac, 0xb7, 0x40, 0xce, 0x0b,
+	0xf9, 0xca, 0x2c, 0x22, 0xc5, 0x4a, 0x8d, 0x8c, 0x24, 0x30, 0x2d, 0xc8, 0xe8, 0x7a, 0xaa, 0x65,
+	0xe1, 0x65, 0xa9, 0xba, 0xaf, 0xd5, 0xea, 0xff, 0x50, 0xdd, 0xbb, 0xe0, 0x97, 0x45, 0x22, 0x4a,
+	0x7a, 0x7c, 0xed, 0x08, 0xff, 0xe2, 0x40, 0x0d, 0xd7, 0xa1, 0x5e, 0x5f, 0x25, 0x93, 0xb2, 0xd7,
+	0x57, 0xc9, 0x04, 0x7d, 0x57, 0xa9, 0xb9, 0xa8, 0xda, 0x9c, 0xc6, 0x88, 0xed, 0x55, 0x34, 0x5b,
+	0x0a, 0x43, 0x5e, 0x6d, 0x60, 0xcf, 0x1a, 0x19, 0xd4, 0xea, 0x6a, 0x2c, 0xf6, 0x10, 0xda, 0xe6,
+	0x5b, 0x91, 0x2e, 0xae, 0xee, 0xf6, 0x4d, 0xa7, 0xdd, 0x4d, 0xf5, 0xcd, 0x6e, 0x2a, 0x94, 0xa5,
+	0xb1, 0x56, 0x96, 0xf0, 0xa7, 0x65, 0x2b, 0xd2, 0xc6, 0x1f, 0x81, 0xbb, 0x54, 0xab, 0x14, 0x1b,
+	0x11, 0x4b, 0xb6, 0x67, 0x95, 0x8c, 0x00, 0xd2, 0xd1, 0xf0, 0x33, 0xf8, 0xd6, 0x73, 0xa1, 0x6c,
+	0x26, 0x19, 0xe4, 0x76, 0x1c, 0x3c, 0xfc, 0xbb, 0x03, 0x6d, 0xcd, 0x4f, 0xa3, 0x4a, 0x37, 0x49,
+	0x21, 0x5e, 0x06, 0x85, 0x14, 0xe2, 0x98, 0x75, 0xa0, 0xa2, 0x52, 0x23, 0x84, 0x15, 0x95, 0x12,
+	0x41, 0x71, 0x21, 0xa3, 0x81, 0xda, 0x28, 0x45, 0xd4, 0xb5, 0x44, 0xf4, 0x9b, 0xc8, 0x5e, 0x59,
+	0x0c, 0xcf, 0x2a, 0x46, 0xf8, 0xb7, 0x06, 0x34, 0xad, 0x83, 0xee, 0x2c, 0xad, 0xf5, 0x9e, 0xa8,
+	0xd0, 0xa2, 0x85, 0x89, 0x97, 0x1a, 0x02, 0x38, 0x52, 0xc9, 0x5c, 0x17, 0xb9, 0xcd, 0x3d, 0x74,
+	0x0c, 0x93, 0xb9, 0x60, 0x47, 0x50, 0xbd, 0x4a, 0xf0, 0x30, 0x08, 0xf5, 0xdd, 0xdd, 0x2d, 0x7a,
+	0x74, 0x26, 0x17, 0x4b, 0xc5, 0x31, 0x91, 0xfd, 0xd8, 0x30, 0xc8, 0xa5, 0x09, 0xf7, 0x6e, 0x98,
+	0x70, 0xb1, 0x54, 0x38, 0x43, 0x13, 0xec, 0x1e, 0xe8, 0xe7, 0x87, 0xfd, 0x5e, 0xd1, 0xef, 0x2d,
+	0x7a, 0x8f, 0x3c, 0x80, 0x96, 0x09, 0xaf, 0xaf, 0x5d, 0x97, 0xeb, 0x47, 0xcc, 0x8b, 0x1b, 0x48,
+	0xe7, 0xd1, 0x29, 0x36, 0x9d, 0xeb, 0xef, 0xd0, 0x41, 0x51, 0x97, 0xaa, 0xe6, 0x3b, 0x74, 0x52,
+	0x06, 0x35, 0x7a, 0x14, 0x80, 0xe6, 0x3e, 0x8e, 0xd7, 0x70, 0x37, 0x6d, 0xee, 0xdf, 0x01, 0x8f,
+	0x06, 0xa3, 0x44, 0x92, 0xe0, 0xb4, 0x78, 0x83, 0xec, 0x33, 0x42, 0x7e, 0x2a, 0x84, 0x96, 0x9a,
+	0x16, 0xa7, 0x31, 0x8a, 0xe4, 0xa5, 0x58, 0x05, 0x1d, 0x72, 0xe1, 0x10, 0x3d, 0xd9, 0x78, 0x4a,
+	0x52, 0xe2, 0x71, 0x1c, 0xb2, 0x63, 0xd8, 0x33, 0x92, 0x59, 0x5e, 0x93, 0x5d, 0x42, 0x30, 0xb8,
+	0x2e, 0x48, 0x05, 0x41, 0x79, 0x47, 0xd9, 0x66, 0xde, 0xfb, 0x87, 0x03, 0x2e, 0x15, 0x82, 0xb5,
+	0xc0, 0x91, 0x54, 0xfb, 0x36, 0x77, 0xd6, 0x64, 0xa8, 0xec, 0xe8, 0xf3, 0xaa, 0xd5, 0xe7, 0xf8,
+	0x50, 0xc5, 0x0e, 0x41, 0x19, 0xad, 0x69, 0x16, 0x14, 0x36, 0x4a, 0x8a, 0x69, 0x50, 0x91, 0x53,
+	0x69, 0x7d, 0xbe, 0x76, 0xac, 0x51, 0xaa, 0x5f, 0x53, 0x88, 0x3c, 0xce, 0x92, 0x85, 0xae, 0x58,
+	0x8b, 0x1b, 0x4b, 0xbf, 0x13, 0x12, 0x39, 0x8e, 0x72, 0x61, 0xde, 0x47, 0xa5, 0xdd, 0xfb, 0x8f,
+	0x03, 0x75, 0xcd, 0x8d, 0x6b, 0x87, 0x28, 0x3f, 0x51, 0xb1, 0x3f, 0xb1, 0xb1, 0xad, 0xea, 0xf5,
+	0x6d, 0xad, 0x37, 0x50, 0xdb, 0xd8, 0x40, 0xd1, 0xd9, 0xae, 0xd5, 0xd9, 0x9f, 0x80, 0x9b, 0x2f,
+	0xf0, 0xfa, 0xd5, 0x97, 0xa3, 0x36, 0x90, 0x31, 0x34, 0x18, 0x11, 0x80, 0x5a, 0x92, 0x7c, 0xf2,
+	0x0c, 0x11, 0xc5, 0xfb, 0xd0, 0xd4, 0xe1, 0x44, 0x4e, 0xc4, 0xca, 0x90, 0x4e, 0xcf, 0x38, 0x43,
+	0x0f, 0x52, 0x57, 0x27, 0x18, 0xea, 0xfa, 0x94, 0xa1, 0x27, 0x69, 0xea, 0x86, 0xdf, 0x87, 0xfd,
+	0x81, 0x90, 0x93, 0x1d, 0x32, 0x65, 0x68, 0xe3, 0x94, 0xb4, 0x09, 0x7f, 0x08, 0xb7, 0xb7, 0x72,
+	0xf3, 0x45, 0x2a, 0x73, 0xb1, 0x53, 0xd3, 0xfe, 0xed, 0x00, 0xeb, 0xe7, 0x2a, 0x99, 0x47, 0x4a,
+	0x3c, 0x13, 0xa2, 0x58, 0x77, 0x1f, 0xea, 0x44, 0x29, 0xad, 0x9f, 0x2e, 0x37, 0x16, 0xfb, 0x14,
+	0x40, 0xc4, 0xa9, 0x4c, 0xe7, 0x49, 0x1c, 0xcd, 0xcc, 0xbd, 0x61, 0x79, 0xd8, 0x6d, 0x68, 0xa8,
+	0x95, 0x7e, 0x37, 0x6b, 0xda, 0xd4, 0xd5, 0x8a, 0x5e, 0xcd, 0xcf, 0xc1, 0xcb, 0x17, 0x22, 0x4e,
+	0xa6, 0x49, 0x6c, 0x74, 0xe2, 0x33, 0x8b, 0xb4, 0xdb, 0x3b, 0x38, 0x1a, 0x98, 0xec, 0xbe, 0x54,
+	0xd9, 0x7b, 0x5e, 0x4e, 0xee, 0xfd, 0x12, 0xda, 0x1b, 0x21, 0x84, 0xe0, 0x9d, 0x78, 0x6f, 0x0e,
+	0x85, 0xc3, 0x4d, 0x1e, 0xf8, 0x86, 0x07, 0x5f, 0x54, 0x7e, 0xee, 0x84, 0x7f, 0x75, 0xe0, 0xd6,
+	0xc6, 0xb7, 0x0c, 0x32, 0x9f, 0x9b, 0x8e, 0xd4, 0x97, 0x45, 0x78, 0xd3, 0xce, 0x74, 0xf6, 0x11,
+	0x8e, 0x29, 0xbf, 0x37, 0x86, 0xea, 0x33, 0x81, 0x14, 0x83, 0xa9, 0x10, 0xa3, 0x85, 0xc8, 0x46,
+	0xaa, 0x28, 0x86, 0x37, 0x15, 0xe2, 0x95, 0xc8, 0x86, 0x2b, 0x76, 0x00, 0xad, 0x22, 0xba, 0x94,
+	0x89, 0x32, 0xec, 0x04, 0x1d, 0x7f, 0x2d, 0x13, 0xfa, 0xc5, 0x80, 0x19, 0xb3, 0x64, 0x9e, 0xe8,
+	0x76, 0xab, 0xd1, 0xf4, 0x97, 0x68, 0x87, 0x3d, 0x08, 0x06, 0xcb, 0x31, 0xd2, 0x72, 0x2c, 0xce,
+	0xc5, 0x1f, 0x4f, 0x70, 0x6b, 0xc5, 0x8f, 0xca, 0xcf, 0xc1, 0x2b, 0x5c, 0xd6, 0x65, 0xab, 0x1b,
+	0xc2, 0x58, 0x58, 0x75, 0xd2, 0x4c, 0xd3, 0xda, 0x38, 0x0e, 0x7f, 0x01, 0x77, 0xca, 0x35, 0x8f,
+	0x8b, 0x5e, 0x28, 0x6a, 0xbf, 0xd1, 0x30, 0xce, 0xb5, 0x86, 0x09, 0x5f, 0x41, 0xcb, 0xcc, 0xe8,
+	0x5f, 0x21, 0xfd, 0x6f, 0x7e, 0xf3, 0x7e, 0x17, 0x2a, 0x6a, 0x45, 0x9f, 0xbd, 0xf9, 0xdd, 0x56,
+	0x51, 0xab, 0x27, 0xff, 0xad, 0x81, 0x7f, 0x52, 0x44, 0xd9, 0xcf, 0xa0, 0x61, 0x7e, 0x39, 0xb3,
+	0x3b, 0xd6, 0xa4, 0xcd, 0x5f, 0xd3, 0x3d, 0xfb, 0x3e, 0xa7, 0xdc, 0xe7, 0xf4, 0x83, 0xdb, 0x7e,
+	0x8c, 0xdf, 0xfb, 0xe0, 0xcf, 0xc0, 0xde, 0xfe, 0xee, 0xf0, 0xe6, 0x42, 0xf4, 0x94, 0xd8, 0xb1,
+	0x90, 0xf5, 0xc6, 0xea, 0xed, 0xef, 0x0e, 0xb3, 0xdf, 0xd2, 0x42, 0xf6, 0x8d, 0x7b, 0xb0, 0x79,
+	0x9e, 0xed, 0x76, 0xee, 0xdd, 0x00, 0x13, 0x7b, 0x03, 0x7b, 0xd7, 0x9a, 0x9a, 0x3d, 0xb0, 0x52,
+	0x77, 0x8b, 0x43, 0x2f, 0xfc, 0x50, 0x8a, 0x61, 0xfe, 0x4b, 0x68, 0x5a, 0x14, 0xdf, 0x38, 0xeb,
+	0x76, 0x53, 0xf6, 0x3e, 0xfd, 0x70, 0x67, 0xb0, 0x0b, 0xf8, 0x78, 0x8b, 0xab, 0xec, 0x3b, 0xf6,
+	0x36, 0x6e, 0x60, 0x72, 0xef, 0x96, 0x95, 0x54, 0xc4, 0x1e, 0x3b, 0xec, 0x35, 0xb0, 0x6d, 0xa2,
+	0xb2, 0x87, 0xbb, 0x56, 0xbc, 0xce, 0xe3, 0xde, 0x6d, 0xbb, 0x30, 0x16, 0x65, 0x1f, 0x3b, 0x27,
+	0xb5, 0x2f, 0x2b, 0x8b, 0xf1, 0xb8, 0x4e, 0xff, 0xb6, 0xf9, 0xc9, 0xff, 0x06, 0x00, 0x0e, 0x74,
+	0x5c, 0xc8, 0xc9, 0x11, 0x00, 0x00,
+}
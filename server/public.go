@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/graphql-go/graphql"
 )
 
 const txsOnPage = 25
@@ -53,6 +54,7 @@ type PublicServer struct {
 	metrics          *common.Metrics
 	is               *common.InternalState
 	templates        []*template.Template
+	graphqlSchema    graphql.Schema
 	debug            bool
 }
 
@@ -101,6 +103,10 @@ func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bch
 		debug:            debugMode,
 	}
 	s.templates = s.parseTemplates()
+	s.graphqlSchema, err = newGraphQLSchema(api)
+	if err != nil {
+		return nil, err
+	}
 
 	// map only basic functions, the rest is enabled by method MapFullPublicInterface
 	serveMux.Handle(path+"favicon.ico", http.FileServer(http.Dir("./static/")))
@@ -179,12 +185,27 @@ func (s *PublicServer) ConnectFullPublicInterface() {
 	serveMux.HandleFunc(path+"api/v2/block-index/", s.jsonHandler(s.apiBlockIndex, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tx-specific/", s.jsonHandler(s.apiTxSpecific, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tx/", s.jsonHandler(s.apiTx, apiV2))
+	serveMux.HandleFunc(path+"api/v2/txs", limitRequestBody(s.jsonHandler(s.apiTxs, apiV2), maxTxsRequestBodySize))
 	serveMux.HandleFunc(path+"api/v2/address/", s.jsonHandler(s.apiAddress, apiV2))
 	serveMux.HandleFunc(path+"api/v2/xpub/", s.jsonHandler(s.apiXpub, apiV2))
+	serveMux.HandleFunc(path+"api/v2/xpub-discovery/", s.jsonHandler(s.apiXpubDiscovery, apiV2))
+	serveMux.HandleFunc(path+"api/v2/export/", s.apiExport)
+	serveMux.HandleFunc(path+"api/v2/balancehistory/", s.jsonHandler(s.apiBalanceHistory, apiV2))
+	serveMux.HandleFunc(path+"api/v2/addresses/", limitRequestBody(s.jsonHandler(s.apiAddresses, apiV2), maxAddressesRequestBodySize))
+	serveMux.HandleFunc(path+"api/v2/addresses-utxo/", limitRequestBody(s.jsonHandler(s.apiAddressesUtxo, apiV2), maxAddressesRequestBodySize))
 	serveMux.HandleFunc(path+"api/v2/utxo/", s.jsonHandler(s.apiUtxo, apiV2))
 	serveMux.HandleFunc(path+"api/v2/block/", s.jsonHandler(s.apiBlock, apiV2))
 	serveMux.HandleFunc(path+"api/v2/sendtx/", s.jsonHandler(s.apiSendTx, apiV2))
 	serveMux.HandleFunc(path+"api/v2/estimatefee/", s.jsonHandler(s.apiEstimateFee, apiV2))
+	serveMux.HandleFunc(path+"api/v2/mempool/stats", s.jsonHandler(s.apiMempoolStats, apiV2))
+	serveMux.HandleFunc(path+"api/v2/mempool/package/", s.jsonHandler(s.apiMempoolTxPackage, apiV2))
+	serveMux.HandleFunc(path+"api/v2/merkleproof/", s.jsonHandler(s.apiMerkleProof, apiV2))
+	serveMux.HandleFunc(path+"api/v2/headers", s.headersHandler(s.jsonHandler(s.apiHeaders, apiV2)))
+	serveMux.HandleFunc(path+"api/v2/blockfilter/", s.jsonHandler(s.apiBlockFilter, apiV2))
+	serveMux.HandleFunc(path+"api/v2/blockfilter-headers", s.jsonHandler(s.apiBlockFilterHeaders, apiV2))
+	serveMux.HandleFunc(path+"api/v2/tickers", s.jsonHandler(s.apiTickers, apiV2))
+	serveMux.HandleFunc(path+"api/v2/graphql", s.jsonHandler(s.apiGraphQL, apiV2))
+	serveMux.HandleFunc(path+"api/v2/openapi.json", s.jsonHandler(s.apiOpenAPI, apiV2))
 	// socket.io interface
 	serveMux.Handle(path+"socket.io/", s.socketio.GetHandler())
 	// websocket interface
@@ -215,6 +236,21 @@ func (s *PublicServer) OnNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor)
 	s.websocket.OnNewTxAddr(tx, desc)
 }
 
+// OnTxReplaced notifies users subscribed to addresses of a mempool transaction about its replacement
+func (s *PublicServer) OnTxReplaced(txid string, replacedBy string, rbf bool, desc bchain.AddressDescriptor) {
+	s.websocket.OnTxReplaced(txid, replacedBy, rbf, desc)
+}
+
+// OnMempoolResync notifies users subscribed to mempool statistics about resynchronized mempool
+func (s *PublicServer) OnMempoolResync() {
+	s.websocket.OnMempoolResync()
+}
+
+// OnNewFiatRatesTicker notifies users subscribed to fiat rates about the downloaded current rates
+func (s *PublicServer) OnNewFiatRatesTicker(ticker *db.CurrencyRatesTicker) {
+	s.websocket.OnNewFiatRatesTicker(ticker)
+}
+
 func (s *PublicServer) txRedirect(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, joinURL(s.explorerURL, r.URL.Path), 302)
 	s.metrics.ExplorerViews.With(common.Labels{"action": "tx-redirect"}).Inc()
@@ -243,10 +279,45 @@ func joinURL(base string, part string) string {
 	return part
 }
 
+// getPathParamAfter returns the rest of the url path after the segment, the param can contain slashes (output descriptors)
+func getPathParamAfter(r *http.Request, segment string) string {
+	i := strings.Index(r.URL.Path, segment)
+	if i < 0 {
+		return ""
+	}
+	return r.URL.Path[i+len(segment):]
+}
+
+// getCurrencies returns the lowercase currencies from the comma separated list in the query parameter currency
+func getCurrencies(r *http.Request) []string {
+	var currencies []string
+	for _, c := range strings.Split(r.URL.Query().Get("currency"), ",") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			currencies = append(currencies, c)
+		}
+	}
+	return currencies
+}
+
 func getFunctionName(i interface{}) string {
 	return runtime.FuncForPC(reflect.ValueOf(i).Pointer()).Name()
 }
 
+const (
+	// maxTxsRequestBodySize is the maximum size of the JSON array of txids, quoted txids of MaxBatchTransactions with separators and whitespace
+	maxTxsRequestBodySize = api.MaxBatchTransactions*80 + 1024
+	// maxAddressesRequestBodySize is the maximum size of the JSON array of addresses, MaxAccountAddresses long addresses
+	maxAddressesRequestBodySize = api.MaxAccountAddresses*160 + 1024
+)
+
+// limitRequestBody limits the size of the request body read by the handler, reading beyond the limit fails
+func limitRequestBody(handler http.HandlerFunc, limit int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		handler(w, r)
+	}
+}
+
 func (s *PublicServer) jsonHandler(handler func(r *http.Request, apiVersion int) (interface{}, error), apiVersion int) func(w http.ResponseWriter, r *http.Request) {
 	type jsonError struct {
 		Text       string `json:"error"`
@@ -411,6 +482,7 @@ type TemplateData struct {
 	NextPage             int
 	PagingRange          []int
 	PageParams           template.URL
+	NextCursor           string
 	TOSLink              string
 	SendTxHex            string
 	Status               string
@@ -636,6 +708,7 @@ func (s *PublicServer) getAddressQueryParams(r *http.Request, accountDetails api
 		TokensToReturn: tokensToReturn,
 		FromHeight:     uint32(from),
 		ToHeight:       uint32(to),
+		Cursor:         r.URL.Query().Get("cursor"),
 	}, filterParam, gap
 }
 
@@ -660,6 +733,7 @@ func (s *PublicServer) explorerAddress(w http.ResponseWriter, r *http.Request) (
 	data.Address = address
 	data.Page = address.Page
 	data.PagingRange, data.PrevPage, data.NextPage = getPagingRange(address.Page, address.TotalPages)
+	data.NextCursor = address.NextCursor
 	if filterParam != "" {
 		data.PageParams = template.URL("&filter=" + filterParam)
 		data.Address.Filter = filterParam
@@ -668,11 +742,7 @@ func (s *PublicServer) explorerAddress(w http.ResponseWriter, r *http.Request) (
 }
 
 func (s *PublicServer) explorerXpub(w http.ResponseWriter, r *http.Request) (tpl, *TemplateData, error) {
-	var xpub string
-	i := strings.LastIndexByte(r.URL.Path, '/')
-	if i > 0 {
-		xpub = r.URL.Path[i+1:]
-	}
+	xpub := getPathParamAfter(r, "/xpub/")
 	if len(xpub) == 0 {
 		return errorTpl, nil, api.NewAPIError("Missing xpub", true)
 	}
@@ -688,6 +758,7 @@ func (s *PublicServer) explorerXpub(w http.ResponseWriter, r *http.Request) (tpl
 	data.Address = address
 	data.Page = address.Page
 	data.PagingRange, data.PrevPage, data.NextPage = getPagingRange(address.Page, address.TotalPages)
+	data.NextCursor = address.NextCursor
 	if filterParam != "" {
 		data.PageParams = template.URL("&filter=" + filterParam)
 		data.Address.Filter = filterParam
@@ -704,7 +775,7 @@ func (s *PublicServer) explorerBlocks(w http.ResponseWriter, r *http.Request) (t
 	if ec != nil {
 		page = 0
 	}
-	blocks, err = s.api.GetBlocks(page, blocksOnPage)
+	blocks, err = s.api.GetBlocks(page, blocksOnPage, r.URL.Query().Get("cursor"))
 	if err != nil {
 		return errorTpl, nil, err
 	}
@@ -712,6 +783,7 @@ func (s *PublicServer) explorerBlocks(w http.ResponseWriter, r *http.Request) (t
 	data.Blocks = blocks
 	data.Page = blocks.Page
 	data.PagingRange, data.PrevPage, data.NextPage = getPagingRange(blocks.Page, blocks.TotalPages)
+	data.NextCursor = blocks.NextCursor
 	return blocksTpl, data, nil
 }
 
@@ -743,7 +815,7 @@ func (s *PublicServer) explorerIndex(w http.ResponseWriter, r *http.Request) (tp
 
 	s.metrics.ExplorerViews.With(common.Labels{"action": "index"}).Inc()
 	si, err = s.api.GetSystemInfo(false)
-	blocks, err = s.api.GetBlocks(0, 10)
+	blocks, err = s.api.GetBlocks(0, 10, "")
 	if err != nil {
 		return errorTpl, nil, err
 	}
@@ -816,7 +888,7 @@ func (s *PublicServer) explorerMempool(w http.ResponseWriter, r *http.Request) (
 	if ec != nil {
 		page = 0
 	}
-	mempoolTxids, err = s.api.GetMempool(page, mempoolTxsOnPage)
+	mempoolTxids, err = s.api.GetMempool(page, mempoolTxsOnPage, r.URL.Query().Get("cursor"))
 	if err != nil {
 		return errorTpl, nil, err
 	}
@@ -824,6 +896,7 @@ func (s *PublicServer) explorerMempool(w http.ResponseWriter, r *http.Request) (
 	data.MempoolTxids = mempoolTxids
 	data.Page = mempoolTxids.Page
 	data.PagingRange, data.PrevPage, data.NextPage = getPagingRange(mempoolTxids.Page, mempoolTxids.TotalPages)
+	data.NextCursor = mempoolTxids.NextCursor
 	return mempoolTpl, data, nil
 }
 
@@ -890,10 +963,11 @@ func (s *PublicServer) apiIndex(r *http.Request, apiVersion int) (interface{}, e
 	return s.api.GetSystemInfo(false)
 }
 
+type resultBlockIndex struct {
+	BlockHash string `json:"blockHash"`
+}
+
 func (s *PublicServer) apiBlockIndex(r *http.Request, apiVersion int) (interface{}, error) {
-	type resBlockIndex struct {
-		BlockHash string `json:"blockHash"`
-	}
 	var err error
 	var hash string
 	height := -1
@@ -911,7 +985,7 @@ func (s *PublicServer) apiBlockIndex(r *http.Request, apiVersion int) (interface
 		glog.Error(err)
 		return nil, err
 	}
-	return resBlockIndex{
+	return resultBlockIndex{
 		BlockHash: hash,
 	}, nil
 }
@@ -937,10 +1011,42 @@ func (s *PublicServer) apiTx(r *http.Request, apiVersion int) (interface{}, erro
 		}
 	}
 	tx, err = s.api.GetTransaction(txid, spendingTxs, false)
-	if err == nil && apiVersion == apiV1 {
+	if err != nil {
+		return nil, err
+	}
+	if apiVersion == apiV1 {
 		return s.api.TxToV1(tx), nil
 	}
-	return tx, err
+	if currencies := getCurrencies(r); len(currencies) > 0 {
+		if err = s.api.SetTxFiatRates(tx, currencies); err != nil {
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+func (s *PublicServer) apiTxs(r *http.Request, apiVersion int) (interface{}, error) {
+	if r.Method != http.MethodPost {
+		return nil, api.NewAPIError("Use POST with JSON array of txids", true)
+	}
+	var txids []string
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-txs"}).Inc()
+	if err = json.NewDecoder(r.Body).Decode(&txids); err != nil {
+		return nil, api.NewAPIError("Cannot parse JSON array of txids", true)
+	}
+	if len(txids) == 0 {
+		return nil, api.NewAPIError("Missing txids", true)
+	}
+	spendingTxs := false
+	p := r.URL.Query().Get("spending")
+	if len(p) > 0 {
+		spendingTxs, err = strconv.ParseBool(p)
+		if err != nil {
+			return nil, api.NewAPIError("Parameter 'spending' cannot be converted to boolean", true)
+		}
+	}
+	return s.api.GetTransactions(txids, spendingTxs)
 }
 
 func (s *PublicServer) apiTxSpecific(r *http.Request, apiVersion int) (interface{}, error) {
@@ -980,11 +1086,7 @@ func (s *PublicServer) apiAddress(r *http.Request, apiVersion int) (interface{},
 }
 
 func (s *PublicServer) apiXpub(r *http.Request, apiVersion int) (interface{}, error) {
-	var xpub string
-	i := strings.LastIndexByte(r.URL.Path, '/')
-	if i > 0 {
-		xpub = r.URL.Path[i+1:]
-	}
+	xpub := getPathParamAfter(r, "/xpub/")
 	if len(xpub) == 0 {
 		return nil, api.NewAPIError("Missing xpub", true)
 	}
@@ -999,6 +1101,91 @@ func (s *PublicServer) apiXpub(r *http.Request, apiVersion int) (interface{}, er
 	return address, err
 }
 
+func (s *PublicServer) apiXpubDiscovery(r *http.Request, apiVersion int) (interface{}, error) {
+	xpub := getPathParamAfter(r, "/xpub-discovery/")
+	if len(xpub) == 0 {
+		return nil, api.NewAPIError("Missing xpub", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub-discovery"}).Inc()
+	gap, ec := strconv.Atoi(r.URL.Query().Get("gap"))
+	if ec != nil {
+		gap = 0
+	}
+	probe, ec := strconv.Atoi(r.URL.Query().Get("probe"))
+	if ec != nil {
+		probe = 0
+	}
+	return s.api.GetXpubDiscovery(xpub, gap, probe)
+}
+
+func (s *PublicServer) apiBalanceHistory(r *http.Request, apiVersion int) (interface{}, error) {
+	descriptor := getPathParamAfter(r, "/balancehistory/")
+	if len(descriptor) == 0 {
+		return nil, api.NewAPIError("Missing address or xpub", true)
+	}
+	var params [3]int64
+	for i, name := range []string{"from", "to", "groupBy"} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		var err error
+		if params[i], err = strconv.ParseInt(v, 10, 64); err != nil || params[i] < 0 {
+			return nil, api.NewAPIError("Parameter '"+name+"' is not a valid non-negative number", true)
+		}
+	}
+	gap, ec := strconv.Atoi(r.URL.Query().Get("gap"))
+	if ec != nil {
+		gap = 0
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-balancehistory"}).Inc()
+	return s.api.GetBalanceHistory(descriptor, params[0], params[1], params[2], gap, getCurrencies(r))
+}
+
+// getAddressesParam returns the list of addresses passed either as JSON array in the body of POST request
+// or as comma separated list in the last part of the url path
+func getAddressesParam(r *http.Request) ([]string, error) {
+	var addresses []string
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&addresses); err != nil {
+			return nil, api.NewAPIError("Cannot parse JSON array of addresses", true)
+		}
+	} else if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 && i < len(r.URL.Path)-1 {
+		addresses = strings.Split(r.URL.Path[i+1:], ",")
+	}
+	if len(addresses) == 0 {
+		return nil, api.NewAPIError("Missing address", true)
+	}
+	return addresses, nil
+}
+
+func (s *PublicServer) apiAddresses(r *http.Request, apiVersion int) (interface{}, error) {
+	addresses, err := getAddressesParam(r)
+	if err != nil {
+		return nil, err
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-addresses"}).Inc()
+	page, pageSize, details, filter, _, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	return s.api.GetAddresses(addresses, page, pageSize, details, filter)
+}
+
+func (s *PublicServer) apiAddressesUtxo(r *http.Request, apiVersion int) (interface{}, error) {
+	addresses, err := getAddressesParam(r)
+	if err != nil {
+		return nil, err
+	}
+	onlyConfirmed := false
+	c := r.URL.Query().Get("confirmed")
+	if len(c) > 0 {
+		onlyConfirmed, err = strconv.ParseBool(c)
+		if err != nil {
+			return nil, api.NewAPIError("Parameter 'confirmed' cannot be converted to boolean", true)
+		}
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-addresses-utxo"}).Inc()
+	return s.api.GetAddressesUtxo(addresses, onlyConfirmed)
+}
+
 func (s *PublicServer) apiUtxo(r *http.Request, apiVersion int) (interface{}, error) {
 	var utxo []api.Utxo
 	var err error
@@ -1015,7 +1202,7 @@ func (s *PublicServer) apiUtxo(r *http.Request, apiVersion int) (interface{}, er
 		if ec != nil {
 			gap = 0
 		}
-		utxo, err = s.api.GetXpubUtxo(r.URL.Path[i+1:], onlyConfirmed, gap)
+		utxo, err = s.api.GetXpubUtxo(getPathParamAfter(r, "/utxo/"), onlyConfirmed, gap)
 		if err == nil {
 			s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub-utxo"}).Inc()
 		} else {
@@ -1113,6 +1300,123 @@ func (s *PublicServer) apiEstimateFee(r *http.Request, apiVersion int) (interfac
 	return nil, api.NewAPIError("Missing parameter 'number of blocks'", true)
 }
 
+func (s *PublicServer) apiMempoolStats(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-mempool-stats"}).Inc()
+	return s.api.GetMempoolStats()
+}
+
+func (s *PublicServer) apiMempoolTxPackage(r *http.Request, apiVersion int) (interface{}, error) {
+	var txid string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		txid = r.URL.Path[i+1:]
+	}
+	if len(txid) == 0 {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-mempool-package"}).Inc()
+	return s.api.GetMempoolTxPackage(txid)
+}
+
+func (s *PublicServer) apiMerkleProof(r *http.Request, apiVersion int) (interface{}, error) {
+	var txid string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		txid = r.URL.Path[i+1:]
+	}
+	if len(txid) == 0 {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-merkleproof"}).Inc()
+	return s.api.GetMerkleProof(txid)
+}
+
+func (s *PublicServer) getHeadersParams(r *http.Request, defaultCount int) (uint32, int, error) {
+	start := r.URL.Query().Get("start")
+	if len(start) == 0 {
+		return 0, 0, api.NewAPIError("Missing parameter 'start'", true)
+	}
+	height, err := strconv.ParseUint(start, 10, 32)
+	if err != nil {
+		return 0, 0, api.NewAPIError("Parameter 'start' is not a number", true)
+	}
+	count := defaultCount
+	if c := r.URL.Query().Get("count"); len(c) > 0 {
+		if count, err = strconv.Atoi(c); err != nil {
+			return 0, 0, api.NewAPIError("Parameter 'count' is not a number", true)
+		}
+	}
+	return uint32(height), count, nil
+}
+
+// headersHandler returns the headers as concatenated binary data if the parameter format=bin is set,
+// otherwise and in case of error the request is passed to the json handler
+func (s *PublicServer) headersHandler(jsonHandler func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") == "bin" {
+			start, count, err := s.getHeadersParams(r, api.MaxBlockHeaders)
+			if err == nil {
+				var headers [][]byte
+				if headers, err = s.api.GetBlockHeadersRaw(start, count); err == nil {
+					s.metrics.ExplorerViews.With(common.Labels{"action": "api-headers-bin"}).Inc()
+					w.Header().Set("Content-Type", "application/octet-stream")
+					for _, h := range headers {
+						if _, err = w.Write(h); err != nil {
+							glog.Warning("headers write ", err)
+							return
+						}
+					}
+					return
+				}
+			}
+		}
+		jsonHandler(w, r)
+	}
+}
+
+func (s *PublicServer) apiHeaders(r *http.Request, apiVersion int) (interface{}, error) {
+	start, count, err := s.getHeadersParams(r, api.MaxBlockHeaders)
+	if err != nil {
+		return nil, err
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-headers"}).Inc()
+	return s.api.GetBlockHeaders(start, count)
+}
+
+func (s *PublicServer) apiBlockFilter(r *http.Request, apiVersion int) (interface{}, error) {
+	var block string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		block = r.URL.Path[i+1:]
+	}
+	if len(block) == 0 {
+		return nil, api.NewAPIError("Missing block height or hash", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-blockfilter"}).Inc()
+	return s.api.GetBlockFilter(block)
+}
+
+func (s *PublicServer) apiBlockFilterHeaders(r *http.Request, apiVersion int) (interface{}, error) {
+	start, count, err := s.getHeadersParams(r, api.MaxBlockFilterHeaders)
+	if err != nil {
+		return nil, err
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-blockfilter-headers"}).Inc()
+	return s.api.GetBlockFilterHeaders(start, count)
+}
+
+func (s *PublicServer) apiTickers(r *http.Request, apiVersion int) (interface{}, error) {
+	var timestamp int64
+	if t := r.URL.Query().Get("timestamp"); t != "" {
+		var err error
+		if timestamp, err = strconv.ParseInt(t, 10, 64); err != nil {
+			return nil, api.NewAPIError("Parameter 'timestamp' is not a valid unix time", true)
+		}
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tickers"}).Inc()
+	return s.api.GetFiatRatesForTimestamp(timestamp, getCurrencies(r))
+}
+
 // returns the amount of tokens on a given zerocoin denom
 func formatDenom(d bchain.ZCsupply) string {
 	val, _ := d.Amount.Float64()
@@ -8,6 +8,7 @@ import (
 	"blockbook/common"
 	"blockbook/db"
 	"blockbook/tests/dbtestdata"
+	"encoding/hex"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -47,6 +48,9 @@ func setupRocksDB(t *testing.T, parser bchain.BlockChainParser) (*db.RocksDB, *c
 		t.Fatal(err)
 	}
 	d.SetInternalState(is)
+	if err := d.SetBlockFilters(true); err != nil {
+		t.Fatal(err)
+	}
 	// import data
 	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(parser)); err != nil {
 		t.Fatal(err)
@@ -54,6 +58,23 @@ func setupRocksDB(t *testing.T, parser bchain.BlockChainParser) (*db.RocksDB, *c
 	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(parser)); err != nil {
 		t.Fatal(err)
 	}
+	// only the header of the first block is stored, the second one is taken from the backend
+	header, _ := hex.DecodeString(dbtestdata.TestBlockHeader1)
+	if err := d.StoreBlockHeaders(dbtestdata.GetTestBitcoinTypeBlock1(parser).Height, [][]byte{header}); err != nil {
+		t.Fatal(err)
+	}
+	// fiat rates of the day and hours of the test blocks
+	if err := d.FiatRatesStoreTickers(db.FiatRatesDaily, []db.CurrencyRatesTicker{
+		{Timestamp: 1534809600, Rates: map[string]float64{"usd": 2.1, "eur": 1.8, "btc": 0.0003}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.FiatRatesStoreTickers(db.FiatRatesHourly, []db.CurrencyRatesTicker{
+		{Timestamp: 1534856400, Rates: map[string]float64{"usd": 2.2, "eur": 1.9, "btc": 0.00031}},
+		{Timestamp: 1534859000, Rates: map[string]float64{"usd": 2.3, "eur": 2, "btc": 0.00032}},
+	}); err != nil {
+		t.Fatal(err)
+	}
 	return d, is, tmp
 }
 
@@ -413,6 +434,15 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","vin":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":2,"n":0,"addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"value":"9876"}],"vout":[{"value":"9000","n":0,"hex":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87","addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"]}],"blockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockheight":225494,"confirmations":1,"blocktime":22549400002,"value":"9000","valueIn":"9876","fees":"876"}`,
 			},
 		},
+		{
+			name:        "apiTx v2 currency without rates",
+			r:           newGetRequest(ts.URL + "/api/v2/tx/05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07?currency=usd"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"blocktime":22549400002,"value":"9000","valueIn":"9876","fees":"876"}`,
+			},
+		},
 		{
 			name:        "apiTx - not found v2",
 			r:           newGetRequest(ts.URL + "/api/v2/tx/1232e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07"),
@@ -422,6 +452,33 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"error":"Transaction '1232e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07' not found"}`,
 			},
 		},
+		{
+			name:        "apiTxs v2",
+			r:           newPostRequest(ts.URL+"/api/v2/txs", `["05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","1232e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07"]`),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","tx":{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","vin":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":2,"n":0,"addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"],"value":"9876"}],"vout":[{"value":"9000","n":0,"hex":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87","addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"]}],"blockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockheight":225494,"confirmations":1,"blocktime":22549400002,"value":"9000","valueIn":"9876","fees":"876"}},{"txid":"1232e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","error":"Transaction '1232e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07' not found"}]`,
+			},
+		},
+		{
+			name:        "apiTxs too large",
+			r:           newPostRequest(ts.URL+"/api/v2/txs", `["`+strings.Repeat("05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07", 2000)+`"]`),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Cannot parse JSON array of txids"}`,
+			},
+		},
+		{
+			name:        "apiTxs GET",
+			r:           newGetRequest(ts.URL + "/api/v2/txs"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Use POST with JSON array of txids"}`,
+			},
+		},
 		{
 			name:        "apiTxSpecific",
 			r:           newGetRequest(ts.URL + "/api/tx-specific/00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840"),
@@ -467,6 +524,33 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"transactions":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vin":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","n":0,"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"],"value":"1234567890123"},{"txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","vout":1,"n":1,"addresses":["mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"],"value":"12345"}],"vout":[{"value":"317283951061","n":0,"spent":true,"hex":"76a914ccaaaf374e1b06cb83118453d102587b4273d09588ac","addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"]},{"value":"917283951061","n":1,"hex":"76a9148d802c045445df49613f6a70ddd2e48526f3701f88ac","addresses":["mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"]}],"blockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockheight":225494,"confirmations":1,"blocktime":22549400000,"value":"1234567902122","valueIn":"1234567902468","fees":"346"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vin":[],"vout":[{"value":"1234567890123","n":0,"spent":true,"hex":"76a914a08eae93007f22668ab5e4a9c83c8cd1c325e3e088ac","addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"]},{"value":"1","n":1,"spent":true,"hex":"a91452724c5178682f70e0ba31c6ec0633755a3b41d987","addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"]},{"value":"9876","n":2,"spent":true,"hex":"a914e921fc4912a315078f370d959f2c4f7b6d2a683c87","addresses":["2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"]}],"blockhash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockheight":225493,"confirmations":2,"blocktime":22549300001,"value":"1234567900000","valueIn":"0","fees":"0"}]}`,
 			},
 		},
+		{
+			name:        "apiAddress v2 pageSize=1",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?pageSize=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":2,"itemsOnPage":1,"nextCursor":"aDIyNTQ5My4w","address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"txids":["7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25"]}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 cursor",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?pageSize=1&cursor=aDIyNTQ5My4w"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"itemsOnPage":1,"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"txids":["effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"]}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 invalid cursor",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?cursor=eDEuMg"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid cursor"}`,
+			},
+		},
 		{
 			name:        "apiAddress v2 missing address",
 			r:           newGetRequest(ts.URL + "/api/v2/address/"),
@@ -539,6 +623,324 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"page":1,"totalPages":1,"itemsOnPage":3,"address":"upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q","balance":"118641975500","totalReceived":"118641975501","totalSent":"1","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"transactions":[{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","vin":[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","n":0,"addresses":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"],"value":"317283951061"},{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":1,"n":1,"addresses":["2MzmAKayJmja784jyHvRUW1bXPget1csRRG"],"value":"1"}],"vout":[{"value":"118641975500","n":0,"hex":"a91495e9fbe306449c991d314afe3c3567d5bf78efd287","addresses":["2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu"]},{"value":"198641975500","n":1,"hex":"76a9143f8ba3fda3ba7b69f5818086e12223c6dd25e3c888ac","addresses":["mmJx9Y8ayz9h14yd9fgCW1bUKoEpkBAquP"]}],"blockhash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockheight":225494,"confirmations":1,"blocktime":22549400001,"value":"317283951000","valueIn":"317283951062","fees":"62"}],"totalTokens":2,"tokens":[{"type":"XPUBAddress","name":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","path":"m/49'/1'/33'/0/0","transfers":2,"decimals":8,"balance":"0","totalReceived":"1","totalSent":"1"},{"type":"XPUBAddress","name":"2MsYfbi6ZdVXLDNrYAQ11ja9Sd3otMk4Pmj","path":"m/49'/1'/33'/0/1","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2MuAZNAjLSo6RLFad2fvHSfgqBD7BoEVy4T","path":"m/49'/1'/33'/0/2","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2NEqKzw3BosGnBE9by5uaDy5QgwjHac4Zbg","path":"m/49'/1'/33'/0/3","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2Mw7vJNC8zUK6VNN4CEjtoTYmuNPLewxZzV","path":"m/49'/1'/33'/0/4","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N1kvo97NFASPXiwephZUxE9PRXunjTxEc4","path":"m/49'/1'/33'/0/5","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2MzSBtRWHbBjeUcu3H5VRDqkvz5sfmDxJKo","path":"m/49'/1'/33'/1/0","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2MtShtAJYb1afWduUTwF1SixJjan7urZKke","path":"m/49'/1'/33'/1/1","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N3cP668SeqyBEr9gnB4yQEmU3VyxeRYith","path":"m/49'/1'/33'/1/2","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu","path":"m/49'/1'/33'/1/3","transfers":1,"decimals":8,"balance":"118641975500","totalReceived":"118641975500","totalSent":"0"},{"type":"XPUBAddress","name":"2NEzatauNhf9kPTwwj6ZfYKjUdy52j4hVUL","path":"m/49'/1'/33'/1/4","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N4RjsDp4LBpkNqyF91aNjgpF9CwDwBkJZq","path":"m/49'/1'/33'/1/5","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N8XygTmQc4NoBBPEy3yybnfCYhsxFtzPDY","path":"m/49'/1'/33'/1/6","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2N5BjBomZvb48sccK2vwLMiQ5ETKp1fdPVn","path":"m/49'/1'/33'/1/7","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"2MybMwbZRPCGU3SMWPwQCpDkbcQFw5Hbwen","path":"m/49'/1'/33'/1/8","transfers":0,"decimals":8}]}`,
 			},
 		},
+		{
+			name:        "apiXpub v2 pageSize=1",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub + "?pageSize=1&details=txids"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":2,"itemsOnPage":1,"nextCursor":"aDIyNTQ5My4w","address":"upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q","balance":"118641975500","totalReceived":"118641975501","totalSent":"1","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"txids":["3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"],"totalTokens":2`,
+			},
+		},
+		{
+			name:        "apiXpub v2 cursor",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub + "?pageSize=1&details=txids&cursor=aDIyNTQ5My4w"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"itemsOnPage":1,"address":"upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q","balance":"118641975500","totalReceived":"118641975501","totalSent":"1","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"txids":["effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"],"totalTokens":2`,
+			},
+		},
+		{
+			name:        "apiAddresses v2",
+			r:           newGetRequest(ts.URL + "/api/v2/addresses/2MzmAKayJmja784jyHvRUW1bXPget1csRRG,2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"","balance":"118641975500","totalReceived":"118641975501","totalSent":"1","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"txids":["3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"],"totalTokens":2,"tokens":[{"type":"XPUBAddress","name":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu","transfers":1,"decimals":8,"balance":"118641975500","totalReceived":"118641975500","totalSent":"0"}]}`,
+			},
+		},
+		{
+			name:        "apiAddresses v2 POST details=basic",
+			r:           newPostRequest(ts.URL+"/api/v2/addresses/?details=basic", `["2MzmAKayJmja784jyHvRUW1bXPget1csRRG","2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu","2MzmAKayJmja784jyHvRUW1bXPget1csRRG"]`),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"address":"","balance":"118641975500","totalReceived":"118641975501","totalSent":"1","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":3,"totalTokens":2}`,
+			},
+		},
+		{
+			name:        "apiAddresses v2 invalid address",
+			r:           newGetRequest(ts.URL + "/api/v2/addresses/2MzmAKayJmja784jyHvRUW1bXPget1csRRG,xyz"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid address 'xyz'`,
+			},
+		},
+		{
+			name:        "apiAddresses v2 POST too large",
+			r:           newPostRequest(ts.URL+"/api/v2/addresses/", `["`+strings.Repeat("mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz", 10000)+`"]`),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Cannot parse JSON array of addresses"}`,
+			},
+		},
+		{
+			name:        "apiAddresses v2 missing address",
+			r:           newGetRequest(ts.URL + "/api/v2/addresses/"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing address"}`,
+			},
+		},
+		{
+			name:        "apiXpub v2 descriptor",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/sh(wpkh(%5B5c9e228d/49'/1'/33'%5D" + dbtestdata.Xpub + "/1/*))?tokens=used"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"tokens":[{"type":"XPUBAddress","name":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu","path":"m/49'/1'/33'/1/3","transfers":1,"decimals":8,"balance":"118641975500","totalReceived":"118641975500","totalSent":"0"}]}`,
+			},
+		},
+		{
+			name:        "apiXpub v2 invalid descriptor",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/wpkh(" + dbtestdata.Xpub + "/0/1)"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid descriptor, `,
+			},
+		},
+		{
+			name:        "apiXpubDiscovery",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub-discovery/" + dbtestdata.Xpub + "?gap=2&probe=10"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"xpub":"upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q","gap":2,"probe":10,"chains":[{"chain":0,"lastUsedIndex":0,"discoveredAddresses":3,"discoveredPaths":"m/49'/1'/33'/0/0-2","probedPaths":"m/49'/1'/33'/0/3-12"},{"chain":1,"lastUsedIndex":-1,"discoveredAddresses":3,"discoveredPaths":"m/49'/1'/33'/1/0-2","probedPaths":"m/49'/1'/33'/1/3-12","usedBeyondGap":[{"address":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu","path":"m/49'/1'/33'/1/3","index":3,"gap":4,"transfers":1,"balance":"118641975500"}],"requiredGap":4}]}`,
+			},
+		},
+		{
+			// the change chain is unused, the gap of the address found by probing is counted from before the index 0
+			name:        "apiXpubDiscovery unused change chain",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub-discovery/" + dbtestdata.Xpub + "?gap=1&probe=2"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"xpub":"upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q","gap":1,"probe":2,"chains":[{"chain":0,"lastUsedIndex":0,"discoveredAddresses":2,"discoveredPaths":"m/49'/1'/33'/0/0-1","probedPaths":"m/49'/1'/33'/0/2-3"},{"chain":1,"lastUsedIndex":-1,"discoveredAddresses":2,"discoveredPaths":"m/49'/1'/33'/1/0-1","probedPaths":"m/49'/1'/33'/1/2-3","usedBeyondGap":[{"address":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu","path":"m/49'/1'/33'/1/3","index":3,"gap":4,"transfers":1,"balance":"118641975500"}],"requiredGap":4}]}`,
+			},
+		},
+		{
+			name:        "apiMerkleProof indexed block",
+			r:           newGetRequest(ts.URL + "/api/v2/merkleproof/" + dbtestdata.TxidB2T3),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","blockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockHeight":225494,"pos":2,"merkle":["fdd824a780cbb718eeb766eb05d83fdefc793a27082cd5e67f856d69798cf7db","ca8b83277505d907b6e5b7c259198d2c4775b47567968b1b3b138422f21cf15b"]}`,
+			},
+		},
+		{
+			name:        "apiMerkleProof block from backend",
+			r:           newGetRequest(ts.URL + "/api/v2/merkleproof/" + dbtestdata.TxidB1T2),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockHeight":225493,"pos":1,"merkle":["00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840"]}`,
+			},
+		},
+		{
+			name:        "apiMerkleProof unknown tx",
+			r:           newGetRequest(ts.URL + "/api/v2/merkleproof/1111111111111111111111111111111111111111111111111111111111111111"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Transaction '1111111111111111111111111111111111111111111111111111111111111111' not found in any block"}`,
+			},
+		},
+		{
+			name:        "apiHeaders",
+			r:           newGetRequest(ts.URL + "/api/v2/headers?start=225493&count=5"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"start":225493,"count":2,"headers":["0100000001","0100000002"]}`,
+			},
+		},
+		{
+			name:        "apiHeaders binary",
+			r:           newGetRequest(ts.URL + "/api/v2/headers?start=225494&format=bin"),
+			status:      http.StatusOK,
+			contentType: "application/octet-stream",
+			body: []string{
+				"\x01\x00\x00\x00\x02",
+			},
+		},
+		{
+			name:        "apiHeaders start above best block",
+			r:           newGetRequest(ts.URL + "/api/v2/headers?start=225495"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Block 225495 not found"}`,
+			},
+		},
+		{
+			name:        "apiHeaders missing start",
+			r:           newGetRequest(ts.URL + "/api/v2/headers"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing parameter 'start'"}`,
+			},
+		},
+		{
+			name:        "apiBlockFilter height",
+			r:           newGetRequest(ts.URL + "/api/v2/blockfilter/225494"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"blockHash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockHeight":225494,"filter":"09`,
+			},
+		},
+		{
+			name:        "apiBlockFilter hash",
+			r:           newGetRequest(ts.URL + "/api/v2/blockfilter/0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"blockHash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockHeight":225493,"filter":"05`,
+			},
+		},
+		{
+			name:        "apiBlockFilter unknown block",
+			r:           newGetRequest(ts.URL + "/api/v2/blockfilter/12345"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Block 12345 not found"}`,
+			},
+		},
+		{
+			name:        "apiBlockFilterHeaders unknown header",
+			r:           newGetRequest(ts.URL + "/api/v2/blockfilter-headers?start=225493"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Filter header of block 225493 not found"}`,
+			},
+		},
+		{
+			name:        "apiTickers current",
+			r:           newGetRequest(ts.URL + "/api/v2/tickers"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"ts":1534859000,"rates":{"btc":0.00032,"eur":2,"usd":2.3}}`,
+			},
+		},
+		{
+			name:        "apiTickers hourly",
+			r:           newGetRequest(ts.URL + "/api/v2/tickers?timestamp=1534858021&currency=USD,eur,xyz"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"ts":1534856400,"rates":{"eur":1.9,"usd":2.2,"xyz":-1}}`,
+			},
+		},
+		{
+			name:        "apiTickers daily",
+			r:           newGetRequest(ts.URL + "/api/v2/tickers?timestamp=1534830000"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"ts":1534809600,"rates":{"btc":0.0003,"eur":1.8,"usd":2.1}}`,
+			},
+		},
+		{
+			name:        "apiTickers not available",
+			r:           newGetRequest(ts.URL + "/api/v2/tickers?timestamp=1534000000"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"No fiat rates available for timestamp 1534000000"}`,
+			},
+		},
+		{
+			name:        "apiTickers invalid timestamp",
+			r:           newGetRequest(ts.URL + "/api/v2/tickers?timestamp=yesterday"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Parameter 'timestamp' is not a valid unix time"}`,
+			},
+		},
+		{
+			name:        "apiGraphQL block",
+			r:           newPostRequest(ts.URL+"/api/v2/graphql", `{"query":"{block(id:\"225494\"){height hash txs(pageSize:1){txid}}}"}`),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"data":{"block":{"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","height":225494,"txs":[{"txid":"` + dbtestdata.TxidB2T1 + `"}]}}}`,
+			},
+		},
+		{
+			name:        "apiGraphQL variables",
+			r:           newPostRequest(ts.URL+"/api/v2/graphql", `{"query":"query B($id: String!){block(id:$id){height previousBlock{height}}}","variables":{"id":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6"}}`),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"data":{"block":{"height":225494,"previousBlock":{"height":225493}}}}`,
+			},
+		},
+		{
+			name:        "apiGraphQL address cursor",
+			r:           newPostRequest(ts.URL+"/api/v2/graphql", `{"query":"{address(address:\"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw\"){nextCursor(pageSize:1) transactions(pageSize:1,cursor:\"aDIyNTQ5My4w\"){txid}}}"}`),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"data":{"address":{"nextCursor":"aDIyNTQ5My4w","transactions":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"}]}}}`,
+			},
+		},
+		{
+			name:        "apiGraphQL max depth",
+			r:           newPostRequest(ts.URL+"/api/v2/graphql", `{"query":"{block(id:\"225494\"){previousBlock{previousBlock{previousBlock{previousBlock{previousBlock{nextBlock{nextBlock{nextBlock{nextBlock{nextBlock{hash}}}}}}}}}}}}"}`),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"data":null,"errors":[{"message":"Query depth 11 exceeds the maximum depth 10"`,
+			},
+		},
+		{
+			name:        "apiGraphQL max cost",
+			r:           newPostRequest(ts.URL+"/api/v2/graphql", `{"query":"{address(address:\"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz\"){transactions(pageSize:1000){vin{previousTransaction{txid}}}}}"}`),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"data":null,"errors":[{"message":"Query exceeds the maximum cost 5000"`,
+			},
+		},
+		{
+			name:        "apiGraphQL max cost variable default",
+			r:           newPostRequest(ts.URL+"/api/v2/graphql", `{"query":"query q($p:Int=1000){address(address:\"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz\"){transactions(pageSize:$p){vin{previousTransaction{txid}}}}}"}`),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"data":null,"errors":[{"message":"Query exceeds the maximum cost 5000"`,
+			},
+		},
+		{
+			name:        "apiGraphQL missing query",
+			r:           newGetRequest(ts.URL + "/api/v2/graphql"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing query"}`,
+			},
+		},
+		{
+			name:        "apiOpenAPI",
+			r:           newGetRequest(ts.URL + "/api/v2/openapi.json"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"openapi":"3.0.3"`,
+				`"/api/v2/tx/{txid}":{"get":{`,
+				`"Tx":{"properties":{`,
+			},
+		},
 		{
 			name:        "apiXpub v2 missing xpub",
 			r:           newGetRequest(ts.URL + "/api/v2/xpub/"),
@@ -548,6 +950,128 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"error":"Missing xpub"}`,
 			},
 		},
+		{
+			name:        "apiExport address csv",
+			r:           newGetRequest(ts.URL + "/api/v2/export/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"),
+			status:      http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			body: []string{
+				"blockTime,blockHeight,txid,direction,amount,fee,balance,counterparties\n" +
+					"1534859123,225494,7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25,outgoing,-1234567890123,0,0,mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL\n" +
+					"1534858021,225493,effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75,incoming,1234567890123,0,1234567890123,\n",
+			},
+		},
+		{
+			name:        "apiExport address jsonl to",
+			r:           newGetRequest(ts.URL + "/api/v2/export/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?format=jsonl&to=225493"),
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson; charset=utf-8",
+			body: []string{
+				`{"blockTime":1534858021,"blockHeight":225493,"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","direction":"incoming","amount":"1234567890123","fee":"0","balance":"1234567890123","counterparties":[]}` + "\n",
+			},
+		},
+		{
+			name:        "apiExport xpub jsonl",
+			r:           newGetRequest(ts.URL + "/api/v2/export/" + dbtestdata.Xpub + "?format=jsonl"),
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson; charset=utf-8",
+			body: []string{
+				`{"blockTime":1534859123,"blockHeight":225494,"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","direction":"incoming","amount":"118641975499","fee":"0","balance":"118641975500","counterparties":["mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX"]}` + "\n" +
+					`{"blockTime":1534858021,"blockHeight":225493,"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","direction":"incoming","amount":"1","fee":"0","balance":"1","counterparties":[]}` + "\n",
+			},
+		},
+		{
+			name:        "apiExport invalid format",
+			r:           newGetRequest(ts.URL + "/api/v2/export/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?format=xls"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid format, use csv or jsonl"}`,
+			},
+		},
+		{
+			name:        "apiExport negative from",
+			r:           newGetRequest(ts.URL + "/api/v2/export/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?from=-1"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Parameter 'from' cannot be negative"}`,
+			},
+		},
+		{
+			name:        "apiExport negative gap",
+			r:           newGetRequest(ts.URL + "/api/v2/export/" + dbtestdata.Xpub + "?to=225494&gap=-5"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Parameter 'gap' cannot be negative"}`,
+			},
+		},
+		{
+			name:        "apiExport address csv currency",
+			r:           newGetRequest(ts.URL + "/api/v2/export/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?currency=USD,eur"),
+			status:      http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			body: []string{
+				"blockTime,blockHeight,txid,direction,amount,fee,balance,counterparties,rate_usd,rate_eur\n" +
+					"1534859123,225494,7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25,outgoing,-1234567890123,0,0,mzB8cYrfRwFRFAGTDzV8LkUQy5BQicxGhX mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL,2.3,2\n" +
+					"1534858021,225493,effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75,incoming,1234567890123,0,1234567890123,,2.2,1.9\n",
+			},
+		},
+		{
+			name:        "apiBalanceHistory address",
+			r:           newGetRequest(ts.URL + "/api/v2/balancehistory/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"time":1534856400,"txs":2,"received":"1234567890123","sent":"1234567890123","balance":"0"}]`,
+			},
+		},
+		{
+			name:        "apiBalanceHistory address groupBy currency",
+			r:           newGetRequest(ts.URL + "/api/v2/balancehistory/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?groupBy=60&currency=USD"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"time":1534858020,"txs":1,"received":"1234567890123","sent":"0","balance":"1234567890123","rates":{"usd":2.2}},{"time":1534859100,"txs":1,"received":"0","sent":"1234567890123","balance":"0","rates":{"usd":2.3}}]`,
+			},
+		},
+		{
+			name:        "apiBalanceHistory xpub from",
+			r:           newGetRequest(ts.URL + "/api/v2/balancehistory/" + dbtestdata.Xpub + "?from=1534859000"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"time":1534856400,"txs":1,"received":"118641975499","sent":"0","balance":"118641975500"}]`,
+			},
+		},
+		{
+			name:        "apiBalanceHistory invalid groupBy",
+			r:           newGetRequest(ts.URL + "/api/v2/balancehistory/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?groupBy=-60"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Parameter 'groupBy' is not a valid non-negative number"}`,
+			},
+		},
+		{
+			name:        "apiExport address jsonl currency",
+			r:           newGetRequest(ts.URL + "/api/v2/export/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?format=jsonl&to=225493&currency=btc,xyz"),
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson; charset=utf-8",
+			body: []string{
+				`{"blockTime":1534858021,"blockHeight":225493,"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","direction":"incoming","amount":"1234567890123","fee":"0","balance":"1234567890123","counterparties":[],"rates":{"btc":0.00031,"xyz":-1}}` + "\n",
+			},
+		},
+		{
+			name:        "apiExport invalid address",
+			r:           newGetRequest(ts.URL + "/api/v2/export/1234"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid address, `,
+			},
+		},
 		{
 			name:        "apiUtxo v1",
 			r:           newGetRequest(ts.URL + "/api/v1/utxo/mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"),
@@ -575,6 +1099,15 @@ func httpTests_BitcoinType(t *testing.T, ts *httptest.Server) {
 				`[{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","vout":0,"value":"118641975500","height":225494,"confirmations":1,"address":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu","path":"m/49'/1'/33'/1/3"}]`,
 			},
 		},
+		{
+			name:        "apiAddressesUtxo v2",
+			r:           newGetRequest(ts.URL + "/api/v2/addresses-utxo/2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu,mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"txid":"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25","vout":1,"value":"917283951061","height":225494,"confirmations":1,"address":"mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"},{"txid":"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71","vout":0,"value":"118641975500","height":225494,"confirmations":1,"address":"2N6utyMZfPNUb1Bk8oz7p2JqJrXkq83gegu"}]`,
+			},
+		},
 		{
 			name:        "apiSendTx",
 			r:           newGetRequest(ts.URL + "/api/v2/sendtx/1234567890"),
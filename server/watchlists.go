@@ -0,0 +1,274 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/db"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// minimal period between two recomputations of the watchlist balances
+const watchlistsRefreshPeriod = 5 * time.Second
+
+// size of the buffer of events of one subscriber, the events are dropped for slow subscribers
+const watchlistEventsBuffer = 64
+
+// WatchlistEvent is sent to the subscribers when the balance of some item of a watchlist changes
+type WatchlistEvent struct {
+	*api.WatchlistBalance
+	Changed []string `json:"changed"`
+}
+
+type watchlists struct {
+	api          *api.Worker
+	db           *db.RocksDB
+	refresh      chan struct{}
+	done         chan struct{}
+	closeOnce    sync.Once
+	balances     map[string]*api.WatchlistBalance
+	subscribers  map[chan []byte]struct{}
+	subscribeMux sync.Mutex
+}
+
+func newWatchlists(w *api.Worker, d *db.RocksDB) *watchlists {
+	wls := &watchlists{
+		api:         w,
+		db:          d,
+		refresh:     make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		balances:    make(map[string]*api.WatchlistBalance),
+		subscribers: make(map[chan []byte]struct{}),
+	}
+	go wls.refreshLoop()
+	return wls
+}
+
+// scheduleRefresh requests recomputation of the watchlist balances, the requests are coalesced
+func (wls *watchlists) scheduleRefresh() {
+	select {
+	case wls.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func (wls *watchlists) close() {
+	wls.closeOnce.Do(func() { close(wls.done) })
+}
+
+func (wls *watchlists) refreshLoop() {
+	for {
+		select {
+		case <-wls.done:
+			return
+		case <-wls.refresh:
+			wls.refreshBalances()
+			// wait before the next recomputation, the requests made in the meantime are coalesced
+			select {
+			case <-wls.done:
+				return
+			case <-time.After(watchlistsRefreshPeriod):
+			}
+		}
+	}
+}
+
+// refreshBalances recomputes the balances of all watchlists and sends events about the changed ones,
+// the first computation of a watchlist only sets the baseline for the following changes
+func (wls *watchlists) refreshBalances() {
+	list, err := wls.db.GetWatchlists()
+	if err != nil {
+		glog.Error("GetWatchlists ", err)
+		return
+	}
+	balances := make(map[string]*api.WatchlistBalance, len(list))
+	for i := range list {
+		b, err := wls.api.GetWatchlistBalance(&list[i])
+		if err != nil {
+			glog.Error("GetWatchlistBalance ", list[i].Name, " ", err)
+			// keep the previous state to detect the change in the next round
+			if prev, found := wls.balances[list[i].Name]; found {
+				balances[list[i].Name] = prev
+			}
+			continue
+		}
+		balances[b.Name] = b
+		if prev, found := wls.balances[b.Name]; found {
+			if changed := watchlistChanges(prev, b); len(changed) > 0 {
+				wls.publish(&WatchlistEvent{WatchlistBalance: b, Changed: changed})
+			}
+		}
+	}
+	wls.balances = balances
+}
+
+// watchlistChanges returns descriptors of the items with changed balance, the items added to the watchlist are also reported
+func watchlistChanges(prev, cur *api.WatchlistBalance) []string {
+	prevItems := make(map[string]*api.WatchlistItemBalance, len(prev.Items))
+	for i := range prev.Items {
+		prevItems[prev.Items[i].Descriptor] = &prev.Items[i]
+	}
+	var changed []string
+	for i := range cur.Items {
+		c := &cur.Items[i]
+		p, found := prevItems[c.Descriptor]
+		if !found || p.Txs != c.Txs || p.UnconfirmedTxs != c.UnconfirmedTxs ||
+			p.BalanceSat.String() != c.BalanceSat.String() ||
+			p.UnconfirmedBalanceSat.String() != c.UnconfirmedBalanceSat.String() {
+			changed = append(changed, c.Descriptor)
+		}
+	}
+	return changed
+}
+
+func (wls *watchlists) publish(e *WatchlistEvent) {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		glog.Error("watchlist event ", err)
+		return
+	}
+	wls.subscribeMux.Lock()
+	defer wls.subscribeMux.Unlock()
+	for c := range wls.subscribers {
+		select {
+		case c <- buf:
+		default:
+			glog.Warning("watchlist events: subscriber is not reading, event dropped")
+		}
+	}
+}
+
+func (wls *watchlists) subscribe() chan []byte {
+	c := make(chan []byte, watchlistEventsBuffer)
+	wls.subscribeMux.Lock()
+	wls.subscribers[c] = struct{}{}
+	wls.subscribeMux.Unlock()
+	return c
+}
+
+func (wls *watchlists) unsubscribe(c chan []byte) {
+	wls.subscribeMux.Lock()
+	delete(wls.subscribers, c)
+	wls.subscribeMux.Unlock()
+}
+
+// authenticated wraps the handler of the internal API and checks the api key passed in the header "Authorization: Bearer <key>"
+func (s *InternalServer) authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(s.apiKey)) != 1 {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"Unauthorized"}` + "\n"))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *InternalServer) jsonHandler(handler func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	type jsonError struct {
+		Text string `json:"error"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusOK
+		data, err := handler(r)
+		if err != nil {
+			if apiErr, ok := err.(*api.APIError); ok && apiErr.Public {
+				status = http.StatusBadRequest
+				data = jsonError{apiErr.Error()}
+			} else {
+				glog.Error(getFunctionName(handler), " error: ", err)
+				status = http.StatusInternalServerError
+				data = jsonError{"Internal server error"}
+			}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		if err = json.NewEncoder(w).Encode(data); err != nil {
+			glog.Warning("json encode ", err)
+		}
+	}
+}
+
+func (s *InternalServer) apiWatchlists(r *http.Request) (interface{}, error) {
+	return s.db.GetWatchlists()
+}
+
+// apiWatchlist returns balances of the watchlist (GET), creates or replaces it (PUT, POST) or deletes it (DELETE)
+func (s *InternalServer) apiWatchlist(r *http.Request) (interface{}, error) {
+	name := getPathParamAfter(r, "/watchlists/")
+	if name == "" {
+		return nil, api.NewAPIError("Missing watchlist name", true)
+	}
+	switch r.Method {
+	case http.MethodGet:
+		wl, err := s.db.GetWatchlist(name)
+		if err != nil {
+			return nil, err
+		}
+		if wl == nil {
+			return nil, api.NewAPIError(fmt.Sprintf("Watchlist '%v' not found", name), true)
+		}
+		return s.api.GetWatchlistBalance(wl)
+	case http.MethodPut, http.MethodPost:
+		wl := db.Watchlist{Name: name}
+		if err := json.NewDecoder(r.Body).Decode(&wl); err != nil {
+			return nil, api.NewAPIError("Invalid watchlist, "+err.Error(), true)
+		}
+		// the name in the url has precedence
+		wl.Name = name
+		if err := s.api.ValidateWatchlist(&wl); err != nil {
+			return nil, err
+		}
+		if err := s.db.StoreWatchlist(&wl); err != nil {
+			return nil, err
+		}
+		if err := s.api.PersistWatchlistXpubs(&wl); err != nil {
+			return nil, err
+		}
+		s.watchlists.scheduleRefresh()
+		return &wl, nil
+	case http.MethodDelete:
+		if err := s.db.DeleteWatchlist(name); err != nil {
+			return nil, err
+		}
+		return struct {
+			Result string `json:"result"`
+		}{Result: "deleted"}, nil
+	}
+	return nil, api.NewAPIError("Unsupported method "+r.Method, true)
+}
+
+// watchlistEvents streams the watchlist events to the client as Server-Sent Events
+func (s *InternalServer) watchlistEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c := s.watchlists.subscribe()
+	defer s.watchlists.unsubscribe(c)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case buf := <-c:
+			if _, err := fmt.Fprintf(w, "event: watchlist\ndata: %s\n\n", buf); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,352 @@
+// +build unittest
+
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain/coins/btc"
+	"blockbook/common"
+	"blockbook/db"
+	"blockbook/tests/dbtestdata"
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testInternalAPIKey = "test-api-key"
+
+var (
+	internalTestMetrics     *common.Metrics
+	internalTestMetricsOnce sync.Once
+)
+
+// getInternalTestMetrics returns metrics of the internal server tests, the metrics can be registered only once in the process
+func getInternalTestMetrics(t *testing.T) *common.Metrics {
+	internalTestMetricsOnce.Do(func() {
+		var err error
+		if internalTestMetrics, err = common.GetMetrics("FakecoinInternal"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return internalTestMetrics
+}
+
+func setupInternalHTTPServer(t *testing.T) (*InternalServer, string) {
+	parser := btc.NewBitcoinParser(
+		btc.GetChainParams("test"),
+		&btc.Configuration{
+			BlockAddressesToKeep:  1,
+			XPubMagic:             70617039,
+			XPubMagicSegwitP2sh:   71979618,
+			XPubMagicSegwitNative: 73342198,
+			Slip44:                1,
+		})
+	d, is, path := setupRocksDB(t, parser)
+	is.Coin = "Fakecoin"
+	is.BestHeight = 225494
+	metrics := getInternalTestMetrics(t)
+	chain, err := dbtestdata.NewFakeBlockChain(parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mempool, err := chain.CreateMempool(chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txCache, err := db.NewTxCache(d, chain, metrics, is, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// s.Run is never called, binding can be to any port
+	s, err := NewInternalServer("localhost:12346", "", d, chain, mempool, txCache, metrics, is, testInternalAPIKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+func closeAndDestroyInternalServer(t *testing.T, s *InternalServer, dbpath string) {
+	s.Close()
+	if err := s.db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	os.RemoveAll(dbpath)
+}
+
+func newInternalRequest(t *testing.T, method, u, auth, body string) *http.Request {
+	r, err := http.NewRequest(method, u, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
+	return r
+}
+
+func Test_InternalServer_watchlists(t *testing.T) {
+	s, dbpath := setupInternalHTTPServer(t)
+	defer closeAndDestroyInternalServer(t, s, dbpath)
+	ts := httptest.NewServer(s.https.Handler)
+	defer ts.Close()
+
+	bearer := "Bearer " + testInternalAPIKey
+	tests := []struct {
+		name   string
+		r      *http.Request
+		status int
+		body   []string
+		check  func(t *testing.T)
+	}{
+		{
+			name:   "missing authorization",
+			r:      newInternalRequest(t, "GET", ts.URL+"/api/watchlists", "", ""),
+			status: http.StatusUnauthorized,
+			body:   []string{`{"error":"Unauthorized"}`},
+		},
+		{
+			name:   "key without Bearer prefix",
+			r:      newInternalRequest(t, "GET", ts.URL+"/api/watchlists", testInternalAPIKey, ""),
+			status: http.StatusUnauthorized,
+			body:   []string{`{"error":"Unauthorized"}`},
+		},
+		{
+			name:   "wrong key",
+			r:      newInternalRequest(t, "GET", ts.URL+"/api/watchlists", "Bearer wrong", ""),
+			status: http.StatusUnauthorized,
+			body:   []string{`{"error":"Unauthorized"}`},
+		},
+		{
+			name:   "unauthorized watchlist change",
+			r:      newInternalRequest(t, "PUT", ts.URL+"/api/watchlists/wl", "Basic "+testInternalAPIKey, `{"items":[{"descriptor":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"}]}`),
+			status: http.StatusUnauthorized,
+			body:   []string{`{"error":"Unauthorized"}`},
+		},
+		{
+			name:   "empty watchlists",
+			r:      newInternalRequest(t, "GET", ts.URL+"/api/watchlists", bearer, ""),
+			status: http.StatusOK,
+			body:   []string{`[]`},
+		},
+		{
+			name:   "invalid item",
+			r:      newInternalRequest(t, "PUT", ts.URL+"/api/watchlists/wl", bearer, `{"items":[{"descriptor":"invalid"}]}`),
+			status: http.StatusBadRequest,
+			body:   []string{`{"error":"Invalid watchlist item 'invalid', not an address or xpub"}`},
+		},
+		{
+			name:   "duplicate item",
+			r:      newInternalRequest(t, "PUT", ts.URL+"/api/watchlists/wl", bearer, `{"items":[{"descriptor":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"},{"descriptor":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"}]}`),
+			status: http.StatusBadRequest,
+			body:   []string{`{"error":"Duplicate watchlist item 'mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw'"}`},
+		},
+		{
+			name:   "store watchlist",
+			r:      newInternalRequest(t, "PUT", ts.URL+"/api/watchlists/wl", bearer, `{"name":"ignored","items":[{"descriptor":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","label":"spent"},{"descriptor":"`+dbtestdata.Xpub+`"}]}`),
+			status: http.StatusOK,
+			body:   []string{`{"name":"wl","items":[{"descriptor":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","label":"spent"},{"descriptor":"` + dbtestdata.Xpub + `"}]}`},
+			check: func(t *testing.T) {
+				// the state of the xpub in the watchlist is persisted
+				if xs, err := s.db.GetXpubState(dbtestdata.Xpub); err != nil || xs == nil {
+					t.Errorf("GetXpubState() = %+v, %v, want the stored state", xs, err)
+				}
+			},
+		},
+		{
+			name:   "list watchlists",
+			r:      newInternalRequest(t, "GET", ts.URL+"/api/watchlists", bearer, ""),
+			status: http.StatusOK,
+			body:   []string{`[{"name":"wl","items":[{"descriptor":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","label":"spent"},{"descriptor":"` + dbtestdata.Xpub + `"}]}]`},
+		},
+		{
+			name:   "watchlist balance",
+			r:      newInternalRequest(t, "GET", ts.URL+"/api/watchlists/wl", bearer, ""),
+			status: http.StatusOK,
+			body: []string{
+				`{"name":"wl","height":225494,"balance":"118641975500","unconfirmedBalance":"0","items":[`,
+				`{"descriptor":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","label":"spent","balance":"0","unconfirmedBalance":"0","txs":2,"unconfirmedTxs":0}`,
+				`{"descriptor":"` + dbtestdata.Xpub + `","balance":"118641975500","unconfirmedBalance":"0","txs":2,"unconfirmedTxs":0}`,
+			},
+		},
+		{
+			name:   "delete watchlist",
+			r:      newInternalRequest(t, "DELETE", ts.URL+"/api/watchlists/wl", bearer, ""),
+			status: http.StatusOK,
+			body:   []string{`{"result":"deleted"}`},
+			check: func(t *testing.T) {
+				// the state of the xpub stays persisted until it is evicted
+				if xs, err := s.db.GetXpubState(dbtestdata.Xpub); err != nil || xs == nil {
+					t.Errorf("GetXpubState() = %+v, %v, want the stored state after the watchlist is deleted", xs, err)
+				}
+			},
+		},
+		{
+			name:   "deleted watchlist",
+			r:      newInternalRequest(t, "GET", ts.URL+"/api/watchlists/wl", bearer, ""),
+			status: http.StatusBadRequest,
+			body:   []string{`{"error":"Watchlist 'wl' not found"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.DefaultClient.Do(tt.r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("StatusCode = %v, want %v", resp.StatusCode, tt.status)
+			}
+			b, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.body {
+				if !strings.Contains(string(b), want) {
+					t.Errorf("body %v does not contain %v", string(b), want)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t)
+			}
+		})
+	}
+}
+
+func Test_watchlists_refreshBalances(t *testing.T) {
+	s, dbpath := setupInternalHTTPServer(t)
+	defer closeAndDestroyInternalServer(t, s, dbpath)
+
+	// the refresh loop of the server is not used, refreshBalances is driven by the test
+	wls := &watchlists{
+		api:         s.api,
+		db:          s.db,
+		done:        make(chan struct{}),
+		balances:    make(map[string]*api.WatchlistBalance),
+		subscribers: make(map[chan []byte]struct{}),
+	}
+	c := wls.subscribe()
+	defer wls.unsubscribe(c)
+	store := func(items ...string) {
+		wl := db.Watchlist{Name: "wl"}
+		for _, d := range items {
+			wl.Items = append(wl.Items, db.WatchlistItem{Descriptor: d})
+		}
+		if err := s.db.StoreWatchlist(&wl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events := func() []WatchlistEvent {
+		var r []WatchlistEvent
+		for {
+			select {
+			case buf := <-c:
+				var e WatchlistEvent
+				if err := json.Unmarshal(buf, &e); err != nil {
+					t.Fatal(err)
+				}
+				r = append(r, e)
+			default:
+				return r
+			}
+		}
+	}
+
+	store("mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw")
+	wls.refreshBalances()
+	if e := events(); len(e) != 0 {
+		t.Errorf("events of the first computation = %+v, want none", e)
+	}
+	if b := wls.balances["wl"]; b == nil || len(b.Items) != 1 || b.Items[0].Txs != 2 {
+		t.Fatalf("balances = %+v", b)
+	}
+
+	// unchanged balances do not produce events
+	wls.refreshBalances()
+	if e := events(); len(e) != 0 {
+		t.Errorf("events of unchanged watchlist = %+v, want none", e)
+	}
+
+	// the added item is reported as changed
+	store("mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw", dbtestdata.Xpub)
+	wls.refreshBalances()
+	e := events()
+	if len(e) != 1 {
+		t.Fatalf("events = %+v, want one event", e)
+	}
+	if !reflect.DeepEqual(e[0].Changed, []string{dbtestdata.Xpub}) {
+		t.Errorf("Changed = %v, want %v", e[0].Changed, []string{dbtestdata.Xpub})
+	}
+	if e[0].Name != "wl" || (*big.Int)(e[0].BalanceSat).String() != "118641975500" {
+		t.Errorf("event = %+v, want balance of wl 118641975500", e[0].WatchlistBalance)
+	}
+}
+
+func Test_InternalServer_watchlistEvents(t *testing.T) {
+	s, dbpath := setupInternalHTTPServer(t)
+	defer closeAndDestroyInternalServer(t, s, dbpath)
+	ts := httptest.NewServer(s.https.Handler)
+	defer ts.Close()
+
+	resp, err := http.DefaultClient.Do(newInternalRequest(t, "GET", ts.URL+"/api/watchlist-events", "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("StatusCode without authorization = %v, want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	resp, err = http.DefaultClient.Do(newInternalRequest(t, "GET", ts.URL+"/api/watchlist-events", "Bearer "+testInternalAPIKey, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("StatusCode = %v, Content-Type = %v", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	// the subscription is registered before the headers are sent
+	s.watchlists.publish(&WatchlistEvent{
+		WatchlistBalance: &api.WatchlistBalance{Name: "stream", Height: 225494},
+		Changed:          []string{"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"},
+	})
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < 2 {
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream closed, received %q", got)
+			}
+			if l != "" {
+				got = append(got, l)
+			}
+		case <-timeout:
+			t.Fatalf("timeout, received %q", got)
+		}
+	}
+	want := []string{
+		"event: watchlist",
+		`data: {"name":"stream","height":225494,"balance":null,"unconfirmedBalance":null,"items":null,"changed":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"]}`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stream = %q, want %q", got, want)
+	}
+}
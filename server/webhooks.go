@@ -0,0 +1,531 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain"
+	"blockbook/common"
+	"blockbook/db"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	webhookDeliveryTimeout = 10 * time.Second
+	webhookMinBackoff      = 5 * time.Second
+	webhookMaxBackoff      = time.Hour
+	// the delivery is dropped after webhookMaxAttempts failed attempts
+	webhookMaxAttempts = 20
+)
+
+// webhookEnvelope is the body of the webhook request
+type webhookEnvelope struct {
+	ID      uint64          `json:"id"`
+	Webhook string          `json:"webhook"`
+	Event   string          `json:"event"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type webhookTxEvent struct {
+	Address string `json:"address"`
+	Xpub    string `json:"xpub,omitempty"`
+	Txid    string `json:"txid"`
+}
+
+type webhookBlockEvent struct {
+	Hash   string `json:"hash"`
+	Height uint32 `json:"height"`
+}
+
+type webhookAddress struct {
+	webhook string
+	address string
+}
+
+// webhookWorker delivers the events of one webhook in the order of the outbox,
+// the delivery of the oldest event is retried until it succeeds or is dropped, the later events wait for it
+type webhookWorker struct {
+	id     string
+	notify chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	exited chan struct{}
+	// backoff of the webhook, accessed only by the worker goroutine
+	failures    int
+	nextAttempt time.Time
+}
+
+type webhooks struct {
+	db          *db.RocksDB
+	chainParser bchain.BlockChainParser
+	metrics     *common.Metrics
+	client      *http.Client
+	backoff     func(failures int) time.Duration
+	// index of the registered webhooks, guarded by indexMux
+	indexMux  sync.RWMutex
+	hooks     map[string]*db.Webhook
+	addresses map[string][]webhookAddress
+	xpubs     map[string][]string
+	blocks    []string
+	// delivery workers by webhook id, guarded by workersMux
+	workersMux sync.Mutex
+	workers    map[string]*webhookWorker
+	// ids of the outbox entries, guarded by outboxMux
+	outboxMux sync.Mutex
+	lastID    uint64
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func newWebhooks(d *db.RocksDB, parser bchain.BlockChainParser, metrics *common.Metrics) (*webhooks, error) {
+	return newWebhooksWithBackoff(d, parser, metrics, webhookBackoff)
+}
+
+func newWebhooksWithBackoff(d *db.RocksDB, parser bchain.BlockChainParser, metrics *common.Metrics, backoff func(int) time.Duration) (*webhooks, error) {
+	lastID, err := d.GetLastWebhookDeliveryID()
+	if err != nil {
+		return nil, err
+	}
+	whs := &webhooks{
+		db:          d,
+		chainParser: parser,
+		metrics:     metrics,
+		client:      &http.Client{Timeout: webhookDeliveryTimeout},
+		backoff:     backoff,
+		workers:     make(map[string]*webhookWorker),
+		lastID:      lastID,
+	}
+	whs.ctx, whs.cancel = context.WithCancel(context.Background())
+	if err = whs.reload(); err != nil {
+		whs.close()
+		return nil, err
+	}
+	return whs, nil
+}
+
+// close stops all delivery workers, the in-flight requests are cancelled
+func (whs *webhooks) close() {
+	whs.cancel()
+}
+
+// reload rebuilds the index of the registered webhooks from db,
+// starts the workers of new webhooks and stops the workers of removed ones
+func (whs *webhooks) reload() error {
+	list, err := whs.db.GetWebhooks()
+	if err != nil {
+		return err
+	}
+	hooks := make(map[string]*db.Webhook, len(list))
+	addresses := make(map[string][]webhookAddress)
+	xpubs := make(map[string][]string)
+	var blocks []string
+	for i := range list {
+		wh := &list[i]
+		hooks[wh.ID] = wh
+		for _, a := range wh.Addresses {
+			addrDesc, err := whs.chainParser.GetAddrDescFromAddress(a)
+			if err != nil {
+				glog.Warning("webhook ", wh.ID, " address ", a, " error ", err)
+				continue
+			}
+			addresses[string(addrDesc)] = append(addresses[string(addrDesc)], webhookAddress{webhook: wh.ID, address: a})
+		}
+		for _, x := range wh.Xpubs {
+			xpubs[x] = append(xpubs[x], wh.ID)
+		}
+		if wh.Blocks {
+			blocks = append(blocks, wh.ID)
+		}
+	}
+	whs.indexMux.Lock()
+	whs.hooks = hooks
+	whs.addresses = addresses
+	whs.xpubs = xpubs
+	whs.blocks = blocks
+	whs.indexMux.Unlock()
+
+	whs.workersMux.Lock()
+	defer whs.workersMux.Unlock()
+	for id, w := range whs.workers {
+		if _, found := hooks[id]; !found {
+			// wait for the worker so that it does not write to the outbox of the removed webhook
+			w.cancel()
+			<-w.exited
+			delete(whs.workers, id)
+			whs.metrics.WebhookPending.DeleteLabelValues(id)
+			whs.metrics.WebhookDuration.DeleteLabelValues(id)
+			for _, status := range []string{"success", "failure", "dropped"} {
+				whs.metrics.WebhookDeliveries.DeleteLabelValues(id, status)
+			}
+		}
+	}
+	for id := range hooks {
+		if _, found := whs.workers[id]; !found {
+			w := &webhookWorker{
+				id:     id,
+				notify: make(chan struct{}, 1),
+				exited: make(chan struct{}),
+			}
+			w.ctx, w.cancel = context.WithCancel(whs.ctx)
+			// deliver the events left in the outbox
+			w.notify <- struct{}{}
+			whs.workers[id] = w
+			go whs.deliveryLoop(w)
+		}
+	}
+	return nil
+}
+
+func (whs *webhooks) getWebhook(id string) *db.Webhook {
+	whs.indexMux.RLock()
+	defer whs.indexMux.RUnlock()
+	return whs.hooks[id]
+}
+
+// onNewTxAddr stores the events for the webhooks subscribed to the address or to an xpub from which the address was derived
+func (whs *webhooks) onNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
+	whs.indexMux.RLock()
+	targets := whs.addresses[string(desc)]
+	hasXpubs := len(whs.xpubs) > 0
+	whs.indexMux.RUnlock()
+	var deliveries []db.WebhookDelivery
+	add := func(webhook string, e *webhookTxEvent) {
+		data, err := json.Marshal(e)
+		if err != nil {
+			glog.Error("webhook ", webhook, " event error ", err)
+			return
+		}
+		deliveries = append(deliveries, db.WebhookDelivery{Webhook: webhook, Event: "tx", Payload: data})
+	}
+	for _, t := range targets {
+		add(t.webhook, &webhookTxEvent{Address: t.address, Txid: tx.Txid})
+	}
+	if hasXpubs {
+		xpubs, err := whs.db.GetAddressXpubs(desc)
+		if err != nil {
+			glog.Error("webhooks: GetAddressXpubs error ", err)
+		}
+		if len(xpubs) > 0 {
+			var address string
+			if a, _, err := whs.chainParser.GetAddressesFromAddrDesc(desc); err == nil && len(a) == 1 {
+				address = a[0]
+			}
+			whs.indexMux.RLock()
+			for _, x := range xpubs {
+				for _, webhook := range whs.xpubs[x] {
+					add(webhook, &webhookTxEvent{Address: address, Xpub: x, Txid: tx.Txid})
+				}
+			}
+			whs.indexMux.RUnlock()
+		}
+	}
+	whs.enqueue(deliveries)
+}
+
+// onNewBlock stores the events for the webhooks subscribed to blocks
+func (whs *webhooks) onNewBlock(hash string, height uint32) {
+	whs.indexMux.RLock()
+	targets := whs.blocks
+	whs.indexMux.RUnlock()
+	if len(targets) == 0 {
+		return
+	}
+	data, err := json.Marshal(&webhookBlockEvent{Hash: hash, Height: height})
+	if err != nil {
+		glog.Error("webhooks: block event error ", err)
+		return
+	}
+	deliveries := make([]db.WebhookDelivery, len(targets))
+	for i, webhook := range targets {
+		deliveries[i] = db.WebhookDelivery{Webhook: webhook, Event: "block", Payload: data}
+	}
+	whs.enqueue(deliveries)
+}
+
+// enqueue stores the deliveries to the outbox so that they survive restart and wakes up the workers of the webhooks
+func (whs *webhooks) enqueue(deliveries []db.WebhookDelivery) {
+	if len(deliveries) == 0 {
+		return
+	}
+	whs.outboxMux.Lock()
+	for i := range deliveries {
+		whs.lastID++
+		deliveries[i].ID = whs.lastID
+	}
+	err := whs.db.StoreWebhookDeliveries(deliveries)
+	whs.outboxMux.Unlock()
+	if err != nil {
+		glog.Error("webhooks: StoreWebhookDeliveries error ", err)
+		return
+	}
+	whs.workersMux.Lock()
+	for i := range deliveries {
+		if w := whs.workers[deliveries[i].Webhook]; w != nil {
+			select {
+			case w.notify <- struct{}{}:
+			default:
+			}
+		}
+	}
+	whs.workersMux.Unlock()
+}
+
+func (whs *webhooks) deliveryLoop(w *webhookWorker) {
+	defer close(w.exited)
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.notify:
+		case <-timer.C:
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if wait := whs.deliverPending(w); wait > 0 {
+			timer.Reset(wait)
+		}
+	}
+}
+
+// webhookBackoff returns the delay before the next attempt of the delivery to the webhook, which failed failures times in a row
+func webhookBackoff(failures int) time.Duration {
+	d := webhookMinBackoff
+	for i := 1; i < failures && d < webhookMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > webhookMaxBackoff {
+		d = webhookMaxBackoff
+	}
+	return d
+}
+
+// deliverPending delivers the events of the webhook from the oldest one and stops at the first failed delivery,
+// it returns the time to wait before the next attempt or 0 if the outbox of the webhook is empty
+func (whs *webhooks) deliverPending(w *webhookWorker) time.Duration {
+	defer whs.updatePending(w.id)
+	for {
+		if w.ctx.Err() != nil {
+			return 0
+		}
+		wh := whs.getWebhook(w.id)
+		if wh == nil {
+			return 0
+		}
+		deliveries, err := whs.db.GetWebhookDeliveries(w.id, 1)
+		if err != nil {
+			glog.Error("webhooks: GetWebhookDeliveries error ", err)
+			return webhookMinBackoff
+		}
+		if len(deliveries) == 0 {
+			return 0
+		}
+		wd := &deliveries[0]
+		// after restart continue with the backoff stored with the oldest delivery
+		if w.failures == 0 && wd.Attempts > 0 {
+			w.failures = wd.Attempts
+			w.nextAttempt = time.Unix(wd.NextAttempt, 0)
+		}
+		if wait := time.Until(w.nextAttempt); wait > 0 {
+			return wait
+		}
+		err = whs.deliver(w.ctx, wh, wd)
+		if w.ctx.Err() != nil {
+			// the worker was stopped during the delivery, the attempt is not counted
+			return 0
+		}
+		if err == nil {
+			whs.metrics.WebhookDeliveries.With(common.Labels{"webhook": wh.ID, "status": "success"}).Inc()
+			w.failures = 0
+			w.nextAttempt = time.Time{}
+			err = whs.db.DeleteWebhookDelivery(w.id, wd.ID)
+		} else {
+			whs.metrics.WebhookDeliveries.With(common.Labels{"webhook": wh.ID, "status": "failure"}).Inc()
+			w.failures++
+			w.nextAttempt = time.Now().Add(whs.backoff(w.failures))
+			wd.Attempts++
+			if wd.Attempts >= webhookMaxAttempts {
+				glog.Error("webhook ", wh.ID, " delivery ", wd.ID, " dropped after ", wd.Attempts, " attempts, last error ", err)
+				whs.metrics.WebhookDeliveries.With(common.Labels{"webhook": wh.ID, "status": "dropped"}).Inc()
+				err = whs.db.DeleteWebhookDelivery(w.id, wd.ID)
+			} else {
+				glog.Warning("webhook ", wh.ID, " delivery ", wd.ID, " attempt ", wd.Attempts, " error ", err)
+				wd.LastError = err.Error()
+				wd.NextAttempt = w.nextAttempt.Unix()
+				err = whs.db.StoreWebhookDeliveries([]db.WebhookDelivery{*wd})
+			}
+			if err != nil {
+				glog.Error("webhooks: outbox update error ", err)
+			}
+			// the webhook keeps its backoff also when the failed delivery was dropped
+			return whs.backoff(w.failures)
+		}
+		if err != nil {
+			glog.Error("webhooks: outbox update error ", err)
+			return webhookMinBackoff
+		}
+	}
+}
+
+func (whs *webhooks) updatePending(webhook string) {
+	n, err := whs.db.CountWebhookDeliveries(webhook)
+	if err != nil {
+		glog.Error("webhooks: CountWebhookDeliveries error ", err)
+		return
+	}
+	whs.metrics.WebhookPending.With(common.Labels{"webhook": webhook}).Set(float64(n))
+}
+
+// webhookSignature returns hex encoded HMAC-SHA256 of the body keyed by the secret of the webhook
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (whs *webhooks) deliver(ctx context.Context, wh *db.Webhook, wd *db.WebhookDelivery) error {
+	body, err := json.Marshal(&webhookEnvelope{ID: wd.ID, Webhook: wh.ID, Event: wd.Event, Data: wd.Payload})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Blockbook-Event", wd.Event)
+	req.Header.Set("X-Blockbook-Delivery", strconv.FormatUint(wd.ID, 10))
+	req.Header.Set("X-Blockbook-Signature", "sha256="+webhookSignature(wh.Secret, body))
+	start := time.Now()
+	resp, err := whs.client.Do(req)
+	whs.metrics.WebhookDuration.With(common.Labels{"webhook": wh.ID}).Observe(float64(time.Since(start)) / 1e6) // in milliseconds
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP status %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// withoutSecret returns copy of the webhook without the secret, the secret is returned only when it is generated by the server
+func withoutSecret(wh *db.Webhook) *db.Webhook {
+	c := *wh
+	c.Secret = ""
+	return &c
+}
+
+func (s *InternalServer) apiWebhooks(r *http.Request) (interface{}, error) {
+	list, err := s.db.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Secret = ""
+	}
+	return list, nil
+}
+
+// apiWebhook returns the webhook (GET), creates or replaces it (PUT, POST) or deletes it (DELETE),
+// if the secret is not specified, the secret of the replaced webhook is kept or a new random secret is generated
+func (s *InternalServer) apiWebhook(r *http.Request) (interface{}, error) {
+	id := getPathParamAfter(r, "/webhooks/")
+	if id == "" {
+		return nil, api.NewAPIError("Missing webhook id", true)
+	}
+	switch r.Method {
+	case http.MethodGet:
+		wh, err := s.db.GetWebhook(id)
+		if err != nil {
+			return nil, err
+		}
+		if wh == nil {
+			return nil, api.NewAPIError(fmt.Sprintf("Webhook '%v' not found", id), true)
+		}
+		return withoutSecret(wh), nil
+	case http.MethodPut, http.MethodPost:
+		var wh db.Webhook
+		if err := json.NewDecoder(r.Body).Decode(&wh); err != nil {
+			return nil, api.NewAPIError("Invalid webhook, "+err.Error(), true)
+		}
+		// the id in the url has precedence
+		wh.ID = id
+		generated := false
+		if wh.Secret == "" {
+			old, err := s.db.GetWebhook(id)
+			if err != nil {
+				return nil, err
+			}
+			if old != nil {
+				wh.Secret = old.Secret
+			} else {
+				buf := make([]byte, 32)
+				if _, err = rand.Read(buf); err != nil {
+					return nil, err
+				}
+				wh.Secret = hex.EncodeToString(buf)
+				generated = true
+			}
+		}
+		if err := s.api.ValidateWebhook(&wh); err != nil {
+			return nil, err
+		}
+		// derive and persist the xpub addresses, their transactions are matched using the stored xpub state
+		for _, x := range wh.Xpubs {
+			if _, err := s.api.GetXpubAddress(x, 1, 1, api.AccountDetailsBasic, &api.AddressFilter{Vout: api.AddressFilterVoutOff}, 0); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.db.StoreWebhook(&wh); err != nil {
+			return nil, err
+		}
+		// the state is persisted only for the xpubs of stored webhooks
+		for _, x := range wh.Xpubs {
+			if err := s.api.PersistXpubState(x); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.webhooks.reload(); err != nil {
+			return nil, err
+		}
+		if generated {
+			return &wh, nil
+		}
+		return withoutSecret(&wh), nil
+	case http.MethodDelete:
+		if err := s.db.DeleteWebhook(id); err != nil {
+			return nil, err
+		}
+		if err := s.webhooks.reload(); err != nil {
+			return nil, err
+		}
+		return struct {
+			Result string `json:"result"`
+		}{Result: "deleted"}, nil
+	}
+	return nil, api.NewAPIError("Unsupported method "+r.Method, true)
+}
@@ -0,0 +1,183 @@
+// +build unittest
+
+package server
+
+import (
+	"blockbook/bchain/coins/btc"
+	"blockbook/db"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_webhookSignature(t *testing.T) {
+	// HMAC-SHA256 test case 2 of RFC 4231
+	got := webhookSignature("Jefe", []byte("what do ya want for nothing?"))
+	want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got != want {
+		t.Errorf("webhookSignature() = %v, want %v", got, want)
+	}
+}
+
+func Test_webhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{5, 80 * time.Second},
+		{10, 2560 * time.Second},
+		{11, time.Hour},
+		{19, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%v) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// webhookEndpoint records the heights of the received block events
+type webhookEndpoint struct {
+	t       *testing.T
+	secret  string
+	mux     sync.Mutex
+	heights []uint32
+	// respond returns the status of the n-th request (from 1)
+	respond func(n int) int
+}
+
+func (e *webhookEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		e.t.Error(err)
+		return
+	}
+	if got, want := r.Header.Get("X-Blockbook-Signature"), "sha256="+webhookSignature(e.secret, body); got != want {
+		e.t.Errorf("X-Blockbook-Signature = %v, want %v", got, want)
+	}
+	var envelope webhookEnvelope
+	var event webhookBlockEvent
+	if err = json.Unmarshal(body, &envelope); err == nil {
+		err = json.Unmarshal(envelope.Data, &event)
+	}
+	if err != nil {
+		e.t.Error(err)
+		return
+	}
+	e.mux.Lock()
+	e.heights = append(e.heights, event.Height)
+	n := len(e.heights)
+	e.mux.Unlock()
+	w.WriteHeader(e.respond(n))
+}
+
+func (e *webhookEndpoint) received() []uint32 {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	return append([]uint32{}, e.heights...)
+}
+
+func waitForCondition(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for ", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func Test_webhooks_delivery(t *testing.T) {
+	parser := btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 1})
+	d, _, path := setupRocksDB(t, parser)
+	defer func() {
+		d.Close()
+		os.RemoveAll(path)
+	}()
+
+	// the first request to the endpoint a waits for release, the first two requests fail
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	defer releaseOnce.Do(func() { close(release) })
+	a := &webhookEndpoint{t: t, secret: "secret-a", respond: func(n int) int {
+		if n == 1 {
+			<-release
+		}
+		if n <= 2 {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	}}
+	tsA := httptest.NewServer(a)
+	defer tsA.Close()
+	b := &webhookEndpoint{t: t, secret: "secret-b", respond: func(int) int { return http.StatusOK }}
+	tsB := httptest.NewServer(b)
+	defer tsB.Close()
+
+	for _, wh := range []db.Webhook{
+		{ID: "test-a", URL: tsA.URL, Secret: a.secret, Blocks: true},
+		{ID: "test-b", URL: tsB.URL, Secret: b.secret, Blocks: true},
+	} {
+		if err := d.StoreWebhook(&wh); err != nil {
+			t.Fatal(err)
+		}
+	}
+	whs, err := newWebhooksWithBackoff(d, parser, getInternalTestMetrics(t), func(int) time.Duration { return 10 * time.Millisecond })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer whs.close()
+	for h := uint32(1); h <= 3; h++ {
+		whs.onNewBlock(strconv.Itoa(int(h)), h)
+	}
+
+	// the blocked webhook does not hold up the other one
+	waitForCondition(t, "deliveries to test-b", func() bool { return len(b.received()) == 3 })
+	if got, want := b.received(), []uint32{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("test-b received %v, want %v", got, want)
+	}
+	if got, want := a.received(), []uint32{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("test-a received %v before release, want %v", got, want)
+	}
+
+	// the failed event is retried and the later events wait for it
+	releaseOnce.Do(func() { close(release) })
+	waitForCondition(t, "deliveries to test-a", func() bool { return len(a.received()) == 5 })
+	if got, want := a.received(), []uint32{1, 1, 1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("test-a received %v, want %v", got, want)
+	}
+	for _, id := range []string{"test-a", "test-b"} {
+		waitForCondition(t, "empty outbox of "+id, func() bool {
+			n, err := d.CountWebhookDeliveries(id)
+			return err == nil && n == 0
+		})
+	}
+
+	// a removed webhook loses its worker and its pending events
+	whs.onNewBlock("4", 4)
+	if err := d.DeleteWebhook("test-b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := whs.reload(); err != nil {
+		t.Fatal(err)
+	}
+	whs.workersMux.Lock()
+	_, found := whs.workers["test-b"]
+	whs.workersMux.Unlock()
+	if found {
+		t.Error("worker of the deleted webhook test-b is running")
+	}
+	if n, err := d.CountWebhookDeliveries("test-b"); err != nil || n != 0 {
+		t.Errorf("CountWebhookDeliveries(test-b) = %v, %v, want 0", n, err)
+	}
+	waitForCondition(t, "delivery of height 4 to test-a", func() bool { return len(a.received()) == 6 })
+}
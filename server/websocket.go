@@ -5,11 +5,13 @@ import (
 	"blockbook/bchain"
 	"blockbook/common"
 	"blockbook/db"
+	"encoding/hex"
 	"encoding/json"
 	"math/big"
 	"net/http"
 	"runtime/debug"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -53,21 +55,33 @@ type websocketChannel struct {
 
 // WebsocketServer is a handle to websocket server
 type WebsocketServer struct {
-	socket                    *websocket.Conn
-	upgrader                  *websocket.Upgrader
-	db                        *db.RocksDB
-	txCache                   *db.TxCache
-	chain                     bchain.BlockChain
-	chainParser               bchain.BlockChainParser
-	mempool                   bchain.Mempool
-	metrics                   *common.Metrics
-	is                        *common.InternalState
-	api                       *api.Worker
-	block0hash                string
-	newBlockSubscriptions     map[*websocketChannel]string
-	newBlockSubscriptionsLock sync.Mutex
-	addressSubscriptions      map[string]map[*websocketChannel]string
-	addressSubscriptionsLock  sync.Mutex
+	socket                        *websocket.Conn
+	upgrader                      *websocket.Upgrader
+	db                            *db.RocksDB
+	txCache                       *db.TxCache
+	chain                         bchain.BlockChain
+	chainParser                   bchain.BlockChainParser
+	mempool                       bchain.Mempool
+	metrics                       *common.Metrics
+	is                            *common.InternalState
+	api                           *api.Worker
+	block0hash                    string
+	newBlockSubscriptions         map[*websocketChannel]string
+	newBlockSubscriptionsLock     sync.Mutex
+	headersSubscriptions          map[*websocketChannel]string
+	headersSubscriptionsLock      sync.Mutex
+	addressSubscriptions          map[string]map[*websocketChannel]string
+	addressSubscriptionsLock      sync.Mutex
+	mempoolStatsSubscriptions     map[*websocketChannel]string
+	mempoolStatsSubscriptionsLock sync.Mutex
+	fiatRatesSubscriptions        map[*websocketChannel]*fiatRatesSubscription
+	fiatRatesSubscriptionsLock    sync.Mutex
+}
+
+// fiatRatesSubscription is the request id and the currencies (all if empty) of a subscription to fiat rates
+type fiatRatesSubscription struct {
+	id         string
+	currencies []string
 }
 
 // NewWebsocketServer creates new websocket interface to blockbook and returns its handle
@@ -86,17 +100,20 @@ func NewWebsocketServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.
 			WriteBufferSize: 1024 * 32,
 			CheckOrigin:     checkOrigin,
 		},
-		db:                    db,
-		txCache:               txCache,
-		chain:                 chain,
-		chainParser:           chain.GetChainParser(),
-		mempool:               mempool,
-		metrics:               metrics,
-		is:                    is,
-		api:                   api,
-		block0hash:            b0,
-		newBlockSubscriptions: make(map[*websocketChannel]string),
-		addressSubscriptions:  make(map[string]map[*websocketChannel]string),
+		db:                        db,
+		txCache:                   txCache,
+		chain:                     chain,
+		chainParser:               chain.GetChainParser(),
+		mempool:                   mempool,
+		metrics:                   metrics,
+		is:                        is,
+		api:                       api,
+		block0hash:                b0,
+		newBlockSubscriptions:     make(map[*websocketChannel]string),
+		headersSubscriptions:      make(map[*websocketChannel]string),
+		addressSubscriptions:      make(map[string]map[*websocketChannel]string),
+		mempoolStatsSubscriptions: make(map[*websocketChannel]string),
+		fiatRatesSubscriptions:    make(map[*websocketChannel]*fiatRatesSubscription),
 	}
 	return s, nil
 }
@@ -213,7 +230,10 @@ func (s *WebsocketServer) onConnect(c *websocketChannel) {
 
 func (s *WebsocketServer) onDisconnect(c *websocketChannel) {
 	s.unsubscribeNewBlock(c)
+	s.unsubscribeHeaders(c)
 	s.unsubscribeAddresses(c)
+	s.unsubscribeMempoolStats(c)
+	s.unsubscribeFiatRates(c)
 	glog.Info("Client disconnected ", c.id, ", ", c.ip)
 	s.metrics.WebsocketClients.Dec()
 }
@@ -226,6 +246,27 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *webs
 		}
 		return
 	},
+	"getAddressesInfo": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		r, err := unmarshalGetAccountInfoRequest(req.Params)
+		if err == nil {
+			if len(r.Addresses) == 0 {
+				return nil, api.NewAPIError("Missing address", true)
+			}
+			rv, err = s.getAccountInfo(r)
+		}
+		return
+	},
+	"getAddressesUtxo": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		r := struct {
+			Addresses []string `json:"addresses"`
+			Confirmed bool     `json:"confirmed"`
+		}{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.api.GetAddressesUtxo(r.Addresses, r.Confirmed)
+		}
+		return
+	},
 	"getInfo": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
 		return s.getInfo()
 	},
@@ -259,6 +300,16 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *webs
 		}
 		return
 	},
+	"getTransactions": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		r := struct {
+			Txids []string `json:"txids"`
+		}{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.api.GetTransactions(r.Txids, false)
+		}
+		return
+	},
 	"getTransactionSpecific": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
 		r := struct {
 			Txid string `json:"txid"`
@@ -288,6 +339,26 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *webs
 	"unsubscribeNewBlock": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
 		return s.unsubscribeNewBlock(c)
 	},
+	"subscribeHeaders": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		return s.subscribeHeaders(c, req)
+	},
+	"unsubscribeHeaders": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		return s.unsubscribeHeaders(c)
+	},
+	"getBlockHeaders": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		r := struct {
+			Start uint32 `json:"start"`
+			Count int    `json:"count"`
+		}{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			if r.Count == 0 {
+				r.Count = api.MaxBlockHeaders
+			}
+			rv, err = s.api.GetBlockHeaders(r.Start, r.Count)
+		}
+		return
+	},
 	"subscribeAddresses": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
 		ad, err := s.unmarshalAddresses(req.Params)
 		if err == nil {
@@ -298,6 +369,30 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *webs
 	"unsubscribeAddresses": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
 		return s.unsubscribeAddresses(c)
 	},
+	"getMempoolStats": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		return s.api.GetMempoolStats()
+	},
+	"subscribeMempoolStats": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		return s.subscribeMempoolStats(c, req)
+	},
+	"unsubscribeMempoolStats": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		return s.unsubscribeMempoolStats(c)
+	},
+	"subscribeFiatRates": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		r := struct {
+			Currencies []string `json:"currencies"`
+		}{}
+		if len(req.Params) > 0 {
+			err = json.Unmarshal(req.Params, &r)
+		}
+		if err == nil {
+			rv, err = s.subscribeFiatRates(c, lowerCurrencies(r.Currencies), req)
+		}
+		return
+	},
+	"unsubscribeFiatRates": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		return s.unsubscribeFiatRates(c)
+	},
 }
 
 func (s *WebsocketServer) onRequest(c *websocketChannel, req *websocketReq) {
@@ -340,14 +435,16 @@ func (s *WebsocketServer) onRequest(c *websocketChannel, req *websocketReq) {
 }
 
 type accountInfoReq struct {
-	Descriptor     string `json:"descriptor"`
-	Details        string `json:"details"`
-	Tokens         string `json:"tokens"`
-	PageSize       int    `json:"pageSize"`
-	Page           int    `json:"page"`
-	FromHeight     int    `json:"from"`
-	ToHeight       int    `json:"to"`
-	ContractFilter string `json:"contractFilter"`
+	Descriptor     string   `json:"descriptor"`
+	Addresses      []string `json:"addresses"`
+	Details        string   `json:"details"`
+	Tokens         string   `json:"tokens"`
+	PageSize       int      `json:"pageSize"`
+	Page           int      `json:"page"`
+	FromHeight     int      `json:"from"`
+	ToHeight       int      `json:"to"`
+	ContractFilter string   `json:"contractFilter"`
+	Cursor         string   `json:"cursor"`
 }
 
 func unmarshalGetAccountInfoRequest(params []byte) (*accountInfoReq, error) {
@@ -388,10 +485,14 @@ func (s *WebsocketServer) getAccountInfo(req *accountInfoReq) (res *api.Address,
 		Contract:       req.ContractFilter,
 		Vout:           api.AddressFilterVoutOff,
 		TokensToReturn: tokensToReturn,
+		Cursor:         req.Cursor,
 	}
 	if req.PageSize == 0 {
 		req.PageSize = txsOnPage
 	}
+	if len(req.Addresses) > 0 {
+		return s.api.GetAddresses(req.Addresses, req.Page, req.PageSize, opt, &filter)
+	}
 	a, err := s.api.GetXpubAddress(req.Descriptor, req.Page, req.PageSize, opt, &filter, 0)
 	if err != nil {
 		return s.api.GetAddress(req.Descriptor, req.Page, req.PageSize, opt, &filter)
@@ -549,6 +650,23 @@ func (s *WebsocketServer) unsubscribeNewBlock(c *websocketChannel) (res interfac
 	return &subscriptionResponse{false}, nil
 }
 
+func (s *WebsocketServer) subscribeHeaders(c *websocketChannel, req *websocketReq) (res interface{}, err error) {
+	if s.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return nil, errors.New("Not supported")
+	}
+	s.headersSubscriptionsLock.Lock()
+	defer s.headersSubscriptionsLock.Unlock()
+	s.headersSubscriptions[c] = req.ID
+	return &subscriptionResponse{true}, nil
+}
+
+func (s *WebsocketServer) unsubscribeHeaders(c *websocketChannel) (res interface{}, err error) {
+	s.headersSubscriptionsLock.Lock()
+	defer s.headersSubscriptionsLock.Unlock()
+	delete(s.headersSubscriptions, c)
+	return &subscriptionResponse{false}, nil
+}
+
 func (s *WebsocketServer) unmarshalAddresses(params []byte) ([]bchain.AddressDescriptor, error) {
 	r := struct {
 		Addresses []string `json:"addresses"`
@@ -599,6 +717,41 @@ func (s *WebsocketServer) unsubscribeAddresses(c *websocketChannel) (res interfa
 	return &subscriptionResponse{false}, nil
 }
 
+func (s *WebsocketServer) subscribeMempoolStats(c *websocketChannel, req *websocketReq) (res interface{}, err error) {
+	s.mempoolStatsSubscriptionsLock.Lock()
+	defer s.mempoolStatsSubscriptionsLock.Unlock()
+	s.mempoolStatsSubscriptions[c] = req.ID
+	return &subscriptionResponse{true}, nil
+}
+
+func (s *WebsocketServer) unsubscribeMempoolStats(c *websocketChannel) (res interface{}, err error) {
+	s.mempoolStatsSubscriptionsLock.Lock()
+	defer s.mempoolStatsSubscriptionsLock.Unlock()
+	delete(s.mempoolStatsSubscriptions, c)
+	return &subscriptionResponse{false}, nil
+}
+
+func lowerCurrencies(currencies []string) []string {
+	for i := range currencies {
+		currencies[i] = strings.ToLower(currencies[i])
+	}
+	return currencies
+}
+
+func (s *WebsocketServer) subscribeFiatRates(c *websocketChannel, currencies []string, req *websocketReq) (res interface{}, err error) {
+	s.fiatRatesSubscriptionsLock.Lock()
+	defer s.fiatRatesSubscriptionsLock.Unlock()
+	s.fiatRatesSubscriptions[c] = &fiatRatesSubscription{id: req.ID, currencies: currencies}
+	return &subscriptionResponse{true}, nil
+}
+
+func (s *WebsocketServer) unsubscribeFiatRates(c *websocketChannel) (res interface{}, err error) {
+	s.fiatRatesSubscriptionsLock.Lock()
+	defer s.fiatRatesSubscriptionsLock.Unlock()
+	delete(s.fiatRatesSubscriptions, c)
+	return &subscriptionResponse{false}, nil
+}
+
 // OnNewBlock is a callback that broadcasts info about new block to subscribed clients
 func (s *WebsocketServer) OnNewBlock(hash string, height uint32) {
 	s.newBlockSubscriptionsLock.Lock()
@@ -619,6 +772,39 @@ func (s *WebsocketServer) OnNewBlock(hash string, height uint32) {
 		}
 	}
 	glog.Info("broadcasting new block ", height, " ", hash, " to ", len(s.newBlockSubscriptions), " channels")
+	s.onNewBlockHeader(hash, height)
+}
+
+// onNewBlockHeader broadcasts the raw header of the new block to the clients subscribed to headers
+func (s *WebsocketServer) onNewBlockHeader(hash string, height uint32) {
+	s.headersSubscriptionsLock.Lock()
+	defer s.headersSubscriptionsLock.Unlock()
+	if len(s.headersSubscriptions) == 0 {
+		return
+	}
+	header, err := s.api.GetBlockHeaderRaw(height)
+	if err != nil {
+		glog.Error("GetBlockHeaderRaw error ", err, " for ", height)
+		return
+	}
+	data := struct {
+		Height uint32 `json:"height"`
+		Hash   string `json:"hash"`
+		Header string `json:"header"`
+	}{
+		Height: height,
+		Hash:   hash,
+		Header: hex.EncodeToString(header),
+	}
+	for c, id := range s.headersSubscriptions {
+		if c.IsAlive() {
+			c.out <- &websocketRes{
+				ID:   id,
+				Data: &data,
+			}
+		}
+	}
+	glog.Info("broadcasting new block header ", height, " ", hash, " to ", len(s.headersSubscriptions), " channels")
 }
 
 // OnNewTxAddr is a callback that broadcasts info about a tx affecting subscribed address
@@ -664,3 +850,82 @@ func (s *WebsocketServer) OnNewTxAddr(tx *bchain.Tx, addrDesc bchain.AddressDesc
 		}
 	}
 }
+
+// OnTxReplaced is a callback that broadcasts info about a replaced mempool tx affecting subscribed address
+func (s *WebsocketServer) OnTxReplaced(txid string, replacedBy string, rbf bool, addrDesc bchain.AddressDescriptor) {
+	s.addressSubscriptionsLock.Lock()
+	as, ok := s.addressSubscriptions[string(addrDesc)]
+	s.addressSubscriptionsLock.Unlock()
+	if ok && len(as) > 0 {
+		addr, _, err := s.chainParser.GetAddressesFromAddrDesc(addrDesc)
+		if err != nil {
+			glog.Error("GetAddressesFromAddrDesc error ", err, " for ", addrDesc)
+			return
+		}
+		if len(addr) == 1 {
+			data := struct {
+				Address    string `json:"address"`
+				Txid       string `json:"txid"`
+				ReplacedBy string `json:"replacedBy"`
+				Rbf        bool   `json:"rbf"`
+			}{
+				Address:    addr[0],
+				Txid:       txid,
+				ReplacedBy: replacedBy,
+				Rbf:        rbf,
+			}
+			s.addressSubscriptionsLock.Lock()
+			defer s.addressSubscriptionsLock.Unlock()
+			as, ok = s.addressSubscriptions[string(addrDesc)]
+			if ok {
+				for c, id := range as {
+					if c.IsAlive() {
+						c.out <- &websocketRes{
+							ID:   id,
+							Data: &data,
+						}
+					}
+				}
+				glog.Info("broadcasting replaced tx ", txid, " by ", replacedBy, " for addr ", addr[0], " to ", len(as), " channels")
+			}
+		}
+	}
+}
+
+// OnMempoolResync is a callback that broadcasts mempool statistics to subscribed clients
+func (s *WebsocketServer) OnMempoolResync() {
+	s.mempoolStatsSubscriptionsLock.Lock()
+	defer s.mempoolStatsSubscriptionsLock.Unlock()
+	if len(s.mempoolStatsSubscriptions) == 0 {
+		return
+	}
+	stats, err := s.api.GetMempoolStats()
+	if err != nil {
+		glog.Error("GetMempoolStats error ", err)
+		return
+	}
+	for c, id := range s.mempoolStatsSubscriptions {
+		if c.IsAlive() {
+			c.out <- &websocketRes{
+				ID:   id,
+				Data: stats,
+			}
+		}
+	}
+	glog.Info("broadcasting mempool stats to ", len(s.mempoolStatsSubscriptions), " channels")
+}
+
+// OnNewFiatRatesTicker is a callback that broadcasts the current fiat rates in the subscribed currencies to subscribed clients
+func (s *WebsocketServer) OnNewFiatRatesTicker(ticker *db.CurrencyRatesTicker) {
+	s.fiatRatesSubscriptionsLock.Lock()
+	defer s.fiatRatesSubscriptionsLock.Unlock()
+	for c, fs := range s.fiatRatesSubscriptions {
+		if c.IsAlive() {
+			c.out <- &websocketRes{
+				ID:   fs.id,
+				Data: api.NewFiatTicker(ticker, fs.currencies),
+			}
+		}
+	}
+	glog.Info("broadcasting fiat rates to ", len(s.fiatRatesSubscriptions), " channels")
+}
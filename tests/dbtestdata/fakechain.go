@@ -18,14 +18,14 @@ func NewFakeBlockChain(parser bchain.BlockChainParser) (bchain.BlockChain, error
 }
 
 func (b *fakeBlockChain) CreateMempool(chain bchain.BlockChain) (bchain.Mempool, error) {
-	return bchain.NewMempoolBitcoinType(chain, 1, 1), nil
+	return bchain.NewMempoolBitcoinType(chain, 1, 1, 0, 0, 0), nil
 }
 
 func (c *fakeBlockChain) Initialize() error {
 	return nil
 }
 
-func (c *fakeBlockChain) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc) error {
+func (c *fakeBlockChain) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOutpointFunc, onNewTxAddr bchain.OnNewTxAddrFunc, onTxReplaced bchain.OnTxReplacedFunc) error {
 	return nil
 }
 
@@ -92,6 +92,22 @@ func (c *fakeBlockChain) GetBlockHeader(hash string) (v *bchain.BlockHeader, err
 	return nil, bchain.ErrBlockNotFound
 }
 
+// fake raw block headers of the test blocks
+const (
+	TestBlockHeader1 = "0100000001"
+	TestBlockHeader2 = "0100000002"
+)
+
+func (c *fakeBlockChain) GetBlockHeaderRaw(hash string) (v string, err error) {
+	if hash == GetTestBitcoinTypeBlock1(c.Parser).BlockHeader.Hash {
+		return TestBlockHeader1, nil
+	}
+	if hash == GetTestBitcoinTypeBlock2(c.Parser).BlockHeader.Hash {
+		return TestBlockHeader2, nil
+	}
+	return "", bchain.ErrBlockNotFound
+}
+
 func (c *fakeBlockChain) GetBlock(hash string, height uint32) (v *bchain.Block, err error) {
 	b1 := GetTestBitcoinTypeBlock1(c.Parser)
 	if hash == b1.BlockHeader.Hash || height == b1.BlockHeader.Height {